@@ -17,6 +17,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -29,6 +30,7 @@ import (
 	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
 	"github.com/joshuar/go-hass-agent/internal/logging"
 	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/recorder"
 )
 
 const (
@@ -68,7 +70,10 @@ type Agent struct {
 	done          chan struct{}
 	prefs         *preferences.Preferences
 	logger        *slog.Logger
+	recorder      *recorder.Recorder
 	id            string
+	pipeline      atomic.Pointer[sensorPipeline]
+	mqttConnected atomic.Bool
 	headless      bool
 	forceRegister bool
 }
@@ -96,6 +101,7 @@ func NewAgent(ctx context.Context, id string, options ...Option) (*Agent, error)
 	}
 
 	agent.prefs = prefs
+	agent.warnUnknownWorkers()
 
 	for _, option := range options {
 		option(agent)
@@ -128,6 +134,14 @@ func WithRegistrationInfo(server, token string, ignoreURLs bool) Option {
 	}
 }
 
+// WithRecorder will record all sensor updates of the running agent with the
+// given recorder.
+func WithRecorder(rec *recorder.Recorder) Option {
+	return func(a *Agent) {
+		a.recorder = rec
+	}
+}
+
 // ForceRegister will force the agent to register against Home Assistant,
 // regardless of whether it is already registered. Only used when the Register
 // command is run.
@@ -148,10 +162,22 @@ func (agent *Agent) Run(ctx context.Context, trk Tracker, reg Registry) error {
 		regWait sync.WaitGroup
 	)
 
-	agent.hass = hass.NewClient(ctx, trk, reg)
+	servers := newServerClients(hass.NewClient(ctx, trk, reg))
+	agent.hass = servers
+	agent.applySensorFilter()
 
 	agent.handleSignals()
 
+	// Closed once registration has been checked and the agent has connected.
+	connected := make(chan struct{})
+
+	wg.Add(1)
+	// Tell systemd the agent has started and keep it updated.
+	go func() {
+		defer wg.Done()
+		agent.runSystemdNotifier(ctx, connected)
+	}()
+
 	regWait.Add(1)
 
 	go func() {
@@ -160,7 +186,12 @@ func (agent *Agent) Run(ctx context.Context, trk Tracker, reg Registry) error {
 		if err := agent.checkRegistration(ctx, trk); err != nil {
 			agent.logger.Log(ctx, logging.LevelFatal, "Error checking registration status.", slog.Any("error", err))
 			close(agent.done)
+
+			return
 		}
+
+		agent.connectServers(ctx, servers)
+		close(connected)
 	}()
 
 	wg.Add(1)
@@ -195,28 +226,69 @@ func (agent *Agent) Run(ctx context.Context, trk Tracker, reg Registry) error {
 			}
 		}
 
+		// Reload requests are buffered so that a pending request is not
+		// lost while a reload is in progress.
+		sensorReloadCh := make(chan struct{}, 1)
+		mqttReloadCh := make(chan struct{}, 1)
+
+		controlBackend := agent.newControlBackend(trk, reg)
+
 		wg.Add(1)
 		// Run workers for any sensor controllers.
 		go func() {
 			defer wg.Done()
-			agent.runSensorWorkers(controllerCtx, sensorControllers...)
+			agent.runSensorWorkers(controllerCtx, sensorReloadCh, controlBackend.requests, sensorControllers...)
 		}()
 
-		if len(mqttControllers) > 0 {
-			wg.Add(1)
-			// Run workers for any MQTT controllers.
-			go func() {
-				defer wg.Done()
-				agent.runMQTTWorkers(controllerCtx, mqttControllers...)
-			}()
-		}
+		wg.Add(1)
+		// Register again if the agent is removed from Home Assistant.
+		go func() {
+			defer wg.Done()
+			agent.runReregistration(controllerCtx, trk, servers, controlBackend)
+		}()
 
 		wg.Add(1)
-		// Listen for notifications from Home Assistant.
+		// Serve the local control API.
 		go func() {
 			defer wg.Done()
-			agent.runNotificationsWorker(controllerCtx)
+			agent.runControlAPI(controllerCtx, controlBackend)
 		}()
+
+		wg.Add(1)
+		// Run workers for any MQTT controllers. This also runs while MQTT is
+		// disabled, so that it can be enabled by a reload.
+		go func() {
+			defer wg.Done()
+			agent.runMQTTWorkers(controllerCtx, mqttReloadCh, mqttControllers...)
+		}()
+
+		wg.Add(1)
+		// Save the sensor state periodically.
+		go func() {
+			defer wg.Done()
+			agent.runStateSaver(controllerCtx, servers, sensor.DefaultSaveInterval)
+		}()
+
+		wg.Add(1)
+		// Reload configuration when it changes.
+		go func() {
+			defer wg.Done()
+			agent.runConfigWatcher(controllerCtx, sensorControllers, sensorReloadCh, mqttReloadCh)
+		}()
+
+		// Listen for notifications from each Home Assistant server.
+		for _, server := range agent.prefs.ActiveServers() {
+			if !server.Registered {
+				continue
+			}
+
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+				agent.runNotificationsWorker(controllerCtx, server)
+			}()
+		}
 	}()
 
 	agent.ui.DisplayTrayIcon(ctx, agent, agent.hass, agent.done)
@@ -224,6 +296,11 @@ func (agent *Agent) Run(ctx context.Context, trk Tracker, reg Registry) error {
 
 	wg.Wait()
 
+	// Save the sensor state, so it is available when the agent next runs.
+	if err := servers.saveState(); err != nil {
+		agent.logger.Warn("Could not save sensor state.", slog.Any("error", err))
+	}
+
 	return nil
 }
 
@@ -297,7 +374,7 @@ func (agent *Agent) GetMQTTPreferences() *preferences.MQTT {
 // part of all agent preferences.
 func (agent *Agent) SaveMQTTPreferences(prefs *preferences.MQTT) error {
 	if agent.prefs != nil {
-		agent.prefs.MQTT = prefs
+		agent.prefs.SetMQTTPreferences(prefs)
 
 		err := agent.prefs.Save()
 		if err != nil {
@@ -316,10 +393,20 @@ func (agent *Agent) GetRestAPIURL() string {
 
 func (agent *Agent) GetRegistryPath() string {
 	if agent != nil {
-		return filepath.Join(xdg.ConfigHome, agent.id, "sensorRegistry")
+		return RegistryPath(agent.id, preferences.DefaultServerName)
+	}
+
+	return RegistryPath(preferences.AppID, preferences.DefaultServerName)
+}
+
+// RegistryPath returns the path of the sensor registry for the server with
+// the given name, of the agent with the given app ID.
+func RegistryPath(appID, server string) string {
+	if server == "" || server == preferences.DefaultServerName {
+		return filepath.Join(xdg.ConfigHome, appID, "sensorRegistry")
 	}
 
-	return filepath.Join(xdg.ConfigHome, preferences.AppID, "sensorRegistry")
+	return filepath.Join(xdg.ConfigHome, appID, "servers", server, "sensorRegistry")
 }
 
 func (agent *Agent) GetPreferencesPath() string {
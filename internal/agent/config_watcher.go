@@ -0,0 +1,263 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/scripts"
+)
+
+// configChangeDelay is how long to wait for further changes after a change to
+// the agent configuration is detected, before acting on it. Editors will
+// often write a file in several steps.
+const configChangeDelay = time.Second
+
+const (
+	preferencesFileName = "preferences.toml"
+	commandsFileName    = "commands.toml"
+)
+
+// configChange records which parts of the agent configuration have changed on
+// disk.
+type configChange struct {
+	prefs    bool
+	commands bool
+	scripts  bool
+}
+
+func (c *configChange) merge(other configChange) {
+	c.prefs = c.prefs || other.prefs
+	c.commands = c.commands || other.commands
+	c.scripts = c.scripts || other.scripts
+}
+
+func (c *configChange) any() bool {
+	return c.prefs || c.commands || c.scripts
+}
+
+// configWatcher uses inotify to watch the agent configuration directory and
+// script directories for changes.
+type configWatcher struct {
+	watcher    *fsnotify.Watcher
+	logger     *slog.Logger
+	configDir  string
+	scriptDirs []string
+	mu         sync.Mutex
+}
+
+// newConfigWatcher creates a new configWatcher for the given configuration and
+// script directories.
+func newConfigWatcher(logger *slog.Logger, configDir string, scriptDirs ...string) (*configWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("could not create config watcher: %w", err)
+	}
+
+	if err := watcher.Add(configDir); err != nil {
+		return nil, errors.Join(fmt.Errorf("could not watch config directory: %w", err), watcher.Close())
+	}
+
+	configWatcher := &configWatcher{
+		watcher:   watcher,
+		logger:    logger.With(slog.String("watcher", "config")),
+		configDir: configDir,
+	}
+	configWatcher.setScriptDirs(scriptDirs...)
+
+	return configWatcher, nil
+}
+
+// setScriptDirs changes the script directories that are watched. Directories
+// that do not exist are not watched until they are created in the
+// configuration directory.
+func (w *configWatcher) setScriptDirs(dirs ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, dir := range w.scriptDirs {
+		if !slices.Contains(dirs, dir) {
+			// The directory might not have been watched.
+			_ = w.watcher.Remove(dir) //nolint:errcheck
+		}
+	}
+
+	for _, dir := range dirs {
+		w.addScriptDir(dir)
+	}
+
+	w.scriptDirs = dirs
+}
+
+// addScriptDir will watch the given script directory, if it exists.
+func (w *configWatcher) addScriptDir(dir string) {
+	if _, err := os.Stat(dir); err != nil {
+		return
+	}
+
+	if err := w.watcher.Add(dir); err != nil {
+		w.logger.Warn("Could not watch script directory.",
+			slog.String("path", dir),
+			slog.Any("error", err))
+	}
+}
+
+// classify returns what part of the configuration the given event changes.
+func (w *configWatcher) classify(event fsnotify.Event) configChange {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var change configChange
+
+	dir := filepath.Dir(event.Name)
+
+	switch {
+	case dir == w.configDir && filepath.Base(event.Name) == preferencesFileName:
+		change.prefs = true
+	case dir == w.configDir && filepath.Base(event.Name) == commandsFileName:
+		change.commands = true
+	case slices.Contains(w.scriptDirs, dir):
+		change.scripts = true
+	case slices.Contains(w.scriptDirs, event.Name):
+		// A script directory itself was created or removed.
+		change.scripts = true
+
+		if event.Has(fsnotify.Create) {
+			w.addScriptDir(event.Name)
+		}
+	}
+
+	return change
+}
+
+// Run will watch for changes until the context is canceled. Changes are sent
+// on the returned channel once no further changes have been seen for
+// configChangeDelay.
+func (w *configWatcher) Run(ctx context.Context) <-chan configChange {
+	changeCh := make(chan configChange)
+
+	go func() {
+		defer close(changeCh)
+		defer w.watcher.Close()
+
+		var pending configChange
+
+		timer := time.NewTimer(configChangeDelay)
+		timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-w.watcher.Events:
+				if !ok {
+					return
+				}
+
+				if change := w.classify(event); change.any() {
+					pending.merge(change)
+					timer.Reset(configChangeDelay)
+				}
+			case err, ok := <-w.watcher.Errors:
+				if !ok {
+					return
+				}
+
+				w.logger.Warn("Error watching config.", slog.Any("error", err))
+			case <-timer.C:
+				select {
+				case changeCh <- pending:
+				case <-ctx.Done():
+					return
+				}
+
+				pending = configChange{}
+			}
+		}
+	}()
+
+	return changeCh
+}
+
+// runConfigWatcher watches the agent configuration for changes until the
+// context is canceled. Changes to the preferences are reloaded. A value is sent
+// on sensorReloadCh when scripts need to be reloaded and on mqttReloadCh when
+// the MQTT configuration needs to be reloaded.
+func (agent *Agent) runConfigWatcher(ctx context.Context, sensorControllers []SensorController, sensorReloadCh, mqttReloadCh chan struct{}) {
+	watcher, err := newConfigWatcher(agent.logger, agent.GetPreferencesPath(), agent.scriptDirs()...)
+	if err != nil {
+		agent.logger.Warn("Not watching for configuration changes.", slog.Any("error", err))
+
+		return
+	}
+
+	agent.logger.Debug("Watching for configuration changes.")
+
+	for change := range watcher.Run(ctx) {
+		if change.prefs {
+			agent.logger.Info("Preferences changed, reloading.")
+
+			sections, err := agent.prefs.Reload()
+			if err != nil {
+				agent.logger.Warn("Could not reload preferences.", slog.Any("error", err))
+			}
+
+			for _, section := range sections {
+				switch section {
+				case preferences.MQTTSection:
+					change.commands = true
+				case preferences.ScriptsSection:
+					dirs := agent.scriptDirs()
+					watcher.setScriptDirs(dirs...)
+
+					for _, controller := range sensorControllers {
+						if scriptsController, ok := controller.(*scripts.Controller); ok {
+							scriptsController.SetDirs(dirs...)
+						}
+					}
+
+					change.scripts = true
+				case preferences.SensorsSection:
+					agent.logger.Info("Sensor filter changed, applying.")
+					agent.applySensorFilter()
+				default:
+					agent.logger.Info("Preferences changed that require a restart of the agent.",
+						slog.String("section", section))
+				}
+			}
+		}
+
+		if change.scripts {
+			agent.logger.Info("Scripts changed, reloading.")
+			notifyReload(sensorReloadCh)
+		}
+
+		if change.commands {
+			agent.logger.Info("MQTT configuration changed, reloading.")
+			notifyReload(mqttReloadCh)
+		}
+	}
+}
+
+// notifyReload will send a reload request on the given channel, unless a
+// request is already pending.
+func notifyReload(reloadCh chan struct{}) {
+	select {
+	case reloadCh <- struct{}{}:
+	default:
+	}
+}
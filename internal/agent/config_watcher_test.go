@@ -0,0 +1,127 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+func TestConfigWatcher(t *testing.T) {
+	configDir := t.TempDir()
+	scriptDir := filepath.Join(configDir, "scripts")
+	systemDir := t.TempDir()
+
+	watcher, err := newConfigWatcher(slog.Default(), configDir, systemDir, scriptDir)
+	require.NoError(t, err)
+
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+
+	changeCh := watcher.Run(ctx)
+
+	waitForChange := func(t *testing.T) configChange {
+		t.Helper()
+		select {
+		case change := <-changeCh:
+			return change
+		case <-time.After(5 * configChangeDelay):
+			t.Fatal("no change detected")
+		}
+
+		return configChange{}
+	}
+
+	tests := []struct {
+		name   string
+		change func(t *testing.T)
+		want   configChange
+	}{
+		{
+			name: "preferences",
+			change: func(t *testing.T) {
+				t.Helper()
+				require.NoError(t, os.WriteFile(filepath.Join(configDir, preferencesFileName), []byte(""), 0o600))
+			},
+			want: configChange{prefs: true},
+		},
+		{
+			name: "commands and system script",
+			change: func(t *testing.T) {
+				t.Helper()
+				require.NoError(t, os.WriteFile(filepath.Join(configDir, commandsFileName), []byte(""), 0o600))
+				require.NoError(t, os.WriteFile(filepath.Join(systemDir, "script.sh"), []byte(""), 0o600))
+			},
+			want: configChange{commands: true, scripts: true},
+		},
+		{
+			name: "user script directory created",
+			change: func(t *testing.T) {
+				t.Helper()
+				require.NoError(t, os.Mkdir(scriptDir, 0o700))
+			},
+			want: configChange{scripts: true},
+		},
+		{
+			name: "user script added",
+			change: func(t *testing.T) {
+				t.Helper()
+				require.NoError(t, os.WriteFile(filepath.Join(scriptDir, "script.sh"), []byte(""), 0o600))
+			},
+			want: configChange{scripts: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change(t)
+			assert.Equal(t, tt.want, waitForChange(t))
+		})
+	}
+}
+
+func TestAgent_runConfigWatcher_sensors(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	client := &recordingHassClient{}
+	agent := &Agent{id: "config_watcher_test", logger: slog.Default(), hass: client}
+
+	require.NoError(t, os.MkdirAll(agent.GetPreferencesPath(), 0o700))
+	agent.prefs = preferences.DefaultPreferences(filepath.Join(agent.GetPreferencesPath(), preferencesFileName))
+	require.NoError(t, agent.prefs.Save())
+
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+
+	go agent.runConfigWatcher(ctx, nil, make(chan struct{}, 1), make(chan struct{}, 1))
+
+	// Changing the sensors section applies the new filter.
+	onDisk, err := preferences.Load(agent.GetPreferencesPath())
+	require.NoError(t, err)
+
+	onDisk.Sensors = &preferences.Sensors{Exclude: []string{"*_rate"}}
+
+	assert.Eventually(t, func() bool {
+		// The watcher might not have started when the file is first written.
+		if err := onDisk.Save(); err != nil {
+			return false
+		}
+
+		return client.sensorFilter() != nil
+	}, 5*time.Second, 2*configChangeDelay)
+}
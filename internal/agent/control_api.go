@@ -0,0 +1,318 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/joshuar/go-hass-agent/internal/control"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+// sensorRequest is a request to act on the sensor controllers. It is run by
+// runSensorWorkers, which has exclusive access to the controllers, with the
+// context of the sensor workers and the channel that merges their updates.
+type sensorRequest func(ctx context.Context, sensorCh *fanIn[sensor.Details], controllers []SensorController)
+
+// controlBackend implements control.Backend for the running agent.
+type controlBackend struct {
+	agent    *Agent
+	tracker  Tracker
+	registry Registry
+	requests chan sensorRequest
+	// workerChs holds the update channels of workers started through the
+	// control API. It is only accessed by sensor requests.
+	workerChs map[string]<-chan sensor.Details
+}
+
+// newControlBackend creates a new controlBackend. Requests that act on sensor
+// workers are sent on its requests channel, which should be passed to
+// runSensorWorkers.
+func (agent *Agent) newControlBackend(trk Tracker, reg Registry) *controlBackend {
+	return &controlBackend{
+		agent:     agent,
+		tracker:   trk,
+		registry:  reg,
+		requests:  make(chan sensorRequest),
+		workerChs: make(map[string]<-chan sensor.Details),
+	}
+}
+
+// runControlAPI serves the control API on the agent control socket until the
+// context is canceled.
+func (agent *Agent) runControlAPI(ctx context.Context, backend *controlBackend) {
+	path := control.SocketPath(agent.id)
+
+	listener, err := control.Listen(ctx, path)
+	if err != nil {
+		agent.logger.Warn("Could not start control API.", slog.Any("error", err))
+
+		return
+	}
+
+	if err := control.NewServer(backend, agent.logger).Serve(ctx, listener); err != nil {
+		agent.logger.Warn("Control API stopped.", slog.Any("error", err))
+	}
+}
+
+// do sends the given request to runSensorWorkers and waits for it to complete.
+func (b *controlBackend) do(ctx context.Context, request sensorRequest) error {
+	done := make(chan struct{})
+
+	wrapped := func(workerCtx context.Context, sensorCh *fanIn[sensor.Details], controllers []SensorController) {
+		defer close(done)
+		request(workerCtx, sensorCh, controllers)
+	}
+
+	select {
+	case b.requests <- wrapped:
+	case <-ctx.Done():
+		return fmt.Errorf("request not handled: %w", ctx.Err())
+	}
+
+	// Requests are run synchronously by runSensorWorkers, so they always
+	// complete once accepted.
+	<-done
+
+	return nil
+}
+
+func (b *controlBackend) Status(ctx context.Context) control.Status {
+	status := control.Status{
+		Version:    preferences.AppVersion,
+		Registered: b.agent.prefs.Registered,
+		Server:     b.agent.prefs.RestAPIURL(),
+	}
+
+	if version := b.agent.hass.HassVersion(ctx); version != "Unknown" {
+		status.HassVersion = version
+		status.Connected = true
+	}
+
+	if mqttPrefs := b.agent.prefs.GetMQTTPreferences(); mqttPrefs != nil {
+		status.MQTT.Enabled = mqttPrefs.IsMQTTEnabled()
+		status.MQTT.Server = mqttPrefs.Server()
+	}
+
+	status.MQTT.Connected = b.agent.mqttConnected.Load()
+
+	if workers, err := b.Workers(ctx); err == nil {
+		for _, worker := range workers {
+			if worker.Active {
+				status.ActiveWorkers++
+			} else {
+				status.InactiveWorkers++
+			}
+		}
+	}
+
+	return status
+}
+
+func (b *controlBackend) Workers(ctx context.Context) ([]control.Worker, error) {
+	var workers []control.Worker
+
+	err := b.do(ctx, func(_ context.Context, _ *fanIn[sensor.Details], controllers []SensorController) {
+		for _, controller := range controllers {
+			for _, id := range controller.ActiveWorkers() {
+				workers = append(workers, control.Worker{ID: id, Active: true})
+			}
+
+			for _, id := range controller.InactiveWorkers() {
+				workers = append(workers, control.Worker{ID: id})
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(workers, func(a, b control.Worker) int { return strings.Compare(a.ID, b.ID) })
+
+	return workers, nil
+}
+
+func (b *controlBackend) StartWorker(ctx context.Context, id string) error {
+	var startErr error
+
+	err := b.do(ctx, func(workerCtx context.Context, sensorCh *fanIn[sensor.Details], controllers []SensorController) {
+		controller, active := findWorker(id, controllers)
+		switch {
+		case controller == nil:
+			startErr = fmt.Errorf("%w: %s", control.ErrUnknownWorker, id)
+		case active:
+			startErr = fmt.Errorf("%w: %s is already started", control.ErrWorkerState, id)
+		default:
+			workerCh, err := controller.Start(workerCtx, id)
+			if err != nil {
+				startErr = fmt.Errorf("could not start worker %s: %w", id, err)
+
+				return
+			}
+
+			sensorCh.Add(workerCh)
+			b.workerChs[id] = workerCh
+
+			b.agent.logger.Info("Started worker.", slog.String("worker", id))
+		}
+	})
+
+	return errors.Join(err, startErr)
+}
+
+func (b *controlBackend) StopWorker(ctx context.Context, id string) error {
+	var stopErr error
+
+	err := b.do(ctx, func(_ context.Context, sensorCh *fanIn[sensor.Details], controllers []SensorController) {
+		controller, active := findWorker(id, controllers)
+		switch {
+		case controller == nil:
+			stopErr = fmt.Errorf("%w: %s", control.ErrUnknownWorker, id)
+		case !active:
+			stopErr = fmt.Errorf("%w: %s is already stopped", control.ErrWorkerState, id)
+		default:
+			if err := controller.Stop(id); err != nil {
+				stopErr = fmt.Errorf("could not stop worker %s: %w", id, err)
+
+				return
+			}
+
+			if workerCh, found := b.workerChs[id]; found {
+				sensorCh.Remove(workerCh)
+				delete(b.workerChs, id)
+			}
+
+			b.agent.logger.Info("Stopped worker.", slog.String("worker", id))
+		}
+	})
+
+	return errors.Join(err, stopErr)
+}
+
+func (b *controlBackend) Sensors(_ context.Context) ([]control.Sensor, error) {
+	ids := b.tracker.SensorList()
+	sensors := make([]control.Sensor, 0, len(ids))
+
+	// The tracker might record when each sensor was last updated.
+	updates, hasUpdates := b.tracker.(interface{ LastUpdated(id string) time.Time })
+
+	for _, id := range ids {
+		details, err := b.tracker.Get(id)
+		if err != nil {
+			continue
+		}
+
+		trackedSensor := control.Sensor{
+			ID:         details.ID(),
+			Name:       details.Name(),
+			State:      details.State(),
+			Units:      details.Units(),
+			Icon:       details.Icon(),
+			Attributes: details.Attributes(),
+			Disabled:   b.registry.IsDisabled(details.ID()),
+		}
+
+		if hasUpdates {
+			trackedSensor.LastUpdated = updates.LastUpdated(details.ID())
+		}
+
+		sensors = append(sensors, trackedSensor)
+	}
+
+	slices.SortFunc(sensors, func(a, b control.Sensor) int { return strings.Compare(a.ID, b.ID) })
+
+	return sensors, nil
+}
+
+func (b *controlBackend) History(_ context.Context, id string) ([]control.HistoryEntry, error) {
+	// The tracker might keep the history of each sensor.
+	trk, ok := b.tracker.(interface {
+		History(id string) ([]sensor.HistoryEntry, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", control.ErrUnknownSensor, id)
+	}
+
+	entries, err := trk.History(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", control.ErrUnknownSensor, id)
+	}
+
+	history := make([]control.HistoryEntry, 0, len(entries))
+	for _, entry := range entries {
+		history = append(history, control.HistoryEntry{Time: entry.Time, State: entry.State})
+	}
+
+	return history, nil
+}
+
+// Refresh fetches the current value of all sensors of all active workers and
+// queues them to be sent to Home Assistant, along with the updates from the
+// workers.
+func (b *controlBackend) Refresh(ctx context.Context) error {
+	var refreshErr error
+
+	err := b.do(ctx, func(workerCtx context.Context, sensorCh *fanIn[sensor.Details], controllers []SensorController) {
+		var sensors []sensor.Details
+
+		for _, c := range controllers {
+			controller, ok := c.(RefreshableController)
+			if !ok {
+				continue
+			}
+
+			controllerSensors, err := controller.Refresh(workerCtx)
+			if err != nil {
+				refreshErr = errors.Join(refreshErr, err)
+			}
+
+			sensors = append(sensors, controllerSensors...)
+		}
+
+		// Send the sensors through the pipeline, so that they are ordered
+		// with and replaced by any newer updates of the same sensors.
+		refreshCh := make(chan sensor.Details, len(sensors))
+		for _, details := range sensors {
+			refreshCh <- details
+		}
+
+		close(refreshCh)
+		sensorCh.Add(refreshCh)
+	})
+	if err != nil {
+		return err
+	}
+
+	if refreshErr != nil {
+		b.agent.logger.Warn("Some sensors could not be refreshed.", slog.Any("error", refreshErr))
+	}
+
+	return nil
+}
+
+// findWorker returns the controller of the worker with the given ID, and
+// whether the worker is active. If no controller has the worker, a nil
+// controller is returned.
+func findWorker(id string, controllers []SensorController) (SensorController, bool) {
+	for _, controller := range controllers {
+		if slices.Contains(controller.ActiveWorkers(), id) {
+			return controller, true
+		}
+
+		if slices.Contains(controller.InactiveWorkers(), id) {
+			return controller, false
+		}
+	}
+
+	return nil, false
+}
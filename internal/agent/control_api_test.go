@@ -0,0 +1,191 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/control"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/scripts"
+)
+
+// discardHassClient is a HassClient that discards all sensors.
+type discardHassClient struct {
+	HassClient
+}
+
+func (c *discardHassClient) ProcessSensor(_ context.Context, _ sensor.Details) error { return nil }
+
+func TestControlBackend_Workers(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+
+	newWorker := func() *WorkerMock {
+		return &WorkerMock{
+			UpdatesFunc: func(_ context.Context) (<-chan sensor.Details, error) {
+				return make(chan sensor.Details), nil
+			},
+			StopFunc: func() error { return nil },
+		}
+	}
+
+	controller := &deviceController{
+		sensorWorkers: map[string]*sensorWorker{
+			"worker_a": {object: newWorker()},
+			"worker_b": {object: newWorker(), disabled: true},
+		},
+		logger: slog.Default(),
+	}
+
+	agent := &Agent{logger: slog.Default(), hass: &discardHassClient{}}
+	backend := agent.newControlBackend(&TrackerMock{}, &RegistryMock{})
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		agent.runSensorWorkers(ctx, nil, backend.requests, controller)
+	}()
+
+	workers, err := backend.Workers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []control.Worker{{ID: "worker_a", Active: true}, {ID: "worker_b"}}, workers)
+
+	// Start a disabled worker.
+	require.NoError(t, backend.StartWorker(ctx, "worker_b"))
+	assert.Contains(t, backend.workerChs, "worker_b")
+	require.ErrorIs(t, backend.StartWorker(ctx, "worker_b"), control.ErrWorkerState)
+
+	// Stop workers.
+	require.NoError(t, backend.StopWorker(ctx, "worker_a"))
+	require.NoError(t, backend.StopWorker(ctx, "worker_b"))
+	assert.NotContains(t, backend.workerChs, "worker_b")
+	require.ErrorIs(t, backend.StopWorker(ctx, "worker_b"), control.ErrWorkerState)
+
+	require.ErrorIs(t, backend.StartWorker(ctx, "unknown"), control.ErrUnknownWorker)
+
+	workers, err = backend.Workers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []control.Worker{{ID: "worker_a"}, {ID: "worker_b"}}, workers)
+
+	cancelFunc()
+	wg.Wait()
+}
+
+// blockingHassClient is a HassClient that does not process sensors until it is
+// released.
+type blockingHassClient struct {
+	HassClient
+	release chan struct{}
+}
+
+func (c *blockingHassClient) ProcessSensor(_ context.Context, _ sensor.Details) error {
+	<-c.release
+
+	return nil
+}
+
+func TestControlBackend_Workers_pipelineFull(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+
+	var sent atomic.Int32
+
+	worker := &WorkerMock{
+		UpdatesFunc: func(ctx context.Context) (<-chan sensor.Details, error) {
+			updates := make(chan sensor.Details)
+
+			go func() {
+				for idx := 0; ; idx++ {
+					select {
+					case updates <- &linux.Sensor{UniqueID: strconv.Itoa(idx)}:
+						sent.Add(1)
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+
+			return updates, nil
+		},
+		StopFunc: func() error { return nil },
+	}
+
+	controller := &deviceController{
+		sensorWorkers: map[string]*sensorWorker{"worker": {object: worker}},
+		logger:        slog.Default(),
+	}
+
+	client := &blockingHassClient{release: make(chan struct{})}
+	agent := &Agent{logger: slog.Default(), hass: client}
+	backend := agent.newControlBackend(&TrackerMock{}, &RegistryMock{})
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		agent.runSensorWorkers(ctx, nil, backend.requests, controller)
+	}()
+
+	// Wait until the pipeline is full.
+	require.Eventually(t, func() bool {
+		return sent.Load() > 2*(pipelineWorkers+pipelineCapacity)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Requests are still handled.
+	requestCtx, requestCancel := context.WithTimeout(ctx, time.Second)
+	defer requestCancel()
+
+	workers, err := backend.Workers(requestCtx)
+	require.NoError(t, err)
+	assert.Equal(t, []control.Worker{{ID: "worker", Active: true}}, workers)
+
+	close(client.release)
+	cancelFunc()
+	wg.Wait()
+}
+
+func TestControlBackend_History(t *testing.T) {
+	trk, err := sensor.NewTracker()
+	require.NoError(t, err)
+
+	require.NoError(t, trk.Add(&scripts.ScriptSensor{SensorID: "counter", SensorName: "Counter", SensorState: 1}))
+	require.NoError(t, trk.Add(&scripts.ScriptSensor{SensorID: "counter", SensorName: "Counter", SensorState: 2}))
+
+	agent := &Agent{logger: slog.Default(), hass: &discardHassClient{}}
+	backend := agent.newControlBackend(trk, &RegistryMock{})
+
+	history, err := backend.History(context.TODO(), "counter")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, 1, history[0].State)
+	assert.Equal(t, 2, history[1].State)
+
+	_, err = backend.History(context.TODO(), "unknown")
+	require.ErrorIs(t, err, control.ErrUnknownSensor)
+
+	// Trackers without history have no sensors with history.
+	backend = agent.newControlBackend(&TrackerMock{}, &RegistryMock{})
+
+	_, err = backend.History(context.TODO(), "counter")
+	require.ErrorIs(t, err, control.ErrUnknownSensor)
+}
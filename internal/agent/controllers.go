@@ -30,6 +30,36 @@ type SensorController interface {
 	StopAll() error
 }
 
+// ReloadableController represents a SensorController that can reload the
+// definitions of its Workers while it is running.
+type ReloadableController interface {
+	SensorController
+	// Reload will update the Workers of the controller from their
+	// definitions. It returns the names of any Workers that were added or
+	// removed. Removed Workers are stopped. Added Workers are not started and
+	// should be started with Start.
+	Reload(ctx context.Context) (added, removed []string, err error)
+}
+
+// RefreshableController represents a SensorController that can fetch the
+// current value of the sensors of its active Workers on demand.
+type RefreshableController interface {
+	SensorController
+	// Refresh returns the current value of all sensors of all active
+	// Workers.
+	Refresh(ctx context.Context) ([]sensor.Details, error)
+}
+
+// QueryableController represents a SensorController that can fetch the
+// current value of the sensors of any of its Workers on demand, whether or not
+// the Worker has been started.
+type QueryableController interface {
+	SensorController
+	// WorkerSensors returns the current value of all sensors of the named
+	// Worker.
+	WorkerSensors(ctx context.Context, name string) ([]sensor.Details, error)
+}
+
 // Worker represents an object that is responsible for controlling the
 // publishing of one or more sensors.
 type Worker interface {
@@ -82,6 +112,28 @@ func (agent *Agent) setupControllers(ctx context.Context) []any {
 		controllers = append(controllers, scriptsController)
 	}
 
+	// Create a controller for any plugins. Plugins that declare entities are
+	// also MQTT controllers.
+	pluginsController, pluginMQTTControllers := agent.newPluginsController(ctx, mqttDevice)
+	if pluginsController != nil {
+		controllers = append(controllers, pluginsController)
+	}
+
+	for _, controller := range pluginMQTTControllers {
+		controllers = append(controllers, controller)
+	}
+
+	// Create a controller for the D-Bus service, through which other
+	// applications can send sensors.
+	if dbusAPIController := agent.newDBusAPIController(ctx); dbusAPIController != nil {
+		controllers = append(controllers, dbusAPIController)
+	}
+
+	// Create a controller for the local push endpoint, if enabled.
+	if pushController := agent.newPushController(ctx); pushController != nil {
+		controllers = append(controllers, pushController)
+	}
+
 	// Create a new device controller. The controller will have all the
 	// necessary configuration for device-specific sensors and MQTT
 	// configuration.
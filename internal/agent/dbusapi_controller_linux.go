@@ -0,0 +1,80 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/joshuar/go-hass-agent/internal/dbusapi"
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/logging"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+var ErrHeadless = errors.New("agent is running headless")
+
+// dbusAPIAgent provides the functionality of the agent to the D-Bus service.
+type dbusAPIAgent struct {
+	agent *Agent
+}
+
+func (a *dbusAPIAgent) FireEvent(ctx context.Context, event *hass.Event) error {
+	return a.agent.hass.FireEvent(ctx, event) //nolint:wrapcheck
+}
+
+func (a *dbusAPIAgent) ShowNotification(title, message string) error {
+	if a.agent.headless {
+		return ErrHeadless
+	}
+
+	a.agent.ui.DisplayNotification(&hass.WebsocketNotification{Title: title, Message: message})
+
+	return nil
+}
+
+// Status returns the connection status of the agent. The server is reported
+// without the webhook, which is a credential.
+func (a *dbusAPIAgent) Status(ctx context.Context) dbusapi.Status {
+	status := dbusapi.Status{
+		Version:       preferences.AppVersion,
+		Registered:    a.agent.prefs.Registered,
+		MQTTConnected: a.agent.mqttConnected.Load(),
+	}
+
+	if a.agent.prefs.Registration != nil {
+		status.Server = a.agent.prefs.Registration.Server
+	}
+
+	if version := a.agent.hass.HassVersion(ctx); version != "Unknown" {
+		status.HassVersion = version
+		status.Connected = true
+	}
+
+	return status
+}
+
+// newDBusAPIController creates a controller for the D-Bus service, which
+// other applications can use to send sensors through the agent. If the session
+// bus is not available, a nil controller is returned.
+func (agent *Agent) newDBusAPIController(ctx context.Context) SensorController {
+	logger := agent.logger.With(slog.String("controller", "dbus_api"))
+
+	service, err := dbusapi.New(logging.ToContext(ctx, logger), &dbusAPIAgent{agent: agent})
+	if err != nil {
+		logger.Debug("Not starting D-Bus service.", slog.Any("error", err))
+
+		return nil
+	}
+
+	return &deviceController{
+		sensorWorkers: map[string]*sensorWorker{
+			service.ID(): {object: service, disabled: !agent.workerEnabled(service.ID())},
+		},
+		logger: logger,
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
+	"slices"
 	"strings"
 	"time"
 
@@ -20,6 +21,8 @@ import (
 	"github.com/joshuar/go-hass-agent/internal/device/helpers"
 	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
 	"github.com/joshuar/go-hass-agent/internal/logging"
+	"github.com/joshuar/go-hass-agent/internal/plugins"
+	"github.com/joshuar/go-hass-agent/internal/push"
 )
 
 const (
@@ -42,8 +45,14 @@ var (
 )
 
 type sensorWorker struct {
-	object  Worker
-	started bool
+	object Worker
+	// health is the health of the worker, kept across restarts.
+	health *workerHealth
+	// cancelFunc stops the supervision of a started worker.
+	cancelFunc context.CancelFunc
+	started    bool
+	// disabled workers are not started by StartAll.
+	disabled bool
 }
 
 type VersionWorker struct {
@@ -95,12 +104,24 @@ func (w *deviceController) Start(ctx context.Context, name string) (<-chan senso
 		return nil, ErrWorkerAlreadyStarted
 	}
 
-	workerCh, err := w.sensorWorkers[name].object.Updates(ctx)
+	if worker.health == nil {
+		worker.health = newWorkerHealth()
+	}
+
+	// Supervise the worker, so that it is restarted if it stops unexpectedly.
+	supervisorCtx, cancelFunc := context.WithCancel(ctx)
+
+	workerSupervisor := newSupervisor(name, worker.object, worker.health, w.logger)
+
+	workerCh, err := workerSupervisor.start(supervisorCtx)
 	if err != nil {
+		cancelFunc()
+
 		return nil, fmt.Errorf("could not start worker: %w", err)
 	}
 
-	w.sensorWorkers[name].started = true
+	worker.cancelFunc = cancelFunc
+	worker.started = true
 
 	return workerCh, nil
 }
@@ -111,11 +132,18 @@ func (w *deviceController) Stop(name string) error {
 	if !exists {
 		return ErrUnknownWorker
 	}
+	// Stop supervising the worker, so that it is not restarted.
+	if worker.cancelFunc != nil {
+		worker.cancelFunc()
+		worker.cancelFunc = nil
+	}
 	// Stop the worker. Report any errors.
 	if err := worker.object.Stop(); err != nil {
 		return fmt.Errorf("error stopping worker: %w", err)
 	}
 
+	worker.started = false
+
 	return nil
 }
 
@@ -124,7 +152,13 @@ func (w *deviceController) StartAll(ctx context.Context) (<-chan sensor.Details,
 
 	var errs error
 
-	for id := range w.sensorWorkers {
+	for id, worker := range w.sensorWorkers {
+		if worker.disabled {
+			w.logger.Debug("Worker disabled in preferences, not starting.", slog.String("worker", id))
+
+			continue
+		}
+
 		workerCh, err := w.Start(ctx, id)
 		if err != nil {
 			errs = errors.Join(errs, err)
@@ -141,7 +175,11 @@ func (w *deviceController) StartAll(ctx context.Context) (<-chan sensor.Details,
 func (w *deviceController) StopAll() error {
 	var errs error
 
-	for id := range w.sensorWorkers {
+	for id, worker := range w.sensorWorkers {
+		if !worker.started {
+			continue
+		}
+
 		if err := w.Stop(id); err != nil {
 			errs = errors.Join(errs, err)
 		}
@@ -150,6 +188,44 @@ func (w *deviceController) StopAll() error {
 	return errs
 }
 
+func (w *deviceController) Refresh(ctx context.Context) ([]sensor.Details, error) {
+	var (
+		sensors []sensor.Details
+		errs    error
+	)
+
+	for id, worker := range w.sensorWorkers {
+		if !worker.started {
+			continue
+		}
+
+		workerSensors, err := worker.object.Sensors(ctx)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", id, err))
+
+			continue
+		}
+
+		sensors = append(sensors, workerSensors...)
+	}
+
+	return sensors, errs
+}
+
+func (w *deviceController) WorkerSensors(ctx context.Context, name string) ([]sensor.Details, error) {
+	worker, exists := w.sensorWorkers[name]
+	if !exists {
+		return nil, ErrUnknownWorker
+	}
+
+	sensors, err := worker.object.Sensors(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get sensors: %w", err)
+	}
+
+	return sensors, nil
+}
+
 func (agent *Agent) newDeviceController(ctx context.Context) SensorController {
 	var worker Worker
 
@@ -160,17 +236,58 @@ func (agent *Agent) newDeviceController(ctx context.Context) SensorController {
 
 	// Set up sensor workers.
 	worker = agent.newVersionWorker()
-	controller.sensorWorkers[worker.ID()] = &sensorWorker{object: worker, started: false}
+	controller.sensorWorkers[worker.ID()] = &sensorWorker{object: worker, disabled: !agent.workerEnabled(worker.ID())}
 	worker = agent.newExternalIPUpdaterWorker()
-	controller.sensorWorkers[worker.ID()] = &sensorWorker{object: worker, started: false}
+	controller.sensorWorkers[worker.ID()] = &sensorWorker{object: worker, disabled: !agent.workerEnabled(worker.ID())}
 
 	return controller
 }
 
+// KnownWorker reports whether the given ID is the ID of a sensor worker of the
+// agent. Plugins are known by the prefix of their ID, as they are only found
+// when the agent runs.
+func KnownWorker(id string) bool {
+	if strings.HasPrefix(id, plugins.IDPrefix) {
+		return true
+	}
+
+	return slices.Contains(osWorkerIDs(), id) ||
+		slices.Contains([]string{versionWorkerID, externalIPWorkerID, push.WorkerID}, id)
+}
+
+// warnUnknownWorkers logs a warning for any workers in the preferences that are
+// not workers of the agent, as their preferences have no effect.
+func (agent *Agent) warnUnknownWorkers() {
+	if agent.prefs == nil {
+		return
+	}
+
+	for id := range agent.prefs.Workers {
+		if !KnownWorker(id) {
+			agent.logger.Warn("Ignoring preferences for unknown worker.",
+				slog.String("worker", id),
+				slog.Any("error", ErrUnknownWorker))
+		}
+	}
+}
+
+// workerEnabled returns whether the worker with the given ID has been enabled
+// in the preferences.
+func (agent *Agent) workerEnabled(id string) bool {
+	if agent.prefs == nil {
+		return true
+	}
+
+	return agent.prefs.WorkerEnabled(id)
+}
+
 func (w *VersionWorker) ID() string { return versionWorkerID }
 
 func (w *VersionWorker) Stop() error { return nil }
 
+// OneShot returns true, as the version is only sent once.
+func (w *VersionWorker) OneShot() bool { return true }
+
 func (w *VersionWorker) Sensors(_ context.Context) ([]sensor.Details, error) {
 	return []sensor.Details{new(version)}, nil
 }
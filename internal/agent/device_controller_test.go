@@ -95,16 +95,16 @@ func Test_deviceController_Start(t *testing.T) {
 	tests := []struct {
 		fields       fields
 		wantErrValue error
-		want         <-chan sensor.Details
 		args         args
 		name         string
 		wantErr      bool
+		wantCh       bool
 	}{
 		{
 			name:         "valid",
 			args:         args{name: "valid"},
 			fields:       fields{sensorWorkers: map[string]*sensorWorker{"valid": {object: worker}}},
-			want:         outCh,
+			wantCh:       true,
 			wantErr:      false,
 			wantErrValue: nil,
 		},
@@ -133,8 +133,12 @@ func Test_deviceController_Start(t *testing.T) {
 				t.Errorf("deviceController.Start() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("deviceController.Start() = %v, want %v", got, tt.want)
+			// Started workers are supervised, so their updates are sent on a
+			// new channel.
+			if tt.wantCh {
+				assert.NotNil(t, got)
+			} else {
+				assert.Nil(t, got)
 			}
 			assert.ErrorIs(t, err, tt.wantErrValue)
 		})
@@ -188,3 +192,53 @@ func Test_deviceController_Stop(t *testing.T) {
 		})
 	}
 }
+
+func Test_deviceController_StartAll(t *testing.T) {
+	newWorker := func() *WorkerMock {
+		return &WorkerMock{
+			UpdatesFunc: func(_ context.Context) (<-chan sensor.Details, error) {
+				ch := make(chan sensor.Details)
+				close(ch)
+
+				return ch, nil
+			},
+		}
+	}
+	enabledWorker := newWorker()
+	disabledWorker := newWorker()
+
+	controller := &deviceController{
+		sensorWorkers: map[string]*sensorWorker{
+			"enabled":  {object: enabledWorker},
+			"disabled": {object: disabledWorker, disabled: true},
+		},
+		logger: slog.Default(),
+	}
+
+	_, err := controller.StartAll(context.TODO())
+	assert.NoError(t, err)
+	assert.Len(t, enabledWorker.UpdatesCalls(), 1)
+	assert.Empty(t, disabledWorker.UpdatesCalls())
+	assert.Equal(t, []string{"enabled"}, controller.ActiveWorkers())
+	assert.Equal(t, []string{"disabled"}, controller.InactiveWorkers())
+}
+
+func TestKnownWorker(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{id: "hwmon_sensors", want: true},
+		{id: "agent_version_sensor", want: true},
+		{id: "push_api", want: true},
+		{id: "dbus_api", want: true},
+		{id: "plugin_weather", want: true},
+		{id: "hwmon_sensor", want: false},
+		{id: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			assert.Equal(t, tt.want, KnownWorker(tt.id))
+		})
+	}
+}
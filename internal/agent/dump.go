@@ -0,0 +1,162 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+)
+
+// DumpSensors runs the sensor workers of the agent without Home Assistant and
+// returns their sensors on the returned channel. If any worker IDs are given,
+// only those workers are run, otherwise all workers enabled in the preferences
+// are run. Unless watch is true, the current value of the sensors is fetched
+// once and the channel is closed. If watch is true, the workers are started and
+// their updates are sent until the context is canceled.
+func (agent *Agent) DumpSensors(ctx context.Context, watch bool, workerIDs ...string) (<-chan sensor.Details, error) {
+	controllers := agent.newSensorControllers(ctx)
+
+	selected, err := agent.selectWorkers(controllers, workerIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	if watch {
+		return agent.watchWorkers(ctx, selected), nil
+	}
+
+	outCh := make(chan sensor.Details)
+
+	go func() {
+		defer close(outCh)
+
+		for controller, ids := range selected {
+			queryable, ok := controller.(QueryableController)
+			if !ok {
+				agent.logger.Warn("Cannot fetch sensors without starting workers, use watch mode instead.",
+					slog.Any("workers", ids))
+
+				continue
+			}
+
+			for _, id := range ids {
+				sensors, err := queryable.WorkerSensors(ctx, id)
+				if err != nil {
+					agent.logger.Warn("Could not fetch sensors.",
+						slog.String("worker", id),
+						slog.Any("error", err))
+
+					continue
+				}
+
+				for _, details := range sensors {
+					select {
+					case outCh <- details:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return outCh, nil
+}
+
+// newSensorControllers creates the sensor controllers of the agent, without any
+// MQTT functionality.
+func (agent *Agent) newSensorControllers(ctx context.Context) []SensorController {
+	var controllers []SensorController
+
+	if scriptsController := agent.newScriptsController(ctx); scriptsController != nil {
+		controllers = append(controllers, scriptsController)
+	}
+
+	if pluginsController, _ := agent.newPluginsController(ctx, nil); pluginsController != nil {
+		controllers = append(controllers, pluginsController)
+	}
+
+	if devController := agent.newDeviceController(ctx); devController != nil {
+		controllers = append(controllers, devController)
+	}
+
+	osController, _ := agent.newOSController(ctx, nil)
+	if osController != nil {
+		controllers = append(controllers, osController)
+	}
+
+	return controllers
+}
+
+// selectWorkers returns the IDs of the workers with the given IDs, grouped by
+// controller. If no IDs are given, all workers enabled in the preferences are
+// returned.
+func (agent *Agent) selectWorkers(controllers []SensorController, workerIDs ...string) (map[SensorController][]string, error) {
+	selected := make(map[SensorController][]string)
+	found := make(map[string]bool)
+
+	for _, controller := range controllers {
+		for _, id := range slices.Concat(controller.ActiveWorkers(), controller.InactiveWorkers()) {
+			switch {
+			case len(workerIDs) == 0 && agent.workerEnabled(id):
+			case slices.Contains(workerIDs, id):
+			default:
+				continue
+			}
+
+			selected[controller] = append(selected[controller], id)
+			found[id] = true
+		}
+	}
+
+	var errs error
+
+	for _, id := range workerIDs {
+		if !found[id] {
+			errs = errors.Join(errs, fmt.Errorf("%w: %s", ErrUnknownWorker, id))
+		}
+	}
+
+	return selected, errs
+}
+
+// watchWorkers starts the given workers and returns a channel of their updates.
+// The workers are stopped and the channel closed when the context is canceled.
+func (agent *Agent) watchWorkers(ctx context.Context, selected map[SensorController][]string) <-chan sensor.Details {
+	sensorCh := newFanIn[sensor.Details](ctx)
+
+	for controller, ids := range selected {
+		for _, id := range ids {
+			workerCh, err := controller.Start(ctx, id)
+			if err != nil {
+				agent.logger.Warn("Could not start worker.",
+					slog.String("worker", id),
+					slog.Any("error", err))
+
+				continue
+			}
+
+			sensorCh.Add(workerCh)
+		}
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		for controller := range selected {
+			if err := controller.StopAll(); err != nil {
+				agent.logger.Debug("Stop controller had errors.", slog.Any("error", err))
+			}
+		}
+	}()
+
+	return sensorCh.Out()
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+func TestAgent_selectWorkers(t *testing.T) {
+	disabled := false
+	prefs := preferences.DefaultPreferences(filepath.Join(t.TempDir(), "preferences.toml"))
+	prefs.Workers = map[string]*preferences.Worker{"worker_b": {Enabled: &disabled}}
+
+	agent := &Agent{prefs: prefs, logger: slog.Default()}
+	controller := &deviceController{
+		sensorWorkers: map[string]*sensorWorker{
+			"worker_a": {},
+			"worker_b": {},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		ids     []string
+		want    []string
+		wantErr error
+	}{
+		{name: "all enabled workers", want: []string{"worker_a"}},
+		{name: "named disabled worker", ids: []string{"worker_b"}, want: []string{"worker_b"}},
+		{name: "unknown worker", ids: []string{"worker_a", "unknown"}, want: []string{"worker_a"}, wantErr: ErrUnknownWorker},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := agent.selectWorkers([]SensorController{controller}, tt.ids...)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.ElementsMatch(t, tt.want, got[controller])
+		})
+	}
+}
+
+func Test_deviceController_WorkerSensors(t *testing.T) {
+	details := &linux.Sensor{UniqueID: "sensor", Value: 1}
+	controller := &deviceController{
+		sensorWorkers: map[string]*sensorWorker{
+			"worker": {object: &WorkerMock{
+				SensorsFunc: func(_ context.Context) ([]sensor.Details, error) {
+					return []sensor.Details{details}, nil
+				},
+			}},
+		},
+	}
+
+	got, err := controller.WorkerSensors(context.TODO(), "worker")
+	require.NoError(t, err)
+	assert.Equal(t, []sensor.Details{details}, got)
+
+	_, err = controller.WorkerSensors(context.TODO(), "unknown")
+	require.ErrorIs(t, err, ErrUnknownWorker)
+}
@@ -11,6 +11,8 @@ import (
 	"fmt"
 	"log/slog"
 	"path/filepath"
+	"slices"
+	"time"
 
 	"github.com/adrg/xdg"
 
@@ -19,9 +21,13 @@ import (
 
 	"github.com/joshuar/go-hass-agent/internal/commands"
 	"github.com/joshuar/go-hass-agent/internal/device"
+	"github.com/joshuar/go-hass-agent/internal/linux"
 	"github.com/joshuar/go-hass-agent/internal/preferences"
 )
 
+// mqttRetryInterval is how often connecting to MQTT is retried if it fails.
+const mqttRetryInterval = time.Minute
+
 func (agent *Agent) newMQTTController(ctx context.Context, mqttDevice *mqtthass.Device) MQTTController {
 	// Don't set up if no MQTT device has been passed in.
 	if mqttDevice == nil {
@@ -46,51 +52,199 @@ func (agent *Agent) newMQTTController(ctx context.Context, mqttDevice *mqtthass.
 
 // runMQTTWorkers will connect to MQTT, publish configs and subscriptions and
 // listen for any messages from all MQTT workers defined by the passed in
-// MQTT controllers.
-func (agent *Agent) runMQTTWorkers(ctx context.Context, controllers ...MQTTController) {
+// MQTT controllers. When a value is received on reloadCh, the commands
+// controller is recreated and the connection to MQTT re-established with the
+// current preferences, subscriptions and configs. Configs for any entities that
+// no longer exist are removed from MQTT. The workers keep running while MQTT is
+// disabled, so that it can be enabled by a reload, and a connection that could
+// not be established is retried every mqttRetryInterval.
+//
+//nolint:gocognit,funlen
+func (agent *Agent) runMQTTWorkers(ctx context.Context, reloadCh <-chan struct{}, controllers ...MQTTController) {
+	msgCh := newFanIn[*mqttapi.Msg](ctx)
+	for _, controller := range controllers {
+		msgCh.Add(controller.Msgs())
+	}
+
+	var (
+		client       *mqttapi.Client
+		cancelClient = func() {}
+	)
+
+	if agent.mqttEnabled() {
+		client, cancelClient = agent.connectMQTT(ctx, controllers...)
+	} else {
+		agent.logger.Debug("MQTT not enabled, waiting for it to be enabled.")
+	}
+
+	defer agent.mqttConnected.Store(false)
+
+	retry := time.NewTicker(mqttRetryInterval)
+	defer retry.Stop()
+
+	agent.logger.Debug("Listening for messages to publish to MQTT.")
+
+	for {
+		select {
+		case msg, ok := <-msgCh.Out():
+			if !ok || client == nil {
+				continue
+			}
+
+			if err := client.Publish(ctx, msg); err != nil {
+				agent.logger.Warn("Unable to publish message to MQTT.",
+					slog.String("topic", msg.Topic),
+					slog.Any("msg", msg.Message))
+			}
+		case <-retry.C:
+			if client != nil || !agent.mqttEnabled() {
+				continue
+			}
+
+			client, cancelClient = agent.connectMQTT(ctx, controllers...)
+			if client != nil {
+				agent.logger.Info("Connected to MQTT.")
+			}
+		case <-reloadCh:
+			reloaded := agent.reloadMQTTControllers(ctx, controllers...)
+			for _, controller := range reloaded {
+				msgCh.Add(controller.Msgs())
+			}
+
+			// Remove the configs of any entities that no longer exist. If
+			// MQTT has been disabled, remove all configs.
+			stale := staleConfigs(controllers, reloaded)
+			if !agent.mqttEnabled() {
+				stale = staleConfigs(controllers, nil)
+			}
+
+			if client != nil && len(stale) > 0 {
+				if err := client.Unpublish(ctx, stale...); err != nil {
+					agent.logger.Warn("Could not remove configs from MQTT.", slog.Any("error", err))
+				}
+			}
+
+			cancelClient()
+
+			controllers = reloaded
+			client, cancelClient = nil, func() {}
+
+			if !agent.mqttEnabled() {
+				agent.mqttConnected.Store(false)
+				agent.logger.Info("MQTT disabled, disconnected from MQTT.")
+
+				continue
+			}
+
+			client, cancelClient = agent.connectMQTT(ctx, controllers...)
+			if client != nil {
+				agent.logger.Info("Reconnected to MQTT with reloaded configuration.")
+			}
+		case <-ctx.Done():
+			cancelClient()
+			agent.logger.Debug("Stopped listening for messages to publish to MQTT.")
+
+			return
+		}
+	}
+}
+
+// mqttEnabled returns whether MQTT is enabled in the current preferences.
+func (agent *Agent) mqttEnabled() bool {
+	prefs := agent.prefs.GetMQTTPreferences()
+
+	return prefs != nil && prefs.IsMQTTEnabled()
+}
+
+// connectMQTT creates a new connection to the MQTT broker with the
+// subscriptions and configs of the given controllers. The configs are
+// published once connected. The returned function will close the connection.
+// If the connection could not be established, a nil client is returned.
+func (agent *Agent) connectMQTT(ctx context.Context, controllers ...MQTTController) (*mqttapi.Client, context.CancelFunc) {
 	var ( //nolint:prealloc
 		subscriptions []*mqttapi.Subscription
 		configs       []*mqttapi.Msg
-		msgCh         []<-chan *mqttapi.Msg
-		err           error
 	)
 
-	prefs := agent.prefs.GetMQTTPreferences()
-	if prefs == nil {
-		agent.logger.Debug("No MQTT preferences found, not running MQTT controller.")
-		return
-	}
-
 	// Add the subscriptions and configs from the controllers.
 	for _, controller := range controllers {
 		subscriptions = append(subscriptions, controller.Subscriptions()...)
 		configs = append(configs, controller.Configs()...)
-		msgCh = append(msgCh, controller.Msgs())
 	}
 
+	clientCtx, cancelFunc := context.WithCancel(ctx)
+
 	// Create a new connection to the MQTT broker. This will also publish the
 	// device subscriptions.
-	client, err := mqttapi.NewClient(ctx, prefs, subscriptions, configs)
+	client, err := mqttapi.NewClient(clientCtx, agent.prefs.GetMQTTPreferences(), subscriptions, configs)
 	if err != nil {
 		agent.logger.Error("Could not connect to MQTT.", slog.Any("error", err))
-		return
+		cancelFunc()
+		agent.mqttConnected.Store(false)
+
+		return nil, func() {}
 	}
 
-	agent.logger.Debug("Listening for messages to publish to MQTT.")
+	agent.mqttConnected.Store(true)
 
-	for {
-		select {
-		case msg := <-mergeCh(ctx, msgCh...):
-			if err := client.Publish(ctx, msg); err != nil {
-				agent.logger.Warn("Unable to publish message to MQTT.",
-					slog.String("topic", msg.Topic),
-					slog.Any("msg", msg.Message))
+	return client, cancelFunc
+}
+
+// reloadMQTTControllers returns the given controllers with the commands
+// controller recreated from the current commands file. If MQTT has been
+// enabled since the agent started, the OS controller is also created.
+func (agent *Agent) reloadMQTTControllers(ctx context.Context, controllers ...MQTTController) []MQTTController {
+	reloaded := slices.DeleteFunc(slices.Clone(controllers), func(c MQTTController) bool {
+		_, isCommands := c.(*commands.Controller)
+
+		return isCommands
+	})
+
+	mqttDevice := agent.newMQTTDevice()
+
+	if cmdController := agent.newMQTTController(ctx, mqttDevice); cmdController != nil {
+		reloaded = append(reloaded, cmdController)
+	}
+
+	hasOSController := slices.ContainsFunc(reloaded, func(c MQTTController) bool {
+		_, isOS := c.(*linuxMQTTController)
+
+		return isOS
+	})
+
+	if !hasOSController && agent.mqttEnabled() {
+		if osController := agent.newOSMQTTController(linux.NewContext(ctx), mqttDevice); osController != nil {
+			reloaded = append(reloaded, osController)
+		}
+	}
+
+	return reloaded
+}
+
+// staleConfigs returns the configs of the old controllers for entities that do
+// not exist in the new controllers.
+func staleConfigs(oldControllers, newControllers []MQTTController) []*mqttapi.Msg {
+	current := make(map[string]bool)
+
+	for _, controller := range newControllers {
+		for _, config := range controller.Configs() {
+			if config != nil {
+				current[config.Topic] = true
 			}
-		case <-ctx.Done():
-			agent.logger.Debug("Stopped listening for messages to publish to MQTT.")
-			return
 		}
 	}
+
+	var stale []*mqttapi.Msg
+
+	for _, controller := range oldControllers {
+		for _, config := range controller.Configs() {
+			if config != nil && !current[config.Topic] {
+				stale = append(stale, config)
+			}
+		}
+	}
+
+	return stale
 }
 
 func (agent *Agent) resetMQTTControllers(ctx context.Context) error {
@@ -113,6 +267,11 @@ func (agent *Agent) resetMQTTControllers(ctx context.Context) error {
 		configs = append(configs, mqttCmdController.Configs()...)
 	}
 
+	_, pluginControllers := agent.newPluginsController(ctx, mqttDevice)
+	for _, controller := range pluginControllers {
+		configs = append(configs, controller.Configs()...)
+	}
+
 	client, err := mqttapi.NewClient(ctx, prefs, nil, nil)
 	if err != nil {
 		return fmt.Errorf("could not connect to MQTT: %w", err)
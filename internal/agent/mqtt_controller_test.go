@@ -7,13 +7,17 @@
 package agent
 
 import (
+	"context"
 	"log/slog"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	mqttapi "github.com/joshuar/go-hass-anything/v11/pkg/mqtt"
+
 	"github.com/joshuar/go-hass-agent/internal/device"
 	"github.com/joshuar/go-hass-agent/internal/preferences"
 )
@@ -69,3 +73,83 @@ func TestAgent_newMQTTDevice(t *testing.T) {
 		})
 	}
 }
+
+func Test_staleConfigs(t *testing.T) {
+	controller := func(topics ...string) *MQTTControllerMock {
+		return &MQTTControllerMock{
+			ConfigsFunc: func() []*mqttapi.Msg {
+				configs := make([]*mqttapi.Msg, 0, len(topics)+1)
+				for _, topic := range topics {
+					configs = append(configs, mqttapi.NewMsg(topic, []byte(`{}`)))
+				}
+				// Configs that could not be generated are nil.
+				return append(configs, nil)
+			},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		oldControllers []MQTTController
+		newControllers []MQTTController
+		want           []string
+	}{
+		{
+			name:           "entity removed",
+			oldControllers: []MQTTController{controller("a", "b"), controller("c")},
+			newControllers: []MQTTController{controller("a"), controller("c", "d")},
+			want:           []string{"b"},
+		},
+		{
+			name:           "no changes",
+			oldControllers: []MQTTController{controller("a")},
+			newControllers: []MQTTController{controller("a")},
+		},
+		{
+			name:           "all removed",
+			oldControllers: []MQTTController{controller("a", "b")},
+			want:           []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, msg := range staleConfigs(tt.oldControllers, tt.newControllers) {
+				got = append(got, msg.Topic)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAgent_runMQTTWorkers_disabled(t *testing.T) {
+	agent := newOrphansTestAgent(t, "http://localhost:8123")
+
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+
+	reloadCh := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		agent.runMQTTWorkers(ctx, reloadCh)
+	}()
+
+	// The workers keep running while MQTT is not enabled, so that it can be
+	// enabled by a reload.
+	reloadCh <- struct{}{}
+	reloadCh <- struct{}{}
+	assert.Never(t, func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}, 100*time.Millisecond, 10*time.Millisecond)
+	assert.False(t, agent.mqttConnected.Load())
+
+	cancelFunc()
+	<-done
+}
@@ -10,33 +10,37 @@ import (
 	"log/slog"
 
 	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
 )
 
 // runNotificationsWorker will run a goroutine that is listening for
-// notification messages from Home Assistant on a websocket connection. Any
-// received notifications will be dipslayed on the device running the agent.
-func (agent *Agent) runNotificationsWorker(ctx context.Context) {
+// notification messages from the given Home Assistant server on a websocket
+// connection. Any received notifications will be dipslayed on the device
+// running the agent.
+func (agent *Agent) runNotificationsWorker(ctx context.Context, server *preferences.Server) {
 	// Don't run if agent is running headless.
 	if agent.headless {
 		return
 	}
 
+	logger := agent.logger.With(slog.String("server", server.Name))
+
 	websocket := hass.NewWebsocket(ctx,
-		agent.prefs.WebsocketURL(),
-		agent.prefs.WebhookID(),
-		agent.prefs.Token())
+		server.WebsocketURL(),
+		server.WebhookID(),
+		server.Token())
 
 	for {
 		select {
 		case <-ctx.Done():
-			agent.logger.Debug("Stopping notifications worker.")
+			logger.Debug("Stopping notifications worker.")
 
 			return
 		default:
 			// Connect the websocket.
 			notifyCh, err := websocket.Connect(ctx)
 			if err != nil {
-				agent.logger.Warn("Failed to connect to websocket.", slog.Any("error", err))
+				logger.Warn("Failed to connect to websocket.", slog.Any("error", err))
 
 				return
 			}
@@ -0,0 +1,139 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor/registry"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+var (
+	ErrServerNotRegistered = errors.New("not registered with server")
+	ErrNoToken             = errors.New("no access token for server")
+)
+
+// registeredServer returns the server with the given name, if the agent is
+// registered with it.
+func (agent *Agent) registeredServer(name string) (*preferences.Server, error) {
+	server, err := agent.prefs.Server(name)
+	if err != nil {
+		return nil, fmt.Errorf("could not find server: %w", err)
+	}
+
+	if !server.Registered {
+		return nil, fmt.Errorf("%w: %s", ErrServerNotRegistered, server.Name)
+	}
+
+	return server, nil
+}
+
+// OrphanedSensors returns the sensors registered with the server with the
+// given name that have not been sent for longer than olderThan. If olderThan
+// is zero, the orphan period from the sensor preferences of the server is
+// used.
+func (agent *Agent) OrphanedSensors(name string, olderThan time.Duration) ([]registry.Entry, error) {
+	server, err := agent.prefs.Server(name)
+	if err != nil {
+		return nil, fmt.Errorf("could not find server: %w", err)
+	}
+
+	if olderThan == 0 {
+		sensorPrefs := server.Sensors
+		if sensorPrefs == nil {
+			sensorPrefs = agent.prefs.SensorPreferences()
+		}
+
+		olderThan = sensorPrefs.OrphanPeriod()
+	}
+
+	reg, err := registry.Load(agent.serverRegistryPath(server.Name))
+	if err != nil {
+		return nil, fmt.Errorf("could not load registry: %w", err)
+	}
+
+	return reg.Orphans(time.Now().Add(-olderThan)), nil
+}
+
+// MarkSensorsUnavailable sets the state of the sensors with the given IDs to
+// unavailable in the server with the given name. The sensors must have been
+// sent since the tracker state was saved, so that their details are known.
+func (agent *Agent) MarkSensorsUnavailable(ctx context.Context, name string, ids ...string) error {
+	server, err := agent.registeredServer(name)
+	if err != nil {
+		return err
+	}
+
+	trk, err := agent.loadTracker(agent.serverRegistryPath(server.Name))
+	if err != nil {
+		return fmt.Errorf("could not load tracker: %w", err)
+	}
+
+	client := hass.NewClient(ctx, trk, nil)
+	client.Endpoint(server.RestAPIURL(), defaultTimeout)
+
+	var errs error
+
+	for _, id := range ids {
+		details, err := trk.Get(id)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", id, err))
+
+			continue
+		}
+
+		if err := client.MarkUnavailable(ctx, details); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", id, err))
+		}
+	}
+
+	return errs
+}
+
+// RemoveSensors removes the sensors with the given IDs from the server with
+// the given name and forgets them in its registry. The token must be a
+// long-lived access token of a Home Assistant administrator. If token is
+// empty, the token used to register with the server is used. The IDs of the
+// sensors that were removed are returned, along with any errors for those
+// that were not.
+func (agent *Agent) RemoveSensors(ctx context.Context, name, token string, ids ...string) ([]string, error) {
+	server, err := agent.registeredServer(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if token == "" {
+		token = server.Token()
+	}
+
+	if token == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNoToken, server.Name)
+	}
+
+	reg, err := registry.Load(agent.serverRegistryPath(server.Name))
+	if err != nil {
+		return nil, fmt.Errorf("could not load registry: %w", err)
+	}
+
+	removed, errs := hass.RemoveEntities(ctx, server.WebsocketURL(), token, server.WebhookID(), ids...)
+
+	for _, id := range removed {
+		if err := reg.Forget(id); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", id, err))
+		}
+	}
+
+	if errs != nil {
+		return removed, fmt.Errorf("could not remove all sensors: %w", errs)
+	}
+
+	return removed, nil
+}
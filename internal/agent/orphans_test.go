@@ -0,0 +1,282 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/lxzan/gws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor/registry"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/scripts"
+)
+
+// newOrphansTestAgent returns an agent registered with the given server URL,
+// with its configuration in a temporary directory.
+func newOrphansTestAgent(t *testing.T, url string) *Agent {
+	t.Helper()
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	return &Agent{
+		id: "orphans_test",
+		prefs: &preferences.Preferences{
+			Registration: &preferences.Registration{Server: url, Token: "token"},
+			Hass:         &preferences.Hass{RestAPIURL: url},
+			Sensors:      &preferences.Sensors{OrphanAfter: "24h"},
+			Registered:   true,
+		},
+		logger: slog.Default(),
+	}
+}
+
+func TestAgent_OrphanedSensors(t *testing.T) {
+	agent := newOrphansTestAgent(t, "http://localhost:8123")
+
+	now := time.Now()
+	registryData, err := json.Marshal(map[string]any{
+		"current":  map[string]any{"registered": true, "last_seen": now},
+		"old":      map[string]any{"registered": true, "last_seen": now.Add(-48 * time.Hour)},
+		"very_old": map[string]any{"registered": true, "last_seen": now.Add(-96 * time.Hour)},
+	})
+	require.NoError(t, err)
+
+	path := agent.serverRegistryPath(preferences.DefaultServerName)
+	require.NoError(t, os.MkdirAll(path, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(path, "registry.json"), registryData, 0o600))
+
+	// The orphan period from the preferences is used by default.
+	orphans, err := agent.OrphanedSensors("", 0)
+	require.NoError(t, err)
+	require.Len(t, orphans, 2)
+	assert.Equal(t, "old", orphans[0].ID)
+	assert.Equal(t, "very_old", orphans[1].ID)
+
+	orphans, err = agent.OrphanedSensors(preferences.DefaultServerName, 72*time.Hour)
+	require.NoError(t, err)
+	require.Len(t, orphans, 1)
+	assert.Equal(t, "very_old", orphans[0].ID)
+
+	_, err = agent.OrphanedSensors("missing", 0)
+	require.ErrorIs(t, err, preferences.ErrUnknownServer)
+}
+
+func TestAgent_OrphanedSensors_sent(t *testing.T) {
+	// A fake Home Assistant webhook that accepts sensor updates.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Data map[string]any `json:"data"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		id, _ := req.Data["unique_id"].(string) //nolint:errcheck
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"` + id + `":{"success":true}}`)) //nolint:errcheck
+	}))
+	defer server.Close()
+
+	agent := newOrphansTestAgent(t, server.URL)
+
+	lastSeen := time.Now().Add(-48 * time.Hour)
+	registryData, err := json.Marshal(map[string]any{
+		"script_sensor":   map[string]any{"registered": true, "last_seen": lastSeen},
+		"excluded_sensor": map[string]any{"registered": true, "last_seen": lastSeen},
+	})
+	require.NoError(t, err)
+
+	path := agent.serverRegistryPath(preferences.DefaultServerName)
+	require.NoError(t, os.MkdirAll(path, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(path, "registry.json"), registryData, 0o600))
+
+	trk, err := sensor.NewTracker()
+	require.NoError(t, err)
+	reg, err := registry.Load(path)
+	require.NoError(t, err)
+
+	filter, err := sensor.NewFilter(nil, []string{"excluded_*"}, nil, nil)
+	require.NoError(t, err)
+
+	client := hass.NewClient(context.TODO(), trk, reg)
+	client.Endpoint(server.URL, time.Second)
+	client.SetSensorFilter(filter)
+
+	require.NoError(t, client.ProcessSensor(context.TODO(), &scripts.ScriptSensor{SensorID: "script_sensor", SensorName: "Script Sensor", SensorState: 1}))
+	require.NoError(t, client.ProcessSensor(context.TODO(), &linux.Sensor{UniqueID: "excluded_sensor", DisplayName: "Excluded", IconString: "mdi:test", Value: 1}))
+	require.NoError(t, client.SaveState())
+
+	// Sensors that are still sent are not orphaned, wherever they come from.
+	// Excluded sensors are not sent, so they are.
+	orphans, err := agent.OrphanedSensors("", 0)
+	require.NoError(t, err)
+	require.Len(t, orphans, 1)
+	assert.Equal(t, "excluded_sensor", orphans[0].ID)
+}
+
+func TestAgent_MarkSensorsUnavailable(t *testing.T) {
+	var states []any
+
+	// A fake Home Assistant webhook that accepts sensor updates.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Data map[string]any `json:"data"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		states = append(states, req.Data["state"])
+		id, _ := req.Data["unique_id"].(string) //nolint:errcheck
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"` + id + `":{"success":true}}`)) //nolint:errcheck
+	}))
+	defer server.Close()
+
+	agent := newOrphansTestAgent(t, server.URL)
+
+	trk, err := agent.loadTracker(agent.serverRegistryPath(preferences.DefaultServerName))
+	require.NoError(t, err)
+	require.NoError(t, trk.Add(&linux.Sensor{UniqueID: "old", DisplayName: "Old", IconString: "mdi:test", Value: 1}))
+	require.NoError(t, trk.Save())
+
+	require.NoError(t, agent.MarkSensorsUnavailable(context.TODO(), "", "old"))
+	assert.Equal(t, []any{"unavailable"}, states)
+
+	// Sensors that are not tracked cannot be marked unavailable.
+	require.Error(t, agent.MarkSensorsUnavailable(context.TODO(), "", "untracked"))
+
+	agent.prefs.Registered = false
+	require.ErrorIs(t, agent.MarkSensorsUnavailable(context.TODO(), "", "old"), ErrServerNotRegistered)
+}
+
+// fakeEntityRegistry is a fake Home Assistant websocket API with an entity
+// registry.
+type fakeEntityRegistry struct {
+	gws.BuiltinEventHandler
+	entities map[string]string
+	token    string
+	removed  []string
+}
+
+func (f *fakeEntityRegistry) OnOpen(socket *gws.Conn) {
+	socket.WriteString(`{"type":"auth_required"}`) //nolint:errcheck
+}
+
+func (f *fakeEntityRegistry) OnMessage(socket *gws.Conn, message *gws.Message) {
+	defer message.Close()
+
+	var req struct {
+		Type        string `json:"type"`
+		AccessToken string `json:"access_token"`
+		EntityID    string `json:"entity_id"`
+		ID          uint64 `json:"id"`
+	}
+
+	if err := json.Unmarshal(message.Bytes(), &req); err != nil {
+		return
+	}
+
+	reply := map[string]any{"id": req.ID, "type": "result", "success": true}
+
+	switch req.Type {
+	case "auth":
+		if req.AccessToken != f.token {
+			socket.WriteString(`{"type":"auth_invalid"}`) //nolint:errcheck
+
+			return
+		}
+
+		socket.WriteString(`{"type":"auth_ok"}`) //nolint:errcheck
+
+		return
+	case "config/entity_registry/list":
+		var entries []map[string]string
+		for uniqueID, entityID := range f.entities {
+			entries = append(entries, map[string]string{"entity_id": entityID, "unique_id": uniqueID, "platform": "mobile_app"})
+		}
+
+		reply["result"] = entries
+	case "config/entity_registry/remove":
+		f.removed = append(f.removed, req.EntityID)
+	}
+
+	data, _ := json.Marshal(reply)            //nolint:errcheck
+	socket.WriteMessage(gws.OpcodeText, data) //nolint:errcheck
+}
+
+func TestAgent_RemoveSensors(t *testing.T) {
+	registry := &fakeEntityRegistry{
+		token:    "token",
+		entities: map[string]string{"webhook_old": "sensor.old"},
+	}
+	upgrader := gws.NewUpgrader(registry, nil)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		socket, err := upgrader.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+
+		go socket.ReadLoop()
+	}))
+	defer server.Close()
+
+	agent := newOrphansTestAgent(t, server.URL)
+	agent.prefs.Hass.WebhookID = "webhook"
+	agent.prefs.Hass.WebsocketURL = "ws" + strings.TrimPrefix(server.URL, "http")
+
+	registryData, err := json.Marshal(map[string]any{
+		"old": map[string]any{"registered": true, "last_seen": time.Now().Add(-48 * time.Hour)},
+	})
+	require.NoError(t, err)
+
+	path := agent.serverRegistryPath(preferences.DefaultServerName)
+	require.NoError(t, os.MkdirAll(path, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(path, "registry.json"), registryData, 0o600))
+
+	// Sensors not in Home Assistant are reported, but do not stop the others
+	// being removed.
+	removed, err := agent.RemoveSensors(context.TODO(), "", "", "old", "missing")
+	require.Error(t, err)
+	assert.Equal(t, []string{"old"}, removed)
+	assert.Equal(t, []string{"sensor.old"}, registry.removed)
+
+	// Removed sensors are forgotten in the registry.
+	orphans, err := agent.OrphanedSensors("", 0)
+	require.NoError(t, err)
+	assert.Empty(t, orphans)
+
+	_, err = agent.RemoveSensors(context.TODO(), "", "wrong", "old")
+	require.Error(t, err)
+}
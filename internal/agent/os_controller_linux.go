@@ -13,6 +13,7 @@ import (
 	mqtthass "github.com/joshuar/go-hass-anything/v11/pkg/hass"
 	mqttapi "github.com/joshuar/go-hass-anything/v11/pkg/mqtt"
 
+	"github.com/joshuar/go-hass-agent/internal/dbusapi"
 	"github.com/joshuar/go-hass-agent/internal/linux"
 	"github.com/joshuar/go-hass-agent/internal/linux/apps"
 	"github.com/joshuar/go-hass-agent/internal/linux/battery"
@@ -26,33 +27,49 @@ import (
 	"github.com/joshuar/go-hass-agent/internal/linux/power"
 	"github.com/joshuar/go-hass-agent/internal/linux/problems"
 	"github.com/joshuar/go-hass-agent/internal/linux/system"
+	"github.com/joshuar/go-hass-agent/internal/linux/textfile"
 	"github.com/joshuar/go-hass-agent/internal/linux/user"
 	"github.com/joshuar/go-hass-agent/internal/logging"
 )
 
-// allworkers is the list of sensor allworkers supported on Linux.
-var allworkers = []func(context.Context) (*linux.SensorWorker, error){
-	apps.NewAppWorker,
-	battery.NewBatteryWorker,
-	cpu.NewUsageWorker,
-	cpu.NewLoadAvgWorker,
-	cpu.NewUsageWorker,
-	desktop.NewDesktopWorker,
-	disk.NewIOWorker,
-	disk.NewUsageWorker,
-	location.NewLocationWorker,
-	mem.NewUsageWorker,
-	net.NewConnectionWorker,
-	net.NewRatesWorker,
-	power.NewLaptopWorker,
-	power.NewProfileWorker,
-	power.NewStateWorker,
-	power.NewScreenLockWorker,
-	problems.NewProblemsWorker,
-	system.NewHWMonWorker,
-	system.NewInfoWorker,
-	system.NewTimeWorker,
-	user.NewUserWorker,
+// allworkers is the list of sensor allworkers supported on Linux, with their
+// IDs.
+var allworkers = []struct {
+	newWorker func(context.Context) (*linux.SensorWorker, error)
+	id        string
+}{
+	{id: apps.WorkerID, newWorker: apps.NewAppWorker},
+	{id: battery.WorkerID, newWorker: battery.NewBatteryWorker},
+	{id: cpu.UsageWorkerID, newWorker: cpu.NewUsageWorker},
+	{id: cpu.LoadAvgsWorkerID, newWorker: cpu.NewLoadAvgWorker},
+	{id: cpu.UsageWorkerID, newWorker: cpu.NewUsageWorker},
+	{id: desktop.WorkerID, newWorker: desktop.NewDesktopWorker},
+	{id: disk.RatesWorkerID, newWorker: disk.NewIOWorker},
+	{id: disk.UsageWorkerID, newWorker: disk.NewUsageWorker},
+	{id: location.WorkerID, newWorker: location.NewLocationWorker},
+	{id: mem.WorkerID, newWorker: mem.NewUsageWorker},
+	{id: net.ConnectionWorkerID, newWorker: net.NewConnectionWorker},
+	{id: net.RatesWorkerID, newWorker: net.NewRatesWorker},
+	{id: power.LaptopWorkerID, newWorker: power.NewLaptopWorker},
+	{id: power.ProfileWorkerID, newWorker: power.NewProfileWorker},
+	{id: power.StateWorkerID, newWorker: power.NewStateWorker},
+	{id: power.ScreenLockWorkerID, newWorker: power.NewScreenLockWorker},
+	{id: problems.WorkerID, newWorker: problems.NewProblemsWorker},
+	{id: system.HWMonWorkerID, newWorker: system.NewHWMonWorker},
+	{id: system.InfoWorkerID, newWorker: system.NewInfoWorker},
+	{id: system.TimeWorkerID, newWorker: system.NewTimeWorker},
+	{id: textfile.WorkerID, newWorker: textfile.NewTextfileWorker},
+	{id: user.WorkerID, newWorker: user.NewUserWorker},
+}
+
+// osWorkerIDs returns the IDs of the sensor workers supported on Linux.
+func osWorkerIDs() []string {
+	ids := []string{dbusapi.WorkerID}
+	for _, worker := range allworkers {
+		ids = append(ids, worker.id)
+	}
+
+	return ids
 }
 
 var (
@@ -179,6 +196,10 @@ func (c *linuxMQTTController) generateConfig(e entity) *mqttapi.Msg {
 func (agent *Agent) newOSController(ctx context.Context, mqttDevice *mqtthass.Device) (SensorController, MQTTController) {
 	ctx = linux.NewContext(ctx)
 
+	if agent.prefs != nil {
+		ctx = linux.WithTextfilePath(ctx, agent.prefs.TextfilePath())
+	}
+
 	logger := agent.logger.With(slog.Group("linux", slog.String("controller", "sensor")))
 	ctx = logging.ToContext(ctx, logger)
 	sensorController := &linuxSensorController{
@@ -189,15 +210,25 @@ func (agent *Agent) newOSController(ctx context.Context, mqttDevice *mqtthass.De
 	}
 
 	// Set up sensor workers.
-	for _, startWorkerFunc := range allworkers {
-		worker, err := startWorkerFunc(ctx)
+	for _, workerDef := range allworkers {
+		worker, err := workerDef.newWorker(ctx)
 		if err != nil {
 			sensorController.logger.Warn("Could not start a sensor worker.", slog.Any("error", err))
 
 			continue
 		}
 
-		sensorController.sensorWorkers[worker.ID()] = &sensorWorker{object: worker, started: false}
+		if agent.prefs != nil {
+			if interval, jitter := agent.prefs.WorkerInterval(worker.ID()); interval > 0 || jitter > 0 {
+				if err := worker.SetPollInterval(interval, jitter); err != nil {
+					sensorController.logger.Warn("Could not override worker interval.",
+						slog.String("worker", worker.ID()),
+						slog.Any("error", err))
+				}
+			}
+		}
+
+		sensorController.sensorWorkers[worker.ID()] = &sensorWorker{object: worker, disabled: !agent.workerEnabled(worker.ID())}
 	}
 
 	// Stop setup if there is no mqttDevice.
@@ -205,7 +236,14 @@ func (agent *Agent) newOSController(ctx context.Context, mqttDevice *mqtthass.De
 		return sensorController, nil
 	}
 
-	logger = agent.logger.With(slog.Group("linux", slog.String("controller", "mqtt")))
+	return sensorController, agent.newOSMQTTController(ctx, mqttDevice)
+}
+
+// newOSMQTTController creates the controller for the OS-specific MQTT
+// entities, such as the power and media controls. The context should be
+// created with linux.NewContext.
+func (agent *Agent) newOSMQTTController(ctx context.Context, mqttDevice *mqtthass.Device) MQTTController {
+	logger := agent.logger.With(slog.Group("linux", slog.String("controller", "mqtt")))
 	ctx = logging.ToContext(ctx, logger)
 	mqttController := &linuxMQTTController{
 		mqttWorker: &mqttWorker{
@@ -262,5 +300,5 @@ func (agent *Agent) newOSController(ctx context.Context, mqttDevice *mqtthass.De
 		<-ctx.Done()
 	}()
 
-	return sensorController, mqttController
+	return mqttController
 }
@@ -0,0 +1,428 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+)
+
+const (
+	// pipelineWorkers is the number of sensor updates processed at the same
+	// time.
+	pipelineWorkers = 4
+	// pipelineCapacity is the number of sensors that can be waiting to be
+	// processed. Once reached, updates of other sensors are dropped.
+	pipelineCapacity = 256
+	// pipelineDrainTimeout is how long to wait for queued sensors to be
+	// processed when the pipeline is closed.
+	pipelineDrainTimeout = 10 * time.Second
+	// pipelineStatsInterval is how often the pipeline diagnostics are sent.
+	pipelineStatsInterval = time.Minute
+	// pipelineRetryInterval is how often updates deferred while Home Assistant
+	// is unavailable are queued again.
+	pipelineRetryInterval = 5 * time.Second
+)
+
+// pipelineEntry is a sensor update waiting to be processed.
+type pipelineEntry struct {
+	queued  time.Time
+	details sensor.Details
+	// servers are the names of the servers a deferred update is still to be
+	// sent to. If nil, it is sent to all servers.
+	servers []string
+}
+
+// pipelineResult is the result of processing a sensor update.
+type pipelineResult struct {
+	// deferred is the update if it was not sent because Home Assistant is
+	// unavailable.
+	deferred *pipelineEntry
+	id       string
+	// servers are the names of the unavailable servers the deferred update
+	// was not sent to. If nil, it was not sent to any server.
+	servers []string
+	latency time.Duration
+}
+
+// stallCheck asks the dispatcher whether the pipeline has stalled.
+type stallCheck struct {
+	reply chan bool
+	after time.Duration
+}
+
+// pipelineStats are the statistics of the pipeline since they were last sent.
+type pipelineStats struct {
+	totalLatency time.Duration
+	maxLatency   time.Duration
+	processed    int
+	coalesced    int
+	dropped      int
+	maxDepth     int
+}
+
+// sensorPipeline processes sensor updates with a bounded number of workers.
+// Updates of the same sensor are processed in order and never at the same
+// time. If a sensor is updated again while an earlier update is waiting to be
+// processed, only the latest update is processed. When the pipeline is full,
+// updates of sensors that are not already waiting are dropped, so that
+// submitting an update never waits for Home Assistant. Updates not sent
+// because Home Assistant is unavailable are deferred and queued again
+// periodically as there is room, unless replaced by a later update of the same
+// sensor.
+type sensorPipeline struct {
+	process    func(ctx context.Context, details sensor.Details) error
+	logger     *slog.Logger
+	processCtx context.Context //nolint:containedctx
+	cancelFunc context.CancelFunc
+	in         chan sensor.Details
+	work       chan *pipelineEntry
+	results    chan pipelineResult
+	finished   chan struct{}
+	stallCh    chan stallCheck
+	// The following are only accessed by the dispatcher.
+	pending  map[string]*pipelineEntry
+	inFlight map[string]bool
+	deferred map[string]*pipelineEntry
+	queue    []string
+	stats    pipelineStats
+	// lastProgress is when an update was last processed, or when the
+	// pipeline last became busy.
+	lastProgress time.Time
+}
+
+// newSensorPipeline creates and starts a pipeline that processes sensor updates
+// with the given function. Updates are processed with a context that is not
+// canceled with the given context, so that queued updates can be processed
+// when the pipeline is closed.
+func newSensorPipeline(ctx context.Context, logger *slog.Logger, process func(ctx context.Context, details sensor.Details) error) *sensorPipeline {
+	processCtx, cancelFunc := context.WithCancel(context.WithoutCancel(ctx))
+
+	pipeline := &sensorPipeline{
+		process:    process,
+		logger:     logger,
+		processCtx: processCtx,
+		cancelFunc: cancelFunc,
+		in:         make(chan sensor.Details),
+		work:       make(chan *pipelineEntry),
+		results:    make(chan pipelineResult),
+		finished:   make(chan struct{}),
+		stallCh:    make(chan stallCheck),
+		pending:    make(map[string]*pipelineEntry),
+		inFlight:   make(map[string]bool),
+		deferred:   make(map[string]*pipelineEntry),
+	}
+
+	var wg sync.WaitGroup
+
+	for range pipelineWorkers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			pipeline.runWorker()
+		}()
+	}
+
+	go pipeline.dispatch()
+
+	go func() {
+		wg.Wait()
+		close(pipeline.finished)
+	}()
+
+	return pipeline
+}
+
+// In returns the channel sensor updates are sent on to be processed. Sending
+// only waits for the dispatcher to take the update, not for any to be
+// processed.
+func (p *sensorPipeline) In() chan<- sensor.Details {
+	return p.in
+}
+
+// Submit queues the given sensor update for processing, or drops it if the
+// pipeline is full. It only waits for the dispatcher to take the update, or
+// until the given context is canceled.
+func (p *sensorPipeline) Submit(ctx context.Context, details sensor.Details) {
+	select {
+	case p.in <- details:
+	case <-ctx.Done():
+	}
+}
+
+// Close stops the pipeline accepting updates and waits for any queued updates
+// to be processed. If they are not processed within pipelineDrainTimeout, they
+// are dropped, as are any deferred updates. Submit must not be called after
+// Close.
+func (p *sensorPipeline) Close() {
+	close(p.in)
+
+	select {
+	case <-p.finished:
+	case <-time.After(pipelineDrainTimeout):
+		p.logger.Warn("Timed out processing queued sensor updates, dropping remaining updates.")
+		p.cancelFunc()
+		<-p.finished
+	}
+
+	p.cancelFunc()
+}
+
+// Stalled reports whether the pipeline has updates waiting or being processed
+// but has not finished processing any within the given duration. A pipeline
+// that does not respond within the duration is also stalled.
+func (p *sensorPipeline) Stalled(after time.Duration) bool {
+	check := stallCheck{after: after, reply: make(chan bool, 1)}
+
+	timer := time.NewTimer(after)
+	defer timer.Stop()
+
+	select {
+	case p.stallCh <- check:
+	case <-p.finished:
+		return false
+	case <-timer.C:
+		return true
+	}
+
+	select {
+	case stalled := <-check.reply:
+		return stalled
+	case <-timer.C:
+		return true
+	}
+}
+
+// runWorker processes updates until the pipeline is closed.
+func (p *sensorPipeline) runWorker() {
+	for entry := range p.work {
+		// Once the pipeline has timed out draining, skip processing.
+		result := pipelineResult{id: entry.details.ID()}
+
+		if p.processCtx.Err() == nil {
+			err := p.process(withServers(p.processCtx, entry.servers), entry.details)
+
+			var deferredErr *deferredError
+
+			switch {
+			case errors.As(err, &deferredErr):
+				// Only some servers are unavailable. The update is sent again
+				// to just those.
+				p.logger.Debug("Deferring sensor update.", slog.String("id", result.id), slog.Any("servers", deferredErr.servers))
+
+				if deferredErr.err != nil {
+					p.logger.Error("Process sensor failed.", slog.Any("error", deferredErr.err))
+				}
+
+				result.deferred = entry
+				result.servers = deferredErr.servers
+			case errors.Is(err, hass.ErrCircuitOpen):
+				p.logger.Debug("Deferring sensor update.", slog.String("id", result.id), slog.Any("error", err))
+
+				result.deferred = entry
+				result.servers = entry.servers
+			case err != nil:
+				p.logger.Error("Process sensor failed.", slog.Any("error", err))
+			}
+		}
+
+		result.latency = time.Since(entry.queued)
+		p.results <- result
+	}
+}
+
+// dispatch hands queued updates to the workers and sends the pipeline
+// diagnostics, until the pipeline is closed and all updates have been
+// processed.
+func (p *sensorPipeline) dispatch() {
+	defer close(p.work)
+
+	ticker := time.NewTicker(pipelineStatsInterval)
+	defer ticker.Stop()
+
+	retryTicker := time.NewTicker(pipelineRetryInterval)
+	defer retryTicker.Stop()
+
+	in := p.in
+
+	for {
+		// Only hand out work when there is an update waiting.
+		var (
+			workCh chan<- *pipelineEntry
+			next   *pipelineEntry
+		)
+
+		if len(p.queue) > 0 {
+			workCh = p.work
+			next = p.pending[p.queue[0]]
+		}
+
+		select {
+		case details, ok := <-in:
+			if !ok {
+				// The pipeline is closed. Finish once all queued updates
+				// are processed.
+				in = nil
+
+				break
+			}
+
+			// Updates of queued sensors replace the queued update, so are
+			// always accepted.
+			if _, queued := p.pending[details.ID()]; queued || len(p.pending) < pipelineCapacity {
+				p.enqueue(details)
+			} else {
+				p.stats.dropped++
+				p.logger.Debug("Sensor pipeline full, dropping sensor update.", slog.String("id", details.ID()))
+			}
+		case workCh <- next:
+			id := p.queue[0]
+			p.queue = p.queue[1:]
+			delete(p.pending, id)
+			p.inFlight[id] = true
+		case result := <-p.results:
+			p.completed(result)
+		case check := <-p.stallCh:
+			busy := len(p.pending) > 0 || len(p.inFlight) > 0
+			check.reply <- busy && time.Since(p.lastProgress) > check.after
+		case <-ticker.C:
+			p.enqueue(p.queueSensor())
+			p.enqueue(p.latencySensor())
+			p.stats = pipelineStats{}
+		case <-retryTicker.C:
+			// Deferred updates are not retried once the pipeline is closed.
+			if in != nil {
+				p.retryDeferred()
+			}
+		}
+
+		if in == nil && len(p.pending) == 0 && len(p.inFlight) == 0 {
+			if len(p.deferred) > 0 {
+				p.logger.Warn("Home Assistant is unavailable, dropping deferred sensor updates.",
+					slog.Int("count", len(p.deferred)))
+			}
+
+			return
+		}
+	}
+}
+
+// enqueue queues the given update. If an update of the same sensor is already
+// waiting or deferred, it is replaced.
+func (p *sensorPipeline) enqueue(details sensor.Details) {
+	id := details.ID()
+
+	if entry, found := p.deferred[id]; found {
+		delete(p.deferred, id)
+		p.stats.coalesced++
+
+		entry.servers = nil
+		p.requeue(entry, details)
+
+		return
+	}
+
+	if entry, found := p.pending[id]; found {
+		// Keep the time the first update was queued, so the latency shows how
+		// stale the sensor in Home Assistant is. The new update is sent to
+		// all servers.
+		entry.details = details
+		entry.servers = nil
+		p.stats.coalesced++
+
+		return
+	}
+
+	p.requeue(&pipelineEntry{details: details, queued: time.Now()}, details)
+}
+
+// requeue queues the given entry with the given update.
+func (p *sensorPipeline) requeue(entry *pipelineEntry, details sensor.Details) {
+	id := details.ID()
+
+	// An idle pipeline has not stalled, so measure progress from when it
+	// became busy.
+	if len(p.pending) == 0 && len(p.inFlight) == 0 {
+		p.lastProgress = time.Now()
+	}
+
+	entry.details = details
+	p.pending[id] = entry
+
+	// If an earlier update of this sensor is being processed, the update is
+	// queued when that completes.
+	if !p.inFlight[id] {
+		p.queue = append(p.queue, id)
+	}
+
+	p.stats.maxDepth = max(p.stats.maxDepth, len(p.pending))
+}
+
+// retryDeferred queues deferred updates again, as long as there is room in the
+// pipeline. Any others are retried later.
+func (p *sensorPipeline) retryDeferred() {
+	for id, entry := range p.deferred {
+		if len(p.pending) >= pipelineCapacity {
+			return
+		}
+
+		delete(p.deferred, id)
+		p.requeue(entry, entry.details)
+	}
+}
+
+// completed records the given result and queues any update of the same sensor
+// that was submitted while it was being processed.
+func (p *sensorPipeline) completed(result pipelineResult) {
+	delete(p.inFlight, result.id)
+
+	p.lastProgress = time.Now()
+
+	if _, found := p.pending[result.id]; found {
+		p.queue = append(p.queue, result.id)
+	} else if result.deferred != nil {
+		// Keep the update to send once Home Assistant is available, unless
+		// a later update has replaced it.
+		result.deferred.servers = result.servers
+		p.deferred[result.id] = result.deferred
+
+		return
+	}
+
+	p.stats.processed++
+	p.stats.totalLatency += result.latency
+	p.stats.maxLatency = max(p.stats.maxLatency, result.latency)
+}
+
+// queueSensor returns a sensor with the current queue depth of the pipeline.
+func (p *sensorPipeline) queueSensor() *pipelineQueueSensor {
+	return &pipelineQueueSensor{
+		depth:     len(p.pending),
+		maxDepth:  p.stats.maxDepth,
+		processed: p.stats.processed,
+		coalesced: p.stats.coalesced,
+		dropped:   p.stats.dropped,
+	}
+}
+
+// latencySensor returns a sensor with the average latency of the pipeline
+// since the last diagnostics were sent.
+func (p *sensorPipeline) latencySensor() *pipelineLatencySensor {
+	latency := &pipelineLatencySensor{maxLatency: p.stats.maxLatency}
+
+	if p.stats.processed > 0 {
+		latency.latency = p.stats.totalLatency / time.Duration(p.stats.processed)
+	}
+
+	return latency
+}
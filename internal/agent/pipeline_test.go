@@ -0,0 +1,244 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+)
+
+func TestSensorPipeline_ordering(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		processed []string
+	)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	pipeline := newSensorPipeline(context.TODO(), slog.Default(), func(_ context.Context, details sensor.Details) error {
+		// Block the first update until the others have been submitted.
+		if details.State() == 1 {
+			close(started)
+			<-release
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		processed = append(processed, details.ID()+"="+strconv.Itoa(details.State().(int)))
+
+		return nil
+	})
+
+	pipeline.Submit(context.TODO(), &linux.Sensor{UniqueID: "a", Value: 1})
+	<-started
+	pipeline.Submit(context.TODO(), &linux.Sensor{UniqueID: "a", Value: 2})
+	pipeline.Submit(context.TODO(), &linux.Sensor{UniqueID: "a", Value: 3})
+	pipeline.Submit(context.TODO(), &linux.Sensor{UniqueID: "b", Value: 4})
+
+	// b is not held up by a.
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(processed) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	close(release)
+	pipeline.Close()
+
+	// The second update of a was replaced by the third, which was only
+	// processed after the first.
+	assert.Equal(t, []string{"b=4", "a=1", "a=3"}, processed)
+}
+
+func TestSensorPipeline_bounded(t *testing.T) {
+	var (
+		mu                sync.Mutex
+		running, maxCount int
+		processed         int
+	)
+
+	pipeline := newSensorPipeline(context.TODO(), slog.Default(), func(_ context.Context, _ sensor.Details) error {
+		mu.Lock()
+		running++
+		maxCount = max(maxCount, running)
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		running--
+		processed++
+		mu.Unlock()
+
+		return nil
+	})
+
+	for idx := range 100 {
+		pipeline.Submit(context.TODO(), &linux.Sensor{UniqueID: strconv.Itoa(idx)})
+	}
+
+	// All queued updates are processed on close.
+	pipeline.Close()
+
+	assert.Equal(t, 100, processed)
+	assert.LessOrEqual(t, maxCount, pipelineWorkers)
+}
+
+func TestSensorPipeline_full(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		processed []string
+	)
+
+	release := make(chan struct{})
+
+	pipeline := newSensorPipeline(context.TODO(), slog.Default(), func(_ context.Context, details sensor.Details) error {
+		<-release
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		processed = append(processed, details.ID())
+
+		return nil
+	})
+
+	// Fill the workers and the queue.
+	for idx := range pipelineWorkers + pipelineCapacity {
+		pipeline.Submit(context.TODO(), &linux.Sensor{UniqueID: strconv.Itoa(idx)})
+	}
+
+	// Updates of sensors already queued are accepted and updates of new
+	// sensors are dropped, without waiting.
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second)
+	defer cancelFunc()
+
+	pipeline.Submit(ctx, &linux.Sensor{UniqueID: strconv.Itoa(pipelineWorkers)})
+	pipeline.Submit(ctx, &linux.Sensor{UniqueID: "dropped"})
+	require.NoError(t, ctx.Err())
+
+	close(release)
+	pipeline.Close()
+
+	assert.Len(t, processed, pipelineWorkers+pipelineCapacity)
+	assert.NotContains(t, processed, "dropped")
+}
+
+func TestSensorPipeline_deferred(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts int
+		sent     []string
+	)
+
+	unavailable := true
+
+	pipeline := newSensorPipeline(context.TODO(), slog.Default(), func(_ context.Context, details sensor.Details) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		attempts++
+
+		if unavailable {
+			return hass.ErrCircuitOpen
+		}
+
+		sent = append(sent, details.ID()+"="+strconv.Itoa(details.State().(int)))
+
+		return nil
+	})
+
+	pipeline.Submit(context.TODO(), &linux.Sensor{UniqueID: "a", Value: 1})
+	pipeline.Submit(context.TODO(), &linux.Sensor{UniqueID: "b", Value: 1})
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return attempts == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// Deferred updates do not stall the pipeline.
+	assert.Eventually(t, func() bool {
+		return !pipeline.Stalled(10 * time.Millisecond)
+	}, time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	unavailable = false
+	mu.Unlock()
+
+	// A later update replaces the deferred update.
+	pipeline.Submit(context.TODO(), &linux.Sensor{UniqueID: "a", Value: 2})
+	pipeline.Close()
+
+	// The remaining deferred update is dropped on close.
+	assert.Equal(t, []string{"a=2"}, sent)
+}
+
+func TestSensorPipeline_stats(t *testing.T) {
+	pipeline := &sensorPipeline{
+		pending:  make(map[string]*pipelineEntry),
+		inFlight: make(map[string]bool),
+	}
+
+	pipeline.enqueue(&linux.Sensor{UniqueID: "a"})
+	pipeline.enqueue(&linux.Sensor{UniqueID: "a"})
+	pipeline.enqueue(&linux.Sensor{UniqueID: "b"})
+	pipeline.completed(pipelineResult{id: "c", latency: 10 * time.Millisecond})
+	pipeline.completed(pipelineResult{id: "d", latency: 30 * time.Millisecond})
+
+	queue := pipeline.queueSensor()
+	assert.Equal(t, 2, queue.State())
+	assert.Equal(t, map[string]any{"max_depth": 2, "processed_count": 2, "coalesced_count": 1, "dropped_count": 0}, queue.Attributes())
+
+	latency := pipeline.latencySensor()
+	assert.Equal(t, int64(20), latency.State())
+	assert.Equal(t, map[string]any{"max_latency": int64(30)}, latency.Attributes())
+}
+
+func TestSensorPipeline_Stalled(t *testing.T) {
+	release := make(chan struct{})
+
+	pipeline := newSensorPipeline(context.TODO(), slog.Default(), func(_ context.Context, _ sensor.Details) error {
+		<-release
+
+		return nil
+	})
+
+	// An idle pipeline has not stalled.
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, pipeline.Stalled(20*time.Millisecond))
+
+	// A pipeline that has not processed its update has.
+	pipeline.Submit(context.TODO(), &linux.Sensor{UniqueID: "a", Value: 1})
+	assert.False(t, pipeline.Stalled(time.Second))
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, pipeline.Stalled(20*time.Millisecond))
+
+	// Once it processes the update, it is no longer stalled.
+	close(release)
+	assert.Eventually(t, func() bool {
+		return !pipeline.Stalled(20 * time.Millisecond)
+	}, time.Second, 10*time.Millisecond)
+
+	pipeline.Close()
+	assert.False(t, pipeline.Stalled(20*time.Millisecond))
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/adrg/xdg"
+
+	mqtthass "github.com/joshuar/go-hass-anything/v11/pkg/hass"
+
+	"github.com/joshuar/go-hass-agent/internal/logging"
+	"github.com/joshuar/go-hass-agent/internal/plugins"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+// newPluginsController creates a controller for the plugins in the plugins
+// directory. Each plugin is a worker of the controller, supervised like the
+// built-in workers. The plugins are also returned as MQTT controllers for any
+// entities they declare. If there are no plugins, a nil controller is
+// returned.
+func (agent *Agent) newPluginsController(ctx context.Context, mqttDevice *mqtthass.Device) (SensorController, []MQTTController) {
+	found, err := plugins.Discover(ctx, agent.pluginsDir(), preferences.AppVersion, mqttDevice)
+	if err != nil {
+		// Problems with individual plugins are not fatal, just report them.
+		agent.logger.Warn("Some plugins could not be added.", slog.Any("error", err))
+	}
+
+	if len(found) == 0 {
+		return nil, nil
+	}
+
+	controller := &deviceController{
+		sensorWorkers: make(map[string]*sensorWorker),
+		logger:        logging.FromContext(ctx).With(slog.String("controller", "plugins")),
+	}
+
+	var mqttControllers []MQTTController
+
+	for _, plugin := range found {
+		controller.sensorWorkers[plugin.ID()] = &sensorWorker{object: plugin, disabled: !agent.workerEnabled(plugin.ID())}
+
+		if mqttDevice != nil {
+			mqttControllers = append(mqttControllers, plugin)
+		}
+
+		agent.logger.Debug("Added plugin.",
+			slog.String("worker", plugin.ID()),
+			slog.String("name", plugin.Name()))
+	}
+
+	return controller, mqttControllers
+}
+
+// pluginsDir returns the directory to search for plugins.
+func (agent *Agent) pluginsDir() string {
+	return filepath.Join(xdg.ConfigHome, agent.id, "plugins")
+}
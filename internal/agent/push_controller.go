@@ -0,0 +1,33 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/joshuar/go-hass-agent/internal/logging"
+	"github.com/joshuar/go-hass-agent/internal/push"
+)
+
+// newPushController creates a controller for the local push endpoint, through
+// which other tools can send sensors in the same format as the mobile_app
+// webhook. If the endpoint is not enabled, a nil controller is returned.
+func (agent *Agent) newPushController(ctx context.Context) SensorController {
+	if agent.prefs == nil || agent.prefs.Push == nil || !agent.prefs.Push.Enabled {
+		return nil
+	}
+
+	logger := agent.logger.With(slog.String("controller", "push"))
+	server := push.New(logging.ToContext(ctx, logger), agent.prefs.Push)
+
+	return &deviceController{
+		sensorWorkers: map[string]*sensorWorker{
+			server.ID(): {object: server, disabled: !agent.workerEnabled(server.ID())},
+		},
+		logger: logger,
+	}
+}
@@ -11,10 +11,8 @@ import (
 	"fmt"
 	"log/slog"
 	"net/url"
-	"path/filepath"
 
 	"github.com/joshuar/go-hass-agent/internal/hass"
-	"github.com/joshuar/go-hass-agent/internal/hass/sensor/registry"
 	"github.com/joshuar/go-hass-agent/internal/preferences"
 )
 
@@ -80,10 +78,8 @@ func (agent *Agent) checkRegistration(ctx context.Context, trk Tracker) error {
 	}
 
 	if agent.forceRegister {
-		trk.Reset()
-
-		if err := registry.Reset(filepath.Dir(agent.GetRegistryPath())); err != nil {
-			agent.logger.Warn("Problem resetting registry.", slog.Any("error", err))
+		if err := agent.resetState(trk); err != nil {
+			agent.logger.Warn("Problem resetting sensor state.", slog.Any("error", err))
 		}
 	}
 
@@ -17,8 +17,12 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor/registry"
+	"github.com/joshuar/go-hass-agent/internal/linux"
 	"github.com/joshuar/go-hass-agent/internal/preferences"
 )
 
@@ -327,3 +331,67 @@ func Test_generateWebsocketURL(t *testing.T) {
 		})
 	}
 }
+
+func TestAgent_resetState(t *testing.T) {
+	details := &linux.Sensor{UniqueID: "old", DisplayName: "Old", IconString: "mdi:test", Value: 1}
+
+	// saveState saves a tracker and registry with a registered sensor.
+	saveState := func(t *testing.T, path string) *sensor.Tracker {
+		t.Helper()
+
+		trk, err := sensor.NewTracker(sensor.WithStateFile(filepath.Join(path, trackerFile)))
+		require.NoError(t, err)
+		require.NoError(t, trk.Add(details))
+		require.NoError(t, trk.Save())
+
+		reg, err := registry.Load(path)
+		require.NoError(t, err)
+		require.NoError(t, reg.SetRegistered(details.ID(), true))
+		require.NoError(t, reg.Save())
+
+		return trk
+	}
+
+	t.Run("not running", func(t *testing.T) {
+		agent := newOrphansTestAgent(t, "http://localhost:8123")
+		path := agent.GetRegistryPath()
+		trk := saveState(t, path)
+
+		require.NoError(t, agent.resetState(trk))
+		assert.NoFileExists(t, filepath.Join(path, trackerFile))
+
+		reg, err := registry.Load(path)
+		require.NoError(t, err)
+		assert.False(t, reg.IsRegistered(details.ID()))
+
+		// The tracker can still be used.
+		assert.Empty(t, trk.SensorList())
+		require.NoError(t, trk.Add(details))
+	})
+
+	t.Run("running", func(t *testing.T) {
+		agent := newOrphansTestAgent(t, "http://localhost:8123")
+		path := agent.GetRegistryPath()
+		trk := saveState(t, path)
+
+		reg, err := registry.Load(path)
+		require.NoError(t, err)
+
+		client := hass.NewClient(context.TODO(), trk, reg)
+		agent.hass = newServerClients(client)
+
+		require.NoError(t, agent.resetState(trk))
+		assert.NoFileExists(t, filepath.Join(path, trackerFile))
+
+		// Saving the state when the agent stops does not restore the sensors.
+		require.NoError(t, client.SaveState())
+
+		reg, err = registry.Load(path)
+		require.NoError(t, err)
+		assert.False(t, reg.IsRegistered(details.ID()))
+
+		trk, err = agent.loadTracker(path)
+		require.NoError(t, err)
+		assert.Empty(t, trk.SensorList())
+	})
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor/registry"
+	"github.com/joshuar/go-hass-agent/internal/recorder"
+)
+
+var ErrReplayFailed = errors.New("some sensor updates could not be replayed")
+
+// ReplaySensors sends the sensor updates in the recording at the given path to
+// Home Assistant, as if they were coming from the sensor workers. Updates are
+// replayed at the original speed multiplied by the given speed, or as fast as
+// possible if speed is 0. If server is not empty, updates are sent to that URL
+// instead of the Home Assistant the agent is registered with. The sensors are
+// tracked and registered separately from the agent, so replaying does not
+// affect its registry.
+func (agent *Agent) ReplaySensors(ctx context.Context, path string, speed float64, server string) error {
+	player, err := recorder.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not replay: %w", err)
+	}
+	defer player.Close()
+
+	if server == "" {
+		server = agent.prefs.RestAPIURL()
+	}
+
+	regPath, err := os.MkdirTemp("", agent.id+"-replay-")
+	if err != nil {
+		return fmt.Errorf("could not create replay registry: %w", err)
+	}
+	defer os.RemoveAll(regPath)
+
+	reg, err := registry.Load(regPath)
+	if err != nil {
+		return fmt.Errorf("could not create replay registry: %w", err)
+	}
+
+	trk, err := sensor.NewTracker()
+	if err != nil {
+		return fmt.Errorf("could not create replay tracker: %w", err)
+	}
+
+	client := hass.NewClient(ctx, trk, reg)
+	client.Endpoint(server, defaultTimeout)
+
+	agent.logger.Info("Replaying sensor updates.",
+		slog.String("recording", path),
+		slog.Time("recorded", player.Header.Started),
+		slog.String("server", server),
+		slog.Float64("speed", speed))
+
+	var total, failed int
+
+	err = player.Play(ctx, speed, func(record *recorder.Record) error {
+		total++
+
+		if err := client.ProcessSensor(ctx, record); err != nil {
+			failed++
+
+			agent.logger.Warn("Could not replay sensor update.",
+				slog.String("id", record.ID()),
+				slog.Any("error", err))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not replay: %w", err)
+	}
+
+	agent.logger.Info("Finished replaying sensor updates.", slog.Int("updates", total))
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d failed", ErrReplayFailed, failed, total)
+	}
+
+	return nil
+}
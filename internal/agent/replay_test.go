@@ -0,0 +1,89 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/recorder"
+)
+
+func TestAgent_ReplaySensors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.gz")
+
+	rec, err := recorder.New(path, preferences.AppVersion)
+	require.NoError(t, err)
+	require.NoError(t, rec.Record(&linux.Sensor{UniqueID: "sensor_a", DisplayName: "Sensor", IconString: "mdi:test", Value: 1}))
+	require.NoError(t, rec.Record(&linux.Sensor{UniqueID: "sensor_b", DisplayName: "Sensor", IconString: "mdi:test", Value: 2}))
+	require.NoError(t, rec.Record(&linux.Sensor{UniqueID: "sensor_a", DisplayName: "Sensor", IconString: "mdi:test", Value: 3}))
+	require.NoError(t, rec.Close())
+
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+
+	// A fake Home Assistant webhook that accepts all requests.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Data map[string]any `json:"data"`
+			Type string         `json:"type"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		id, _ := req.Data["unique_id"].(string) //nolint:errcheck
+
+		mu.Lock()
+		requests = append(requests, req.Type+" "+id)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+
+		switch req.Type {
+		case sensor.RequestTypeRegister:
+			w.Write([]byte(`{"success":true}`)) //nolint:errcheck
+		case sensor.RequestTypeUpdate:
+			w.Write([]byte(`{"` + id + `":{"success":true}}`)) //nolint:errcheck
+		}
+	}))
+	defer server.Close()
+
+	agent := &Agent{
+		id:     "replay_test",
+		prefs:  &preferences.Preferences{},
+		logger: slog.Default(),
+	}
+
+	require.NoError(t, agent.ReplaySensors(context.TODO(), path, 0, server.URL))
+	assert.Equal(t, []string{
+		"register_sensor sensor_a",
+		"register_sensor sensor_b",
+		"update_sensor_states sensor_a",
+	}, requests)
+
+	// Sending to a server that rejects the updates should fail.
+	server.Config.Handler = http.NotFoundHandler()
+	require.ErrorIs(t, agent.ReplaySensors(context.TODO(), path, 0, server.URL), ErrReplayFailed)
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+var ErrReregisterDisabled = errors.New("registering again automatically is not enabled")
+
+// removableClient is a Home Assistant client that reports when the mobile_app
+// integration of the agent is removed.
+type removableClient interface {
+	IntegrationRemoved() <-chan struct{}
+	Endpoint(url string, timeout time.Duration)
+	ResetState() error
+}
+
+// runReregistration watches each server for the mobile_app integration of the
+// agent being removed from Home Assistant, until the context is canceled.
+func (agent *Agent) runReregistration(ctx context.Context, trk Tracker, servers *serverClients, backend *controlBackend) {
+	var wg sync.WaitGroup
+
+	for _, server := range servers.list() {
+		client, ok := server.client.(removableClient)
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			agent.watchRemoval(ctx, trk, server.name, client, backend)
+		}()
+	}
+
+	wg.Wait()
+}
+
+// watchRemoval registers with the server with the given name again whenever
+// its client reports that the agent was removed. Once registered, the tracker
+// and registry of the client are reset and all sensors are sent again, so that
+// they are registered. If the agent cannot register again, the client stops
+// sending to the server and the user is notified.
+func (agent *Agent) watchRemoval(ctx context.Context, trk Tracker, name string, client removableClient, backend *controlBackend) {
+	logger := agent.logger.With(slog.String("server", name))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-client.IntegrationRemoved():
+		}
+
+		if err := agent.reregister(ctx, trk, name); err != nil {
+			logger.Error("Could not register again with Home Assistant.", slog.Any("error", err))
+			agent.ui.DisplayNotification(&hass.WebsocketNotification{
+				Title:   "Go Hass Agent was removed from Home Assistant",
+				Message: reregisterHint(name),
+			})
+
+			return
+		}
+
+		if err := client.ResetState(); err != nil {
+			logger.Warn("Could not reset sensor registry.", slog.Any("error", err))
+		}
+
+		server, err := agent.prefs.Server(name)
+		if err != nil {
+			logger.Error("Could not find server.", slog.Any("error", err))
+
+			return
+		}
+
+		client.Endpoint(server.RestAPIURL(), defaultTimeout)
+
+		logger.Info("Agent registered again with Home Assistant, sending all sensors.")
+
+		if err := backend.Refresh(ctx); err != nil {
+			logger.Warn("Could not send all sensors.", slog.Any("error", err))
+		}
+	}
+}
+
+// reregister registers the agent with the server with the given name again.
+// If the registration preferences of the server do not allow this, the user
+// is asked to confirm the registration details first, which is only possible
+// for the default server with the GUI.
+func (agent *Agent) reregister(ctx context.Context, trk Tracker, name string) error {
+	server, err := agent.prefs.Server(name)
+	if err != nil {
+		return fmt.Errorf("could not find server: %w", err)
+	}
+
+	if !server.Registration.Reregister {
+		if agent.headless || name != preferences.DefaultServerName {
+			return ErrReregisterDisabled
+		}
+
+		agent.ui.DisplayNotification(&hass.WebsocketNotification{
+			Title:   "Go Hass Agent was removed from Home Assistant",
+			Message: "Please register the agent again.",
+		})
+
+		select {
+		case <-agent.ui.DisplayRegistrationWindow(agent.prefs, agent.done):
+		case <-ctx.Done():
+			return fmt.Errorf("registration aborted: %w", ctx.Err())
+		}
+	}
+
+	if name == preferences.DefaultServerName {
+		agent.prefs.Registered = false
+
+		return agent.checkRegistration(ctx, trk)
+	}
+
+	return agent.registerServer(ctx, server)
+}
+
+// reregisterHint returns how the user can register with the server with the
+// given name again.
+func reregisterHint(name string) string {
+	if name == preferences.DefaultServerName {
+		return "Run 'go-hass-agent register --force' and restart the agent, " +
+			"or set reregister = true in the registration preferences to register again automatically."
+	}
+
+	return "Set reregister = true in the registration preferences of server " + name +
+		" and restart the agent to register again automatically."
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/agent/ui"
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor/registry"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+// refreshController is a sensor controller that returns the given sensors when
+// refreshed.
+type refreshController struct {
+	*SensorControllerMock
+	sensors []sensor.Details
+}
+
+func (c *refreshController) Refresh(_ context.Context) ([]sensor.Details, error) {
+	return c.sensors, nil
+}
+
+// removedServer is a fake Home Assistant that has removed the integration of
+// the agent with the webhook ID "old", and registers it again with the
+// webhook ID "new".
+type removedServer struct {
+	*httptest.Server
+	requests []string
+	mu       sync.Mutex
+}
+
+func newRemovedServer(t *testing.T) *removedServer {
+	t.Helper()
+
+	server := &removedServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Path == hass.RegistrationPath {
+			w.Write([]byte(`{"webhook_id":"new"}`)) //nolint:errcheck
+
+			return
+		}
+
+		if path.Base(r.URL.Path) == "old" {
+			w.WriteHeader(http.StatusGone)
+
+			return
+		}
+
+		var req struct {
+			Data map[string]any `json:"data"`
+			Type string         `json:"type"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		id, _ := req.Data["unique_id"].(string) //nolint:errcheck
+
+		server.mu.Lock()
+		server.requests = append(server.requests, req.Type+" "+id)
+		server.mu.Unlock()
+
+		w.Write([]byte(`{"success":true}`)) //nolint:errcheck
+	}))
+
+	return server
+}
+
+func (s *removedServer) received(request string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Contains(s.requests, request)
+}
+
+// newRemovedAgent returns an agent registered with the given server with the
+// webhook ID "old", and a client for the server.
+func newRemovedAgent(t *testing.T, server *removedServer, reregister bool) (*Agent, *hass.Client, *controlBackend) {
+	t.Helper()
+
+	prefs := preferences.DefaultPreferences(filepath.Join(t.TempDir(), "preferences.toml"))
+	prefs.Registered = true
+	prefs.Registration.Server = server.URL
+	prefs.Registration.Token = "valid"
+	prefs.Registration.Reregister = reregister
+	prefs.Hass.WebhookID = "old"
+	prefs.Hass.RestAPIURL = server.URL + hass.WebHookPath + "old"
+
+	trk, err := sensor.NewTracker()
+	require.NoError(t, err)
+	reg, err := registry.Load(t.TempDir())
+	require.NoError(t, err)
+
+	client := hass.NewClient(context.TODO(), trk, reg)
+	client.Endpoint(prefs.RestAPIURL(), defaultTimeout)
+
+	agent := &Agent{
+		id:       "reregister_test",
+		prefs:    prefs,
+		logger:   slog.Default(),
+		headless: true,
+		done:     make(chan struct{}),
+		hass:     client,
+	}
+
+	return agent, client, agent.newControlBackend(trk, reg)
+}
+
+func TestAgent_watchRemoval(t *testing.T) {
+	server := newRemovedServer(t)
+	defer server.Close()
+
+	agent, client, backend := newRemovedAgent(t, server, true)
+
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+
+	// Handle the refresh requests made once registered again. The refreshed
+	// sensors are sent through the pipeline.
+	controller := &refreshController{
+		SensorControllerMock: &SensorControllerMock{
+			StartAllFunc: func(_ context.Context) (<-chan sensor.Details, error) {
+				ch := make(chan sensor.Details)
+				close(ch)
+
+				return ch, nil
+			},
+			StopAllFunc: func() error { return nil },
+		},
+		sensors: []sensor.Details{&linux.Sensor{UniqueID: "sensor_a", DisplayName: "Sensor", IconString: "mdi:test", Value: 1}},
+	}
+
+	go agent.runSensorWorkers(ctx, nil, backend.requests, controller)
+
+	go agent.watchRemoval(ctx, nil, preferences.DefaultServerName, client, backend)
+
+	// The removed integration stops the client sending requests.
+	details := &linux.Sensor{UniqueID: "sensor_b", DisplayName: "Sensor", IconString: "mdi:test", Value: 1}
+	require.ErrorIs(t, client.ProcessSensor(ctx, details), hass.ErrIntegrationRemoved)
+
+	// The agent registers again and sends all sensors.
+	assert.Eventually(t, func() bool {
+		return server.received(sensor.RequestTypeRegister + " sensor_a")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "new", agent.prefs.Hass.WebhookID)
+
+	require.NoError(t, client.ProcessSensor(ctx, details))
+	assert.True(t, server.received(sensor.RequestTypeRegister+" sensor_b"))
+}
+
+func TestAgent_watchRemoval_disabled(t *testing.T) {
+	server := newRemovedServer(t)
+	defer server.Close()
+
+	agent, client, backend := newRemovedAgent(t, server, false)
+
+	notified := make(chan struct{})
+	agent.ui = &UIMock{DisplayNotificationFunc: func(_ ui.Notification) { close(notified) }}
+
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+		agent.watchRemoval(context.TODO(), nil, preferences.DefaultServerName, client, backend)
+	}()
+
+	details := &linux.Sensor{UniqueID: "sensor_a", DisplayName: "Sensor", IconString: "mdi:test", Value: 1}
+	require.ErrorIs(t, client.ProcessSensor(context.TODO(), details), hass.ErrIntegrationRemoved)
+
+	// Without registering again automatically, the user is notified and the
+	// client does not send any more requests.
+	select {
+	case <-notified:
+	case <-time.After(5 * time.Second):
+		t.Fatal("user was not notified")
+	}
+
+	<-finished
+
+	require.NoError(t, client.ProcessSensor(context.TODO(), details))
+	assert.Equal(t, "old", agent.prefs.Hass.WebhookID)
+	assert.Empty(t, server.requests)
+}
@@ -12,13 +12,12 @@ import (
 
 	"github.com/adrg/xdg"
 
+	"github.com/joshuar/go-hass-agent/internal/preferences"
 	"github.com/joshuar/go-hass-agent/internal/scripts"
 )
 
 func (agent *Agent) newScriptsController(ctx context.Context) SensorController {
-	scriptPath := filepath.Join(xdg.ConfigHome, agent.id, "scripts")
-
-	scriptController, err := scripts.NewScriptsController(ctx, scriptPath)
+	scriptController, err := scripts.NewScriptsController(ctx, agent.scriptDirs()...)
 	if err != nil {
 		agent.logger.Error("Could not set up scripts controller.", slog.Any("error", err))
 
@@ -27,3 +26,14 @@ func (agent *Agent) newScriptsController(ctx context.Context) SensorController {
 
 	return scriptController
 }
+
+// scriptDirs returns the directories to search for scripts.
+func (agent *Agent) scriptDirs() []string {
+	userDir := filepath.Join(xdg.ConfigHome, agent.id, "scripts")
+
+	if agent.prefs != nil {
+		return agent.prefs.ScriptDirs(userDir)
+	}
+
+	return []string{preferences.DefaultSystemScriptsPath, userDir}
+}
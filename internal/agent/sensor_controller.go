@@ -11,42 +11,89 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/joshuar/go-hass-agent/internal/hass"
 	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
 )
 
 type HassClient interface {
 	ProcessSensor(ctx context.Context, details sensor.Details) error
 	SensorList() []string
 	GetSensor(id string) (sensor.Details, error)
+	SensorHistory(id string) ([]sensor.HistoryEntry, error)
 	HassVersion(ctx context.Context) string
 	Endpoint(url string, timeout time.Duration)
+	SetSensorFilter(filter *sensor.Filter)
+	FireEvent(ctx context.Context, event *hass.Event) error
+}
+
+// applySensorFilter sets the sensor filter of the Home Assistant client from
+// the sensors section of the preferences.
+func (agent *Agent) applySensorFilter() {
+	var prefs *preferences.Sensors
+	if agent.prefs != nil {
+		prefs = agent.prefs.SensorPreferences()
+	}
+
+	if prefs == nil {
+		agent.hass.SetSensorFilter(nil)
+
+		return
+	}
+
+	filter, err := sensor.NewFilter(prefs.Include, prefs.Exclude, prefs.IncludeAttributes, prefs.ExcludeAttributes)
+	if err != nil {
+		agent.logger.Warn("Invalid sensor filter, all sensors will be sent.", slog.Any("error", err))
+	}
+
+	agent.hass.SetSensorFilter(filter)
 }
 
 // runSensorWorkers will start all the sensor worker functions for all sensor
-// controllers passed in. It returns a single merged channel of sensor updates.
-//
-//nolint:gocognit
-func (agent *Agent) runSensorWorkers(ctx context.Context, controllers ...SensorController) {
-	var sensorCh []<-chan sensor.Details
+// controllers passed in and process the sensor updates from them. When a value
+// is received on reloadCh, any controllers that can reload their workers are
+// reloaded. Any requests received on requestCh are run with access to the
+// controllers.
+func (agent *Agent) runSensorWorkers(ctx context.Context, reloadCh <-chan struct{}, requestCh <-chan sensorRequest, controllers ...SensorController) {
+	sensorCh := newFanIn[sensor.Details](ctx)
+
+	var started int
 
 	for _, controller := range controllers {
 		ch, err := controller.StartAll(ctx)
 		if err != nil {
 			agent.logger.Warn("Start controller had errors.", slog.Any("errors", err))
 		} else {
-			sensorCh = append(sensorCh, ch)
+			sensorCh.Add(ch)
+			started++
 		}
 	}
 
-	if len(sensorCh) == 0 {
+	if started == 0 {
 		agent.logger.Warn("No workers were started by any controllers.")
-
-		return
 	}
 
 	agent.logger.Debug("Processing sensor updates.")
 
+	pipeline := newSensorPipeline(ctx, agent.logger, agent.hass.ProcessSensor)
+
+	// Monitor the pipeline for the systemd watchdog.
+	agent.pipeline.Store(pipeline)
+
+	// held is an update waiting to be taken by the pipeline. Reloads and
+	// requests are still handled while it waits.
+	var held sensor.Details
+
 	for {
+		sensorOutCh := sensorCh.Out()
+
+		var pipelineCh chan<- sensor.Details
+
+		if held != nil {
+			sensorOutCh = nil
+			pipelineCh = pipeline.In()
+		}
+
 		select {
 		case <-ctx.Done():
 			agent.logger.Debug("Stopping all sensor controllers.")
@@ -57,17 +104,62 @@ func (agent *Agent) runSensorWorkers(ctx context.Context, controllers ...SensorC
 				}
 			}
 
+			pipeline.Close()
+
 			return
-		default:
-			for details := range mergeCh(ctx, sensorCh...) {
-				go func(details sensor.Details) {
-					if err := agent.hass.ProcessSensor(ctx, details); err != nil {
-						agent.logger.Error("Process sensor failed.", slog.Any("error", err))
-					}
-				}(details)
+		case <-reloadCh:
+			agent.reloadSensorControllers(ctx, sensorCh, controllers...)
+		case request := <-requestCh:
+			request(ctx, sensorCh, controllers)
+		case pipelineCh <- held:
+			held = nil
+		case details, ok := <-sensorOutCh:
+			if !ok {
+				continue
 			}
 
-			return
+			if agent.recorder != nil {
+				if err := agent.recorder.Record(details); err != nil {
+					agent.logger.Warn("Could not record sensor update.", slog.Any("error", err))
+				}
+			}
+
+			held = details
+		}
+	}
+}
+
+// reloadSensorControllers will reload the workers of any of the given
+// controllers that support it. Any new workers are started and their updates
+// added to the given sensor channel.
+func (agent *Agent) reloadSensorControllers(ctx context.Context, sensorCh *fanIn[sensor.Details], controllers ...SensorController) {
+	for _, c := range controllers {
+		controller, ok := c.(ReloadableController)
+		if !ok {
+			continue
+		}
+
+		added, removed, err := controller.Reload(ctx)
+		if err != nil {
+			agent.logger.Warn("Reload controller had errors.", slog.Any("error", err))
+		}
+
+		if len(removed) > 0 {
+			agent.logger.Info("Removed workers.", slog.Any("workers", removed))
+		}
+
+		for _, name := range added {
+			ch, err := controller.Start(ctx, name)
+			if err != nil {
+				agent.logger.Warn("Could not start worker.",
+					slog.String("worker", name),
+					slog.Any("error", err))
+
+				continue
+			}
+
+			agent.logger.Info("Started worker.", slog.String("worker", name))
+			sensorCh.Add(ch)
 		}
 	}
 }
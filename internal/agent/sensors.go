@@ -20,6 +20,33 @@ type address struct {
 	addr net.IP
 }
 
+// pipelineQueueSensor is a diagnostic sensor with the number of sensor updates
+// waiting to be sent to Home Assistant.
+type pipelineQueueSensor struct {
+	depth     int
+	maxDepth  int
+	processed int
+	coalesced int
+	dropped   int
+}
+
+// pipelineLatencySensor is a diagnostic sensor with how long sensor updates
+// waited before being sent to Home Assistant.
+type pipelineLatencySensor struct {
+	latency    time.Duration
+	maxLatency time.Duration
+}
+
+// healthSensor is a diagnostic sensor with the health of a supervised worker.
+type healthSensor struct {
+	lastSuccess time.Time
+	workerID    string
+	status      string
+	lastError   string
+	errors      int
+	restarts    int
+}
+
 func (v *version) Name() string { return "Go Hass Agent Version" }
 
 func (v *version) ID() string { return "agent_version" }
@@ -91,3 +118,95 @@ func (a *address) Attributes() map[string]any {
 
 	return attributes
 }
+
+func (h *healthSensor) Name() string { return "Worker Health (" + h.workerID + ")" }
+
+func (h *healthSensor) ID() string { return h.workerID + "_health" }
+
+func (h *healthSensor) Icon() string {
+	if h.status == healthOK {
+		return "mdi:heart-pulse"
+	}
+
+	return "mdi:heart-broken"
+}
+
+func (h *healthSensor) SensorType() types.SensorClass { return types.Sensor }
+
+func (h *healthSensor) DeviceClass() types.DeviceClass { return 0 }
+
+func (h *healthSensor) StateClass() types.StateClass { return 0 }
+
+func (h *healthSensor) State() any { return h.status }
+
+func (h *healthSensor) Units() string { return "" }
+
+func (h *healthSensor) Category() string { return "diagnostic" }
+
+func (h *healthSensor) Attributes() map[string]any {
+	attributes := map[string]any{
+		"error_count":   h.errors,
+		"restart_count": h.restarts,
+	}
+
+	if !h.lastSuccess.IsZero() {
+		attributes["last_success"] = h.lastSuccess.Format(time.RFC3339)
+	}
+
+	if h.lastError != "" {
+		attributes["last_error"] = h.lastError
+	}
+
+	return attributes
+}
+
+func (q *pipelineQueueSensor) Name() string { return "Sensor Queue Depth" }
+
+func (q *pipelineQueueSensor) ID() string { return "sensor_queue_depth" }
+
+func (q *pipelineQueueSensor) Icon() string { return "mdi:tray-full" }
+
+func (q *pipelineQueueSensor) SensorType() types.SensorClass { return types.Sensor }
+
+func (q *pipelineQueueSensor) DeviceClass() types.DeviceClass { return 0 }
+
+func (q *pipelineQueueSensor) StateClass() types.StateClass { return types.StateClassMeasurement }
+
+func (q *pipelineQueueSensor) State() any { return q.depth }
+
+func (q *pipelineQueueSensor) Units() string { return "updates" }
+
+func (q *pipelineQueueSensor) Category() string { return "diagnostic" }
+
+func (q *pipelineQueueSensor) Attributes() map[string]any {
+	return map[string]any{
+		"max_depth":       q.maxDepth,
+		"processed_count": q.processed,
+		"coalesced_count": q.coalesced,
+		"dropped_count":   q.dropped,
+	}
+}
+
+func (l *pipelineLatencySensor) Name() string { return "Sensor Processing Latency" }
+
+func (l *pipelineLatencySensor) ID() string { return "sensor_processing_latency" }
+
+func (l *pipelineLatencySensor) Icon() string { return "mdi:timer-sand" }
+
+func (l *pipelineLatencySensor) SensorType() types.SensorClass { return types.Sensor }
+
+func (l *pipelineLatencySensor) DeviceClass() types.DeviceClass { return types.DeviceClassDuration }
+
+func (l *pipelineLatencySensor) StateClass() types.StateClass { return types.StateClassMeasurement }
+
+func (l *pipelineLatencySensor) State() any { return l.latency.Milliseconds() }
+
+func (l *pipelineLatencySensor) Units() string { return "ms" }
+
+func (l *pipelineLatencySensor) Category() string { return "diagnostic" }
+
+func (l *pipelineLatencySensor) Attributes() map[string]any {
+	return map[string]any{
+		"max_latency": l.maxLatency.Milliseconds(),
+	}
+}
@@ -0,0 +1,353 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor/registry"
+	"github.com/joshuar/go-hass-agent/internal/logging"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+// deferredError is returned when the update of a sensor was not sent to some
+// servers because they are unavailable. It wraps hass.ErrCircuitOpen and any
+// errors from the other servers.
+type deferredError struct {
+	err error
+	// servers are the names of the servers the update was not sent to.
+	servers []string
+}
+
+func (e *deferredError) Error() string {
+	msg := fmt.Sprintf("%s: %s", hass.ErrCircuitOpen, strings.Join(e.servers, ", "))
+	if e.err != nil {
+		msg += "; " + e.err.Error()
+	}
+
+	return msg
+}
+
+func (e *deferredError) Unwrap() []error {
+	return []error{hass.ErrCircuitOpen, e.err}
+}
+
+// serversKey is the context key for the names of the servers to send to.
+type serversKey struct{}
+
+// withServers returns a context that limits sending sensor updates to the
+// servers with the given names. If names is nil, updates are sent to all
+// servers.
+func withServers(ctx context.Context, names []string) context.Context {
+	if names == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, serversKey{}, names)
+}
+
+// serverClient is the client for a single Home Assistant server.
+type serverClient struct {
+	client HassClient
+	name   string
+	// ownFilter is whether the server has its own sensor filter, rather than
+	// the filter from the sensors section of the preferences.
+	ownFilter bool
+}
+
+// serverClients is a HassClient that sends sensor updates to all of the Home
+// Assistant servers the agent is registered with. Other requests are handled
+// by the default server.
+type serverClients struct {
+	filter  *sensor.Filter
+	servers []*serverClient
+	mu      sync.RWMutex
+}
+
+// newServerClients creates a serverClients with the given client for the
+// default server.
+func newServerClients(defaultClient HassClient) *serverClients {
+	return &serverClients{
+		servers: []*serverClient{{name: preferences.DefaultServerName, client: defaultClient}},
+	}
+}
+
+// add adds the client for the server with the given name. If filter is not
+// nil, it is used instead of the filter from the preferences.
+func (c *serverClients) add(name string, client HassClient, filter *sensor.Filter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	server := &serverClient{name: name, client: client, ownFilter: filter != nil}
+
+	if server.ownFilter {
+		client.SetSensorFilter(filter)
+	} else {
+		client.SetSensorFilter(c.filter)
+	}
+
+	c.servers = append(c.servers, server)
+}
+
+// list returns the servers.
+func (c *serverClients) list() []*serverClient {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return slices.Clone(c.servers)
+}
+
+// defaultClient returns the client of the default server.
+func (c *serverClients) defaultClient() HassClient {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.servers[0].client
+}
+
+// ProcessSensor sends the sensor to all servers at the same time, so that a
+// slow server does not delay the others, or only to the servers set in the
+// context with withServers. Any errors are combined. If any server is
+// unavailable, a *deferredError naming those servers is returned, so that the
+// update can be sent to just them later.
+func (c *serverClients) ProcessSensor(ctx context.Context, details sensor.Details) error {
+	servers := c.list()
+
+	if names, ok := ctx.Value(serversKey{}).([]string); ok {
+		servers = slices.DeleteFunc(servers, func(server *serverClient) bool {
+			return !slices.Contains(names, server.name)
+		})
+	}
+
+	var (
+		mu       sync.Mutex
+		deferred []string
+	)
+
+	err := each(servers, func(server *serverClient) error {
+		err := server.client.ProcessSensor(ctx, details)
+		if errors.Is(err, hass.ErrCircuitOpen) {
+			mu.Lock()
+			deferred = append(deferred, server.name)
+			mu.Unlock()
+
+			return nil
+		}
+
+		return err //nolint:wrapcheck
+	})
+
+	if len(deferred) > 0 {
+		slices.Sort(deferred)
+
+		return &deferredError{servers: deferred, err: err}
+	}
+
+	return err
+}
+
+// FireEvent fires the event on all servers. Any errors are combined.
+func (c *serverClients) FireEvent(ctx context.Context, event *hass.Event) error {
+	return each(c.list(), func(server *serverClient) error {
+		return server.client.FireEvent(ctx, event) //nolint:wrapcheck
+	})
+}
+
+// each calls the given function with each of the given servers at the same
+// time, and combines any errors.
+func each(servers []*serverClient, call func(server *serverClient) error) error {
+	if len(servers) == 1 {
+		return call(servers[0])
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs error
+	)
+
+	for _, server := range servers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := call(server); err != nil {
+				mu.Lock()
+				errs = errors.Join(errs, fmt.Errorf("server %s: %w", server.name, err))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return errs
+}
+
+func (c *serverClients) SensorList() []string {
+	return c.defaultClient().SensorList()
+}
+
+func (c *serverClients) GetSensor(id string) (sensor.Details, error) {
+	return c.defaultClient().GetSensor(id) //nolint:wrapcheck
+}
+
+func (c *serverClients) SensorHistory(id string) ([]sensor.HistoryEntry, error) {
+	return c.defaultClient().SensorHistory(id) //nolint:wrapcheck
+}
+
+func (c *serverClients) HassVersion(ctx context.Context) string {
+	return c.defaultClient().HassVersion(ctx)
+}
+
+// Endpoint sets the endpoint of the default server.
+func (c *serverClients) Endpoint(url string, timeout time.Duration) {
+	c.defaultClient().Endpoint(url, timeout)
+}
+
+// SetSensorFilter sets the filter of all servers that do not have their own
+// filter.
+func (c *serverClients) SetSensorFilter(filter *sensor.Filter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.filter = filter
+
+	for _, server := range c.servers {
+		if !server.ownFilter {
+			server.client.SetSensorFilter(filter)
+		}
+	}
+}
+
+// saveState saves the tracker and registry state of each server whose client
+// can save it. Any errors are combined.
+func (c *serverClients) saveState() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var errs error
+
+	for _, server := range c.servers {
+		client, ok := server.client.(interface{ SaveState() error })
+		if !ok {
+			continue
+		}
+
+		if err := client.SaveState(); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("server %s: %w", server.name, err))
+		}
+	}
+
+	return errs
+}
+
+// connectServers registers with any of the additional servers in the
+// preferences that the agent is not yet registered with and adds a client for
+// each of them to the given serverClients. Servers that cannot be registered
+// with or connected to are skipped.
+func (agent *Agent) connectServers(ctx context.Context, clients *serverClients) {
+	for _, server := range agent.prefs.ActiveServers() {
+		if server.Name == preferences.DefaultServerName {
+			continue
+		}
+
+		logger := agent.logger.With(slog.String("server", server.Name))
+
+		if !server.Registered {
+			if err := agent.registerServer(ctx, server); err != nil {
+				logger.Warn("Could not register with server.", slog.Any("error", err))
+
+				continue
+			}
+
+			logger.Info("Agent registered with server.")
+		}
+
+		client, filter, err := agent.newServerClient(logging.ToContext(ctx, logger), server)
+		if err != nil {
+			logger.Warn("Could not connect to server.", slog.Any("error", err))
+
+			continue
+		}
+
+		clients.add(server.Name, client, filter)
+	}
+}
+
+// newServerClient creates a client for the given server, with its own sensor
+// registry and tracker. If the server has its own sensor filter, it is also
+// returned.
+func (agent *Agent) newServerClient(ctx context.Context, server *preferences.Server) (*hass.Client, *sensor.Filter, error) {
+	reg, err := registry.Load(agent.serverRegistryPath(server.Name))
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not load registry: %w", err)
+	}
+
+	trk, err := agent.loadTracker(agent.serverRegistryPath(server.Name))
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create tracker: %w", err)
+	}
+
+	var filter *sensor.Filter
+
+	if server.Sensors != nil {
+		filter, err = sensor.NewFilter(server.Sensors.Include, server.Sensors.Exclude,
+			server.Sensors.IncludeAttributes, server.Sensors.ExcludeAttributes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid sensor filter: %w", err)
+		}
+	}
+
+	client := hass.NewClient(ctx, trk, reg)
+	client.Endpoint(server.RestAPIURL(), defaultTimeout)
+
+	return client, filter, nil
+}
+
+// registerServer registers the agent with the given server and saves the
+// registration details in the preferences.
+func (agent *Agent) registerServer(ctx context.Context, server *preferences.Server) error {
+	hassPrefs, err := hass.RegisterDevice(ctx, agent.prefs.Device, server.Registration)
+	if err != nil {
+		return fmt.Errorf("device registration failed: %w", err)
+	}
+
+	hassPrefs.IgnoreHassURLs = server.Hass != nil && server.Hass.IgnoreHassURLs
+
+	if hassPrefs.RestAPIURL, err = generateAPIURL(server.Registration.Server, hassPrefs); err != nil {
+		return fmt.Errorf("unable to save registration: %w", err)
+	}
+
+	if hassPrefs.WebsocketURL, err = generateWebsocketURL(server.Registration.Server); err != nil {
+		return fmt.Errorf("unable to save registration: %w", err)
+	}
+
+	server.Hass = hassPrefs
+	server.Registered = true
+
+	if err := agent.prefs.Save(); err != nil {
+		return fmt.Errorf("unable to save preferences: %w", err)
+	}
+
+	return nil
+}
+
+// serverRegistryPath returns the path of the sensor registry of the server with
+// the given name.
+func (agent *Agent) serverRegistryPath(name string) string {
+	return RegistryPath(agent.id, name)
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+)
+
+var errServerDown = errors.New("server down")
+
+// recordingHassClient is a HassClient that records the sensors it is sent and
+// the filter it is given.
+type recordingHassClient struct {
+	HassClient
+	err     error
+	filter  *sensor.Filter
+	sensors []string
+	mu      sync.Mutex
+}
+
+func (c *recordingHassClient) ProcessSensor(_ context.Context, details sensor.Details) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sensors = append(c.sensors, details.ID())
+
+	return c.err
+}
+
+func (c *recordingHassClient) SetSensorFilter(filter *sensor.Filter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.filter = filter
+}
+
+func (c *recordingHassClient) sensorFilter() *sensor.Filter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.filter
+}
+
+func TestServerClients_ProcessSensor(t *testing.T) {
+	details := &linux.Sensor{UniqueID: "cpu_usage", DisplayName: "CPU Usage", IconString: "mdi:cpu-64-bit", Value: 1}
+
+	defaultClient := &recordingHassClient{}
+	labClient := &recordingHassClient{}
+	downClient := &recordingHassClient{err: errServerDown}
+
+	clients := newServerClients(defaultClient)
+	clients.add("lab", labClient, nil)
+	clients.add("down", downClient, nil)
+
+	err := clients.ProcessSensor(context.TODO(), details)
+	require.ErrorIs(t, err, errServerDown)
+	assert.ErrorContains(t, err, "server down: ")
+
+	for _, client := range []*recordingHassClient{defaultClient, labClient, downClient} {
+		assert.Equal(t, []string{"cpu_usage"}, client.sensors)
+	}
+}
+
+func TestServerClients_ProcessSensor_deferred(t *testing.T) {
+	details := &linux.Sensor{UniqueID: "cpu_usage", DisplayName: "CPU Usage", IconString: "mdi:cpu-64-bit", Value: 1}
+
+	defaultClient := &recordingHassClient{}
+	labClient := &recordingHassClient{err: hass.ErrCircuitOpen}
+	downClient := &recordingHassClient{err: errServerDown}
+
+	clients := newServerClients(defaultClient)
+	clients.add("lab", labClient, nil)
+	clients.add("down", downClient, nil)
+
+	// Only the unavailable server is deferred. Other errors are kept.
+	err := clients.ProcessSensor(context.TODO(), details)
+	require.ErrorIs(t, err, hass.ErrCircuitOpen)
+	require.ErrorIs(t, err, errServerDown)
+
+	var deferredErr *deferredError
+	require.ErrorAs(t, err, &deferredErr)
+	assert.Equal(t, []string{"lab"}, deferredErr.servers)
+
+	// Sending the deferred update again only sends it to the deferred
+	// server.
+	err = clients.ProcessSensor(withServers(context.TODO(), deferredErr.servers), details)
+	require.ErrorAs(t, err, &deferredErr)
+	assert.Equal(t, []string{"cpu_usage"}, defaultClient.sensors)
+	assert.Equal(t, []string{"cpu_usage", "cpu_usage"}, labClient.sensors)
+	assert.Equal(t, []string{"cpu_usage"}, downClient.sensors)
+}
+
+func TestServerClients_SetSensorFilter(t *testing.T) {
+	ownFilter, err := sensor.NewFilter([]string{"cpu_*"}, nil, nil, nil)
+	require.NoError(t, err)
+
+	prefsFilter, err := sensor.NewFilter(nil, []string{"cpu_*"}, nil, nil)
+	require.NoError(t, err)
+
+	defaultClient := &recordingHassClient{}
+	labClient := &recordingHassClient{}
+	ownClient := &recordingHassClient{}
+
+	clients := newServerClients(defaultClient)
+	clients.SetSensorFilter(prefsFilter)
+	// Servers added after the filter is set use it unless they have their
+	// own.
+	clients.add("lab", labClient, nil)
+	clients.add("own", ownClient, ownFilter)
+
+	assert.Same(t, prefsFilter, defaultClient.filter)
+	assert.Same(t, prefsFilter, labClient.filter)
+	assert.Same(t, ownFilter, ownClient.filter)
+
+	// Changing the filter does not affect servers with their own filter.
+	clients.SetSensorFilter(nil)
+	assert.Nil(t, defaultClient.filter)
+	assert.Nil(t, labClient.filter)
+	assert.Same(t, ownFilter, ownClient.filter)
+}
@@ -0,0 +1,318 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+)
+
+const (
+	supervisorMinBackoff = time.Second
+	supervisorMaxBackoff = 5 * time.Minute
+	// supervisorMaxErrors is the number of consecutive errors after which a
+	// worker is restarted.
+	supervisorMaxErrors = 5
+	// healthInterval is how often the health of a worker is sent, in
+	// addition to whenever it changes.
+	healthInterval = 5 * time.Minute
+)
+
+const (
+	healthOK         = "OK"
+	healthFailing    = "Failing"
+	healthRestarting = "Restarting"
+)
+
+// errorReportingWorker is a Worker that can report errors that occur while it
+// is sending updates.
+type errorReportingWorker interface {
+	SetErrorHandler(handler func(err error))
+}
+
+// oneShotWorker is a Worker that may send its sensors once and then close its
+// updates channel. Such workers are not restarted when their channel closes.
+type oneShotWorker interface {
+	OneShot() bool
+}
+
+// workerHealth tracks the health of a supervised worker.
+type workerHealth struct {
+	lastSuccess time.Time
+	lastError   error
+	status      string
+	errors      int
+	consecutive int
+	restarts    int
+	mu          sync.Mutex
+}
+
+func newWorkerHealth() *workerHealth {
+	return &workerHealth{status: healthOK}
+}
+
+// succeeded records that the worker sent an update. It returns true if the
+// worker was previously not healthy.
+func (h *workerHealth) succeeded() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	recovered := h.status != healthOK
+
+	h.lastSuccess = time.Now()
+	h.consecutive = 0
+	h.status = healthOK
+
+	return recovered
+}
+
+// failed records an error from the worker and returns the number of
+// consecutive errors.
+func (h *workerHealth) failed(err error) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastError = err
+	h.errors++
+	h.consecutive++
+	h.status = healthFailing
+
+	return h.consecutive
+}
+
+// restarting records that the worker is being restarted.
+func (h *workerHealth) restarting() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.restarts++
+	h.consecutive = 0
+	h.status = healthRestarting
+}
+
+// sensor returns a sensor with the current health of the worker with the
+// given ID.
+func (h *workerHealth) sensor(workerID string) *healthSensor {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	health := &healthSensor{
+		workerID:    workerID,
+		status:      h.status,
+		lastSuccess: h.lastSuccess,
+		errors:      h.errors,
+		restarts:    h.restarts,
+	}
+
+	if h.lastError != nil {
+		health.lastError = h.lastError.Error()
+	}
+
+	return health
+}
+
+// supervisor runs a worker and restarts it, with exponential backoff, if its
+// updates channel closes unexpectedly or it reports too many consecutive
+// errors.
+type supervisor struct {
+	worker    Worker
+	health    *workerHealth
+	logger    *slog.Logger
+	restartCh chan struct{}
+	// changedCh is signaled when the health of the worker changes outside of
+	// the supervisor.
+	changedCh chan struct{}
+	id        string
+	// backoff is the time to wait before the next restart. It doubles with
+	// each restart and is reset to minBackoff when the worker sends an
+	// update.
+	backoff    time.Duration
+	minBackoff time.Duration
+}
+
+// newSupervisor creates a supervisor for the worker with the given ID, that
+// records the health of the worker in the given workerHealth.
+func newSupervisor(id string, worker Worker, health *workerHealth, logger *slog.Logger) *supervisor {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &supervisor{
+		id:         id,
+		worker:     worker,
+		health:     health,
+		logger:     logger.With(slog.String("worker", id)),
+		restartCh:  make(chan struct{}, 1),
+		changedCh:  make(chan struct{}, 1),
+		backoff:    supervisorMinBackoff,
+		minBackoff: supervisorMinBackoff,
+	}
+}
+
+// start starts the worker and returns a channel of its updates and health.
+// The worker is supervised until the context is canceled, when the channel is
+// closed.
+func (s *supervisor) start(ctx context.Context) (<-chan sensor.Details, error) {
+	if worker, ok := s.worker.(errorReportingWorker); ok {
+		worker.SetErrorHandler(s.handleError)
+	}
+
+	workerCh, err := s.worker.Updates(ctx)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	outCh := make(chan sensor.Details)
+
+	go s.run(ctx, workerCh, outCh)
+
+	return outCh, nil
+}
+
+// handleError records an error reported by the worker and requests a restart
+// if it has failed too many times in a row.
+func (s *supervisor) handleError(err error) {
+	if s.health.failed(err) >= supervisorMaxErrors {
+		notify(s.restartCh)
+	}
+
+	notify(s.changedCh)
+}
+
+func (s *supervisor) run(ctx context.Context, workerCh <-chan sensor.Details, outCh chan<- sensor.Details) {
+	defer close(outCh)
+
+	s.send(ctx, outCh, s.health.sensor(s.id))
+
+	for {
+		finished := s.forward(ctx, workerCh, outCh)
+
+		switch {
+		case ctx.Err() != nil:
+			return
+		case finished:
+			if worker, ok := s.worker.(oneShotWorker); ok && worker.OneShot() {
+				return
+			}
+
+			s.logger.Warn("Worker stopped unexpectedly, restarting.", slog.Duration("backoff", s.backoff))
+		default:
+			s.logger.Warn("Worker is failing, restarting.", slog.Duration("backoff", s.backoff))
+
+			if err := s.worker.Stop(); err != nil {
+				s.logger.Debug("Could not stop worker.", slog.Any("error", err))
+			}
+		}
+
+		workerCh = s.restart(ctx, outCh)
+		if workerCh == nil {
+			return
+		}
+	}
+}
+
+// forward sends updates from the worker, and its health, until the worker
+// channel closes, a restart is requested or the context is canceled. It
+// returns true if the worker channel closed.
+func (s *supervisor) forward(ctx context.Context, workerCh <-chan sensor.Details, outCh chan<- sensor.Details) bool {
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			go drain(workerCh)
+
+			return false
+		case <-s.restartCh:
+			go drain(workerCh)
+
+			return false
+		case <-ticker.C:
+			s.send(ctx, outCh, s.health.sensor(s.id))
+		case <-s.changedCh:
+			s.send(ctx, outCh, s.health.sensor(s.id))
+		case details, ok := <-workerCh:
+			if !ok {
+				return true
+			}
+
+			recovered := s.health.succeeded()
+			s.backoff = s.minBackoff
+
+			s.send(ctx, outCh, details)
+
+			if recovered {
+				s.send(ctx, outCh, s.health.sensor(s.id))
+			}
+		}
+	}
+}
+
+// restart waits for the current backoff and starts the worker again, until it
+// starts or the context is canceled. It returns the updates channel of the
+// restarted worker, or nil if the context was canceled.
+func (s *supervisor) restart(ctx context.Context, outCh chan<- sensor.Details) <-chan sensor.Details {
+	for {
+		s.health.restarting()
+		s.send(ctx, outCh, s.health.sensor(s.id))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(s.backoff):
+		}
+
+		s.backoff = min(s.backoff*2, supervisorMaxBackoff) //nolint:mnd
+
+		workerCh, err := s.worker.Updates(ctx)
+		if err != nil {
+			s.logger.Warn("Could not restart worker.", slog.Any("error", err))
+			s.health.failed(err)
+
+			continue
+		}
+
+		// Discard any restart requested while waiting.
+		select {
+		case <-s.restartCh:
+		default:
+		}
+
+		s.logger.Info("Worker restarted.")
+
+		return workerCh
+	}
+}
+
+// notify sends a value on the given buffered channel, if it is not already
+// full.
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// send sends the given sensor on the given channel, unless the context is
+// canceled.
+func (s *supervisor) send(ctx context.Context, outCh chan<- sensor.Details, details sensor.Details) {
+	select {
+	case outCh <- details:
+	case <-ctx.Done():
+	}
+}
+
+// drain discards all values from the given channel until it is closed, so that
+// a stopped worker is not blocked sending an update.
+func drain[T any](ch <-chan T) {
+	for range ch { //nolint:revive
+	}
+}
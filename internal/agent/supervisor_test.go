@@ -0,0 +1,198 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+)
+
+var errWorkerFailed = errors.New("worker failed")
+
+// testSupervisedWorker is a worker that reports errors and can be one-shot.
+type testSupervisedWorker struct {
+	*WorkerMock
+	handler func(err error)
+	oneShot bool
+	mu      sync.Mutex
+}
+
+func (w *testSupervisedWorker) SetErrorHandler(handler func(err error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.handler = handler
+}
+
+func (w *testSupervisedWorker) reportError(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.handler(err)
+}
+
+func (w *testSupervisedWorker) OneShot() bool { return w.oneShot }
+
+// newTestSupervisedWorker creates a worker that sends a sensor and then either
+// closes its channel, if closeCh is true, or blocks until stopped.
+func newTestSupervisedWorker(closeCh bool) *testSupervisedWorker {
+	var (
+		mu         sync.Mutex
+		cancelFunc context.CancelFunc
+	)
+
+	return &testSupervisedWorker{
+		WorkerMock: &WorkerMock{
+			UpdatesFunc: func(ctx context.Context) (<-chan sensor.Details, error) {
+				updatesCtx, cancel := context.WithCancel(ctx)
+
+				mu.Lock()
+				cancelFunc = cancel
+				mu.Unlock()
+
+				outCh := make(chan sensor.Details)
+
+				go func() {
+					defer close(outCh)
+
+					select {
+					case outCh <- &linux.Sensor{UniqueID: "sensor"}:
+					case <-updatesCtx.Done():
+						return
+					}
+
+					if !closeCh {
+						<-updatesCtx.Done()
+					}
+				}()
+
+				return outCh, nil
+			},
+			StopFunc: func() error {
+				mu.Lock()
+				defer mu.Unlock()
+
+				cancelFunc()
+
+				return nil
+			},
+		},
+	}
+}
+
+// receive returns the IDs of the sensors received on the given channel until
+// the given number of worker sensors have been received or the channel is
+// closed.
+func receive(t *testing.T, ch <-chan sensor.Details, count int) []string {
+	t.Helper()
+
+	var ids []string
+
+	timeout := time.After(5 * time.Second)
+
+	for received := 0; received < count; {
+		select {
+		case details, ok := <-ch:
+			if !ok {
+				return ids
+			}
+
+			ids = append(ids, details.ID())
+
+			if _, isHealth := details.(*healthSensor); !isHealth {
+				received++
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for sensors")
+		}
+	}
+
+	return ids
+}
+
+func TestSupervisor_restartOnClose(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+
+	worker := newTestSupervisedWorker(true)
+	health := newWorkerHealth()
+	supervised := newSupervisor("test_worker", worker, health, slog.Default())
+	supervised.backoff = time.Millisecond
+	supervised.minBackoff = time.Millisecond
+
+	outCh, err := supervised.start(ctx)
+	require.NoError(t, err)
+
+	// The worker is restarted after its channel closes, so it will send its
+	// sensor again.
+	ids := receive(t, outCh, 2)
+	assert.Equal(t, "test_worker_health", ids[0])
+	assert.Contains(t, ids, "sensor")
+	assert.GreaterOrEqual(t, len(worker.UpdatesCalls()), 2)
+
+	restarted := health.sensor("test_worker")
+	assert.GreaterOrEqual(t, restarted.restarts, 1)
+	assert.Equal(t, healthOK, restarted.status)
+	assert.False(t, restarted.lastSuccess.IsZero())
+
+	cancelFunc()
+	receive(t, outCh, 100)
+}
+
+func TestSupervisor_restartOnErrors(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+
+	worker := newTestSupervisedWorker(false)
+	health := newWorkerHealth()
+	supervised := newSupervisor("test_worker", worker, health, slog.Default())
+	supervised.backoff = time.Millisecond
+	supervised.minBackoff = time.Millisecond
+
+	outCh, err := supervised.start(ctx)
+	require.NoError(t, err)
+	receive(t, outCh, 1)
+
+	for range supervisorMaxErrors {
+		worker.reportError(errWorkerFailed)
+	}
+
+	// The worker is stopped and started again.
+	receive(t, outCh, 1)
+	assert.Len(t, worker.StopCalls(), 1)
+	assert.Len(t, worker.UpdatesCalls(), 2)
+
+	failed := health.sensor("test_worker")
+	assert.Equal(t, 1, failed.restarts)
+	assert.Equal(t, supervisorMaxErrors, failed.errors)
+	assert.Equal(t, errWorkerFailed.Error(), failed.lastError)
+}
+
+func TestSupervisor_oneShot(t *testing.T) {
+	worker := newTestSupervisedWorker(true)
+	worker.oneShot = true
+
+	health := newWorkerHealth()
+
+	outCh, err := newSupervisor("test_worker", worker, health, slog.Default()).start(context.TODO())
+	require.NoError(t, err)
+
+	// The channel is closed once the worker has sent its sensors.
+	assert.Equal(t, []string{"test_worker_health", "sensor"}, receive(t, outCh, 100))
+	assert.Len(t, worker.UpdatesCalls(), 1)
+	assert.Zero(t, health.sensor("test_worker").restarts)
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/joshuar/go-hass-agent/internal/systemd"
+)
+
+// systemdStatusInterval is how often the connection state shown by systemctl
+// status is refreshed.
+const systemdStatusInterval = 5 * time.Minute
+
+// notifySystemd sends the given notifications to systemd, if the agent is
+// running as a systemd notify service.
+func (agent *Agent) notifySystemd(states ...string) {
+	if err := systemd.Notify(states...); err != nil {
+		agent.logger.Debug("Could not notify systemd.", slog.Any("error", err))
+	}
+}
+
+// runSystemdNotifier tells systemd the agent is ready and keeps its status up
+// to date until the context is canceled or the agent is done. The agent is
+// ready as soon as it is running, as registration can wait on the user and
+// Home Assistant might be slow to respond. The connection state is reported in
+// the status once the connected channel is closed. If the systemd watchdog is
+// enabled, it is only notified while the sensor pipeline is processing sensor
+// updates, so that systemd restarts an agent that has stopped sending them.
+func (agent *Agent) runSystemdNotifier(ctx context.Context, connected <-chan struct{}) {
+	if !systemd.Enabled() {
+		return
+	}
+
+	agent.notifySystemd(systemd.Ready, systemd.Status("Checking registration with Home Assistant."))
+	defer agent.notifySystemd(systemd.Stopping, systemd.Status("Stopping."))
+
+	statusTicker := time.NewTicker(systemdStatusInterval)
+	defer statusTicker.Stop()
+
+	// The status is only refreshed once the agent has connected.
+	var statusCh <-chan time.Time
+
+	interval, err := systemd.WatchdogInterval()
+	if err != nil {
+		agent.logger.Warn("Not using systemd watchdog.", slog.Any("error", err))
+	}
+
+	// Without a watchdog, the ticker never fires.
+	var watchdogCh <-chan time.Time
+
+	if interval > 0 {
+		// Notify at half the interval, as recommended by sd_watchdog_enabled(3).
+		watchdogTicker := time.NewTicker(interval / 2)
+		defer watchdogTicker.Stop()
+
+		watchdogCh = watchdogTicker.C
+
+		agent.logger.Debug("Using systemd watchdog.", slog.Duration("interval", interval))
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-agent.done:
+			return
+		case <-connected:
+			// Only report the connection once.
+			connected = nil
+
+			agent.notifySystemd(systemd.Status(agent.connectionStatus(ctx)))
+			statusTicker.Reset(systemdStatusInterval)
+
+			statusCh = statusTicker.C
+		case <-statusCh:
+			agent.notifySystemd(systemd.Status(agent.connectionStatus(ctx)))
+		case <-watchdogCh:
+			// Until the sensor workers have started, there is no pipeline
+			// to stall.
+			if pipeline := agent.pipeline.Load(); pipeline != nil && pipeline.Stalled(interval) {
+				agent.logger.Warn("Sensor updates have stopped being processed, not notifying systemd watchdog.")
+				agent.notifySystemd(systemd.Status("Sensor updates have stopped being processed."))
+
+				continue
+			}
+
+			agent.notifySystemd(systemd.Watchdog)
+		}
+	}
+}
+
+// connectionStatus returns a description of the connection state of the agent
+// to Home Assistant and MQTT.
+func (agent *Agent) connectionStatus(ctx context.Context) string {
+	status := "Cannot connect to Home Assistant."
+	if version := agent.hass.HassVersion(ctx); version != "Unknown" {
+		status = "Connected to Home Assistant " + version + "."
+	}
+
+	if mqttPrefs := agent.prefs.GetMQTTPreferences(); mqttPrefs != nil && mqttPrefs.IsMQTTEnabled() {
+		if agent.mqttConnected.Load() {
+			status += " Connected to MQTT."
+		} else {
+			status += " Not connected to MQTT."
+		}
+	}
+
+	return status
+}
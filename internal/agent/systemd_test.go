@@ -0,0 +1,76 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+// versionHassClient is a HassClient that reports the given Home Assistant
+// version.
+type versionHassClient struct {
+	HassClient
+	version string
+}
+
+func (c *versionHassClient) HassVersion(_ context.Context) string {
+	return c.version
+}
+
+func TestAgent_runSystemdNotifier(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", socket)
+	t.Setenv("WATCHDOG_USEC", "")
+
+	read := func() string {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+		buf := make([]byte, 1024)
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+
+		return string(buf[:n])
+	}
+
+	agent := &Agent{
+		done:   make(chan struct{}),
+		logger: slog.Default(),
+		hass:   &versionHassClient{version: "2024.10.0"},
+		prefs:  preferences.DefaultPreferences(filepath.Join(t.TempDir(), "test.toml")),
+	}
+	connected := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+		agent.runSystemdNotifier(context.TODO(), connected)
+	}()
+
+	// Ready is sent before the agent has connected.
+	assert.Equal(t, "READY=1\nSTATUS=Checking registration with Home Assistant.", read())
+
+	close(connected)
+	assert.Equal(t, "STATUS=Connected to Home Assistant 2024.10.0.", read())
+
+	close(agent.done)
+	assert.Equal(t, "STOPPING=1\nSTATUS=Stopping.", read())
+	<-finished
+}
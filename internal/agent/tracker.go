@@ -0,0 +1,106 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor/registry"
+)
+
+// trackerFile is the file in a registry directory that the state of the
+// sensor tracker is saved to.
+const trackerFile = "tracker.json"
+
+// LoadTracker creates the sensor tracker of the agent, with any state saved
+// when the agent last ran.
+func (agent *Agent) LoadTracker() (*sensor.Tracker, error) {
+	return agent.loadTracker(agent.GetRegistryPath())
+}
+
+// loadTracker creates a sensor tracker that saves its state in the given
+// registry directory. Saved state that is invalid is discarded.
+func (agent *Agent) loadTracker(path string) (*sensor.Tracker, error) {
+	file := filepath.Join(path, trackerFile)
+
+	trk, err := sensor.NewTracker(sensor.WithStateFile(file))
+	if errors.Is(err, sensor.ErrInvalidTrackerState) {
+		agent.logger.Warn("Discarding invalid saved sensor state.", slog.Any("error", err))
+
+		if err := os.Remove(file); err != nil {
+			return nil, fmt.Errorf("could not remove tracker state: %w", err)
+		}
+
+		trk, err = sensor.NewTracker(sensor.WithStateFile(file))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not load tracker: %w", err)
+	}
+
+	return trk, nil
+}
+
+// resetState removes all sensors from the tracker and registry of the default
+// server, along with their saved state, so that they are registered again when
+// next sent. The tracker can still be used afterwards.
+func (agent *Agent) resetState(trk Tracker) error {
+	if clearable, ok := trk.(interface{ Clear() }); ok {
+		clearable.Clear()
+	} else {
+		trk.Reset()
+	}
+
+	path := agent.GetRegistryPath()
+
+	if err := os.Remove(filepath.Join(path, trackerFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not remove tracker state: %w", err)
+	}
+
+	// If the registry is loaded, reset it through its client, so that its
+	// sensors are not written again when the agent saves its state.
+	if servers, ok := agent.hass.(*serverClients); ok {
+		if client, ok := servers.defaultClient().(interface{ ResetState() error }); ok {
+			if err := client.ResetState(); err != nil {
+				return fmt.Errorf("could not reset registry: %w", err)
+			}
+
+			return nil
+		}
+	}
+
+	if err := registry.Reset(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not reset registry: %w", err)
+	}
+
+	return nil
+}
+
+// runStateSaver saves the sensor state of each server periodically until the
+// context is canceled, so that little is lost if the agent does not stop
+// cleanly. Saving it when the agent stops is left to the caller.
+func (agent *Agent) runStateSaver(ctx context.Context, servers *serverClients, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := servers.saveState(); err != nil {
+				agent.logger.Warn("Could not save sensor state.", slog.Any("error", err))
+			}
+		}
+	}
+}
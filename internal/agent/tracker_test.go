@@ -0,0 +1,55 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor/registry"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+)
+
+func TestAgent_runStateSaver(t *testing.T) {
+	agent := newOrphansTestAgent(t, "http://localhost:8123")
+	path := agent.GetRegistryPath()
+
+	trk, err := agent.loadTracker(path)
+	require.NoError(t, err)
+
+	reg, err := registry.Load(path)
+	require.NoError(t, err)
+
+	servers := newServerClients(hass.NewClient(context.TODO(), trk, reg))
+
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		agent.runStateSaver(ctx, servers, 10*time.Millisecond)
+	}()
+
+	require.NoError(t, trk.Add(&linux.Sensor{UniqueID: "sensor", DisplayName: "Sensor", IconString: "mdi:test", Value: 1}))
+
+	// The state is saved without the agent stopping.
+	assert.Eventually(t, func() bool {
+		loaded, err := agent.loadTracker(path)
+
+		return err == nil && len(loaded.SensorList()) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.FileExists(t, filepath.Join(path, trackerFile))
+
+	cancelFunc()
+	<-done
+}
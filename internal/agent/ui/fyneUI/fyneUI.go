@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -344,6 +345,14 @@ func (i *FyneUI) sensorsWindow(client ui.HassClient) fyne.Window {
 			label.SetText("Value")
 		}
 	}
+	// Show the recent values of a sensor when it is selected.
+	sensorsTable.OnSelected = func(id widget.TableCellID) {
+		sensorsTable.Unselect(id)
+
+		if id.Row >= 0 && id.Row < len(sensors) {
+			i.sensorHistoryWindow(client, sensors[id.Row]).Show()
+		}
+	}
 	// ?: this is clunky. better way would be use Fyne bindings to sensor values
 	doneCh := make(chan struct{})
 
@@ -377,6 +386,48 @@ func (i *FyneUI) sensorsWindow(client ui.HassClient) fyne.Window {
 	return window
 }
 
+// sensorHistoryWindow creates a window that displays the recent values of the
+// sensor with the given ID, newest first.
+func (i *FyneUI) sensorHistoryWindow(client ui.HassClient, id string) fyne.Window {
+	var units string
+
+	if details, err := client.GetSensor(id); err == nil && details.Units() != "" {
+		units = " " + details.Units()
+	}
+
+	history, err := client.SensorHistory(id)
+	if err != nil {
+		i.logger.Debug("Could not get sensor history.", slog.String("sensor", id), slog.Any("error", err))
+	}
+
+	values := make([]string, 0, len(history))
+	for _, entry := range slices.Backward(history) {
+		values = append(values, fmt.Sprintf("%s  %v%s", entry.Time.Local().Format(time.DateTime), entry.State, units))
+	}
+
+	historyList := widget.NewList(
+		func() int {
+			return len(values)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel(longestString(values))
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			label, ok := obj.(*widget.Label)
+			if !ok {
+				return
+			}
+
+			label.SetText(values[id])
+		})
+
+	window := i.app.NewWindow(i.Translate("Sensor History") + ": " + id)
+	window.SetContent(historyList)
+	window.Resize(fyne.NewSize(480, 480))
+
+	return window
+}
+
 // registrationFields generates a list of form item widgets for selecting a
 // server to register the agent against.
 func (i *FyneUI) registrationFields(prefs *preferences.Preferences) []*widget.FormItem {
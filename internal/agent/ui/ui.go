@@ -24,6 +24,7 @@ type Agent interface {
 type HassClient interface {
 	SensorList() []string
 	GetSensor(id string) (sensor.Details, error)
+	SensorHistory(id string) ([]sensor.HistoryEntry, error)
 	HassVersion(ctx context.Context) string
 }
 
@@ -50,3 +50,121 @@ func mergeCh[T any](ctx context.Context, inCh ...<-chan T) chan T {
 
 	return outCh
 }
+
+// fanIn is a fan-in of channels where channels can be added and removed while
+// it is running. Unlike mergeCh, the output channel is only closed once the
+// context is canceled and all added channels have been drained.
+type fanIn[T any] struct {
+	ctx    context.Context //nolint:containedctx
+	outCh  chan T
+	inputs map[<-chan T]*fanInput
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	closed bool
+}
+
+// fanInput is a channel added to a fanIn.
+type fanInput struct {
+	cancelFunc context.CancelFunc
+}
+
+// newFanIn creates a new fanIn. The output channel will be closed after the
+// given context is canceled.
+func newFanIn[T any](ctx context.Context) *fanIn[T] {
+	fan := &fanIn[T]{
+		ctx:    ctx,
+		outCh:  make(chan T),
+		inputs: make(map[<-chan T]*fanInput),
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		fan.mu.Lock()
+		fan.closed = true
+		fan.mu.Unlock()
+
+		fan.wg.Wait()
+		close(fan.outCh)
+	}()
+
+	return fan
+}
+
+// Add will add the given channels to the fan-in. Any nil channels are ignored.
+// Channels added after the context has been canceled are ignored.
+func (f *fanIn[T]) Add(inCh ...<-chan T) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return
+	}
+
+	for _, ch := range inCh {
+		if ch == nil {
+			continue
+		}
+
+		if _, found := f.inputs[ch]; found {
+			continue
+		}
+
+		inputCtx, cancelFunc := context.WithCancel(f.ctx)
+		input := &fanInput{cancelFunc: cancelFunc}
+		f.inputs[ch] = input
+
+		f.wg.Add(1)
+
+		go func() {
+			defer f.wg.Done()
+			defer f.remove(ch, input)
+
+			for {
+				select {
+				case n, ok := <-ch:
+					// Don't forward values received after the channel
+					// was removed.
+					if !ok || inputCtx.Err() != nil {
+						return
+					}
+					select {
+					case f.outCh <- n:
+					case <-inputCtx.Done():
+						return
+					}
+				case <-inputCtx.Done():
+					return
+				}
+			}
+		}()
+	}
+}
+
+// Remove will stop forwarding values from the given channels. The channels are
+// not drained or closed.
+func (f *fanIn[T]) Remove(inCh ...<-chan T) {
+	for _, ch := range inCh {
+		f.remove(ch, nil)
+	}
+}
+
+// remove will stop forwarding values from the given channel. If input is not
+// nil, the channel is only removed if it has not been re-added since.
+func (f *fanIn[T]) remove(ch <-chan T, input *fanInput) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	current, found := f.inputs[ch]
+	if !found || (input != nil && current != input) {
+		return
+	}
+
+	current.cancelFunc()
+	delete(f.inputs, ch)
+}
+
+// Out returns the output channel of the fan-in.
+func (f *fanIn[T]) Out() <-chan T {
+	return f.outCh
+}
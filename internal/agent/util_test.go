@@ -10,6 +10,7 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func Test_mergeCh(t *testing.T) {
@@ -59,3 +60,62 @@ func Test_mergeCh(t *testing.T) {
 		})
 	}
 }
+
+func Test_fanIn(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	fan := newFanIn[int](ctx)
+
+	send := func(count int) <-chan int {
+		ch := make(chan int)
+		go func() {
+			defer close(ch)
+			for i := range count {
+				ch <- i
+			}
+		}()
+
+		return ch
+	}
+
+	fan.Add(send(5), nil)
+
+	var got int
+	for range 5 {
+		<-fan.Out()
+		got++
+	}
+
+	// Channels can be added after the fan-in has started.
+	fan.Add(send(10))
+
+	for range 10 {
+		<-fan.Out()
+		got++
+	}
+
+	if got != 15 {
+		t.Errorf("fanIn received %v, want %v", got, 15)
+	}
+
+	// Removed channels are no longer forwarded.
+	removed := make(chan int, 1)
+	fan.Add(removed)
+	fan.Remove(removed)
+	removed <- 1
+
+	select {
+	case <-fan.Out():
+		t.Error("fanIn received from removed channel")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// The output channel is closed once the context is canceled.
+	cancelFunc()
+
+	for range fan.Out() {
+		t.Error("fanIn output received after cancel")
+	}
+
+	// Channels added after cancel are ignored.
+	fan.Add(send(1))
+}
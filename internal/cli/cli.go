@@ -7,11 +7,13 @@ package cli
 
 import (
 	"embed"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 
+	"github.com/joshuar/go-hass-agent/internal/control"
 	"github.com/joshuar/go-hass-agent/internal/logging"
 )
 
@@ -105,3 +107,20 @@ func showHelpTxt(file string) string {
 
 	return string(helpTxt)
 }
+
+// newControlClient returns a client for the control API of the running agent.
+func newControlClient(ctx *Context) *control.Client {
+	return control.NewClient(control.SocketPath(ctx.AppID))
+}
+
+// printJSON prints the given value as indented JSON.
+func printJSON(value any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Errorf("could not encode output: %w", err)
+	}
+
+	return nil
+}
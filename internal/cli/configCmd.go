@@ -8,23 +8,33 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"os"
 	"path/filepath"
+	"slices"
+	"text/tabwriter"
 
 	"github.com/adrg/xdg"
 
+	"github.com/joshuar/go-hass-agent/internal/agent"
 	"github.com/joshuar/go-hass-agent/internal/preferences"
 )
 
 var ErrMQTTServerRequired = errors.New("mqtt-server not specified")
 
 type ConfigCmd struct {
+	MQTT    ConfigMQTTCmd    `cmd:"" default:"withargs" help:"Set MQTT options (default)."`
+	Workers ConfigWorkersCmd `cmd:"" help:"Enable/disable sensor workers and override their intervals."`
+}
+
+type ConfigMQTTCmd struct {
 	Path       string `kong:"hidden"`
 	MQTTConfig `kong:"help='Set MQTT options.'"`
 }
 
 type MQTTConfig preferences.MQTT
 
-func (r *ConfigCmd) Run(ctx *Context) error {
+func (r *ConfigMQTTCmd) Run(ctx *Context) error {
 	r.Path = filepath.Join(xdg.ConfigHome, ctx.AppID)
 
 	prefs, err := preferences.Load(r.Path)
@@ -55,3 +65,104 @@ func (r *MQTTConfig) Validate() error {
 
 	return nil
 }
+
+type ConfigWorkersCmd struct {
+	Enable   []string          `help:"Enable the given worker(s)." placeholder:"WORKER"`
+	Disable  []string          `help:"Disable the given worker(s)." placeholder:"WORKER"`
+	Interval map[string]string `help:"Override the poll interval of a worker." placeholder:"WORKER=DURATION"`
+	Jitter   map[string]string `help:"Override the poll jitter of a worker." placeholder:"WORKER=DURATION"`
+	Reset    []string          `help:"Remove all preferences for the given worker(s)." placeholder:"WORKER"`
+}
+
+func (r *ConfigWorkersCmd) Help() string {
+	return showHelpTxt("config-workers-help")
+}
+
+func (r *ConfigWorkersCmd) Run(ctx *Context) error {
+	prefs, err := preferences.Load(filepath.Join(xdg.ConfigHome, ctx.AppID))
+	if err != nil {
+		return fmt.Errorf("config: load preferences: %w", err)
+	}
+
+	// Preferences for unknown workers can still be reset.
+	for _, id := range slices.Concat(r.Enable, r.Disable, slices.Collect(maps.Keys(r.Interval)), slices.Collect(maps.Keys(r.Jitter))) {
+		if !agent.KnownWorker(id) {
+			return fmt.Errorf("config: %w: %s", agent.ErrUnknownWorker, id)
+		}
+	}
+
+	if prefs.Workers == nil {
+		prefs.Workers = make(map[string]*preferences.Worker)
+	}
+
+	worker := func(id string) *preferences.Worker {
+		if _, found := prefs.Workers[id]; !found {
+			prefs.Workers[id] = &preferences.Worker{}
+		}
+
+		return prefs.Workers[id]
+	}
+
+	for _, id := range r.Enable {
+		enabled := true
+		worker(id).Enabled = &enabled
+	}
+
+	for _, id := range r.Disable {
+		enabled := false
+		worker(id).Enabled = &enabled
+	}
+
+	for id, interval := range r.Interval {
+		worker(id).Interval = interval
+	}
+
+	for id, jitter := range r.Jitter {
+		worker(id).Jitter = jitter
+	}
+
+	for _, id := range r.Reset {
+		delete(prefs.Workers, id)
+	}
+
+	if len(r.Enable)+len(r.Disable)+len(r.Interval)+len(r.Jitter)+len(r.Reset) > 0 {
+		if err := prefs.Save(); err != nil {
+			return fmt.Errorf("config: save preferences: %w", err)
+		}
+	}
+
+	return showWorkerPrefs(prefs)
+}
+
+// showWorkerPrefs prints a table of the workers that have preferences set.
+func showWorkerPrefs(prefs *preferences.Preferences) error {
+	if len(prefs.Workers) == 0 {
+		fmt.Fprintln(os.Stdout, "No worker preferences set. All workers are enabled with default intervals.")
+
+		return nil
+	}
+
+	ids := make([]string, 0, len(prefs.Workers))
+	for id := range prefs.Workers {
+		ids = append(ids, id)
+	}
+
+	slices.Sort(ids)
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd
+	fmt.Fprintln(table, "WORKER\tENABLED\tINTERVAL\tJITTER")
+
+	for _, id := range ids {
+		fmt.Fprintf(table, "%s\t%t\t%s\t%s\n",
+			id,
+			prefs.WorkerEnabled(id),
+			valueOrDash(prefs.Workers[id].Interval),
+			valueOrDash(prefs.Workers[id].Jitter))
+	}
+
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//revive:disable:unused-receiver
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joshuar/go-hass-agent/internal/linux/snapshot"
+	"github.com/joshuar/go-hass-agent/internal/logging"
+)
+
+type DebugCmd struct {
+	Capture DebugCaptureCmd `cmd:"" help:"Capture the procfs and sysfs files read by the sensor workers."`
+}
+
+type DebugCaptureCmd struct {
+	Output       string `short:"o" help:"File to write the snapshot to." type:"path" default:"go-hass-agent-snapshot.tar.gz"`
+	MaxFileSize  int64  `help:"Skip files larger than this many bytes." default:"1048576"`
+	MaxTotalSize int64  `help:"Fail if the snapshot would be larger than this many bytes." default:"33554432"`
+	NoRedact     bool   `help:"Do not redact user names, host names and mount credentials."`
+}
+
+func (r *DebugCaptureCmd) Help() string {
+	return showHelpTxt("debug-capture-help")
+}
+
+func (r *DebugCaptureCmd) Run(ctx *Context) error {
+	captureCtx, cancelFunc := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancelFunc()
+
+	captureCtx = logging.ToContext(captureCtx, logging.NewStderr(ctx.LogLevel))
+
+	file, err := os.Create(r.Output)
+	if err != nil {
+		return fmt.Errorf("debug capture: %w", err)
+	}
+
+	count, err := snapshot.Capture(captureCtx, file, snapshot.Options{
+		MaxFileSize:  r.MaxFileSize,
+		MaxTotalSize: r.MaxTotalSize,
+		NoRedact:     r.NoRedact,
+	})
+	if err = errors.Join(err, file.Close()); err != nil {
+		return errors.Join(fmt.Errorf("debug capture: %w", err), os.Remove(r.Output))
+	}
+
+	fmt.Fprintf(os.Stdout, "Captured %d files to %s.\n", count, r.Output)
+
+	return nil
+}
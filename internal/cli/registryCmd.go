@@ -0,0 +1,269 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//revive:disable:unused-receiver
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joshuar/go-hass-agent/internal/agent"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor/registry"
+	"github.com/joshuar/go-hass-agent/internal/logging"
+)
+
+var ErrAgentRunning = errors.New("the agent is running, stop it before changing the registry")
+
+type RegistryCmd struct {
+	List    RegistryListCmd    `cmd:"" default:"withargs" help:"List the sensors in the registry."`
+	Enable  RegistryEnableCmd  `cmd:"" help:"Enable sensors that were disabled with the disable command."`
+	Disable RegistryDisableCmd `cmd:"" help:"Stop sending sensors to Home Assistant, even if enabled there."`
+	Forget  RegistryForgetCmd  `cmd:"" help:"Remove sensors from the registry, so they are registered again when next sent."`
+	Orphans RegistryOrphansCmd `cmd:"" help:"Find sensors that have not been sent for a while and clean them up in Home Assistant."`
+}
+
+// registryFlags are the flags shared by the registry commands.
+type registryFlags struct {
+	Server string `help:"Use the registry of the additional server with this name." placeholder:"NAME"`
+}
+
+// load loads the registry selected by the flags.
+func (f *registryFlags) load(ctx *Context) (registryStore, error) {
+	reg, err := registry.Load(agent.RegistryPath(ctx.AppID, f.Server))
+	if err != nil {
+		return nil, fmt.Errorf("registry: %w", err)
+	}
+
+	return reg, nil
+}
+
+// registryStore is the registry as used by the registry commands.
+type registryStore interface {
+	Entries() []registry.Entry
+	SetDisabledByUser(id string, value bool) error
+	Forget(id string) error
+}
+
+// checkNotRunning returns ErrAgentRunning if the agent is running, as it would
+// overwrite any changes made to its registry.
+func checkNotRunning(ctx *Context) error {
+	if _, err := newControlClient(ctx).Status(context.Background()); err == nil {
+		return ErrAgentRunning
+	}
+
+	return nil
+}
+
+type RegistryListCmd struct {
+	registryFlags `embed:""`
+
+	JSON bool `help:"Output as JSON."`
+}
+
+func (r *RegistryListCmd) Run(ctx *Context) error {
+	reg, err := r.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	entries := reg.Entries()
+
+	if r.JSON {
+		return printJSON(entries)
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd
+	fmt.Fprintln(table, "SENSOR\tNAME\tREGISTERED\tDISABLED\tLAST SEEN")
+
+	for _, entry := range entries {
+		fmt.Fprintf(table, "%s\t%s\t%t\t%s\t%s\n",
+			entry.ID,
+			valueOrDash(entry.Name),
+			entry.Registered,
+			disabledBy(entry),
+			sinceUpdated(entry.LastSeen))
+	}
+
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("registry: %w", err)
+	}
+
+	return nil
+}
+
+// disabledBy returns who disabled the sensor, or "-" if it is not disabled.
+func disabledBy(entry registry.Entry) string {
+	switch {
+	case entry.DisabledByUser:
+		return "user"
+	case entry.Disabled:
+		return "home assistant"
+	default:
+		return "-"
+	}
+}
+
+type RegistryEnableCmd struct {
+	registryFlags `embed:""`
+
+	IDs []string `arg:"" name:"id" help:"IDs of the sensors to enable."`
+}
+
+func (r *RegistryEnableCmd) Run(ctx *Context) error {
+	return r.update(ctx, r.IDs, "Enabled", func(reg registryStore, id string) error {
+		return reg.SetDisabledByUser(id, false) //nolint:wrapcheck
+	})
+}
+
+type RegistryDisableCmd struct {
+	registryFlags `embed:""`
+
+	IDs []string `arg:"" name:"id" help:"IDs of the sensors to disable."`
+}
+
+func (r *RegistryDisableCmd) Run(ctx *Context) error {
+	return r.update(ctx, r.IDs, "Disabled", func(reg registryStore, id string) error {
+		return reg.SetDisabledByUser(id, true) //nolint:wrapcheck
+	})
+}
+
+type RegistryForgetCmd struct {
+	registryFlags `embed:""`
+
+	IDs []string `arg:"" name:"id" help:"IDs of the sensors to forget."`
+}
+
+func (r *RegistryForgetCmd) Run(ctx *Context) error {
+	return r.update(ctx, r.IDs, "Forgot", func(reg registryStore, id string) error {
+		return reg.Forget(id) //nolint:wrapcheck
+	})
+}
+
+// update applies the change to each of the sensors with the given IDs, once
+// it has checked the agent is not running.
+func (f *registryFlags) update(ctx *Context, ids []string, done string, change func(reg registryStore, id string) error) error {
+	if err := checkNotRunning(ctx); err != nil {
+		return fmt.Errorf("registry: %w", err)
+	}
+
+	reg, err := f.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := change(reg, id); err != nil {
+			return fmt.Errorf("registry: %w", err)
+		}
+
+		fmt.Fprintf(os.Stdout, "%s %s.\n", done, id)
+	}
+
+	return nil
+}
+
+type RegistryOrphansCmd struct {
+	registryFlags `embed:""`
+
+	OlderThan       time.Duration `help:"Find sensors not sent for longer than this. Defaults to the orphan_after sensor preference (30 days)." placeholder:"DURATION"`
+	Token           string        `help:"Long-lived access token of a Home Assistant administrator, used with --remove. Defaults to the registration token." placeholder:"TOKEN"`
+	JSON            bool          `help:"Output as JSON."`
+	MarkUnavailable bool          `help:"Set the state of the orphaned sensors to unavailable in Home Assistant."`
+	Remove          bool          `help:"Remove the orphaned sensors from Home Assistant and the registry."`
+}
+
+func (r *RegistryOrphansCmd) Run(ctx *Context) error {
+	orphansCtx, cancelFunc := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancelFunc()
+
+	orphansCtx = logging.ToContext(orphansCtx, logging.NewStderr(ctx.LogLevel))
+
+	if r.Remove {
+		if err := checkNotRunning(ctx); err != nil {
+			return fmt.Errorf("registry: %w", err)
+		}
+	}
+
+	gohassagent, err := agent.NewAgent(orphansCtx, ctx.AppID, agent.Headless(true))
+	if err != nil {
+		return fmt.Errorf("registry: %w", err)
+	}
+
+	orphans, err := gohassagent.OrphanedSensors(r.Server, r.OlderThan)
+	if err != nil {
+		return fmt.Errorf("registry: %w", err)
+	}
+
+	if !r.MarkUnavailable && !r.Remove {
+		return printOrphans(orphans, r.JSON)
+	}
+
+	ids := make([]string, 0, len(orphans))
+	for _, entry := range orphans {
+		ids = append(ids, entry.ID)
+	}
+
+	if len(ids) == 0 {
+		fmt.Fprintln(os.Stdout, "No orphaned sensors.")
+
+		return nil
+	}
+
+	if r.MarkUnavailable {
+		if err := gohassagent.MarkSensorsUnavailable(orphansCtx, r.Server, ids...); err != nil {
+			return fmt.Errorf("registry: %w", err)
+		}
+
+		fmt.Fprintf(os.Stdout, "Marked %d sensors unavailable.\n", len(ids))
+	}
+
+	if r.Remove {
+		removed, err := gohassagent.RemoveSensors(orphansCtx, r.Server, r.Token, ids...)
+
+		for _, id := range removed {
+			fmt.Fprintf(os.Stdout, "Removed %s.\n", id)
+		}
+
+		if err != nil {
+			return fmt.Errorf("registry: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// printOrphans prints the orphaned sensors as a table or JSON.
+func printOrphans(orphans []registry.Entry, asJSON bool) error {
+	if asJSON {
+		if orphans == nil {
+			orphans = []registry.Entry{}
+		}
+
+		return printJSON(orphans)
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd
+	fmt.Fprintln(table, "SENSOR\tNAME\tLAST SEEN")
+
+	for _, entry := range orphans {
+		fmt.Fprintf(table, "%s\t%s\t%s\n",
+			entry.ID,
+			valueOrDash(entry.Name),
+			sinceUpdated(entry.LastSeen))
+	}
+
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("registry: %w", err)
+	}
+
+	return nil
+}
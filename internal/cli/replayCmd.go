@@ -0,0 +1,46 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//revive:disable:unused-receiver
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joshuar/go-hass-agent/internal/agent"
+	"github.com/joshuar/go-hass-agent/internal/logging"
+)
+
+type ReplayCmd struct {
+	Server    string  `help:"URL of the server to send the sensor updates to, instead of the registered Home Assistant."`
+	Recording string  `arg:"" help:"Recording to replay." type:"existingfile"`
+	Speed     float64 `help:"Speed to replay at, relative to the recording. Use 0 to replay as fast as possible." default:"1"`
+}
+
+func (r *ReplayCmd) Help() string {
+	return showHelpTxt("replay-help")
+}
+
+func (r *ReplayCmd) Run(ctx *Context) error {
+	replayCtx, cancelFunc := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancelFunc()
+
+	replayCtx = logging.ToContext(replayCtx, logging.NewStderr(ctx.LogLevel))
+
+	gohassagent, err := agent.NewAgent(replayCtx, ctx.AppID, agent.Headless(true))
+	if err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+
+	if err := gohassagent.ReplaySensors(replayCtx, r.Recording, r.Speed, r.Server); err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+
+	return nil
+}
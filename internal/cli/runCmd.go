@@ -9,6 +9,7 @@ package cli
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"path/filepath"
 
 	"github.com/adrg/xdg"
@@ -17,9 +18,13 @@ import (
 	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
 	"github.com/joshuar/go-hass-agent/internal/hass/sensor/registry"
 	"github.com/joshuar/go-hass-agent/internal/logging"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/recorder"
 )
 
-type RunCmd struct{}
+type RunCmd struct {
+	Record string `help:"Record all sensor updates to the given file, for replaying with the replay command." type:"path"`
+}
 
 func (r *RunCmd) Help() string {
 	return showHelpTxt("run-help")
@@ -40,8 +45,24 @@ func (r *RunCmd) Run(ctx *Context) error {
 	logger := logging.New(ctx.LogLevel, logFile)
 	agentCtx = logging.ToContext(agentCtx, logger)
 
-	gohassagent, err := agent.NewAgent(agentCtx, ctx.AppID,
-		agent.Headless(ctx.Headless))
+	options := []agent.Option{agent.Headless(ctx.Headless)}
+
+	if r.Record != "" {
+		rec, err := recorder.New(r.Record, preferences.AppVersion)
+		if err != nil {
+			return fmt.Errorf("failed to run: %w", err)
+		}
+
+		defer func() {
+			if err := rec.Close(); err != nil {
+				logger.Warn("Could not close recording.", slog.Any("error", err))
+			}
+		}()
+
+		options = append(options, agent.WithRecorder(rec))
+	}
+
+	gohassagent, err := agent.NewAgent(agentCtx, ctx.AppID, options...)
 	if err != nil {
 		return fmt.Errorf("failed to run: %w", err)
 	}
@@ -53,7 +74,7 @@ func (r *RunCmd) Run(ctx *Context) error {
 		return fmt.Errorf("could not start registry: %w", err)
 	}
 
-	if trk, err = sensor.NewTracker(); err != nil {
+	if trk, err = gohassagent.LoadTracker(); err != nil {
 		return fmt.Errorf("could not start sensor tracker: %w", err)
 	}
 
@@ -0,0 +1,85 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//revive:disable:unused-receiver
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/adrg/xdg"
+
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/scripts"
+)
+
+type ScriptsCmd struct {
+	List ScriptsListCmd `cmd:"" help:"List discovered scripts and any problems with them."`
+}
+
+type ScriptsListCmd struct{}
+
+func (r *ScriptsListCmd) Help() string {
+	return showHelpTxt("scripts-list-help")
+}
+
+func (r *ScriptsListCmd) Run(ctx *Context) error {
+	userDir := filepath.Join(xdg.ConfigHome, ctx.AppID, "scripts")
+
+	// If the preferences cannot be loaded, the defaults will be used.
+	prefs, _ := preferences.Load(filepath.Join(xdg.ConfigHome, ctx.AppID)) //nolint:errcheck
+	scriptDirs := prefs.ScriptDirs(userDir)
+
+	results, err := scripts.DiscoverDirs(scriptDirs...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Some script directories could not be searched: %s\n", err.Error())
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintf(os.Stdout, "No scripts found in %s.\n", strings.Join(scriptDirs, ", "))
+
+		return nil
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd
+	fmt.Fprintln(table, "SCRIPT\tSTATUS\tSCHEDULE\tFORMAT\tSOURCE\tDETAILS")
+
+	for _, result := range results {
+		var details string
+		if result.Overrides != "" {
+			details = "overrides " + result.Overrides
+		}
+
+		switch {
+		case result.Err != nil:
+			fmt.Fprintf(table, "%s\terror\t-\t-\t-\t%s\n", result.Path, result.Err.Error())
+		case !result.Script.Enabled():
+			fmt.Fprintf(table, "%s\tdisabled\t%s\t%s\t%s\t%s\n",
+				result.Path, valueOrDash(result.Script.Schedule()), result.Script.Format(), result.Script.Source(), details)
+		default:
+			fmt.Fprintf(table, "%s\tok\t%s\t%s\t%s\t%s\n",
+				result.Path, result.Script.Schedule(), result.Script.Format(), result.Script.Source(), details)
+		}
+	}
+
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("scripts: %w", err)
+	}
+
+	return nil
+}
+
+// valueOrDash returns the given string or a "-" if it is empty.
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
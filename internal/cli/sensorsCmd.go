@@ -0,0 +1,230 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//revive:disable:unused-receiver
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joshuar/go-hass-agent/internal/agent"
+	"github.com/joshuar/go-hass-agent/internal/control"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/linux/snapshot"
+	"github.com/joshuar/go-hass-agent/internal/logging"
+)
+
+type SensorsCmd struct {
+	List    SensorsListCmd    `cmd:"" default:"withargs" help:"List the sensors tracked by the running agent."`
+	History SensorsHistoryCmd `cmd:"" help:"Show the recent values of a sensor tracked by the running agent."`
+	Dump    SensorsDumpCmd    `cmd:"" help:"Run sensor workers without Home Assistant and print their sensors."`
+}
+
+type SensorsListCmd struct {
+	JSON bool `help:"Output as JSON."`
+}
+
+func (r *SensorsListCmd) Run(ctx *Context) error {
+	sensors, err := newControlClient(ctx).Sensors(context.Background())
+	if err != nil {
+		return fmt.Errorf("sensors: %w", err)
+	}
+
+	if r.JSON {
+		return printJSON(sensors)
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd
+	fmt.Fprintln(table, "SENSOR\tNAME\tVALUE\tUPDATED\tDISABLED")
+
+	for _, details := range sensors {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%t\n",
+			details.ID,
+			details.Name,
+			sensorValue(details),
+			sinceUpdated(details.LastUpdated),
+			details.Disabled)
+	}
+
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("sensors: %w", err)
+	}
+
+	return nil
+}
+
+// sensorValue returns the value of the sensor with any units.
+func sensorValue(details control.Sensor) string {
+	if details.Units == "" {
+		return fmt.Sprint(details.State)
+	}
+
+	return fmt.Sprintf("%v %s", details.State, details.Units)
+}
+
+// sinceUpdated returns how long ago the given time was, or "-" for the zero
+// time.
+func sinceUpdated(updated time.Time) string {
+	if updated.IsZero() {
+		return "-"
+	}
+
+	return time.Since(updated).Round(time.Second).String() + " ago"
+}
+
+type SensorsHistoryCmd struct {
+	ID   string `arg:"" help:"ID of the sensor."`
+	JSON bool   `help:"Output as JSON."`
+}
+
+func (r *SensorsHistoryCmd) Run(ctx *Context) error {
+	history, err := newControlClient(ctx).History(context.Background(), r.ID)
+	if err != nil {
+		return fmt.Errorf("sensors: %w", err)
+	}
+
+	if r.JSON {
+		return printJSON(history)
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd
+	fmt.Fprintln(table, "TIME\tVALUE")
+
+	for _, entry := range history {
+		fmt.Fprintf(table, "%s\t%v\n", entry.Time.Local().Format(time.DateTime), entry.State)
+	}
+
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("sensors: %w", err)
+	}
+
+	return nil
+}
+
+type SensorsDumpCmd struct {
+	Worker   []string `short:"w" help:"Only run the worker with this ID. Can be repeated."`
+	Watch    bool     `help:"Keep running and print sensor updates as they happen."`
+	JSON     bool     `help:"Output as JSON (one sensor per line)."`
+	Snapshot string   `help:"Read procfs and sysfs from a snapshot (tarball or extracted directory) made with debug capture." type:"existingpath"`
+}
+
+func (r *SensorsDumpCmd) Help() string {
+	return showHelpTxt("sensors-dump-help")
+}
+
+// dumpedSensor is the JSON representation of a sensor output by the dump
+// command.
+type dumpedSensor struct {
+	State       any            `json:"state"`
+	Attributes  map[string]any `json:"attributes,omitempty"`
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	Type        string         `json:"type"`
+	Icon        string         `json:"icon,omitempty"`
+	Units       string         `json:"units,omitempty"`
+	DeviceClass string         `json:"device_class,omitempty"`
+	StateClass  string         `json:"state_class,omitempty"`
+	Category    string         `json:"category,omitempty"`
+}
+
+func newDumpedSensor(details sensor.Details) *dumpedSensor {
+	dumped := &dumpedSensor{
+		State:      details.State(),
+		Attributes: details.Attributes(),
+		ID:         details.ID(),
+		Name:       details.Name(),
+		Type:       details.SensorType().String(),
+		Icon:       details.Icon(),
+		Units:      details.Units(),
+		Category:   details.Category(),
+	}
+
+	if details.DeviceClass() > 0 {
+		dumped.DeviceClass = details.DeviceClass().String()
+	}
+
+	if details.StateClass() > 0 {
+		dumped.StateClass = details.StateClass().String()
+	}
+
+	return dumped
+}
+
+func (r *SensorsDumpCmd) Run(ctx *Context) error {
+	dumpCtx, cancelFunc := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancelFunc()
+
+	// Log to stderr so that the output can be piped.
+	dumpCtx = logging.ToContext(dumpCtx, logging.NewStderr(ctx.LogLevel))
+
+	if r.Snapshot != "" {
+		cleanup, err := snapshot.Load(r.Snapshot)
+		defer cleanup() //nolint:errcheck
+
+		if err != nil {
+			return fmt.Errorf("sensors: %w", err)
+		}
+	}
+
+	gohassagent, err := agent.NewAgent(dumpCtx, ctx.AppID, agent.Headless(true))
+	if err != nil {
+		return fmt.Errorf("sensors: %w", err)
+	}
+
+	sensorCh, err := gohassagent.DumpSensors(dumpCtx, r.Watch, r.Worker...)
+	if err != nil {
+		return fmt.Errorf("sensors: %w", err)
+	}
+
+	if r.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+
+		for details := range sensorCh {
+			if err := encoder.Encode(newDumpedSensor(details)); err != nil {
+				return fmt.Errorf("sensors: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	if r.Watch {
+		for details := range sensorCh {
+			fmt.Fprintf(os.Stdout, "%s  %s = %s\n",
+				time.Now().Format(time.TimeOnly), details.ID(), dumpedValue(details))
+		}
+
+		return nil
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd
+	fmt.Fprintln(table, "SENSOR\tNAME\tVALUE")
+
+	for details := range sensorCh {
+		fmt.Fprintf(table, "%s\t%s\t%s\n", details.ID(), details.Name(), dumpedValue(details))
+	}
+
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("sensors: %w", err)
+	}
+
+	return nil
+}
+
+// dumpedValue returns the value of the sensor with any units.
+func dumpedValue(details sensor.Details) string {
+	if details.Units() == "" {
+		return fmt.Sprint(details.State())
+	}
+
+	return fmt.Sprintf("%v %s", details.State(), details.Units())
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//revive:disable:unused-receiver
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+type StatusCmd struct {
+	JSON bool `help:"Output as JSON."`
+}
+
+func (r *StatusCmd) Help() string {
+	return showHelpTxt("status-help")
+}
+
+func (r *StatusCmd) Run(ctx *Context) error {
+	status, err := newControlClient(ctx).Status(context.Background())
+	if err != nil {
+		return fmt.Errorf("status: %w", err)
+	}
+
+	if r.JSON {
+		return printJSON(status)
+	}
+
+	hassStatus := "disconnected"
+	if status.Connected {
+		hassStatus = "connected (Home Assistant " + status.HassVersion + ")"
+	}
+
+	mqttStatus := "disabled"
+	if status.MQTT.Enabled {
+		mqttStatus = "disconnected"
+		if status.MQTT.Connected {
+			mqttStatus = "connected"
+		}
+
+		mqttStatus += " (" + status.MQTT.Server + ")"
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd
+	fmt.Fprintf(table, "Version:\t%s\n", status.Version)
+	fmt.Fprintf(table, "Server:\t%s\n", status.Server)
+	fmt.Fprintf(table, "Registered:\t%t\n", status.Registered)
+	fmt.Fprintf(table, "Home Assistant:\t%s\n", hassStatus)
+	fmt.Fprintf(table, "MQTT:\t%s\n", mqttStatus)
+	fmt.Fprintf(table, "Workers:\t%d active, %d inactive\n", status.ActiveWorkers, status.InactiveWorkers)
+
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("status: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//revive:disable:unused-receiver
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+type WorkersCmd struct {
+	List  WorkersListCmd  `cmd:"" default:"withargs" help:"List the sensor workers of the running agent."`
+	Start WorkersStartCmd `cmd:"" help:"Start sensor workers in the running agent."`
+	Stop  WorkersStopCmd  `cmd:"" help:"Stop sensor workers in the running agent."`
+}
+
+type WorkersListCmd struct {
+	JSON bool `help:"Output as JSON."`
+}
+
+func (r *WorkersListCmd) Run(ctx *Context) error {
+	workers, err := newControlClient(ctx).Workers(context.Background())
+	if err != nil {
+		return fmt.Errorf("workers: %w", err)
+	}
+
+	if r.JSON {
+		return printJSON(workers)
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0) //nolint:mnd
+	fmt.Fprintln(table, "WORKER\tSTATUS")
+
+	for _, worker := range workers {
+		status := "inactive"
+		if worker.Active {
+			status = "active"
+		}
+
+		fmt.Fprintf(table, "%s\t%s\n", worker.ID, status)
+	}
+
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("workers: %w", err)
+	}
+
+	return nil
+}
+
+type WorkersStartCmd struct {
+	IDs []string `arg:"" name:"id" help:"IDs of the workers to start."`
+}
+
+func (r *WorkersStartCmd) Run(ctx *Context) error {
+	client := newControlClient(ctx)
+
+	for _, id := range r.IDs {
+		if err := client.StartWorker(context.Background(), id); err != nil {
+			return fmt.Errorf("workers: %w", err)
+		}
+
+		fmt.Fprintf(os.Stdout, "Started %s.\n", id)
+	}
+
+	return nil
+}
+
+type WorkersStopCmd struct {
+	IDs []string `arg:"" name:"id" help:"IDs of the workers to stop."`
+}
+
+func (r *WorkersStopCmd) Run(ctx *Context) error {
+	client := newControlClient(ctx)
+
+	for _, id := range r.IDs {
+		if err := client.StopWorker(context.Background(), id); err != nil {
+			return fmt.Errorf("workers: %w", err)
+		}
+
+		fmt.Fprintf(os.Stdout, "Stopped %s.\n", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// baseURL is used for all requests. The host is ignored as requests are
+	// made over the Unix socket.
+	baseURL = "http://go-hass-agent"
+
+	defaultTimeout = 30 * time.Second
+)
+
+// Client makes requests to the control API of a running agent.
+type Client struct {
+	client *http.Client
+}
+
+// NewClient creates a new Client for the agent listening on the control socket
+// at the given path.
+func NewClient(path string) *Client {
+	dialer := &net.Dialer{}
+
+	return &Client{
+		client: &http.Client{
+			Timeout: defaultTimeout,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", path)
+				},
+			},
+		},
+	}
+}
+
+// Status returns the status of the agent.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	status := &Status{}
+
+	if err := c.do(ctx, http.MethodGet, "/v1/status", status); err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// Workers returns the sensor workers of the agent.
+func (c *Client) Workers(ctx context.Context) ([]Worker, error) {
+	var workers []Worker
+
+	if err := c.do(ctx, http.MethodGet, "/v1/workers", &workers); err != nil {
+		return nil, err
+	}
+
+	return workers, nil
+}
+
+// StartWorker starts the sensor worker with the given ID.
+func (c *Client) StartWorker(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/v1/workers/"+url.PathEscape(id)+"/start", nil)
+}
+
+// StopWorker stops the sensor worker with the given ID.
+func (c *Client) StopWorker(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/v1/workers/"+url.PathEscape(id)+"/stop", nil)
+}
+
+// Sensors returns the sensors tracked by the agent.
+func (c *Client) Sensors(ctx context.Context) ([]Sensor, error) {
+	var sensors []Sensor
+
+	if err := c.do(ctx, http.MethodGet, "/v1/sensors", &sensors); err != nil {
+		return nil, err
+	}
+
+	return sensors, nil
+}
+
+// History returns the most recent states of the sensor with the given ID,
+// oldest first.
+func (c *Client) History(ctx context.Context, id string) ([]HistoryEntry, error) {
+	var history []HistoryEntry
+
+	if err := c.do(ctx, http.MethodGet, "/v1/sensors/"+url.PathEscape(id)+"/history", &history); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// Refresh requests the agent immediately send the current value of all
+// sensors to Home Assistant.
+func (c *Client) Refresh(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/v1/refresh", nil)
+}
+
+// do makes a request to the given path. If result is not nil, the response
+// body is decoded into it.
+func (c *Client) do(ctx context.Context, method, path string, result any) error {
+	req, err := http.NewRequestWithContext(ctx, method, baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("could not create request: %w", err)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		if errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.ECONNREFUSED) {
+			return ErrNotRunning
+		}
+
+		return fmt.Errorf("%w: %w", ErrRequestFailed, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return responseError(res)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return fmt.Errorf("%w: could not decode response: %w", ErrRequestFailed, err)
+	}
+
+	return nil
+}
+
+// responseError returns an error for a failed request.
+func responseError(res *http.Response) error {
+	var (
+		baseErr error
+		errRes  errorResponse
+	)
+
+	switch res.StatusCode {
+	case http.StatusNotFound:
+		baseErr = ErrUnknownWorker
+	case http.StatusConflict:
+		baseErr = ErrWorkerState
+	default:
+		baseErr = ErrRequestFailed
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil || json.Unmarshal(body, &errRes) != nil || errRes.Error == "" {
+		return fmt.Errorf("%w: %s", baseErr, res.Status)
+	}
+
+	// Both unknown workers and unknown sensors are not found.
+	if res.StatusCode == http.StatusNotFound && strings.HasPrefix(errRes.Error, ErrUnknownSensor.Error()) {
+		baseErr = ErrUnknownSensor
+	}
+
+	// Avoid repeating the error when the message already wraps it.
+	return fmt.Errorf("%w: %s", baseErr, strings.TrimPrefix(errRes.Error, baseErr.Error()+": "))
+}
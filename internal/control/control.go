@@ -0,0 +1,157 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package control provides a local API for controlling a running agent. The
+// API is served over HTTP on a Unix socket that is only accessible by the user
+// running the agent.
+//
+//revive:disable:max-public-structs
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/adrg/xdg"
+)
+
+const (
+	socketFile = "control.sock"
+
+	socketDirPerms = 0o700
+	socketPerms    = 0o600
+)
+
+var (
+	ErrUnknownWorker  = errors.New("unknown worker")
+	ErrUnknownSensor  = errors.New("unknown sensor")
+	ErrWorkerState    = errors.New("worker is already in the requested state")
+	ErrAlreadyRunning = errors.New("control socket is in use by another agent")
+	ErrInsecureDir    = errors.New("control socket directory is accessible by other users")
+	ErrNotRunning     = errors.New("agent is not running")
+	ErrRequestFailed  = errors.New("control request failed")
+)
+
+// Status is the connection and registration status of the agent.
+type Status struct {
+	Version         string     `json:"version"`
+	Server          string     `json:"server"`
+	HassVersion     string     `json:"hass_version,omitempty"`
+	MQTT            MQTTStatus `json:"mqtt"`
+	ActiveWorkers   int        `json:"active_workers"`
+	InactiveWorkers int        `json:"inactive_workers"`
+	Registered      bool       `json:"registered"`
+	Connected       bool       `json:"connected"`
+}
+
+// MQTTStatus is the status of the connection of the agent to MQTT.
+type MQTTStatus struct {
+	Server    string `json:"server,omitempty"`
+	Enabled   bool   `json:"enabled"`
+	Connected bool   `json:"connected"`
+}
+
+// Worker is a sensor worker of the agent.
+type Worker struct {
+	ID     string `json:"id"`
+	Active bool   `json:"active"`
+}
+
+// Sensor is a sensor tracked by the agent, with its current value.
+type Sensor struct {
+	LastUpdated time.Time      `json:"last_updated"`
+	State       any            `json:"state"`
+	Attributes  map[string]any `json:"attributes,omitempty"`
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	Units       string         `json:"units,omitempty"`
+	Icon        string         `json:"icon,omitempty"`
+	Disabled    bool           `json:"disabled"`
+}
+
+// HistoryEntry is a past state of a sensor tracked by the agent.
+type HistoryEntry struct {
+	Time  time.Time `json:"time"`
+	State any       `json:"state"`
+}
+
+// SocketPath returns the path of the control socket for the agent with the
+// given app ID.
+func SocketPath(appID string) string {
+	return filepath.Join(xdg.RuntimeDir, appID, socketFile)
+}
+
+// Listen creates a listener on the Unix socket at the given path. The
+// directory containing the socket is created if needed and the socket is only
+// accessible by the current user. If the directory already exists but could be
+// accessed by other users, ErrInsecureDir is returned. A stale socket left
+// behind by an agent that did not exit cleanly is replaced. If another agent is
+// listening on the socket, ErrAlreadyRunning is returned.
+func Listen(ctx context.Context, path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), socketDirPerms); err != nil {
+		return nil, fmt.Errorf("could not create socket directory: %w", err)
+	}
+
+	if err := checkSocketDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		dialer := &net.Dialer{Timeout: time.Second}
+
+		if conn, err := dialer.DialContext(ctx, "unix", path); err == nil {
+			conn.Close()
+
+			return nil, ErrAlreadyRunning
+		}
+
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("could not remove stale socket: %w", err)
+		}
+	}
+
+	var listenConfig net.ListenConfig
+
+	listener, err := listenConfig.Listen(ctx, "unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen on socket: %w", err)
+	}
+
+	if err := os.Chmod(path, socketPerms); err != nil {
+		return nil, errors.Join(fmt.Errorf("could not set socket permissions: %w", err), listener.Close())
+	}
+
+	return listener, nil
+}
+
+// checkSocketDir checks that the given directory is owned by the current user
+// and only accessible by them, as the directory may have existed before the
+// agent created it.
+func checkSocketDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("could not check socket directory: %w", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("%w: %s is not a directory", ErrInsecureDir, dir)
+	}
+
+	if info.Mode().Perm() != socketDirPerms {
+		return fmt.Errorf("%w: %s has permissions %o", ErrInsecureDir, dir, info.Mode().Perm())
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%w: %s is owned by another user (uid %d)", ErrInsecureDir, dir, stat.Uid)
+	}
+
+	return nil
+}
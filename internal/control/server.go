@@ -0,0 +1,196 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	readHeaderTimeout = 5 * time.Second
+	shutdownTimeout   = 5 * time.Second
+)
+
+// Backend is the running agent, which handles the requests made to the control
+// API.
+type Backend interface {
+	// Status returns the current status of the agent.
+	Status(ctx context.Context) Status
+	// Workers returns all sensor workers of the agent.
+	Workers(ctx context.Context) ([]Worker, error)
+	// StartWorker starts the sensor worker with the given ID.
+	StartWorker(ctx context.Context, id string) error
+	// StopWorker stops the sensor worker with the given ID.
+	StopWorker(ctx context.Context, id string) error
+	// Sensors returns all sensors tracked by the agent.
+	Sensors(ctx context.Context) ([]Sensor, error)
+	// History returns the most recent states of the sensor with the given ID,
+	// oldest first.
+	History(ctx context.Context, id string) ([]HistoryEntry, error)
+	// Refresh fetches the current value of all sensors of all active workers
+	// and sends them to Home Assistant.
+	Refresh(ctx context.Context) error
+}
+
+// errorResponse is the body of a response to a failed request.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server serves the control API for a Backend.
+type Server struct {
+	backend Backend
+	logger  *slog.Logger
+	server  *http.Server
+}
+
+// NewServer creates a new Server for the given Backend.
+func NewServer(backend Backend, logger *slog.Logger) *Server {
+	srv := &Server{
+		backend: backend,
+		logger:  logger.With(slog.String("subsystem", "control")),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", srv.handleStatus)
+	mux.HandleFunc("GET /v1/workers", srv.handleWorkers)
+	mux.HandleFunc("POST /v1/workers/{id}/start", srv.handleStartWorker)
+	mux.HandleFunc("POST /v1/workers/{id}/stop", srv.handleStopWorker)
+	mux.HandleFunc("GET /v1/sensors", srv.handleSensors)
+	mux.HandleFunc("GET /v1/sensors/{id}/history", srv.handleHistory)
+	mux.HandleFunc("POST /v1/refresh", srv.handleRefresh)
+
+	srv.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	return srv
+}
+
+// Serve serves the control API on the given listener until the context is
+// canceled.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	s.server.BaseContext = func(_ net.Listener) context.Context { return ctx }
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancelFunc := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancelFunc()
+
+		if err := s.server.Shutdown(shutdownCtx); err != nil { //nolint:contextcheck
+			s.logger.Debug("Could not cleanly shut down control API.", slog.Any("error", err))
+		}
+	}()
+
+	s.logger.Debug("Serving control API.", slog.String("address", listener.Addr().String()))
+
+	if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("control API failed: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Server) handleStatus(res http.ResponseWriter, req *http.Request) {
+	s.writeJSON(res, http.StatusOK, s.backend.Status(req.Context()))
+}
+
+func (s *Server) handleWorkers(res http.ResponseWriter, req *http.Request) {
+	workers, err := s.backend.Workers(req.Context())
+	if err != nil {
+		s.writeError(res, err)
+
+		return
+	}
+
+	s.writeJSON(res, http.StatusOK, workers)
+}
+
+func (s *Server) handleStartWorker(res http.ResponseWriter, req *http.Request) {
+	if err := s.backend.StartWorker(req.Context(), req.PathValue("id")); err != nil {
+		s.writeError(res, err)
+
+		return
+	}
+
+	res.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleStopWorker(res http.ResponseWriter, req *http.Request) {
+	if err := s.backend.StopWorker(req.Context(), req.PathValue("id")); err != nil {
+		s.writeError(res, err)
+
+		return
+	}
+
+	res.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleSensors(res http.ResponseWriter, req *http.Request) {
+	sensors, err := s.backend.Sensors(req.Context())
+	if err != nil {
+		s.writeError(res, err)
+
+		return
+	}
+
+	s.writeJSON(res, http.StatusOK, sensors)
+}
+
+func (s *Server) handleHistory(res http.ResponseWriter, req *http.Request) {
+	history, err := s.backend.History(req.Context(), req.PathValue("id"))
+	if err != nil {
+		s.writeError(res, err)
+
+		return
+	}
+
+	s.writeJSON(res, http.StatusOK, history)
+}
+
+func (s *Server) handleRefresh(res http.ResponseWriter, req *http.Request) {
+	if err := s.backend.Refresh(req.Context()); err != nil {
+		s.writeError(res, err)
+
+		return
+	}
+
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// writeJSON writes the given value as the JSON body of the response.
+func (s *Server) writeJSON(res http.ResponseWriter, code int, value any) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(code)
+
+	if err := json.NewEncoder(res).Encode(value); err != nil {
+		s.logger.Debug("Could not write control API response.", slog.Any("error", err))
+	}
+}
+
+// writeError writes the given error as the response, with a status code
+// matching the error.
+func (s *Server) writeError(res http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+
+	switch {
+	case errors.Is(err, ErrUnknownWorker), errors.Is(err, ErrUnknownSensor):
+		code = http.StatusNotFound
+	case errors.Is(err, ErrWorkerState):
+		code = http.StatusConflict
+	}
+
+	s.writeJSON(res, code, &errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package control
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testHistoryTime = time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+type testBackend struct {
+	workers   map[string]bool
+	mu        sync.Mutex
+	refreshed bool
+}
+
+func (b *testBackend) Status(_ context.Context) Status {
+	return Status{Version: "test", Registered: true, Connected: true}
+}
+
+func (b *testBackend) Workers(_ context.Context) ([]Worker, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	workers := make([]Worker, 0, len(b.workers))
+	for id, active := range b.workers {
+		workers = append(workers, Worker{ID: id, Active: active})
+	}
+
+	slices.SortFunc(workers, func(a, b Worker) int { return strings.Compare(a.ID, b.ID) })
+
+	return workers, nil
+}
+
+func (b *testBackend) setWorker(id string, active bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current, found := b.workers[id]
+	if !found {
+		return fmt.Errorf("%w: %s", ErrUnknownWorker, id)
+	}
+
+	if current == active {
+		return fmt.Errorf("%w: %s", ErrWorkerState, id)
+	}
+
+	b.workers[id] = active
+
+	return nil
+}
+
+func (b *testBackend) StartWorker(_ context.Context, id string) error {
+	return b.setWorker(id, true)
+}
+
+func (b *testBackend) StopWorker(_ context.Context, id string) error {
+	return b.setWorker(id, false)
+}
+
+func (b *testBackend) Sensors(_ context.Context) ([]Sensor, error) {
+	return []Sensor{{ID: "sensor_a", Name: "Sensor A", State: 1.5, Units: "%"}}, nil
+}
+
+func (b *testBackend) History(_ context.Context, id string) ([]HistoryEntry, error) {
+	if id != "sensor_a" {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSensor, id)
+	}
+
+	return []HistoryEntry{{Time: testHistoryTime, State: 1.0}, {Time: testHistoryTime.Add(time.Minute), State: 1.5}}, nil
+}
+
+func (b *testBackend) Refresh(_ context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refreshed = true
+
+	return nil
+}
+
+func newTestServer(t *testing.T) (*testBackend, *Client, string) {
+	t.Helper()
+
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	t.Cleanup(cancelFunc)
+
+	path := filepath.Join(t.TempDir(), "agent", socketFile)
+
+	listener, err := Listen(ctx, path)
+	require.NoError(t, err)
+
+	backend := &testBackend{workers: map[string]bool{"active": true, "inactive": false}}
+
+	go func() {
+		assert.NoError(t, NewServer(backend, slog.Default()).Serve(ctx, listener))
+	}()
+
+	return backend, NewClient(path), path
+}
+
+func TestServer(t *testing.T) {
+	backend, client, path := newTestServer(t)
+	ctx := context.TODO()
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(socketPerms), info.Mode().Perm())
+
+	status, err := client.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &Status{Version: "test", Registered: true, Connected: true}, status)
+
+	workers, err := client.Workers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []Worker{{ID: "active", Active: true}, {ID: "inactive"}}, workers)
+
+	require.NoError(t, client.StartWorker(ctx, "inactive"))
+	require.NoError(t, client.StopWorker(ctx, "active"))
+	assert.Equal(t, map[string]bool{"active": false, "inactive": true}, backend.workers)
+
+	err = client.StopWorker(ctx, "active")
+	require.ErrorIs(t, err, ErrWorkerState)
+	assert.Equal(t, "worker is already in the requested state: active", err.Error())
+
+	err = client.StartWorker(ctx, "unknown")
+	require.ErrorIs(t, err, ErrUnknownWorker)
+
+	sensors, err := client.Sensors(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []Sensor{{ID: "sensor_a", Name: "Sensor A", State: 1.5, Units: "%"}}, sensors)
+
+	history, err := client.History(ctx, "sensor_a")
+	require.NoError(t, err)
+	assert.Equal(t, []HistoryEntry{{Time: testHistoryTime, State: 1.0}, {Time: testHistoryTime.Add(time.Minute), State: 1.5}}, history)
+
+	_, err = client.History(ctx, "unknown")
+	require.ErrorIs(t, err, ErrUnknownSensor)
+
+	require.NoError(t, client.Refresh(ctx))
+	assert.True(t, backend.refreshed)
+}
+
+func TestListen(t *testing.T) {
+	_, _, path := newTestServer(t)
+
+	// Another agent is already listening.
+	_, err := Listen(context.TODO(), path)
+	require.ErrorIs(t, err, ErrAlreadyRunning)
+
+	// Stale socket.
+	staleDir := filepath.Join(t.TempDir(), "agent")
+	require.NoError(t, os.Mkdir(staleDir, socketDirPerms))
+
+	stale := filepath.Join(staleDir, socketFile)
+	require.NoError(t, os.WriteFile(stale, nil, socketPerms))
+
+	listener, err := Listen(context.TODO(), stale)
+	require.NoError(t, err)
+	require.NoError(t, listener.Close())
+
+	// Directory accessible by other users.
+	insecure := filepath.Join(t.TempDir(), "agent")
+	require.NoError(t, os.Mkdir(insecure, 0o755)) //nolint:gosec
+
+	_, err = Listen(context.TODO(), filepath.Join(insecure, socketFile))
+	require.ErrorIs(t, err, ErrInsecureDir)
+	assert.NoFileExists(t, filepath.Join(insecure, socketFile))
+
+	// Symlink to a directory.
+	link := filepath.Join(t.TempDir(), "link")
+	require.NoError(t, os.Symlink(t.TempDir(), link))
+
+	_, err = Listen(context.TODO(), filepath.Join(link, socketFile))
+	require.ErrorIs(t, err, ErrInsecureDir)
+}
+
+func TestClient_NotRunning(t *testing.T) {
+	client := NewClient(filepath.Join(t.TempDir(), socketFile))
+
+	_, err := client.Status(context.TODO())
+	require.ErrorIs(t, err, ErrNotRunning)
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package dbusapi
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/godbus/dbus/v5"
+
+	"github.com/joshuar/go-hass-agent/internal/hass"
+)
+
+// object is the agent object exported on the session bus. Its exported methods
+// are the D-Bus methods of the Interface.
+type object struct {
+	service *Service
+}
+
+// RegisterSensor registers a sensor with the given ID and name. The options can
+// set the "icon", "units", "device_class", "state_class", "type" ("binary" for
+// a binary sensor) and "category" ("diagnostic") of the sensor. Registering an
+// existing sensor again replaces its options. The sensor is sent to Home
+// Assistant when it is first updated.
+func (o *object) RegisterSensor(id, name string, options map[string]dbus.Variant) *dbus.Error {
+	if err := o.service.registerSensor(id, name, variantMap(options)); err != nil {
+		return newError(err)
+	}
+
+	o.service.logger.Debug("Sensor registered over D-Bus.", slog.String("sensor", id))
+
+	return nil
+}
+
+// UpdateSensor sets the state and attributes of a registered sensor and sends
+// it to Home Assistant.
+func (o *object) UpdateSensor(id string, state dbus.Variant, attributes map[string]dbus.Variant) *dbus.Error {
+	if err := o.service.updateSensor(id, variantValue(state), variantMap(attributes)); err != nil {
+		return newError(err)
+	}
+
+	return nil
+}
+
+// FireEvent fires an event of the given type, with the given data, in Home
+// Assistant.
+func (o *object) FireEvent(eventType string, data map[string]dbus.Variant) *dbus.Error {
+	if eventType == "" {
+		return dbus.NewError(errInvalidArgs, []any{"event type is required"})
+	}
+
+	o.service.mu.Lock()
+	ctx := o.service.ctx
+	o.service.mu.Unlock()
+
+	if err := o.service.agent.FireEvent(ctx, &hass.Event{Type: eventType, Data: variantMap(data)}); err != nil {
+		return newError(err)
+	}
+
+	return nil
+}
+
+// ShowNotification displays a notification with the given title and message
+// on the device.
+func (o *object) ShowNotification(title, message string) *dbus.Error {
+	if message == "" {
+		return dbus.NewError(errInvalidArgs, []any{"message is required"})
+	}
+
+	if err := o.service.agent.ShowNotification(title, message); err != nil {
+		return newError(err)
+	}
+
+	return nil
+}
+
+// newError returns the D-Bus error for the given error.
+func newError(err error) *dbus.Error {
+	name := errFailed
+
+	switch {
+	case errors.Is(err, ErrInvalidSensor):
+		name = errInvalidArgs
+	case errors.Is(err, ErrUnknownSensor):
+		name = errUnknownSensor
+	case errors.Is(err, ErrNotRunning):
+		name = errNotRunning
+	}
+
+	return dbus.NewError(name, []any{err.Error()})
+}
+
+// variantMap converts a map of variants, as used for dictionaries of mixed
+// values over D-Bus, into a map of their values.
+func variantMap(variants map[string]dbus.Variant) map[string]any {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	values := make(map[string]any, len(variants))
+	for key, variant := range variants {
+		values[key] = variantValue(variant)
+	}
+
+	return values
+}
+
+// variantValue returns the value of the given variant. Any variants nested in
+// the value are also converted.
+func variantValue(variant dbus.Variant) any {
+	switch value := variant.Value().(type) {
+	case dbus.Variant:
+		return variantValue(value)
+	case map[string]dbus.Variant:
+		return variantMap(value)
+	case []dbus.Variant:
+		values := make([]any, 0, len(value))
+		for _, v := range value {
+			values = append(values, variantValue(v))
+		}
+
+		return values
+	default:
+		return value
+	}
+}
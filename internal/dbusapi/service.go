@@ -0,0 +1,315 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package dbusapi provides a D-Bus service on the session bus that local
+// applications can use to send sensors, events and notifications to Home
+// Assistant through the agent, without needing their own Home Assistant
+// credentials.
+package dbusapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/joshuar/go-hass-agent/internal/hass"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/logging"
+	"github.com/joshuar/go-hass-agent/internal/scripts"
+	"github.com/joshuar/go-hass-agent/pkg/linux/dbusx"
+)
+
+const (
+	// ServiceName is the well-known name of the service on the session bus.
+	ServiceName = "org.joshuar.GoHassAgent"
+	// ObjectPath is the path of the agent object.
+	ObjectPath = "/org/joshuar/GoHassAgent"
+	// Interface is the interface of the agent object.
+	Interface = ServiceName
+
+	// WorkerID is the ID of the worker for the service.
+	WorkerID = "dbus_api"
+
+	// statusInterval is how often the status properties are refreshed.
+	statusInterval = time.Minute
+)
+
+// Names of the errors returned to callers of the service.
+const (
+	errInvalidArgs   = Interface + ".Error.InvalidArgs"
+	errUnknownSensor = Interface + ".Error.UnknownSensor"
+	errFailed        = Interface + ".Error.Failed"
+	errNotRunning    = Interface + ".Error.NotRunning"
+)
+
+var (
+	ErrNoSessionBus  = errors.New("no session bus")
+	ErrRunning       = errors.New("service already running")
+	ErrNotRunning    = errors.New("service not running")
+	ErrInvalidSensor = errors.New("invalid sensor")
+	ErrUnknownSensor = errors.New("sensor not registered")
+)
+
+// Agent is the functionality of the agent that the service makes available to
+// other applications.
+type Agent interface {
+	// FireEvent fires the event in Home Assistant.
+	FireEvent(ctx context.Context, event *hass.Event) error
+	// ShowNotification displays a notification on the device.
+	ShowNotification(title, message string) error
+	// Status returns the current connection status of the agent.
+	Status(ctx context.Context) Status
+}
+
+// Status is the connection status of the agent, available as properties of the
+// agent object.
+type Status struct {
+	Version       string
+	Server        string
+	HassVersion   string
+	Registered    bool
+	Connected     bool
+	MQTTConnected bool
+}
+
+// properties returns the status as D-Bus properties.
+func (s Status) properties() map[string]any {
+	return map[string]any{
+		"Version":       s.Version,
+		"Server":        s.Server,
+		"HassVersion":   s.HassVersion,
+		"Registered":    s.Registered,
+		"Connected":     s.Connected,
+		"MQTTConnected": s.MQTTConnected,
+	}
+}
+
+// Service is a worker that exports the agent object on the session bus while
+// it is running. Sensors registered and updated by other applications through
+// the object are published as updates of the worker.
+type Service struct {
+	agent  Agent
+	bus    *dbusx.Bus
+	logger *slog.Logger
+	// sensors are the sensors registered by other applications, by ID.
+	sensors    map[string]*scripts.ScriptSensor
+	updates    chan sensor.Details
+	ctx        context.Context //nolint:containedctx
+	cancelFunc context.CancelFunc
+	// senders tracks updates being sent, so that the updates channel is only
+	// closed once they are done.
+	senders sync.WaitGroup
+	mu      sync.Mutex
+}
+
+// New creates the service, with its own connection to the session bus. It
+// returns an error if the session bus is not available.
+func New(ctx context.Context, agent Agent) (*Service, error) {
+	bus, err := dbusx.NewBus(ctx, dbusx.SessionBus)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNoSessionBus, err)
+	}
+
+	return &Service{
+		agent:   agent,
+		bus:     bus,
+		logger:  logging.FromContext(ctx).With(slog.String("worker", WorkerID)),
+		sensors: make(map[string]*scripts.ScriptSensor),
+	}, nil
+}
+
+func (s *Service) ID() string {
+	return WorkerID
+}
+
+// Sensors returns the current value of all sensors registered by other
+// applications that have been updated at least once.
+func (s *Service) Sensors(_ context.Context) ([]sensor.Details, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sensors := make([]sensor.Details, 0, len(s.sensors))
+
+	for _, details := range s.sensors {
+		if details.SensorState != nil {
+			sensors = append(sensors, details)
+		}
+	}
+
+	return sensors, nil
+}
+
+// Updates exports the agent object on the session bus. The returned channel is
+// closed once the context is canceled or Stop is called, after the object is
+// removed from the bus.
+func (s *Service) Updates(ctx context.Context) (<-chan sensor.Details, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.updates != nil {
+		return nil, ErrRunning
+	}
+
+	if err := s.bus.RequestName(ServiceName); err != nil {
+		return nil, fmt.Errorf("could not start D-Bus service: %w", err)
+	}
+
+	status := s.agent.Status(ctx)
+
+	exported, err := s.bus.Export(&object{service: s}, ObjectPath, Interface, status.properties())
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("could not start D-Bus service: %w", err), s.bus.ReleaseName(ServiceName))
+	}
+
+	s.ctx, s.cancelFunc = context.WithCancel(ctx)
+	s.updates = make(chan sensor.Details)
+
+	go s.run(s.ctx, exported)
+
+	return s.updates, nil
+}
+
+// run refreshes the status properties until the service is stopped, then
+// removes the agent object from the bus.
+func (s *Service) run(ctx context.Context, exported *dbusx.ExportedObject) {
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := exported.Unexport(); err != nil {
+				s.logger.Debug("Could not remove D-Bus object.", slog.Any("error", err))
+			}
+
+			if err := s.bus.ReleaseName(ServiceName); err != nil {
+				s.logger.Debug("Could not release D-Bus name.", slog.Any("error", err))
+			}
+
+			s.mu.Lock()
+			updates := s.updates
+			s.updates = nil
+			s.mu.Unlock()
+
+			s.senders.Wait()
+			close(updates)
+
+			return
+		case <-ticker.C:
+			for name, value := range s.agent.Status(ctx).properties() {
+				if err := exported.SetProperty(name, value); err != nil {
+					s.logger.Debug("Could not update D-Bus property.", slog.String("property", name), slog.Any("error", err))
+				}
+			}
+		}
+	}
+}
+
+// Stop removes the agent object from the bus.
+func (s *Service) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancelFunc != nil {
+		s.cancelFunc()
+	}
+
+	return nil
+}
+
+// registerSensor adds or updates the details of the sensor with the given ID.
+// Any existing state of the sensor is kept.
+func (s *Service) registerSensor(id, name string, options map[string]any) error {
+	if strcase.ToSnake(id) != id || id == "" {
+		return fmt.Errorf("%w: sensor ID must be in snake_case: %q", ErrInvalidSensor, id)
+	}
+
+	if name == "" {
+		return fmt.Errorf("%w: sensor name is required", ErrInvalidSensor)
+	}
+
+	details := &scripts.ScriptSensor{SensorID: id, SensorName: name}
+
+	for option, value := range options {
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%w: option %s should be a string", ErrInvalidSensor, option)
+		}
+
+		switch option {
+		case "icon":
+			details.SensorIcon = text
+		case "units":
+			details.SensorUnits = text
+		case "device_class":
+			details.SensorDeviceClass = text
+		case "state_class":
+			details.SensorStateClass = text
+		case "type":
+			details.SensorStateType = text
+		case "category":
+			details.SensorCategory = text
+		default:
+			return fmt.Errorf("%w: unknown option %s", ErrInvalidSensor, option)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, found := s.sensors[id]; found {
+		details.SensorState = existing.SensorState
+		details.SensorAttributes = existing.SensorAttributes
+	}
+
+	s.sensors[id] = details
+
+	return nil
+}
+
+// updateSensor sets the state and attributes of the registered sensor with the
+// given ID and publishes it as an update. It blocks until the update is
+// accepted by the agent.
+func (s *Service) updateSensor(id string, state any, attributes map[string]any) error {
+	s.mu.Lock()
+
+	existing, found := s.sensors[id]
+	if !found {
+		s.mu.Unlock()
+
+		return fmt.Errorf("%w: %s", ErrUnknownSensor, id)
+	}
+
+	if s.updates == nil {
+		s.mu.Unlock()
+
+		return ErrNotRunning
+	}
+
+	details := *existing
+	details.SensorState = state
+	details.SensorAttributes = maps.Clone(attributes)
+	s.sensors[id] = &details
+
+	updates, ctx := s.updates, s.ctx
+
+	s.senders.Add(1)
+	defer s.senders.Done()
+
+	s.mu.Unlock()
+
+	select {
+	case updates <- &details:
+		return nil
+	case <-ctx.Done():
+		return ErrNotRunning
+	}
+}
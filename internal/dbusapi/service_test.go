@@ -0,0 +1,151 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package dbusapi
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/hass"
+)
+
+// startSessionBus starts a private session bus for the test. The test is
+// skipped if dbus-daemon is not installed.
+func startSessionBus(t *testing.T) {
+	t.Helper()
+
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("dbus-daemon not available")
+	}
+
+	cmd := exec.Command("dbus-daemon", "--session", "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill() //nolint:errcheck
+		_ = cmd.Wait()         //nolint:errcheck
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	require.NoError(t, err)
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", strings.TrimSpace(address))
+}
+
+// fakeAgent records the events and notifications sent through the service.
+type fakeAgent struct {
+	events        []*hass.Event
+	notifications []string
+	mu            sync.Mutex
+}
+
+func (a *fakeAgent) FireEvent(_ context.Context, event *hass.Event) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.events = append(a.events, event)
+
+	return nil
+}
+
+func (a *fakeAgent) ShowNotification(title, message string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.notifications = append(a.notifications, title+": "+message)
+
+	return nil
+}
+
+func (a *fakeAgent) Status(_ context.Context) Status {
+	return Status{Version: "test", Server: "http://localhost:8123", Registered: true}
+}
+
+//revive:disable:function-length
+func TestService(t *testing.T) {
+	startSessionBus(t)
+
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+
+	agent := &fakeAgent{}
+
+	service, err := New(ctx, agent)
+	require.NoError(t, err)
+
+	updates, err := service.Updates(ctx)
+	require.NoError(t, err)
+
+	_, err = service.Updates(ctx)
+	require.ErrorIs(t, err, ErrRunning)
+
+	conn, err := dbus.ConnectSessionBus()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	obj := conn.Object(ServiceName, ObjectPath)
+
+	// Properties show the status of the agent.
+	server, err := obj.GetProperty(Interface + ".Server")
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8123", server.Value())
+
+	// Sensors must be registered before they can be updated.
+	err = obj.Call(Interface+".UpdateSensor", 0, "build_status", dbus.MakeVariant("passing"), map[string]dbus.Variant{}).Err
+	var dbusErr dbus.Error
+	require.ErrorAs(t, err, &dbusErr)
+	assert.Equal(t, errUnknownSensor, dbusErr.Name)
+
+	err = obj.Call(Interface+".RegisterSensor", 0, "Build Status", "Build Status", map[string]dbus.Variant{}).Err
+	require.ErrorAs(t, err, &dbusErr)
+	assert.Equal(t, errInvalidArgs, dbusErr.Name)
+
+	require.NoError(t, obj.Call(Interface+".RegisterSensor", 0, "build_status", "Build Status",
+		map[string]dbus.Variant{"icon": dbus.MakeVariant("mdi:hammer")}).Err)
+
+	// Updates are published by the worker.
+	call := obj.Go(Interface+".UpdateSensor", 0, nil, "build_status", dbus.MakeVariant("passing"),
+		map[string]dbus.Variant{"jobs": dbus.MakeVariant(map[string]dbus.Variant{"unit": dbus.MakeVariant(int32(3))})})
+
+	details := <-updates
+	require.NoError(t, (<-call.Done).Err)
+	assert.Equal(t, "build_status", details.ID())
+	assert.Equal(t, "Build Status", details.Name())
+	assert.Equal(t, "mdi:hammer", details.Icon())
+	assert.Equal(t, "passing", details.State())
+	assert.Equal(t, map[string]any{"jobs": map[string]any{"unit": int32(3)}}, details.Attributes())
+
+	sensors, err := service.Sensors(ctx)
+	require.NoError(t, err)
+	assert.Len(t, sensors, 1)
+
+	// Events and notifications are passed to the agent.
+	require.NoError(t, obj.Call(Interface+".FireEvent", 0, "build_finished",
+		map[string]dbus.Variant{"result": dbus.MakeVariant("passing")}).Err)
+	require.NoError(t, obj.Call(Interface+".ShowNotification", 0, "Build", "Build passed.").Err)
+
+	agent.mu.Lock()
+	assert.Equal(t, []*hass.Event{{Type: "build_finished", Data: map[string]any{"result": "passing"}}}, agent.events)
+	assert.Equal(t, []string{"Build: Build passed."}, agent.notifications)
+	agent.mu.Unlock()
+
+	// Once stopped, the object is removed from the bus.
+	require.NoError(t, service.Stop())
+
+	_, open := <-updates
+	assert.False(t, open)
+	require.Error(t, obj.Call(Interface+".ShowNotification", 0, "Build", "Build passed.").Err)
+}
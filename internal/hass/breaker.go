@@ -0,0 +1,180 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package hass
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	// breakerThreshold is the number of consecutive failed requests that opens
+	// the circuit breaker.
+	breakerThreshold = 5
+	// breakerMinBackoff is how long the circuit breaker first stays open.
+	breakerMinBackoff = 5 * time.Second
+	// breakerMaxBackoff is the longest the circuit breaker stays open, unless
+	// Home Assistant asks to wait longer.
+	breakerMaxBackoff = 5 * time.Minute
+)
+
+var ErrCircuitOpen = errors.New("request not sent as Home Assistant is unavailable")
+
+type breakerState int
+
+const (
+	// breakerClosed is the normal state, where all requests are sent.
+	breakerClosed breakerState = iota
+	// breakerOpen is the state after too many failed requests, where no
+	// requests are sent until the backoff has passed.
+	breakerOpen
+	// breakerHalfOpen is the state after the backoff has passed, where a
+	// single request is sent to probe whether Home Assistant is available.
+	breakerHalfOpen
+)
+
+// circuitBreaker stops requests being sent to Home Assistant while it is
+// unavailable, so that they fail immediately rather than each waiting for the
+// request timeout. The breaker opens after breakerThreshold consecutive failed
+// requests, or when Home Assistant asks for requests to be delayed. Once the
+// backoff has passed, a single request is let through. If it succeeds, the
+// breaker closes. If it fails, the breaker opens again with double the
+// backoff.
+type circuitBreaker struct {
+	openUntil time.Time
+	backoff   time.Duration
+	failures  int
+	state     breakerState
+	mu        sync.Mutex
+}
+
+// allow returns whether a request can be sent. If the breaker is open, an
+// error wrapping ErrCircuitOpen is returned.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if wait := time.Until(b.openUntil); wait > 0 {
+			return fmt.Errorf("%w: retrying in %s", ErrCircuitOpen, wait.Round(time.Second))
+		}
+
+		// This request probes whether Home Assistant is available.
+		b.state = breakerHalfOpen
+
+		return nil
+	case breakerHalfOpen:
+		return fmt.Errorf("%w: waiting for probe request", ErrCircuitOpen)
+	default:
+		return nil
+	}
+}
+
+// success records a successful request. It returns whether this closed the
+// breaker.
+func (b *circuitBreaker) success() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	closed := b.state != breakerClosed
+
+	b.state = breakerClosed
+	b.failures = 0
+	b.backoff = 0
+
+	return closed
+}
+
+// canceled records a request that was canceled before it completed. If it was
+// the probe request, another request can probe instead.
+func (b *circuitBreaker) canceled() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+	}
+}
+
+// failure records a failed request, with any delay requested by Home
+// Assistant. It returns how long the breaker was opened for, or 0 if it was
+// not opened.
+func (b *circuitBreaker) failure(retryAfter time.Duration) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+
+	switch {
+	case b.state == breakerOpen:
+		// The request was sent before the breaker opened. Only wait longer if
+		// Home Assistant asked to.
+		if until := time.Now().Add(retryAfter); until.After(b.openUntil) {
+			b.openUntil = until
+		}
+
+		return 0
+	case b.state == breakerClosed && b.failures < breakerThreshold && retryAfter <= 0:
+		return 0
+	}
+
+	if b.backoff == 0 {
+		b.backoff = breakerMinBackoff
+	} else {
+		b.backoff = min(2*b.backoff, breakerMaxBackoff)
+	}
+
+	wait := max(b.backoff, retryAfter)
+
+	b.state = breakerOpen
+	b.openUntil = time.Now().Add(wait)
+
+	return wait
+}
+
+// isFailure returns whether the response to a request shows Home Assistant is
+// unavailable or overloaded, rather than rejecting the request itself. A
+// request without a response, such as one that could not connect or timed
+// out, has failed.
+func isFailure(resp *resty.Response) bool {
+	if resp == nil || resp.StatusCode() == 0 {
+		return true
+	}
+
+	return resp.StatusCode() == http.StatusTooManyRequests || resp.StatusCode() >= http.StatusInternalServerError
+}
+
+// retryAfter returns the delay requested by the Retry-After header of the
+// response, which is either a number of seconds or a date. It returns 0 if
+// there is no valid header.
+func retryAfter(resp *resty.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+
+	value := strings.TrimSpace(resp.Header().Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+
+	return 0
+}
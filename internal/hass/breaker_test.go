@@ -0,0 +1,178 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package hass
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor/registry"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+)
+
+func Test_circuitBreaker(t *testing.T) {
+	var breaker circuitBreaker
+
+	// The breaker stays closed until the threshold is reached.
+	for range breakerThreshold - 1 {
+		assert.Zero(t, breaker.failure(0))
+		require.NoError(t, breaker.allow())
+	}
+
+	assert.Equal(t, breakerMinBackoff, breaker.failure(0))
+	require.ErrorIs(t, breaker.allow(), ErrCircuitOpen)
+
+	// Requests sent before the breaker opened do not open it for longer.
+	assert.Zero(t, breaker.failure(0))
+
+	// Once the backoff has passed, a single probe request is allowed.
+	breaker.openUntil = time.Now()
+	require.NoError(t, breaker.allow())
+	require.ErrorIs(t, breaker.allow(), ErrCircuitOpen)
+
+	// A failed probe opens the breaker with double the backoff.
+	assert.Equal(t, 2*breakerMinBackoff, breaker.failure(0))
+
+	// A successful probe closes the breaker.
+	breaker.openUntil = time.Now()
+	require.NoError(t, breaker.allow())
+	assert.True(t, breaker.success())
+	assert.False(t, breaker.success())
+	require.NoError(t, breaker.allow())
+
+	// The backoff is capped.
+	breaker.backoff = breakerMaxBackoff
+	breaker.state = breakerHalfOpen
+	assert.Equal(t, breakerMaxBackoff, breaker.failure(0))
+}
+
+func Test_circuitBreaker_retryAfter(t *testing.T) {
+	var breaker circuitBreaker
+
+	// Home Assistant asking to wait opens the breaker immediately, for as
+	// long as asked.
+	assert.Equal(t, time.Hour, breaker.failure(time.Hour))
+	require.ErrorIs(t, breaker.allow(), ErrCircuitOpen)
+
+	breaker.success()
+
+	// The backoff is used if it is longer.
+	assert.Equal(t, breakerMinBackoff, breaker.failure(time.Second))
+}
+
+func Test_circuitBreaker_canceled(t *testing.T) {
+	breaker := circuitBreaker{state: breakerOpen, backoff: breakerMinBackoff, openUntil: time.Now()}
+
+	// A canceled probe request lets another request probe.
+	require.NoError(t, breaker.allow())
+	breaker.canceled()
+	require.NoError(t, breaker.allow())
+}
+
+func Test_retryAfter(t *testing.T) {
+	newResponse := func(value string) *resty.Response {
+		header := http.Header{}
+		if value != "" {
+			header.Set("Retry-After", value)
+		}
+
+		return &resty.Response{RawResponse: &http.Response{Header: header}}
+	}
+
+	tests := []struct {
+		resp *resty.Response
+		name string
+		want time.Duration
+	}{
+		{name: "seconds", resp: newResponse("120"), want: 2 * time.Minute},
+		{name: "negative", resp: newResponse("-1"), want: 0},
+		{name: "past date", resp: newResponse("Wed, 21 Oct 2015 07:28:00 GMT"), want: 0},
+		{name: "invalid", resp: newResponse("soon"), want: 0},
+		{name: "missing", resp: newResponse(""), want: 0},
+		{name: "no response", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, retryAfter(tt.resp))
+		})
+	}
+
+	t.Run("future date", func(t *testing.T) {
+		date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+		got := retryAfter(newResponse(date))
+		assert.InDelta(t, time.Hour, got, float64(2*time.Second))
+	})
+}
+
+func TestClient_tooManyRequests(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	trk, err := sensor.NewTracker()
+	require.NoError(t, err)
+
+	reg, err := registry.Load(t.TempDir())
+	require.NoError(t, err)
+
+	client := NewClient(context.TODO(), trk, reg)
+	client.Endpoint(server.URL, time.Second)
+
+	details := &linux.Sensor{UniqueID: "sensor_a", DisplayName: "Sensor", IconString: "mdi:test", Value: 1}
+
+	// A long delay is not retried, but stops further requests.
+	require.Error(t, client.ProcessSensor(context.TODO(), details))
+	require.ErrorIs(t, client.ProcessSensor(context.TODO(), details), ErrCircuitOpen)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestClient_canceled(t *testing.T) {
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	trk, err := sensor.NewTracker()
+	require.NoError(t, err)
+
+	reg, err := registry.Load(t.TempDir())
+	require.NoError(t, err)
+
+	client := NewClient(context.TODO(), trk, reg)
+	client.Endpoint(server.URL, time.Minute)
+
+	details := &linux.Sensor{UniqueID: "sensor_a", DisplayName: "Sensor", IconString: "mdi:test", Value: 1}
+
+	// Requests canceled by the agent do not open the breaker.
+	for range breakerThreshold {
+		ctx, cancelFunc := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+		err := client.ProcessSensor(ctx, details)
+
+		cancelFunc()
+		require.Error(t, err)
+		require.NotErrorIs(t, err, ErrCircuitOpen)
+	}
+
+	require.NoError(t, client.breaker.allow())
+}
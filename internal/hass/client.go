@@ -14,6 +14,8 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-resty/resty/v2"
@@ -22,6 +24,10 @@ import (
 	"github.com/joshuar/go-hass-agent/internal/logging"
 )
 
+// stateUnavailable is the sensor state that Home Assistant shows as
+// unavailable.
+const stateUnavailable = "unavailable"
+
 var (
 	ErrGetConfigFailed   = errors.New("could not fetch Home Assistant config")
 	ErrGenRequestFailed  = errors.New("unable to generate request for sensor")
@@ -32,17 +38,36 @@ var (
 	ErrRegDisableFailed   = errors.New("failed to disable sensor in registry")
 	ErrRegAddFailed       = errors.New("failed to set registered status for sensor in registry")
 	ErrTrkUpdateFailed    = errors.New("failed to update sensor state in tracker")
+	ErrHistoryUnavailable = errors.New("sensor history not available")
 	ErrRegistrationFailed = errors.New("sensor registration failed")
 
+	ErrIntegrationRemoved = errors.New("mobile_app integration removed from Home Assistant")
+
 	ErrInvalidURL        = errors.New("invalid URL")
 	ErrInvalidClient     = errors.New("invalid client")
 	ErrResponseMalformed = errors.New("malformed response")
 	ErrUnknown           = errors.New("unknown error occurred")
 
 	defaultTimeout = 30 * time.Second
-	defaultRetry   = func(r *resty.Response, _ error) bool {
-		return r.StatusCode() == http.StatusTooManyRequests
+	// defaultRetry retries requests that Home Assistant rejected as too many,
+	// if it did not ask to wait longer than maxRetryWait. Otherwise, the
+	// circuit breaker stops requests for as long as asked.
+	defaultRetry = func(r *resty.Response, _ error) bool {
+		return r.StatusCode() == http.StatusTooManyRequests && retryAfter(r) <= maxRetryWait
 	}
+	// defaultRetryAfter waits for as long as Home Assistant asked before
+	// retrying, or the default backoff if it did not ask.
+	defaultRetryAfter = func(_ *resty.Client, r *resty.Response) (time.Duration, error) {
+		return retryAfter(r), nil
+	}
+)
+
+const (
+	// retryCount is the number of times a request rejected as too many is
+	// retried.
+	retryCount = 1
+	// maxRetryWait is the longest to wait before retrying a request.
+	maxRetryWait = 10 * time.Second
 )
 
 // Validate is a request that supports validation of its values.
@@ -89,12 +114,21 @@ type Client struct {
 	tracker  Tracker
 	registry Registry
 	logger   *slog.Logger
+	filter   atomic.Pointer[sensor.Filter]
+	orphans  map[string]struct{}
+	// removed is closed when Home Assistant reports that the mobile_app
+	// integration of the agent was removed.
+	removed chan struct{}
+	breaker circuitBreaker
+	mu      sync.Mutex
 }
 
 func NewClient(ctx context.Context, trk Tracker, reg Registry) *Client {
 	client := &Client{
 		tracker:  trk,
 		registry: reg,
+		orphans:  make(map[string]struct{}),
+		removed:  make(chan struct{}),
 	}
 
 	client.logger = logging.FromContext(ctx).With(slog.String("subsystem", "hass"))
@@ -109,8 +143,68 @@ func (c *Client) Endpoint(url string, timeout time.Duration) {
 
 	c.endpoint = resty.New().
 		SetTimeout(timeout).
+		SetRetryCount(retryCount).
+		SetRetryMaxWaitTime(maxRetryWait).
+		SetRetryAfter(defaultRetryAfter).
 		AddRetryCondition(defaultRetry).
 		SetBaseURL(url)
+
+	// A new endpoint is used after registering again, so requests can be sent
+	// again.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-c.removed:
+		c.removed = make(chan struct{})
+	default:
+	}
+}
+
+// IntegrationRemoved returns a channel that is closed when Home Assistant
+// reports that the mobile_app integration of the agent was removed. No
+// requests are sent until the endpoint is set again.
+func (c *Client) IntegrationRemoved() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.removed
+}
+
+// isRemoved returns whether Home Assistant has reported that the mobile_app
+// integration of the agent was removed.
+func (c *Client) isRemoved() bool {
+	select {
+	case <-c.IntegrationRemoved():
+		return true
+	default:
+		return false
+	}
+}
+
+// setRemoved records that Home Assistant has reported that the mobile_app
+// integration of the agent was removed.
+func (c *Client) setRemoved() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-c.removed:
+	default:
+		close(c.removed)
+		c.logger.Error("The agent was removed from Home Assistant. No sensors will be sent until it is registered again.")
+	}
+}
+
+// SetSensorFilter sets the filter that decides which sensors are sent to Home
+// Assistant. A nil filter allows all sensors.
+func (c *Client) SetSensorFilter(filter *sensor.Filter) {
+	c.filter.Store(filter)
+
+	// Report any sensors that are still filtered again.
+	c.mu.Lock()
+	c.orphans = make(map[string]struct{})
+	c.mu.Unlock()
 }
 
 func (c *Client) GetSensor(id string) (sensor.Details, error) {
@@ -126,6 +220,81 @@ func (c *Client) SensorList() []string {
 	return c.tracker.SensorList()
 }
 
+// SensorHistory returns the most recent states of the sensor, oldest first, if
+// the tracker keeps them.
+func (c *Client) SensorHistory(id string) ([]sensor.HistoryEntry, error) {
+	trk, ok := c.tracker.(interface {
+		History(id string) ([]sensor.HistoryEntry, error)
+	})
+	if !ok {
+		return nil, ErrHistoryUnavailable
+	}
+
+	entries, err := trk.History(id)
+	if err != nil {
+		return nil, fmt.Errorf("could not get sensor history: %w", err)
+	}
+
+	return entries, nil
+}
+
+// sensorSeen records that the sensor is still being sent, if the registry
+// keeps this. Sensors that are not seen for a while are orphaned.
+func (c *Client) sensorSeen(details sensor.Details) {
+	reg, ok := c.registry.(interface {
+		Seen(id, name string) error
+	})
+	if !ok {
+		return
+	}
+
+	if err := reg.Seen(details.ID(), details.Name()); err != nil {
+		c.logger.Debug("Could not record sensor in registry.", sensorLogAttrs(details), slog.Any("error", err))
+	}
+}
+
+// ResetState removes all sensors from the tracker and registry, so that they
+// are registered again when next sent.
+func (c *Client) ResetState() error {
+	if trk, ok := c.tracker.(interface{ Clear() }); ok {
+		trk.Clear()
+	} else {
+		c.tracker.Reset()
+	}
+
+	c.mu.Lock()
+	c.orphans = make(map[string]struct{})
+	c.mu.Unlock()
+
+	if reg, ok := c.registry.(interface{ Reset() error }); ok {
+		if err := reg.Reset(); err != nil {
+			return fmt.Errorf("could not reset registry: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SaveState saves the state of the tracker and registry, if they can be
+// saved.
+func (c *Client) SaveState() error {
+	var errs error
+
+	if trk, ok := c.tracker.(interface{ Save() error }); ok {
+		if err := trk.Save(); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("could not save tracker: %w", err))
+		}
+	}
+
+	if reg, ok := c.registry.(interface{ Save() error }); ok {
+		if err := reg.Save(); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("could not save registry: %w", err))
+		}
+	}
+
+	return errs
+}
+
 func (c *Client) HassVersion(ctx context.Context) string {
 	config, err := send[Config](ctx, c, &configRequest{})
 	if err != nil {
@@ -138,8 +307,24 @@ func (c *Client) HassVersion(ctx context.Context) string {
 }
 
 func (c *Client) ProcessSensor(ctx context.Context, details sensor.Details) error {
+	// The removal is reported once, rather than for every sensor.
+	if c.isRemoved() {
+		c.logger.Debug("Not sending request as the agent was removed from Home Assistant.", sensorLogAttrs(details))
+
+		return nil
+	}
+
+	if !c.filter.Load().Allowed(details) {
+		c.handleFiltered(details)
+
+		return nil
+	}
+
 	if c.isDisabled(ctx, details) {
 		c.logger.Debug("Not sending request for disabled sensor.", sensorLogAttrs(details))
+		// The sensor is still in Home Assistant and would be sent if enabled,
+		// so it is not orphaned.
+		c.sensorSeen(details)
 
 		return nil
 	}
@@ -157,6 +342,67 @@ func (c *Client) ProcessSensor(ctx context.Context, details sensor.Details) erro
 	return c.handleRegistration(ctx, details)
 }
 
+// handleFiltered handles a sensor that is excluded by the sensor filter. The
+// sensor is not sent to Home Assistant. If it was previously registered, it
+// will remain in Home Assistant without any updates, so it is reported (once)
+// as orphaned.
+func (c *Client) handleFiltered(details sensor.Details) {
+	if !c.registry.IsRegistered(details.ID()) {
+		c.logger.Debug("Not sending request for filtered sensor.", sensorLogAttrs(details))
+
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, found := c.orphans[details.ID()]; found {
+		return
+	}
+
+	c.orphans[details.ID()] = struct{}{}
+
+	c.logger.Warn("Sensor is registered but excluded by the sensor filter. It will no longer be updated and can be removed from Home Assistant.",
+		sensorLogAttrs(details))
+}
+
+// unavailableSensor is a sensor with the unavailable state.
+type unavailableSensor struct {
+	sensor.Details
+}
+
+func (s *unavailableSensor) State() any { return stateUnavailable }
+
+// MarkUnavailable sets the state of the sensor in Home Assistant to
+// unavailable. It is used for sensors that will no longer be updated, so that
+// their last state is not mistaken for a current one.
+func (c *Client) MarkUnavailable(ctx context.Context, details sensor.Details) error {
+	req, err := sensor.NewRequest(sensor.RequestTypeUpdate, &unavailableSensor{Details: details})
+	if err != nil {
+		return fmt.Errorf("unable to mark sensor unavailable: %w", err)
+	}
+
+	response, err := send[sensor.StateUpdateResponse](ctx, c, req)
+	if err != nil {
+		return fmt.Errorf("failed to send sensor update request for %s: %w", details.ID(), err)
+	}
+
+	status, found := response[details.ID()]
+	if !found {
+		return ErrStateUpdateUnknown
+	}
+
+	if !status.IsSuccess {
+		if status.ErrorDetails != nil {
+			return fmt.Errorf("%w: %w", ErrStateUpdateFailed, status.ErrorDetails)
+		}
+
+		return ErrStateUpdateFailed
+	}
+
+	return nil
+}
+
 func (c *Client) handleLocationUpdate(ctx context.Context, details sensor.Details) error {
 	// req, err := sensor.NewLocationUpdateRequest(details)
 	req, err := sensor.NewRequest(sensor.RequestTypeLocation, details)
@@ -194,6 +440,8 @@ func (c *Client) handleSensorUpdate(ctx context.Context, details sensor.Details)
 
 	// At this point, the sensor update was successful. Any errors are really
 	// warnings and non-critical.
+	c.sensorSeen(details)
+
 	var warnings error
 
 	for id, update := range response {
@@ -255,6 +503,8 @@ func (c *Client) handleRegistration(ctx context.Context, details sensor.Details)
 	if err != nil {
 		warnings = errors.Join(warnings, fmt.Errorf("%w: %w", ErrRegAddFailed, err))
 	}
+
+	c.sensorSeen(details)
 	// Update the sensor state in the tracker.
 	if err := c.tracker.Add(details); err != nil {
 		warnings = errors.Join(warnings, fmt.Errorf("%w: %w", ErrTrkUpdateFailed, err))
@@ -291,7 +541,8 @@ func (c *Client) isDisabled(ctx context.Context, details sensor.Details) bool {
 			return true
 		}
 
-		return false
+		// The sensor might still be disabled by the user.
+		return c.registry.IsDisabled(details.ID())
 	}
 
 	// Sensor is disabled in both the local registry and Home Assistant.
@@ -339,6 +590,10 @@ func send[T any](ctx context.Context, client *Client, requestDetails any) (T, er
 		return response, ErrInvalidClient
 	}
 
+	if client.isRemoved() {
+		return response, ErrIntegrationRemoved
+	}
+
 	// If the request supports validation, make sure it is valid.
 	if a, ok := requestDetails.(Validate); ok {
 		if err := a.Validate(); err != nil {
@@ -346,6 +601,10 @@ func send[T any](ctx context.Context, client *Client, requestDetails any) (T, er
 		}
 	}
 
+	if err := client.breaker.allow(); err != nil {
+		return response, err
+	}
+
 	requestObj := client.endpoint.R().SetContext(ctx)
 	requestObj = requestObj.SetError(&responseErr)
 	requestObj = requestObj.SetResult(&response)
@@ -355,6 +614,8 @@ func send[T any](ctx context.Context, client *Client, requestDetails any) (T, er
 		requestObj = requestObj.SetAuthToken(a.Auth())
 	}
 
+	var err error
+
 	switch req := requestDetails.(type) {
 	case PostRequest:
 		client.logger.
@@ -364,7 +625,7 @@ func send[T any](ctx context.Context, client *Client, requestDetails any) (T, er
 				slog.String("body", string(req.RequestBody())),
 				slog.Time("sent_at", time.Now()))
 
-		responseObj, _ = requestObj.SetBody(req.RequestBody()).Post("") //nolint:errcheck // error is checked with responseObj.IsError()
+		responseObj, err = requestObj.SetBody(req.RequestBody()).Post("")
 	case GetRequest:
 		client.logger.
 			LogAttrs(ctx, logging.LevelTrace,
@@ -372,7 +633,15 @@ func send[T any](ctx context.Context, client *Client, requestDetails any) (T, er
 				slog.String("method", "GET"),
 				slog.Time("sent_at", time.Now()))
 
-		responseObj, _ = requestObj.Get("") //nolint:errcheck // error is checked with responseObj.IsError()
+		responseObj, err = requestObj.Get("")
+	}
+
+	client.recordResult(ctx, responseObj, err)
+
+	// Errors with a response, such as an invalid response body, are handled
+	// by the status checks below.
+	if responseObj == nil || responseObj.StatusCode() == 0 {
+		return response, fmt.Errorf("%w: %w", ErrSendRequestFailed, err)
 	}
 
 	client.logger.
@@ -384,6 +653,15 @@ func send[T any](ctx context.Context, client *Client, requestDetails any) (T, er
 			slog.Duration("time", responseObj.Time()),
 			slog.String("body", string(responseObj.Body())))
 
+	// Home Assistant responds with 410 Gone to all requests once the
+	// mobile_app integration of the agent is removed.
+	if responseObj.StatusCode() == http.StatusGone {
+		client.setRemoved()
+
+		return response, fmt.Errorf("%w: %w", ErrIntegrationRemoved,
+			&sensor.APIError{Code: responseObj.StatusCode(), Message: responseObj.Status()})
+	}
+
 	if responseObj.IsError() {
 		return response, &sensor.APIError{Code: responseObj.StatusCode(), Message: responseObj.Status()}
 	}
@@ -391,6 +669,31 @@ func send[T any](ctx context.Context, client *Client, requestDetails any) (T, er
 	return response, nil
 }
 
+// recordResult records the result of a request in the circuit breaker, and logs
+// when this opens or closes the breaker. Requests canceled by the agent, such
+// as when it is stopping, say nothing about whether Home Assistant is
+// available, so are not recorded.
+func (c *Client) recordResult(ctx context.Context, resp *resty.Response, err error) {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		c.breaker.canceled()
+
+		return
+	}
+
+	if !isFailure(resp) {
+		if c.breaker.success() {
+			c.logger.Info("Home Assistant is available again, resuming requests.")
+		}
+
+		return
+	}
+
+	if wait := c.breaker.failure(retryAfter(resp)); wait > 0 {
+		c.logger.Warn("Home Assistant is unavailable, pausing requests.",
+			slog.Duration("retry_in", wait))
+	}
+}
+
 // sensorLogAttrs is a convienience function that returns some slog attributes
 // for priting sensor details in the log.
 func sensorLogAttrs(details sensor.Details) slog.Attr {
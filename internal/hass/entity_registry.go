@@ -0,0 +1,239 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package hass
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lxzan/gws"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+)
+
+const mobileAppPlatform = "mobile_app"
+
+var (
+	ErrWebsocketAuth   = errors.New("websocket authentication failed")
+	ErrWebsocketClosed = errors.New("websocket connection closed")
+	ErrEntityNotFound  = errors.New("entity not found in Home Assistant")
+)
+
+type entityRegistryRequest struct {
+	Type        string `json:"type"`
+	AccessToken string `json:"access_token,omitempty"`
+	EntityID    string `json:"entity_id,omitempty"`
+	ID          uint64 `json:"id,omitempty"`
+}
+
+type entityRegistryResponse struct {
+	Error   *sensor.APIError `json:"error,omitempty"`
+	Type    string           `json:"type"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	ID      uint64           `json:"id,omitempty"`
+	Success bool             `json:"success,omitempty"`
+}
+
+type entityRegistryEntry struct {
+	EntityID string `json:"entity_id"`
+	UniqueID string `json:"unique_id"`
+	Platform string `json:"platform"`
+}
+
+// entityRegistryConn is a connection to the websocket API of Home Assistant,
+// used to manage its entity registry. Unlike Websocket, requests are made one
+// at a time and wait for their result.
+type entityRegistryConn struct {
+	gws.BuiltinEventHandler
+	socket *gws.Conn
+	msgCh  chan []byte
+	done   chan struct{}
+	nextID uint64
+}
+
+func (c *entityRegistryConn) OnMessage(_ *gws.Conn, message *gws.Message) {
+	defer message.Close()
+
+	select {
+	case c.msgCh <- bytes.Clone(message.Bytes()):
+	case <-c.done:
+	}
+}
+
+func (c *entityRegistryConn) OnClose(_ *gws.Conn, _ error) {
+	close(c.done)
+}
+
+// send sends the request on the websocket.
+func (c *entityRegistryConn) send(req *entityRegistryRequest) error {
+	msg, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to send: %w", err)
+	}
+
+	if err := c.socket.WriteMessage(gws.OpcodeText, msg); err != nil {
+		return fmt.Errorf("failed to send: %w", err)
+	}
+
+	return nil
+}
+
+// receive waits for the next message on the websocket.
+func (c *entityRegistryConn) receive(ctx context.Context) (*entityRegistryResponse, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("no response: %w", ctx.Err())
+	case <-c.done:
+		return nil, ErrWebsocketClosed
+	case msg := <-c.msgCh:
+		var response entityRegistryResponse
+
+		if err := json.Unmarshal(msg, &response); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrResponseMalformed, err)
+		}
+
+		return &response, nil
+	}
+}
+
+// authenticate authenticates the connection with the given token.
+func (c *entityRegistryConn) authenticate(ctx context.Context, token string) error {
+	response, err := c.receive(ctx)
+	if err != nil {
+		return err
+	}
+
+	if response.Type != "auth_required" {
+		return fmt.Errorf("%w: unexpected %s message", ErrWebsocketAuth, response.Type)
+	}
+
+	if err := c.send(&entityRegistryRequest{Type: "auth", AccessToken: token}); err != nil {
+		return err
+	}
+
+	response, err = c.receive(ctx)
+	if err != nil {
+		return err
+	}
+
+	if response.Type != "auth_ok" {
+		return fmt.Errorf("%w: %s", ErrWebsocketAuth, response.Type)
+	}
+
+	return nil
+}
+
+// call sends the request and waits for its result.
+func (c *entityRegistryConn) call(ctx context.Context, req *entityRegistryRequest) (json.RawMessage, error) {
+	c.nextID++
+	req.ID = c.nextID
+
+	if err := c.send(req); err != nil {
+		return nil, err
+	}
+
+	for {
+		response, err := c.receive(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if response.Type != "result" || response.ID != req.ID {
+			continue
+		}
+
+		if !response.Success {
+			if response.Error != nil {
+				return nil, fmt.Errorf("%s failed: %w", req.Type, response.Error)
+			}
+
+			return nil, fmt.Errorf("%s failed: %w", req.Type, ErrUnknown)
+		}
+
+		return response.Result, nil
+	}
+}
+
+// RemoveEntities removes the entities of the sensors with the given IDs from
+// the entity registry of Home Assistant, using its websocket API at the given
+// URL. The token must be a long-lived access token of an administrator. The
+// IDs of the sensors that were removed are returned, along with any errors
+// for those that were not.
+func RemoveEntities(ctx context.Context, url, token, webhookID string, ids ...string) ([]string, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, defaultTimeout)
+	defer cancelFunc()
+
+	conn := &entityRegistryConn{
+		msgCh: make(chan []byte),
+		done:  make(chan struct{}),
+	}
+
+	socket, resp, err := gws.NewClient(conn, &gws.ClientOption{Addr: url})
+	if err != nil {
+		return nil, fmt.Errorf("could not establish connection: %w", err)
+	}
+
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+
+	conn.socket = socket
+
+	go socket.ReadLoop()
+	defer socket.WriteClose(closeNormal, nil)
+
+	if err := conn.authenticate(ctx, token); err != nil {
+		return nil, err
+	}
+
+	result, err := conn.call(ctx, &entityRegistryRequest{Type: "config/entity_registry/list"})
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []entityRegistryEntry
+
+	if err := json.Unmarshal(result, &entries); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrResponseMalformed, err)
+	}
+
+	// Home Assistant prefixes the unique ID of mobile app sensors with the
+	// webhook ID of the device.
+	entities := make(map[string]string)
+
+	for _, entry := range entries {
+		if entry.Platform == mobileAppPlatform {
+			entities[entry.UniqueID] = entry.EntityID
+		}
+	}
+
+	var (
+		removed []string
+		errs    error
+	)
+
+	for _, id := range ids {
+		entityID, found := entities[webhookID+"_"+id]
+		if !found {
+			errs = errors.Join(errs, fmt.Errorf("%w: %s", ErrEntityNotFound, id))
+
+			continue
+		}
+
+		if _, err := conn.call(ctx, &entityRegistryRequest{Type: "config/entity_registry/remove", EntityID: entityID}); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("could not remove %s: %w", id, err))
+
+			continue
+		}
+
+		removed = append(removed, id)
+	}
+
+	return removed, errs
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package hass
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidEvent = errors.New("invalid event")
+
+// Event is an event fired on the Home Assistant event bus, which can be used to
+// trigger automations.
+type Event struct {
+	Data map[string]any `json:"event_data,omitempty"`
+	Type string         `json:"event_type"`
+}
+
+type eventRequest struct {
+	Data *Event `json:"data"`
+	Type string `json:"type"`
+}
+
+func (e *eventRequest) RequestBody() json.RawMessage {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil
+	}
+
+	return data
+}
+
+func (e *eventRequest) Validate() error {
+	if e.Data == nil || e.Data.Type == "" {
+		return fmt.Errorf("%w: event type is required", ErrInvalidEvent)
+	}
+
+	return nil
+}
+
+// FireEvent fires the given event in Home Assistant.
+func (c *Client) FireEvent(ctx context.Context, event *Event) error {
+	if _, err := send[json.RawMessage](ctx, c, &eventRequest{Type: "fire_event", Data: event}); err != nil {
+		return fmt.Errorf("failed to fire event: %w", err)
+	}
+
+	return nil
+}
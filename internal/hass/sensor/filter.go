@@ -0,0 +1,93 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package sensor
+
+import (
+	"fmt"
+	"path"
+)
+
+// Filter decides which sensors are sent to Home Assistant, using lists of glob
+// patterns (as understood by path.Match) that are matched against sensor IDs
+// and, optionally, sensor attribute values.
+type Filter struct {
+	include           []string
+	exclude           []string
+	includeAttributes map[string]string
+	excludeAttributes map[string]string
+}
+
+// NewFilter creates a new Filter. A sensor is allowed if there are no include
+// rules or it matches any include rule, and it does not match any exclude
+// rule. The attribute maps are keyed by attribute name with a glob pattern for
+// the attribute value.
+func NewFilter(include, exclude []string, includeAttributes, excludeAttributes map[string]string) (*Filter, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	for _, attributes := range []map[string]string{includeAttributes, excludeAttributes} {
+		for name, pattern := range attributes {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q for attribute %s: %w", pattern, name, err)
+			}
+		}
+	}
+
+	return &Filter{
+		include:           include,
+		exclude:           exclude,
+		includeAttributes: includeAttributes,
+		excludeAttributes: excludeAttributes,
+	}, nil
+}
+
+// Allowed returns whether the given sensor passes the filter. A nil Filter
+// allows all sensors.
+func (f *Filter) Allowed(details State) bool {
+	if f == nil {
+		return true
+	}
+
+	if len(f.include) > 0 || len(f.includeAttributes) > 0 {
+		if !matchID(details.ID(), f.include) && !matchAttributes(details.Attributes(), f.includeAttributes) {
+			return false
+		}
+	}
+
+	return !matchID(details.ID(), f.exclude) && !matchAttributes(details.Attributes(), f.excludeAttributes)
+}
+
+// matchID returns whether the given ID matches any of the patterns.
+func matchID(id string, patterns []string) bool {
+	for _, pattern := range patterns {
+		// Patterns are validated when the filter is created.
+		if matched, _ := path.Match(pattern, id); matched { //nolint:errcheck
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchAttributes returns whether any of the given attributes has a value
+// matching the pattern for that attribute.
+func matchAttributes(attributes map[string]any, patterns map[string]string) bool {
+	for name, pattern := range patterns {
+		value, found := attributes[name]
+		if !found {
+			continue
+		}
+
+		if matched, _ := path.Match(pattern, fmt.Sprint(value)); matched { //nolint:errcheck
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package sensor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFilterMock(id string, attributes map[string]any) *StateMock {
+	return &StateMock{
+		IDFunc:         func() string { return id },
+		AttributesFunc: func() map[string]any { return attributes },
+	}
+}
+
+func TestFilter_Allowed(t *testing.T) {
+	type args struct {
+		include           []string
+		exclude           []string
+		includeAttributes map[string]string
+		excludeAttributes map[string]string
+	}
+
+	tests := []struct {
+		details State
+		name    string
+		args    args
+		want    bool
+	}{
+		{
+			name:    "no rules",
+			details: newFilterMock("cpu_usage", nil),
+			want:    true,
+		},
+		{
+			name:    "included",
+			details: newFilterMock("cpu_usage", nil),
+			args:    args{include: []string{"cpu_*"}},
+			want:    true,
+		},
+		{
+			name:    "not included",
+			details: newFilterMock("mem_usage", nil),
+			args:    args{include: []string{"cpu_*"}},
+			want:    false,
+		},
+		{
+			name:    "excluded",
+			details: newFilterMock("cpu_usage", nil),
+			args:    args{exclude: []string{"*_usage"}},
+			want:    false,
+		},
+		{
+			name:    "included then excluded",
+			details: newFilterMock("cpu_usage", nil),
+			args:    args{include: []string{"cpu_*"}, exclude: []string{"cpu_usage"}},
+			want:    false,
+		},
+		{
+			name:    "included by attribute",
+			details: newFilterMock("sda_read_rate", map[string]any{"device": "sda"}),
+			args:    args{include: []string{"cpu_*"}, includeAttributes: map[string]string{"device": "sd?"}},
+			want:    true,
+		},
+		{
+			name:    "excluded by attribute",
+			details: newFilterMock("loop0_read_rate", map[string]any{"device": "loop0"}),
+			args:    args{excludeAttributes: map[string]string{"device": "loop*"}},
+			want:    false,
+		},
+		{
+			name:    "non-string attribute",
+			details: newFilterMock("cpu_usage", map[string]any{"core": 3}),
+			args:    args{excludeAttributes: map[string]string{"core": "3"}},
+			want:    false,
+		},
+		{
+			name:    "missing attribute",
+			details: newFilterMock("cpu_usage", nil),
+			args:    args{excludeAttributes: map[string]string{"device": "*"}},
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewFilter(tt.args.include, tt.args.exclude, tt.args.includeAttributes, tt.args.excludeAttributes)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, filter.Allowed(tt.details))
+		})
+	}
+}
+
+func TestNewFilter(t *testing.T) {
+	_, err := NewFilter([]string{"cpu_["}, nil, nil, nil)
+	require.Error(t, err)
+
+	_, err = NewFilter(nil, nil, nil, map[string]string{"device": "["})
+	require.Error(t, err)
+
+	var filter *Filter
+	assert.True(t, filter.Allowed(newFilterMock("cpu_usage", nil)))
+}
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 )
 
 //go:generate stringer -type=state -output state_generated.go -linecomment
@@ -24,9 +25,43 @@ var (
 	ErrInvalidMetadata = errors.New("invalid sensor metadata")
 )
 
+// metadata is what the registry stores about each sensor.
 type metadata struct {
-	Registered bool `json:"registered"`
-	Disabled   bool `json:"disabled"`
+	// FirstRegistered is when the sensor was first registered with Home
+	// Assistant.
+	FirstRegistered time.Time `json:"first_registered"`
+	// LastSeen is when an update of the sensor was last sent.
+	LastSeen   time.Time `json:"last_seen"`
+	Name       string    `json:"name,omitempty"`
+	Registered bool      `json:"registered"`
+	// Disabled is whether the sensor is disabled in Home Assistant.
+	Disabled bool `json:"disabled"`
+	// DisabledByUser is whether the sensor was disabled in the registry by
+	// the user. Unlike Disabled, it is not changed by Home Assistant.
+	DisabledByUser bool `json:"disabled_by_user,omitempty"`
+}
+
+// Entry is a sensor in the registry.
+type Entry struct {
+	FirstRegistered time.Time `json:"first_registered"`
+	LastSeen        time.Time `json:"last_seen"`
+	ID              string    `json:"id"`
+	Name            string    `json:"name,omitempty"`
+	Registered      bool      `json:"registered"`
+	Disabled        bool      `json:"disabled"`
+	DisabledByUser  bool      `json:"disabled_by_user"`
+}
+
+func newEntry(id string, m metadata) Entry {
+	return Entry{
+		FirstRegistered: m.FirstRegistered,
+		LastSeen:        m.LastSeen,
+		ID:              id,
+		Name:            m.Name,
+		Registered:      m.Registered,
+		Disabled:        m.Disabled,
+		DisabledByUser:  m.DisabledByUser,
+	}
 }
 
 func Reset(path string) error {
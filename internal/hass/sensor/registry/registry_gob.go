@@ -10,131 +10,33 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log/slog"
 	"os"
-	"path/filepath"
-	"sync"
 )
 
 const (
-	registryFile     = "sensor.reg"
-	defaultFilePerms = 0o640
+	// gobRegistryFile is the file of the old gob registry, which is migrated
+	// to the JSON registry.
+	gobRegistryFile = "sensor.reg"
+	// migratedSuffix is added to the name of the old registry once it has
+	// been migrated.
+	migratedSuffix = ".migrated"
 )
 
-type gobRegistry struct {
-	sensors map[string]metadata
-	file    string
-	mu      sync.Mutex
-}
-
-func (g *gobRegistry) write() error {
-	regFS, err := os.OpenFile(g.file, os.O_RDWR|os.O_CREATE, defaultFilePerms)
-	if err != nil {
-		return fmt.Errorf("could not open registry for writing: %w", err)
-	}
-
-	enc := gob.NewEncoder(regFS)
-
-	err = enc.Encode(&g.sensors)
-	if err != nil {
-		return fmt.Errorf("could not encode registry data: %w", err)
-	}
-
-	return nil
-}
-
-func (g *gobRegistry) read() error {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
-	regFS, err := os.OpenFile(g.file, os.O_RDWR|os.O_CREATE, defaultFilePerms)
+// readGobRegistry reads the sensors from the old gob registry in the given
+// file. The old registry only stored whether each sensor was registered and
+// disabled.
+func readGobRegistry(file string) (map[string]metadata, error) {
+	regFS, err := os.Open(file)
 	if err != nil {
-		return fmt.Errorf("could not open registry for reading: %w", err)
-	}
-
-	dec := gob.NewDecoder(regFS)
-
-	err = dec.Decode(&g.sensors)
-	if err != nil && !errors.Is(err, io.EOF) {
-		return fmt.Errorf("could not decode registry data: %w", err)
-	}
-
-	return nil
-}
-
-func (g *gobRegistry) IsDisabled(id string) bool {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
-	sensor, ok := g.sensors[id]
-	if !ok {
-		slog.Warn("Sensor not found in registry.", slog.String("sensor_id", id))
-
-		return false
-	}
-
-	return sensor.Disabled
-}
-
-func (g *gobRegistry) IsRegistered(id string) bool {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
-	sensor, ok := g.sensors[id]
-	if !ok {
-		slog.Warn("Sensor not found in registry.", slog.String("sensor_id", id))
-
-		return false
-	}
-
-	return sensor.Registered
-}
-
-func (g *gobRegistry) SetDisabled(id string, value bool) error {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
-	m := g.sensors[id]
-	m.Disabled = value
-	g.sensors[id] = m
-
-	if err := g.write(); err != nil {
-		return fmt.Errorf("could not write to registry: %w", err)
+		return nil, fmt.Errorf("could not open old registry for reading: %w", err)
 	}
+	defer regFS.Close()
 
-	return nil
-}
-
-func (g *gobRegistry) SetRegistered(id string, value bool) error {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
-	m := g.sensors[id]
-	m.Registered = value
-	g.sensors[id] = m
-
-	if err := g.write(); err != nil {
-		return fmt.Errorf("could not write to registry: %w", err)
-	}
-
-	return nil
-}
-
-//revive:disable:unexported-return
-func Load(path string) (*gobRegistry, error) {
-	reg := &gobRegistry{
-		sensors: make(map[string]metadata),
-		mu:      sync.Mutex{},
-		file:    filepath.Join(path, registryFile),
-	}
-
-	if err := checkPath(path); err != nil {
-		return nil, fmt.Errorf("could not load registry: %w", err)
-	}
+	sensors := make(map[string]metadata)
 
-	if err := reg.read(); err != nil {
-		return nil, fmt.Errorf("could not read from registry: %w", err)
+	if err := gob.NewDecoder(regFS).Decode(&sensors); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("could not decode old registry data: %w", err)
 	}
 
-	return reg, nil
+	return sensors, nil
 }
@@ -0,0 +1,357 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	registryFile = "registry.json"
+	// saveInterval is how often the registry is saved when only the last
+	// seen time of sensors has changed.
+	saveInterval = time.Minute
+)
+
+// jsonRegistry is a registry stored as a JSON file. The file is replaced
+// atomically whenever it is written.
+type jsonRegistry struct {
+	sensors map[string]metadata
+	file    string
+	// saved is when the registry was last written.
+	saved time.Time
+	// dirty is whether there are changes that have not been written.
+	dirty bool
+	mu    sync.Mutex
+}
+
+// write writes the registry to its file. The registry must be locked.
+func (j *jsonRegistry) write() error {
+	data, err := json.MarshalIndent(j.sensors, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode registry data: %w", err)
+	}
+
+	if err := writeFile(j.file, data); err != nil {
+		return fmt.Errorf("could not write registry: %w", err)
+	}
+
+	j.saved = time.Now()
+	j.dirty = false
+
+	return nil
+}
+
+// read reads the registry from its file. It returns an error satisfying
+// errors.Is(err, os.ErrNotExist) if the file does not exist.
+func (j *jsonRegistry) read() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	data, err := os.ReadFile(j.file)
+	if err != nil {
+		return fmt.Errorf("could not open registry for reading: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &j.sensors); err != nil {
+		return fmt.Errorf("could not decode registry data: %w", err)
+	}
+
+	if j.sensors == nil {
+		j.sensors = make(map[string]metadata)
+	}
+
+	return nil
+}
+
+// migrate replaces the registry with the contents of the old gob registry in
+// the given file, then renames the old registry so it is not migrated again.
+func (j *jsonRegistry) migrate(gobFile string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	sensors, err := readGobRegistry(gobFile)
+	if err != nil {
+		return err
+	}
+
+	// The old registry did not record when sensors were last seen. Treat
+	// them as seen now, so that sensors no longer sent are found as orphans
+	// once they have not been seen for a while.
+	now := time.Now()
+
+	for id, m := range sensors {
+		m.LastSeen = now
+		sensors[id] = m
+	}
+
+	j.sensors = sensors
+
+	if err := j.write(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(gobFile, gobFile+migratedSuffix); err != nil {
+		return fmt.Errorf("could not rename old registry: %w", err)
+	}
+
+	return nil
+}
+
+func (j *jsonRegistry) IsDisabled(id string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	sensor, ok := j.sensors[id]
+	if !ok {
+		slog.Warn("Sensor not found in registry.", slog.String("sensor_id", id))
+
+		return false
+	}
+
+	return sensor.Disabled || sensor.DisabledByUser
+}
+
+func (j *jsonRegistry) IsRegistered(id string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	sensor, ok := j.sensors[id]
+	if !ok {
+		slog.Warn("Sensor not found in registry.", slog.String("sensor_id", id))
+
+		return false
+	}
+
+	return sensor.Registered
+}
+
+// SetDisabled sets whether the sensor is disabled in Home Assistant.
+func (j *jsonRegistry) SetDisabled(id string, value bool) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	m := j.sensors[id]
+	m.Disabled = value
+	j.sensors[id] = m
+
+	if err := j.write(); err != nil {
+		return fmt.Errorf("could not write to registry: %w", err)
+	}
+
+	return nil
+}
+
+// SetRegistered sets whether the sensor is registered with Home Assistant.
+// The first time it is registered is recorded.
+func (j *jsonRegistry) SetRegistered(id string, value bool) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	m := j.sensors[id]
+	m.Registered = value
+
+	if value && m.FirstRegistered.IsZero() {
+		m.FirstRegistered = time.Now()
+	}
+
+	if value && m.LastSeen.IsZero() {
+		m.LastSeen = m.FirstRegistered
+	}
+
+	j.sensors[id] = m
+
+	if err := j.write(); err != nil {
+		return fmt.Errorf("could not write to registry: %w", err)
+	}
+
+	return nil
+}
+
+// SetDisabledByUser sets whether the user has disabled the sensor. A sensor
+// disabled by the user stays disabled, even if it is enabled in Home
+// Assistant. Enabling the sensor also clears whether it is disabled in Home
+// Assistant, which will be set again by the next update if it still is.
+func (j *jsonRegistry) SetDisabledByUser(id string, value bool) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	m, ok := j.sensors[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	m.DisabledByUser = value
+
+	if !value {
+		m.Disabled = false
+	}
+
+	j.sensors[id] = m
+
+	if err := j.write(); err != nil {
+		return fmt.Errorf("could not write to registry: %w", err)
+	}
+
+	return nil
+}
+
+// Seen records that an update of the sensor, with the given name, was sent. As
+// this happens for every update, the registry is only written if it has not
+// been written recently.
+func (j *jsonRegistry) Seen(id, name string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	m := j.sensors[id]
+	m.LastSeen = time.Now()
+	m.Name = name
+	j.sensors[id] = m
+	j.dirty = true
+
+	if time.Since(j.saved) < saveInterval {
+		return nil
+	}
+
+	if err := j.write(); err != nil {
+		return fmt.Errorf("could not write to registry: %w", err)
+	}
+
+	return nil
+}
+
+// Forget removes the sensor from the registry. If it is sent again, it will
+// be registered with Home Assistant again.
+func (j *jsonRegistry) Forget(id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, ok := j.sensors[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	delete(j.sensors, id)
+
+	if err := j.write(); err != nil {
+		return fmt.Errorf("could not write to registry: %w", err)
+	}
+
+	return nil
+}
+
+// Entries returns all sensors in the registry, sorted by ID.
+func (j *jsonRegistry) Entries() []Entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries := make([]Entry, 0, len(j.sensors))
+
+	for id, m := range j.sensors {
+		entries = append(entries, newEntry(id, m))
+	}
+
+	slices.SortFunc(entries, func(a, b Entry) int { return strings.Compare(a.ID, b.ID) })
+
+	return entries
+}
+
+// Orphans returns the registered sensors that were last seen before the given
+// time, sorted by ID. These are likely no longer sent by any worker, and are
+// left in Home Assistant with a stale state.
+func (j *jsonRegistry) Orphans(seenBefore time.Time) []Entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var entries []Entry
+
+	for id, m := range j.sensors {
+		if m.Registered && !m.LastSeen.IsZero() && m.LastSeen.Before(seenBefore) {
+			entries = append(entries, newEntry(id, m))
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b Entry) int { return strings.Compare(a.ID, b.ID) })
+
+	return entries
+}
+
+// Reset removes all sensors from the registry, so that they are registered
+// again when next sent.
+func (j *jsonRegistry) Reset() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.sensors = make(map[string]metadata)
+
+	if err := j.write(); err != nil {
+		return fmt.Errorf("could not write to registry: %w", err)
+	}
+
+	return nil
+}
+
+// Save writes any changes that have not yet been written.
+func (j *jsonRegistry) Save() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.dirty {
+		return nil
+	}
+
+	if err := j.write(); err != nil {
+		return fmt.Errorf("could not write to registry: %w", err)
+	}
+
+	return nil
+}
+
+// Load loads the registry in the given directory, creating the directory if
+// needed. An old gob registry in the directory is migrated automatically.
+//
+//revive:disable:unexported-return
+func Load(path string) (*jsonRegistry, error) {
+	reg := &jsonRegistry{
+		sensors: make(map[string]metadata),
+		mu:      sync.Mutex{},
+		file:    filepath.Join(path, registryFile),
+	}
+
+	if err := checkPath(path); err != nil {
+		return nil, fmt.Errorf("could not load registry: %w", err)
+	}
+
+	err := reg.read()
+
+	switch {
+	case err == nil:
+		return reg, nil
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("could not read from registry: %w", err)
+	}
+
+	gobFile := filepath.Join(path, gobRegistryFile)
+	if _, err := os.Stat(gobFile); err != nil {
+		return reg, nil //nolint:nilerr // no old registry to migrate
+	}
+
+	if err := reg.migrate(gobFile); err != nil {
+		return nil, fmt.Errorf("could not migrate registry: %w", err)
+	}
+
+	slog.Info("Migrated sensor registry.", slog.String("file", reg.file))
+
+	return reg, nil
+}
@@ -0,0 +1,443 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package registry
+
+import (
+	"encoding/gob"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockSensors are the sensors of a mock registry. Tests must use a clone, as
+// the registry changes them.
+var mockSensors = map[string]metadata{
+	"disabledSensor":   {Disabled: true, Registered: true},
+	"registeredSensor": {Disabled: false, Registered: true},
+}
+
+func newMockReg(t *testing.T) *jsonRegistry {
+	t.Helper()
+	mockReg, err := Load(filepath.Join(t.TempDir()))
+	require.NoError(t, err)
+	mockReg.sensors = maps.Clone(mockSensors)
+	err = mockReg.write()
+	require.NoError(t, err)
+	return mockReg
+}
+
+func Test_jsonRegistry_write(t *testing.T) {
+	type fields struct {
+		sensors map[string]metadata
+		file    string
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		wantErr bool
+	}{
+		{
+			name:   "valid path",
+			fields: fields{sensors: maps.Clone(mockSensors), file: filepath.Join(t.TempDir(), registryFile)},
+		},
+		{
+			name:    "invalid path",
+			fields:  fields{sensors: maps.Clone(mockSensors), file: filepath.Join(t.TempDir(), "nonexistent", registryFile)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &jsonRegistry{
+				sensors: tt.fields.sensors,
+				file:    tt.fields.file,
+			}
+			if err := g.write(); (err != nil) != tt.wantErr {
+				t.Errorf("jsonRegistry.write() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_jsonRegistry_read(t *testing.T) {
+	mockReg := newMockReg(t)
+
+	invalidRegistry := filepath.Join(t.TempDir(), registryFile)
+	err := os.WriteFile(invalidRegistry, []byte(`invalid`), 0o600)
+	require.NoError(t, err)
+
+	type fields struct {
+		sensors map[string]metadata
+		file    string
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		wantErr bool
+	}{
+		{
+			name:   "valid file",
+			fields: fields{file: mockReg.file},
+		},
+		{
+			name:    "invalid file",
+			fields:  fields{file: filepath.Join(t.TempDir(), "nonexistent", registryFile)},
+			wantErr: true,
+		},
+		{
+			name:    "invalid contents",
+			fields:  fields{file: invalidRegistry},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &jsonRegistry{
+				sensors: tt.fields.sensors,
+				file:    tt.fields.file,
+			}
+			if err := g.read(); (err != nil) != tt.wantErr {
+				t.Errorf("jsonRegistry.read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				assert.Equal(t, mockReg.sensors, g.sensors)
+			}
+		})
+	}
+}
+
+func Test_jsonRegistry_IsDisabled(t *testing.T) {
+	type fields struct {
+		sensors map[string]metadata
+		file    string
+	}
+	type args struct {
+		id string
+	}
+	tests := []struct {
+		name   string
+		args   args
+		fields fields
+		want   bool
+	}{
+		{
+			name:   "disabled sensor",
+			fields: fields{sensors: maps.Clone(mockSensors)},
+			args:   args{id: "disabledSensor"},
+			want:   true,
+		},
+		{
+			name:   "not disabled sensor",
+			fields: fields{sensors: maps.Clone(mockSensors)},
+			args:   args{id: "registeredSensor"},
+			want:   false,
+		},
+		{
+			name:   "not found",
+			fields: fields{sensors: maps.Clone(mockSensors)},
+			args:   args{id: "nonexistent"},
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &jsonRegistry{
+				sensors: tt.fields.sensors,
+				file:    tt.fields.file,
+			}
+			if got := g.IsDisabled(tt.args.id); got != tt.want {
+				t.Errorf("jsonRegistry.IsDisabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_jsonRegistry_IsRegistered(t *testing.T) {
+	type fields struct {
+		sensors map[string]metadata
+		file    string
+	}
+	type args struct {
+		id string
+	}
+	tests := []struct {
+		name   string
+		args   args
+		fields fields
+		want   bool
+	}{
+		{
+			name:   "registered sensor",
+			fields: fields{sensors: maps.Clone(mockSensors)},
+			args:   args{id: "registeredSensor"},
+			want:   true,
+		},
+		{
+			name:   "not registered sensor",
+			fields: fields{sensors: maps.Clone(mockSensors)},
+			args:   args{id: "unRegistered"},
+			want:   false,
+		},
+		{
+			name:   "not found",
+			fields: fields{sensors: maps.Clone(mockSensors)},
+			args:   args{id: "nonexistent"},
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &jsonRegistry{
+				sensors: tt.fields.sensors,
+				file:    tt.fields.file,
+			}
+			if got := g.IsRegistered(tt.args.id); got != tt.want {
+				t.Errorf("jsonRegistry.IsRegistered() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_jsonRegistry_SetDisabled(t *testing.T) {
+	type fields struct {
+		sensors map[string]metadata
+		file    string
+	}
+	type args struct {
+		id    string
+		value bool
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		wantErr bool
+	}{
+		{
+			name:    "change disabled state",
+			fields:  fields{sensors: maps.Clone(mockSensors), file: filepath.Join(t.TempDir(), registryFile)},
+			args:    args{id: "disabledSensor", value: false},
+			wantErr: false,
+		},
+		{
+			name:    "invalid path",
+			fields:  fields{sensors: maps.Clone(mockSensors), file: filepath.Join(t.TempDir(), "nonexistent", registryFile)},
+			args:    args{id: "disabledSensor", value: false},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &jsonRegistry{
+				sensors: tt.fields.sensors,
+				file:    tt.fields.file,
+			}
+			if err := g.SetDisabled(tt.args.id, tt.args.value); (err != nil) != tt.wantErr {
+				t.Errorf("jsonRegistry.SetDisabled() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_jsonRegistry_SetRegistered(t *testing.T) {
+	type fields struct {
+		sensors map[string]metadata
+		file    string
+	}
+	type args struct {
+		id    string
+		value bool
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		args    args
+		wantErr bool
+	}{
+		{
+			name:    "change registered state",
+			fields:  fields{sensors: maps.Clone(mockSensors), file: filepath.Join(t.TempDir(), registryFile)},
+			args:    args{id: "unRegisteredSensor", value: true},
+			wantErr: false,
+		},
+		{
+			name:    "invalid path",
+			fields:  fields{sensors: maps.Clone(mockSensors), file: filepath.Join(t.TempDir(), "nonexistent", registryFile)},
+			args:    args{id: "disabledSensor", value: false},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &jsonRegistry{
+				sensors: tt.fields.sensors,
+				file:    tt.fields.file,
+			}
+			if err := g.SetRegistered(tt.args.id, tt.args.value); (err != nil) != tt.wantErr {
+				t.Errorf("jsonRegistry.SetRegistered() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	goodPath := t.TempDir()
+
+	type args struct {
+		path string
+	}
+	tests := []struct {
+		want    *jsonRegistry
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name:    "good path",
+			args:    args{path: goodPath},
+			want:    &jsonRegistry{sensors: make(map[string]metadata), file: filepath.Join(goodPath, registryFile)},
+			wantErr: false,
+		},
+		{
+			name:    "bad path",
+			args:    args{path: "/nonexistent"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(tt.args.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_migrate(t *testing.T) {
+	path := t.TempDir()
+
+	// The old registry only stored whether sensors were registered and
+	// disabled.
+	type oldMetadata struct {
+		Registered bool
+		Disabled   bool
+	}
+
+	oldFile, err := os.Create(filepath.Join(path, gobRegistryFile))
+	require.NoError(t, err)
+	require.NoError(t, gob.NewEncoder(oldFile).Encode(map[string]oldMetadata{
+		"disabledSensor":   {Registered: true, Disabled: true},
+		"registeredSensor": {Registered: true},
+	}))
+	require.NoError(t, oldFile.Close())
+
+	reg, err := Load(path)
+	require.NoError(t, err)
+	assert.True(t, reg.IsRegistered("registeredSensor"))
+	assert.False(t, reg.IsDisabled("registeredSensor"))
+	assert.True(t, reg.IsDisabled("disabledSensor"))
+	assert.Empty(t, reg.Orphans(time.Now().Add(-time.Minute)))
+
+	// The old registry is kept, but not migrated again.
+	assert.NoFileExists(t, filepath.Join(path, gobRegistryFile))
+	assert.FileExists(t, filepath.Join(path, gobRegistryFile+migratedSuffix))
+
+	reloaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Len(t, reloaded.sensors, len(reg.sensors))
+
+	for id, m := range reg.sensors {
+		assert.Equal(t, m.Registered, reloaded.sensors[id].Registered)
+		assert.Equal(t, m.Disabled, reloaded.sensors[id].Disabled)
+		assert.True(t, m.LastSeen.Equal(reloaded.sensors[id].LastSeen))
+	}
+}
+
+func Test_jsonRegistry_metadata(t *testing.T) {
+	path := t.TempDir()
+
+	reg, err := Load(path)
+	require.NoError(t, err)
+
+	require.NoError(t, reg.Seen("sensor_b", "Sensor B"))
+	require.NoError(t, reg.SetRegistered("sensor_b", true))
+	require.NoError(t, reg.Seen("sensor_a", "Sensor A"))
+	require.NoError(t, reg.SetRegistered("sensor_a", true))
+	require.NoError(t, reg.Save())
+
+	reloaded, err := Load(path)
+	require.NoError(t, err)
+
+	entries := reloaded.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "sensor_a", entries[0].ID)
+	assert.Equal(t, "Sensor A", entries[0].Name)
+	assert.True(t, entries[0].Registered)
+	assert.WithinDuration(t, time.Now(), entries[0].FirstRegistered, time.Minute)
+	assert.WithinDuration(t, time.Now(), entries[0].LastSeen, time.Minute)
+
+	// Registering again does not change when the sensor was first registered.
+	first := entries[0].FirstRegistered
+	require.NoError(t, reloaded.SetRegistered("sensor_a", true))
+	assert.Equal(t, first, reloaded.Entries()[0].FirstRegistered)
+
+	// Sensors disabled by the user stay disabled when enabled in Home
+	// Assistant.
+	require.NoError(t, reloaded.SetDisabledByUser("sensor_a", true))
+	require.NoError(t, reloaded.SetDisabled("sensor_a", false))
+	assert.True(t, reloaded.IsDisabled("sensor_a"))
+	require.NoError(t, reloaded.SetDisabledByUser("sensor_a", false))
+	assert.False(t, reloaded.IsDisabled("sensor_a"))
+
+	require.NoError(t, reloaded.Forget("sensor_b"))
+	assert.Len(t, reloaded.Entries(), 1)
+	require.ErrorIs(t, reloaded.Forget("sensor_b"), ErrNotFound)
+	require.ErrorIs(t, reloaded.SetDisabledByUser("sensor_b", true), ErrNotFound)
+}
+
+func Test_jsonRegistry_Orphans(t *testing.T) {
+	now := time.Now()
+
+	reg := &jsonRegistry{
+		sensors: map[string]metadata{
+			"current":      {Registered: true, LastSeen: now},
+			"orphan_b":     {Registered: true, LastSeen: now.Add(-48 * time.Hour)},
+			"orphan_a":     {Registered: true, LastSeen: now.Add(-72 * time.Hour), Disabled: true},
+			"unregistered": {LastSeen: now.Add(-72 * time.Hour)},
+			"never_seen":   {Registered: true},
+		},
+	}
+
+	orphans := reg.Orphans(now.Add(-24 * time.Hour))
+	require.Len(t, orphans, 2)
+	assert.Equal(t, "orphan_a", orphans[0].ID)
+	assert.True(t, orphans[0].Disabled)
+	assert.Equal(t, "orphan_b", orphans[1].ID)
+
+	assert.Empty(t, reg.Orphans(now.Add(-96*time.Hour)))
+}
+
+func Test_jsonRegistry_Reset(t *testing.T) {
+	reg := newMockReg(t)
+	require.NoError(t, reg.Reset())
+	assert.Empty(t, reg.Entries())
+
+	reloaded, err := Load(filepath.Dir(reg.file))
+	require.NoError(t, err)
+	assert.Empty(t, reloaded.Entries())
+}
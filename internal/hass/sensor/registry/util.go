@@ -8,8 +8,11 @@ package registry
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 )
 
+const defaultFilePerms = 0o640
+
 func checkPath(path string) error {
 	_, err := os.Stat(path)
 	if os.IsNotExist(err) {
@@ -21,3 +24,42 @@ func checkPath(path string) error {
 
 	return nil
 }
+
+// writeFile writes the data to a temporary file in the same directory as the
+// given file, then renames it over the file. The file is therefore either
+// completely written or unchanged.
+func writeFile(file string, data []byte) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return fmt.Errorf("could not create file: %w", err)
+	}
+
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck // removed by rename on success
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close() //nolint:errcheck
+
+		return fmt.Errorf("could not write file: %w", err)
+	}
+
+	// Make sure the data is on disk before the file is replaced.
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close() //nolint:errcheck
+
+		return fmt.Errorf("could not write file: %w", err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("could not write file: %w", err)
+	}
+
+	if err := os.Chmod(tmpFile.Name(), defaultFilePerms); err != nil {
+		return fmt.Errorf("could not set file permissions: %w", err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), file); err != nil {
+		return fmt.Errorf("could not replace file: %w", err)
+	}
+
+	return nil
+}
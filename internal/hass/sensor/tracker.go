@@ -7,8 +7,19 @@ package sensor
 
 import (
 	"errors"
+	"fmt"
 	"sort"
 	"sync"
+	"time"
+)
+
+const (
+	// DefaultHistorySize is the number of states kept for each sensor if no
+	// other size is set.
+	DefaultHistorySize = 100
+	// DefaultSaveInterval is how often the state of a tracker with a state
+	// file should be saved while sensors are added.
+	DefaultSaveInterval = time.Minute
 )
 
 var (
@@ -16,9 +27,76 @@ var (
 	ErrSensorNotFound  = errors.New("sensor not found in tracker")
 )
 
+// HistoryEntry is a state of a sensor and when it was added to the tracker.
+type HistoryEntry struct {
+	Time  time.Time `json:"time"`
+	State any       `json:"state"`
+}
+
+// history is a ring buffer of the most recent states of a sensor.
+type history struct {
+	entries []HistoryEntry
+	// next is the index the next entry is written to.
+	next int
+	full bool
+}
+
+func newHistory(size int) *history {
+	return &history{entries: make([]HistoryEntry, size)}
+}
+
+// add adds an entry, replacing the oldest entry if the history is full.
+func (h *history) add(entry HistoryEntry) {
+	h.entries[h.next] = entry
+	h.next = (h.next + 1) % len(h.entries)
+
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// list returns the entries, oldest first.
+func (h *history) list() []HistoryEntry {
+	if !h.full {
+		return append([]HistoryEntry(nil), h.entries[:h.next]...)
+	}
+
+	return append(append([]HistoryEntry(nil), h.entries[h.next:]...), h.entries[:h.next]...)
+}
+
+// TrackerOption is an option for a new Tracker.
+type TrackerOption func(*Tracker)
+
+// WithHistorySize sets the number of states kept for each sensor.
+func WithHistorySize(size int) TrackerOption {
+	return func(t *Tracker) {
+		if size > 0 {
+			t.historySize = size
+		}
+	}
+}
+
+// WithStateFile sets the file the tracker state is saved to and loaded from,
+// so that it survives restarts.
+func WithStateFile(path string) TrackerOption {
+	return func(t *Tracker) {
+		t.file = path
+	}
+}
+
 type Tracker struct {
-	sensor map[string]Details
-	mu     sync.Mutex
+	sensor  map[string]Details
+	updated map[string]time.Time
+	history map[string]*history
+	// file is where the state is saved. If empty, the state is only kept in
+	// memory.
+	file        string
+	historySize int
+	dirty       bool
+	mu          sync.Mutex
+	// saveMu ensures the state is written by one save at a time, so that an
+	// older state does not replace a newer one.
+	saveMu sync.Mutex
 }
 
 // Get fetches a sensors current tracked state.
@@ -55,6 +133,8 @@ func (t *Tracker) SensorList() []string {
 }
 
 // Add creates a new sensor in the tracker based on a received state update.
+// The state is also added to the history of the sensor. The state is not
+// saved, Save should be called periodically to do that.
 func (t *Tracker) Add(sensor Details) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -63,21 +143,143 @@ func (t *Tracker) Add(sensor Details) error {
 		return ErrTrackerNotReady
 	}
 
+	if t.updated == nil {
+		t.updated = make(map[string]time.Time)
+	}
+
+	if t.history == nil {
+		t.history = make(map[string]*history)
+	}
+
+	now := time.Now()
+
 	t.sensor[sensor.ID()] = sensor
+	t.updated[sensor.ID()] = now
+
+	sensorHistory, found := t.history[sensor.ID()]
+	if !found {
+		sensorHistory = newHistory(t.size())
+		t.history[sensor.ID()] = sensorHistory
+	}
+
+	sensorHistory.add(HistoryEntry{Time: now, State: sensor.State()})
+
+	t.dirty = true
+
+	return nil
+}
+
+// LastUpdated returns the time the sensor was last added to the tracker. If the
+// sensor is not tracked, the zero time is returned.
+func (t *Tracker) LastUpdated(id string) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.updated[id]
+}
+
+// History returns the most recent states of the sensor, oldest first.
+func (t *Tracker) History(id string) ([]HistoryEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sensorHistory, found := t.history[id]
+	if !found {
+		return nil, ErrSensorNotFound
+	}
+
+	return sensorHistory.list(), nil
+}
+
+// Save saves the tracker state to its state file, if it has one and the state
+// has changed since it was last saved. The tracker is only locked while the
+// state is encoded, not while it is written.
+func (t *Tracker) Save() error {
+	t.saveMu.Lock()
+	defer t.saveMu.Unlock()
+
+	t.mu.Lock()
+
+	if t.file == "" || !t.dirty {
+		t.mu.Unlock()
+
+		return nil
+	}
+
+	data, err := t.encode()
+	if err == nil {
+		t.dirty = false
+	}
+
+	t.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("could not save tracker state: %w", err)
+	}
+
+	if err := t.write(data); err != nil {
+		// Try again on the next save.
+		t.mu.Lock()
+		t.dirty = true
+		t.mu.Unlock()
+
+		return fmt.Errorf("could not save tracker state: %w", err)
+	}
 
 	return nil
 }
 
 func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	if t.sensor != nil {
 		t.sensor = nil
+		t.updated = nil
+		t.history = nil
+		t.dirty = true
+	}
+}
+
+// Clear removes all sensors from the tracker. Unlike Reset, the tracker can
+// still be used afterwards.
+func (t *Tracker) Clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sensor = make(map[string]Details)
+	t.updated = make(map[string]time.Time)
+	t.history = make(map[string]*history)
+	t.dirty = true
+}
+
+// size returns the number of states kept for each sensor.
+func (t *Tracker) size() int {
+	if t.historySize > 0 {
+		return t.historySize
 	}
+
+	return DefaultHistorySize
 }
 
-func NewTracker() (*Tracker, error) {
+// NewTracker creates a new tracker with the given options. If the tracker has
+// a state file, any saved state is loaded from it.
+func NewTracker(options ...TrackerOption) (*Tracker, error) {
 	sensorTracker := &Tracker{
-		sensor: make(map[string]Details),
-		mu:     sync.Mutex{},
+		sensor:  make(map[string]Details),
+		updated: make(map[string]time.Time),
+		history: make(map[string]*history),
+		mu:      sync.Mutex{},
+	}
+
+	for _, option := range options {
+		option(sensorTracker)
+	}
+
+	if sensorTracker.file != "" {
+		if err := sensorTracker.load(); err != nil {
+			return nil, fmt.Errorf("could not load tracker state: %w", err)
+		}
 	}
 
 	return sensorTracker, nil
@@ -0,0 +1,175 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package sensor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor/types"
+)
+
+const trackerFilePerms = 0o640
+
+var ErrInvalidTrackerState = errors.New("invalid tracker state")
+
+// trackerState is the tracker state saved to disk. Each sensor is encoded
+// separately, so that a sensor that cannot be encoded does not prevent the
+// others from being saved.
+type trackerState struct {
+	Sensors map[string]json.RawMessage `json:"sensors"`
+}
+
+// savedSensor is the saved state of a tracked sensor. It is also used as the
+// sensor details of a loaded sensor until it is next updated.
+type savedSensor struct {
+	Updated           time.Time         `json:"updated"`
+	SensorState       any               `json:"state"`
+	SensorAttributes  map[string]any    `json:"attributes,omitempty"`
+	SensorID          string            `json:"id"`
+	SensorName        string            `json:"name"`
+	SensorIcon        string            `json:"icon,omitempty"`
+	SensorUnits       string            `json:"units,omitempty"`
+	SensorCategory    string            `json:"category,omitempty"`
+	History           []HistoryEntry    `json:"history,omitempty"`
+	SensorClass       types.SensorClass `json:"type"`
+	SensorDeviceClass types.DeviceClass `json:"device_class,omitempty"`
+	SensorStateClass  types.StateClass  `json:"state_class,omitempty"`
+}
+
+func newSavedSensor(details Details, updated time.Time, sensorHistory *history) *savedSensor {
+	saved := &savedSensor{
+		Updated:           updated,
+		SensorState:       details.State(),
+		SensorAttributes:  details.Attributes(),
+		SensorID:          details.ID(),
+		SensorName:        details.Name(),
+		SensorIcon:        details.Icon(),
+		SensorUnits:       details.Units(),
+		SensorCategory:    details.Category(),
+		SensorClass:       details.SensorType(),
+		SensorDeviceClass: details.DeviceClass(),
+		SensorStateClass:  details.StateClass(),
+	}
+
+	if sensorHistory != nil {
+		saved.History = sensorHistory.list()
+	}
+
+	return saved
+}
+
+func (s *savedSensor) ID() string                     { return s.SensorID }
+func (s *savedSensor) Name() string                   { return s.SensorName }
+func (s *savedSensor) Icon() string                   { return s.SensorIcon }
+func (s *savedSensor) State() any                     { return s.SensorState }
+func (s *savedSensor) SensorType() types.SensorClass  { return s.SensorClass }
+func (s *savedSensor) Units() string                  { return s.SensorUnits }
+func (s *savedSensor) Attributes() map[string]any     { return s.SensorAttributes }
+func (s *savedSensor) DeviceClass() types.DeviceClass { return s.SensorDeviceClass }
+func (s *savedSensor) StateClass() types.StateClass   { return s.SensorStateClass }
+func (s *savedSensor) Category() string               { return s.SensorCategory }
+
+// encode encodes the tracker state for saving. Sensors with a state that cannot
+// be encoded are not saved. The tracker must be locked.
+func (t *Tracker) encode() ([]byte, error) {
+	sensors := make(map[string]json.RawMessage, len(t.sensor))
+
+	for id, details := range t.sensor {
+		saved, err := json.Marshal(newSavedSensor(details, t.updated[id], t.history[id]))
+		if err != nil {
+			continue
+		}
+
+		sensors[id] = saved
+	}
+
+	data, err := json.Marshal(&trackerState{Sensors: sensors})
+	if err != nil {
+		return nil, fmt.Errorf("could not encode state: %w", err)
+	}
+
+	return data, nil
+}
+
+// write writes the encoded tracker state to the state file. The state is
+// written to a temporary file first, so that the state file is never partially
+// written.
+func (t *Tracker) write(data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(t.file), os.ModePerm); err != nil {
+		return fmt.Errorf("could not create state directory: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(t.file), filepath.Base(t.file)+".*")
+	if err != nil {
+		return fmt.Errorf("could not create state file: %w", err)
+	}
+
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck // removed by rename on success
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close() //nolint:errcheck
+
+		return fmt.Errorf("could not write state file: %w", err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("could not write state file: %w", err)
+	}
+
+	if err := os.Chmod(tmpFile.Name(), trackerFilePerms); err != nil {
+		return fmt.Errorf("could not write state file: %w", err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), t.file); err != nil {
+		return fmt.Errorf("could not write state file: %w", err)
+	}
+
+	return nil
+}
+
+// load reads the tracker state from the state file, if it exists. History
+// beyond the size of the tracker history is discarded.
+func (t *Tracker) load() error {
+	data, err := os.ReadFile(t.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("could not read state file: %w", err)
+	}
+
+	var state trackerState
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTrackerState, err)
+	}
+
+	for id, data := range state.Sensors {
+		var saved *savedSensor
+
+		if err := json.Unmarshal(data, &saved); err != nil || saved == nil || saved.SensorID != id {
+			return fmt.Errorf("%w: sensor %s", ErrInvalidTrackerState, id)
+		}
+
+		sensorHistory := newHistory(t.size())
+		for _, entry := range saved.History {
+			sensorHistory.add(entry)
+		}
+
+		t.sensor[id] = saved
+		t.updated[id] = saved.Updated
+		t.history[id] = sensorHistory
+		saved.History = nil
+	}
+
+	return nil
+}
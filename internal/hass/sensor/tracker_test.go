@@ -7,11 +7,15 @@
 package sensor
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTracker_Get(t *testing.T) {
@@ -145,6 +149,9 @@ func TestTracker_Add(t *testing.T) {
 			if err := tr.Add(tt.args.sensor); (err != nil) != tt.wantErr {
 				t.Errorf("Tracker.Add() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if !tt.wantErr {
+				assert.WithinDuration(t, time.Now(), tr.LastUpdated(tt.args.sensor.ID()), time.Second)
+			}
 		})
 	}
 }
@@ -179,6 +186,21 @@ func TestTracker_Reset(t *testing.T) {
 	}
 }
 
+func TestTracker_Clear(t *testing.T) {
+	details, _, _ := newMockDetails(t)
+
+	tracker, err := NewTracker()
+	require.NoError(t, err)
+	require.NoError(t, tracker.Add(details))
+
+	tracker.Clear()
+	assert.Empty(t, tracker.SensorList())
+
+	// The tracker can still be used.
+	require.NoError(t, tracker.Add(details))
+	assert.Equal(t, []string{details.ID()}, tracker.SensorList())
+}
+
 func TestNewTracker(t *testing.T) {
 	tests := []struct {
 		want    *Tracker
@@ -188,8 +210,10 @@ func TestNewTracker(t *testing.T) {
 		{
 			name: "new tracker",
 			want: &Tracker{
-				sensor: make(map[string]Details),
-				mu:     sync.Mutex{},
+				sensor:  make(map[string]Details),
+				updated: make(map[string]time.Time),
+				history: make(map[string]*history),
+				mu:      sync.Mutex{},
 			},
 		},
 	}
@@ -206,3 +230,135 @@ func TestNewTracker(t *testing.T) {
 		})
 	}
 }
+
+func TestTracker_History(t *testing.T) {
+	tests := []struct {
+		name   string
+		states []any
+		want   []any
+	}{
+		{
+			name:   "not full",
+			states: []any{1, 2},
+			want:   []any{1, 2},
+		},
+		{
+			name:   "full",
+			states: []any{1, 2, 3},
+			want:   []any{1, 2, 3},
+		},
+		{
+			name:   "wrapped",
+			states: []any{1, 2, 3, 4, 5},
+			want:   []any{3, 4, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := NewTracker(WithHistorySize(3))
+			require.NoError(t, err)
+
+			for _, state := range tt.states {
+				require.NoError(t, tr.Add(&savedSensor{SensorID: "sensor", SensorState: state}))
+			}
+
+			entries, err := tr.History("sensor")
+			require.NoError(t, err)
+
+			got := make([]any, 0, len(entries))
+			for _, entry := range entries {
+				got = append(got, entry.State)
+			}
+
+			assert.Equal(t, tt.want, got)
+
+			_, err = tr.History("unknown")
+			require.ErrorIs(t, err, ErrSensorNotFound)
+		})
+	}
+}
+
+func TestTracker_Save(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tracker.json")
+
+	tr, err := NewTracker(WithStateFile(file))
+	require.NoError(t, err)
+
+	mockSensor, _, _ := newMockDetails(t)
+	require.NoError(t, tr.Add(mockSensor))
+	require.NoError(t, tr.Add(&savedSensor{SensorID: "counter", SensorName: "Counter", SensorState: 1.0}))
+	require.NoError(t, tr.Add(&savedSensor{SensorID: "counter", SensorName: "Counter", SensorState: 2.0}))
+	require.NoError(t, tr.Save())
+
+	// The state is loaded by a new tracker.
+	loaded, err := NewTracker(WithStateFile(file))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"counter", mockSensor.ID()}, loaded.SensorList())
+
+	details, err := loaded.Get(mockSensor.ID())
+	require.NoError(t, err)
+	assert.Equal(t, mockSensor.Name(), details.Name())
+	assert.Equal(t, mockSensor.State(), details.State())
+	assert.Equal(t, mockSensor.Units(), details.Units())
+	assert.Equal(t, mockSensor.DeviceClass(), details.DeviceClass())
+	assert.Equal(t, mockSensor.StateClass(), details.StateClass())
+	assert.True(t, tr.LastUpdated(mockSensor.ID()).Equal(loaded.LastUpdated(mockSensor.ID())))
+
+	entries, err := loaded.History("counter")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.InDelta(t, 1.0, entries[0].State, 0)
+	assert.InDelta(t, 2.0, entries[1].State, 0)
+
+	// Reset state is saved.
+	loaded.Reset()
+	require.NoError(t, loaded.Save())
+
+	reset, err := NewTracker(WithStateFile(file))
+	require.NoError(t, err)
+	assert.Empty(t, reset.SensorList())
+
+	// Invalid state is reported.
+	require.NoError(t, os.WriteFile(file, []byte("not json"), trackerFilePerms))
+
+	_, err = NewTracker(WithStateFile(file))
+	require.ErrorIs(t, err, ErrInvalidTrackerState)
+}
+
+func TestTracker_Save_concurrent(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tracker.json")
+
+	tr, err := NewTracker(WithStateFile(file))
+	require.NoError(t, err)
+
+	// Adding sensors does not save the state.
+	require.NoError(t, tr.Add(&savedSensor{SensorID: "counter", SensorName: "Counter", SensorState: 0.0}))
+	assert.NoFileExists(t, file)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for i := range 100 {
+			assert.NoError(t, tr.Add(&savedSensor{SensorID: "counter", SensorName: "Counter", SensorState: float64(i)}))
+		}
+	}()
+
+	for range 10 {
+		require.NoError(t, tr.Save())
+	}
+
+	wg.Wait()
+	require.NoError(t, tr.Save())
+
+	// The last state is saved.
+	loaded, err := NewTracker(WithStateFile(file))
+	require.NoError(t, err)
+
+	details, err := loaded.Get("counter")
+	require.NoError(t, err)
+	assert.InDelta(t, 99.0, details.State(), 0)
+}
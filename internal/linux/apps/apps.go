@@ -25,7 +25,8 @@ const (
 	appStateDBusInterface = "org.freedesktop.impl.portal.Background"
 	appStateDBusEvent     = "org.freedesktop.impl.portal.Background.RunningApplicationsChanged"
 
-	workerID = "app_sensors"
+	// WorkerID is the ID of the apps worker.
+	WorkerID = "app_sensors"
 )
 
 var ErrNoApps = errors.New("no running apps")
@@ -39,7 +40,7 @@ type worker struct {
 
 func (w *worker) Events(ctx context.Context) (chan sensor.Details, error) {
 	sensorCh := make(chan sensor.Details)
-	logger := slog.Default().With(slog.String("worker", workerID))
+	logger := slog.Default().With(slog.String("worker", WorkerID))
 
 	sendSensors := func(ctx context.Context, sensorCh chan sensor.Details) {
 		appSensors, err := w.Sensors(ctx)
@@ -148,7 +149,7 @@ func NewAppWorker(ctx context.Context) (*linux.SensorWorker, error) {
 				runningApps: newRunningAppsSensor(),
 				triggerCh:   triggerCh,
 			},
-			WorkerID: workerID,
+			WorkerID: WorkerID,
 		},
 		nil
 }
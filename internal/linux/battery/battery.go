@@ -48,7 +48,8 @@ const (
 
 	batteryIcon = "mdi:battery"
 
-	workerID = "battery_sensors"
+	// WorkerID is the ID of the battery worker.
+	WorkerID = "battery_sensors"
 )
 
 type batterySensor int
@@ -636,11 +637,11 @@ func NewBatteryWorker(ctx context.Context) (*linux.SensorWorker, error) {
 
 	return &linux.SensorWorker{
 			Value: &batterySensorWorker{
-				logger:      logging.FromContext(ctx).With(slog.String("worker", workerID)),
+				logger:      logging.FromContext(ctx).With(slog.String("worker", WorkerID)),
 				bus:         bus,
 				batteryList: make(map[dbus.ObjectPath]context.CancelFunc),
 			},
-			WorkerID: workerID,
+			WorkerID: WorkerID,
 		},
 		nil
 }
@@ -25,6 +25,7 @@ const (
 	boottimeContextKey      contextKey = "boottime"
 	sessionPathContextKey   contextKey = "sessionPath"
 	desktopPortalContextKey contextKey = "desktopPortal"
+	textfilePathContextKey  contextKey = "textfilePath"
 )
 
 var (
@@ -133,3 +134,18 @@ func CtxGetSessionPath(ctx context.Context) (string, bool) {
 
 	return path, true
 }
+
+// WithTextfilePath stores the directory containing Prometheus node_exporter
+// textfile collector files in the context.
+func WithTextfilePath(ctx context.Context, path string) context.Context {
+	return context.WithValue(ctx, textfilePathContextKey, path)
+}
+
+func CtxGetTextfilePath(ctx context.Context) (string, bool) {
+	path, ok := ctx.Value(textfilePathContextKey).(string)
+	if !ok {
+		return path, false
+	}
+
+	return path, true
+}
@@ -30,7 +30,8 @@ const (
 
 	loadAvgsTotal = 3
 
-	loadAvgsWorkerID = "load_averages_sensors"
+	// LoadAvgsWorkerID is the ID of the load averages worker.
+	LoadAvgsWorkerID = "load_averages_sensors"
 )
 
 var ErrParseLoadAvgs = errors.New("could not parse load averages")
@@ -102,7 +103,7 @@ func parseLoadAvgs(data []byte) ([]string, error) {
 func NewLoadAvgWorker(_ context.Context) (*linux.SensorWorker, error) {
 	return &linux.SensorWorker{
 			Value:    &loadAvgsSensorWorker{loadAvgs: newLoadAvgSensors(), path: filepath.Join(linux.ProcFSRoot, "loadavg")},
-			WorkerID: loadAvgsWorkerID,
+			WorkerID: LoadAvgsWorkerID,
 		},
 		nil
 }
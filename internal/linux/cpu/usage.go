@@ -29,7 +29,8 @@ const (
 	usageUpdateInterval = 10 * time.Second
 	usageUpdateJitter   = 500 * time.Millisecond
 
-	usageWorkerID = "cpu_usage_sensors"
+	// UsageWorkerID is the ID of the CPU usage worker.
+	UsageWorkerID = "cpu_usage_sensors"
 
 	totalCPUString = "cpu"
 )
@@ -127,7 +128,7 @@ func NewUsageWorker(ctx context.Context) (*linux.SensorWorker, error) {
 				boottime: boottime,
 				path:     filepath.Join(linux.ProcFSRoot, "stat"),
 			},
-			WorkerID: usageWorkerID,
+			WorkerID: UsageWorkerID,
 		},
 		nil
 }
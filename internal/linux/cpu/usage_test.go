@@ -178,7 +178,7 @@ func TestNewUsageWorker(t *testing.T) {
 				Value: &usageWorker{
 					clktck: clktck,
 				},
-				WorkerID: usageWorkerID,
+				WorkerID: UsageWorkerID,
 			},
 		},
 	}
@@ -30,7 +30,8 @@ const (
 	colorSchemeProp         = "color-scheme"
 	accentColorProp         = "accent-color"
 
-	workerID = "desktop_settings_sensors"
+	// WorkerID is the ID of the desktop settings worker.
+	WorkerID = "desktop_settings_sensors"
 )
 
 var ErrUnknownProp = errors.New("unknown desktop property")
@@ -101,7 +102,7 @@ func (w *worker) newColorSchemeSensor(scheme string) (*desktopSettingSensor, err
 //nolint:cyclop,gocognit
 func (w *worker) Events(ctx context.Context) (chan sensor.Details, error) {
 	sensorCh := make(chan sensor.Details)
-	logger := logging.FromContext(ctx).With(slog.String("worker", workerID))
+	logger := logging.FromContext(ctx).With(slog.String("worker", WorkerID))
 
 	go func() {
 		defer close(sensorCh)
@@ -217,7 +218,7 @@ func NewDesktopWorker(ctx context.Context) (*linux.SensorWorker, error) {
 				},
 				triggerCh: triggerCh,
 			},
-			WorkerID: workerID,
+			WorkerID: WorkerID,
 		},
 		nil
 }
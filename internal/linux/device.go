@@ -10,15 +10,12 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 )
 
-const (
-	UptimeFile = "/proc/uptime"
-)
-
 var (
 	ErrDesktopPortalMissing = errors.New("no portal present")
 	ErrUptimeInvalid        = errors.New("invalid uptime")
@@ -40,7 +37,7 @@ func FindPortal() (string, error) {
 }
 
 func getBootTime() (time.Time, error) {
-	data, err := os.Open(UptimeFile)
+	data, err := os.Open(filepath.Join(ProcFSRoot, "uptime"))
 	if err != nil {
 		return time.Now(), fmt.Errorf("unable to read uptime: %w", err)
 	}
@@ -23,7 +23,8 @@ const (
 	ratesUpdateInterval = 5 * time.Second
 	ratesUpdateJitter   = time.Second
 
-	ratesWorkerID = "disk_rates_sensors"
+	// RatesWorkerID is the ID of the disk rates worker.
+	RatesWorkerID = "disk_rates_sensors"
 )
 
 // ioWorker creates sensors for disk IO counts and rates per device. It
@@ -97,7 +98,7 @@ func (w *ioWorker) Sensors(ctx context.Context, duration time.Duration) ([]senso
 		dev, stats, err := getDevice(name)
 		if err != nil {
 			logging.FromContext(ctx).
-				With(slog.String("worker", ratesWorkerID)).
+				With(slog.String("worker", RatesWorkerID)).
 				Debug("Unable to read device stats.", slog.Any("error", err))
 
 			continue
@@ -142,7 +143,7 @@ func NewIOWorker(ctx context.Context) (*linux.SensorWorker, error) {
 
 	return &linux.SensorWorker{
 			Value:    worker,
-			WorkerID: ratesWorkerID,
+			WorkerID: RatesWorkerID,
 		},
 		nil
 }
@@ -138,7 +138,7 @@ func getMounts(ctx context.Context) ([]*mount, error) {
 
 			if err := validmount.getMountInfo(); err != nil {
 				logging.FromContext(ctx).
-					With(slog.String("worker", usageWorkerID)).
+					With(slog.String("worker", UsageWorkerID)).
 					Debug("Error getting mount info.", slog.Any("error", err))
 			} else {
 				mounts = append(mounts, validmount)
@@ -148,7 +148,7 @@ func getMounts(ctx context.Context) ([]*mount, error) {
 
 	if err := data.Close(); err != nil {
 		logging.FromContext(ctx).
-			With(slog.String("worker", usageWorkerID)).
+			With(slog.String("worker", UsageWorkerID)).
 			Debug("Failed to close mounts file.", slog.Any("error", err))
 	}
 
@@ -19,7 +19,8 @@ const (
 	usageUpdateInterval = time.Minute
 	usageUpdateJitter   = 10 * time.Second
 
-	usageWorkerID = "disk_usage_sensors"
+	// UsageWorkerID is the ID of the disk usage worker.
+	UsageWorkerID = "disk_usage_sensors"
 )
 
 type usageWorker struct{}
@@ -46,7 +47,7 @@ func (w *usageWorker) Sensors(ctx context.Context, _ time.Duration) ([]sensor.De
 func NewUsageWorker(_ context.Context) (*linux.SensorWorker, error) {
 	return &linux.SensorWorker{
 			Value:    &usageWorker{},
-			WorkerID: usageWorkerID,
+			WorkerID: UsageWorkerID,
 		},
 		nil
 }
@@ -34,7 +34,8 @@ const (
 	timeThresholdProp     = clientInterface + ".TimeThreshold"
 	locationUpdatedSignal = clientInterface + ".LocationUpdated"
 
-	workerID = "location_sensor"
+	// WorkerID is the ID of the location worker.
+	WorkerID = "location_sensor"
 )
 
 type locationSensor struct {
@@ -54,7 +55,7 @@ type worker struct {
 
 //nolint:gocognit
 func (w *worker) Events(ctx context.Context) (chan sensor.Details, error) {
-	logger := logging.FromContext(ctx).With(slog.String("worker", workerID))
+	logger := logging.FromContext(ctx).With(slog.String("worker", WorkerID))
 
 	err := w.startMethod.Call(ctx)
 	if err != nil {
@@ -170,7 +171,7 @@ func NewLocationWorker(ctx context.Context) (*linux.SensorWorker, error) {
 		triggerCh:   triggerCh,
 	}
 
-	return &linux.SensorWorker{Value: worker, WorkerID: workerID}, nil
+	return &linux.SensorWorker{Value: worker, WorkerID: WorkerID}, nil
 }
 
 func createClient(bus *dbusx.Bus) (string, error) {
@@ -191,7 +192,7 @@ func createClient(bus *dbusx.Bus) (string, error) {
 func setThresholds(bus *dbusx.Bus, clientPath string) {
 	var err error
 
-	logger := slog.With(slog.String("worker", workerID))
+	logger := slog.With(slog.String("worker", WorkerID))
 
 	// Set a distance threshold.
 	if err = dbusx.NewProperty[uint32](bus, clientPath, geoclueInterface, distanceThresholdProp).Set(0); err != nil {
@@ -145,7 +145,9 @@ var statNames = map[string]memStatID{
 	"DirectMap1G":       memDirectMap4k,
 }
 
-var memStatFile = filepath.Join(linux.ProcFSRoot, "meminfo")
+// memStatFile is the file memory statistics are read from. If empty, meminfo
+// under linux.ProcFSRoot is used.
+var memStatFile string
 
 // memStat holds the value and any units for a memory statistic.
 type memStat struct {
@@ -166,7 +168,12 @@ func (m memoryStats) get(id memStatID) (uint64, string) {
 
 // getMemStats will create a memoryStats map for this device.
 func getMemStats() (memoryStats, error) {
-	statsFH, err := os.Open(memStatFile)
+	file := memStatFile
+	if file == "" {
+		file = filepath.Join(linux.ProcFSRoot, "meminfo")
+	}
+
+	statsFH, err := os.Open(file)
 	if err != nil {
 		return nil, fmt.Errorf("getMemStats: %w", err)
 	}
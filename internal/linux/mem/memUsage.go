@@ -28,7 +28,8 @@ const (
 	memoryUsageSensorUnits   = "B"
 	memoryUsageSensorPcUnits = "%"
 
-	workerID = "memory_usage_sensors"
+	// WorkerID is the ID of the memory usage worker.
+	WorkerID = "memory_usage_sensors"
 )
 
 // Lists of the memory statistics we want to track as sensors. See /proc/meminfo
@@ -146,7 +147,7 @@ func (w *usageWorker) Sensors(_ context.Context, _ time.Duration) ([]sensor.Deta
 func NewUsageWorker(_ context.Context) (*linux.SensorWorker, error) {
 	return &linux.SensorWorker{
 			Value:    &usageWorker{},
-			WorkerID: workerID,
+			WorkerID: WorkerID,
 		},
 		nil
 }
@@ -32,7 +32,8 @@ const (
 	bytesSentRate                   // Bytes Sent Throughput
 	bytesRecvRate                   // Bytes Received Throughput
 
-	netRatesWorkerID = "network_rates_sensors"
+	// RatesWorkerID is the ID of the network rates worker.
+	RatesWorkerID = "network_rates_sensors"
 )
 
 type rateSensor int
@@ -176,7 +177,7 @@ func NewRatesWorker(_ context.Context) (*linux.SensorWorker, error) {
 				bytesRxRate: newNetIORateSensor(bytesRecvRate),
 				bytesTxRate: newNetIORateSensor(bytesSentRate),
 			},
-			WorkerID: netRatesWorkerID,
+			WorkerID: RatesWorkerID,
 		},
 		nil
 }
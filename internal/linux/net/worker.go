@@ -32,7 +32,8 @@ const (
 	statePropName          = "State"
 	activeConnectionsProp  = "ActivatingConnection"
 
-	netConnWorkerID = "network_connection_sensors"
+	// ConnectionWorkerID is the ID of the network connection worker.
+	ConnectionWorkerID = "network_connection_sensors"
 )
 
 type connectionsWorker struct {
@@ -164,9 +165,9 @@ func NewConnectionWorker(ctx context.Context) (*linux.SensorWorker, error) {
 	return &linux.SensorWorker{
 			Value: &connectionsWorker{
 				bus:    bus,
-				logger: slog.With(slog.String("worker", netConnWorkerID)),
+				logger: slog.With(slog.String("worker", ConnectionWorkerID)),
 			},
-			WorkerID: netConnWorkerID,
+			WorkerID: ConnectionWorkerID,
 		},
 		nil
 }
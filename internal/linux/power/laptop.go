@@ -25,7 +25,8 @@ const (
 	lidClosedProp     = managerInterface + ".LidClosed"
 	externalPowerProp = managerInterface + ".OnExternalPower"
 
-	laptopWorkerID = "laptop_sensors"
+	// LaptopWorkerID is the ID of the laptop worker.
+	LaptopWorkerID = "laptop_sensors"
 )
 
 var laptopPropList = []string{dockedProp, lidClosedProp, externalPowerProp}
@@ -106,7 +107,7 @@ func (w *laptopWorker) Events(ctx context.Context) (chan sensor.Details, error)
 			case event := <-w.triggerCh:
 				props, err := dbusx.ParsePropertiesChanged(event.Content)
 				if err != nil {
-					slog.With(slog.String("worker", laptopWorkerID)).
+					slog.With(slog.String("worker", LaptopWorkerID)).
 						Debug("Received unknown event from D-Bus.", slog.Any("error", err))
 				} else {
 					sendChangedProps(props.Changed, sensorCh)
@@ -119,7 +120,7 @@ func (w *laptopWorker) Events(ctx context.Context) (chan sensor.Details, error)
 	go func() {
 		sensors, err := w.Sensors(ctx)
 		if err != nil {
-			slog.With(slog.String("worker", laptopWorkerID)).
+			slog.With(slog.String("worker", LaptopWorkerID)).
 				Debug("Could not retrieve laptop properties from D-Bus.", slog.Any("error", err))
 		}
 
@@ -138,7 +139,7 @@ func (w *laptopWorker) Sensors(_ context.Context) ([]sensor.Details, error) {
 	for name, prop := range w.properties {
 		state, err := prop.Get()
 		if err != nil {
-			slog.With(slog.String("worker", laptopWorkerID)).
+			slog.With(slog.String("worker", LaptopWorkerID)).
 				Debug("Could not retrieve property",
 					slog.String("property", name),
 					slog.Any("error", err))
@@ -189,7 +190,7 @@ func NewLaptopWorker(ctx context.Context) (*linux.SensorWorker, error) {
 
 	return &linux.SensorWorker{
 			Value:    worker,
-			WorkerID: laptopWorkerID,
+			WorkerID: LaptopWorkerID,
 		},
 		nil
 }
@@ -198,7 +199,7 @@ func sendChangedProps(props map[string]dbus.Variant, sensorCh chan sensor.Detail
 	for prop, value := range props {
 		if slices.Contains(laptopPropList, prop) {
 			if state, err := dbusx.VariantToValue[bool](value); err != nil {
-				slog.With(slog.String("worker", laptopWorkerID)).
+				slog.With(slog.String("worker", LaptopWorkerID)).
 					Debug("Could not parse property value.",
 						slog.String("property", prop),
 						slog.Any("error", err))
@@ -21,7 +21,8 @@ const (
 	powerProfilesInterface = "org.freedesktop.UPower.PowerProfiles"
 	activeProfileProp      = "ActiveProfile"
 
-	powerProfileWorkerID = "power_profile_sensor"
+	// ProfileWorkerID is the ID of the power profile worker.
+	ProfileWorkerID = "power_profile_sensor"
 
 	powerProfileIcon = "mdi:flash"
 )
@@ -44,7 +45,7 @@ type profileWorker struct {
 
 func (w *profileWorker) Events(ctx context.Context) (chan sensor.Details, error) {
 	sensorCh := make(chan sensor.Details)
-	logger := slog.With(slog.String("worker", powerProfileWorkerID))
+	logger := slog.With(slog.String("worker", ProfileWorkerID))
 
 	// Get the current power profile and send it as an initial sensor value.
 	sensors, err := w.Sensors(ctx)
@@ -110,7 +111,7 @@ func NewProfileWorker(ctx context.Context) (*linux.SensorWorker, error) {
 					powerProfilesInterface+"."+activeProfileProp),
 				triggerCh: triggerCh,
 			},
-			WorkerID: powerProfileWorkerID,
+			WorkerID: ProfileWorkerID,
 		},
 		nil
 }
@@ -23,7 +23,8 @@ const (
 	sleepSignal    = "PrepareForSleep"
 	shutdownSignal = "PrepareForShutdown"
 
-	powerStateWorkerID = "power_state_sensor"
+	// StateWorkerID is the ID of the power state worker.
+	StateWorkerID = "power_state_sensor"
 )
 
 type powerSignal int
@@ -154,7 +155,7 @@ func NewStateWorker(ctx context.Context) (*linux.SensorWorker, error) {
 			Value: &stateWorker{
 				triggerCh: triggerCh,
 			},
-			WorkerID: powerStateWorkerID,
+			WorkerID: StateWorkerID,
 		},
 		nil
 }
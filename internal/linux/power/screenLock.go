@@ -17,7 +17,8 @@ import (
 )
 
 const (
-	screenLockWorkerID = "screen_lock_sensor"
+	// ScreenLockWorkerID is the ID of the screen lock worker.
+	ScreenLockWorkerID = "screen_lock_sensor"
 
 	screenLockedIcon      = "mdi:eye-lock"
 	screenUnlockedIcon    = "mdi:eye-lock-open"
@@ -72,7 +73,7 @@ func (w *screenLockWorker) Events(ctx context.Context) (chan sensor.Details, err
 				case dbusx.PropChangedSignal:
 					changed, lockState, err := dbusx.HasPropertyChanged[bool](event.Content, sessionLockedProp)
 					if err != nil {
-						slog.With(slog.String("worker", screenLockWorkerID)).Debug("Could not parse received D-Bus signal.", slog.Any("error", err))
+						slog.With(slog.String("worker", ScreenLockWorkerID)).Debug("Could not parse received D-Bus signal.", slog.Any("error", err))
 					} else {
 						if changed {
 							sensorCh <- newScreenlockEvent(lockState)
@@ -118,7 +119,7 @@ func NewScreenLockWorker(ctx context.Context) (*linux.SensorWorker, error) {
 			Value: &screenLockWorker{
 				triggerCh: triggerCh,
 			},
-			WorkerID: screenLockWorkerID,
+			WorkerID: ScreenLockWorkerID,
 		},
 		nil
 }
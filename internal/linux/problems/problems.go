@@ -25,7 +25,8 @@ const (
 	problemInterval = 15 * time.Minute
 	problemJitter   = time.Minute
 
-	problemsWorkerID = "abrt_problems_sensor"
+	// WorkerID is the ID of the ABRT problems worker.
+	WorkerID = "abrt_problems_sensor"
 
 	dBusProblemsDest = "/org/freedesktop/problems"
 	dBusProblemIntr  = "org.freedesktop.problems"
@@ -110,7 +111,7 @@ func (w *worker) Sensors(ctx context.Context, _ time.Duration) ([]sensor.Details
 		details, err := w.getProblemDetails(problem)
 		if err != nil {
 			logging.FromContext(ctx).
-				With(slog.String("worker", problemsWorkerID)).
+				With(slog.String("worker", WorkerID)).
 				Debug("Unable to get problem details.",
 					slog.String("problem", problem),
 					slog.Any("error", err))
@@ -160,7 +161,7 @@ func NewProblemsWorker(ctx context.Context) (*linux.SensorWorker, error) {
 				},
 				bus: bus,
 			},
-			WorkerID: problemsWorkerID,
+			WorkerID: WorkerID,
 		},
 		nil
 }
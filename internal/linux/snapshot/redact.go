@@ -0,0 +1,143 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// networkFilesystems are filesystems whose mount source can identify a server
+// or user.
+var networkFilesystems = []string{"nfs", "nfs4", "cifs", "smb3", "smbfs", "fuse.sshfs", "fuse.rclone", "9p"}
+
+// sensitiveOptions are mount options whose value can identify a user or
+// contain a secret.
+var sensitiveOptions = []string{"user", "username", "password", "pass", "credentials", "cred", "domain", "addr", "clientaddr"}
+
+// redactor removes potentially identifying information from captured files.
+type redactor struct {
+	// replacements are the values to replace and their placeholders, in
+	// order.
+	replacements [][2]string
+}
+
+// newRedactor creates a redactor that replaces the current user name, home
+// directory and hostname with placeholders.
+func newRedactor() *redactor {
+	redact := &redactor{}
+
+	if current, err := user.Current(); err == nil {
+		if current.HomeDir != "" && current.HomeDir != "/" {
+			redact.add(current.HomeDir, filepath.Join(filepath.Dir(current.HomeDir), "user"))
+		}
+
+		redact.add(current.Username, "user")
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		redact.add(hostname, "hostname")
+	}
+
+	return redact
+}
+
+// add replaces the given value with the given placeholder.
+func (r *redactor) add(value, placeholder string) {
+	if value == "" {
+		return
+	}
+
+	r.replacements = append(r.replacements, [2]string{value, placeholder})
+}
+
+// redact returns the given contents of the file with the given name in the
+// snapshot, with any identifying information removed.
+func (r *redactor) redact(name string, contents []byte) []byte {
+	if name == filepath.Join(procDir, "mounts") {
+		contents = redactMounts(contents)
+	}
+
+	redactedContents := string(contents)
+	for _, replacement := range r.replacements {
+		redactedContents = replaceTokens(redactedContents, replacement[0], replacement[1])
+	}
+
+	return []byte(redactedContents)
+}
+
+// replaceTokens replaces each occurrence of old in s with replacement, where
+// old is not part of a longer word, so that, for example, a user "root" does
+// not change "rootfs" or "chroot".
+func replaceTokens(s, old, replacement string) string {
+	var replaced strings.Builder
+
+	for {
+		idx := strings.Index(s, old)
+		if idx == -1 {
+			break
+		}
+
+		end := idx + len(old)
+		if (idx > 0 && isTokenByte(s[idx-1])) || (end < len(s) && isTokenByte(s[end])) {
+			replaced.WriteString(s[:end])
+		} else {
+			replaced.WriteString(s[:idx] + replacement)
+		}
+
+		s = s[end:]
+	}
+
+	replaced.WriteString(s)
+
+	return replaced.String()
+}
+
+// isTokenByte reports whether the given byte can be part of a user name or
+// hostname label.
+func isTokenByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '_' || b == '-'
+}
+
+// redactMounts removes the source of network filesystems and the values of
+// sensitive options from the given contents of /proc/mounts.
+func redactMounts(contents []byte) []byte {
+	var redactedMounts bytes.Buffer
+
+	lines := bufio.NewScanner(bytes.NewReader(contents))
+	for lines.Scan() {
+		fields := strings.Fields(lines.Text())
+		if len(fields) < 4 { //nolint:mnd
+			redactedMounts.WriteString(lines.Text() + "\n")
+
+			continue
+		}
+
+		if slices.Contains(networkFilesystems, fields[2]) {
+			fields[0] = redacted
+		}
+
+		options := strings.Split(fields[3], ",")
+		for idx, option := range options {
+			if key, _, found := strings.Cut(option, "="); found && slices.Contains(sensitiveOptions, key) {
+				options[idx] = key + "=" + redacted
+			}
+		}
+
+		fields[3] = strings.Join(options, ",")
+
+		redactedMounts.WriteString(strings.Join(fields, " ") + "\n")
+	}
+
+	return redactedMounts.Bytes()
+}
@@ -0,0 +1,323 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package snapshot captures the procfs and sysfs files read by the Linux sensor
+// workers into a tarball, and points the workers at an extracted snapshot, so
+// that they can be run against the data of another machine.
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/logging"
+)
+
+const (
+	procDir = "proc"
+	sysDir  = "sys"
+
+	DefaultMaxFileSize  = 1 << 20
+	DefaultMaxTotalSize = 32 << 20
+
+	dirPerms  = 0o755
+	filePerms = 0o644
+)
+
+var (
+	ErrTooLarge        = errors.New("snapshot is too large")
+	ErrInvalidSnapshot = errors.New("invalid snapshot")
+)
+
+// procFiles are the files under procfs read by the workers.
+var procFiles = []string{
+	"stat",        // cpu usage and frequencies.
+	"loadavg",     // cpu load averages.
+	"meminfo",     // memory usage.
+	"uptime",      // uptime and boot time.
+	"partitions",  // disk IO.
+	"filesystems", // disk usage.
+	"mounts",      // disk usage.
+	"net/dev",     // network rates.
+}
+
+// sysFiles are the files under sysfs read by the workers. Patterns are
+// expanded with filepath.Glob.
+var sysFiles = []string{
+	"devices/system/cpu/cpu[0-9]*/cpufreq/scaling_cur_freq", // cpu frequencies.
+	"devices/system/cpu/cpu[0-9]*/cpufreq/scaling_governor", // cpu frequencies.
+	"devices/system/cpu/cpu[0-9]*/cpufreq/scaling_driver",   // cpu frequencies.
+	"block/*/stat",               // disk IO.
+	"block/*/device/model",       // disk IO.
+	"class/hwmon/*/*",            // hardware sensors.
+	"class/hwmon/*/device/model", // hardware sensors.
+}
+
+// Options control what is captured in a snapshot.
+type Options struct {
+	// MaxFileSize is the size above which files are skipped.
+	MaxFileSize int64
+	// MaxTotalSize is the maximum size of all captured files.
+	MaxTotalSize int64
+	// NoRedact disables redacting potentially identifying information.
+	NoRedact bool
+}
+
+// Capture writes a gzip-compressed tarball of the procfs and sysfs files read
+// by the workers to the given writer. Files that cannot be read or are larger
+// than the maximum file size are skipped. If the files would be larger than the
+// maximum total size, ErrTooLarge is returned. The number of files captured is
+// returned.
+func Capture(ctx context.Context, writer io.Writer, options Options) (int, error) {
+	if options.MaxFileSize <= 0 {
+		options.MaxFileSize = DefaultMaxFileSize
+	}
+
+	if options.MaxTotalSize <= 0 {
+		options.MaxTotalSize = DefaultMaxTotalSize
+	}
+
+	var redact *redactor
+	if !options.NoRedact {
+		redact = newRedactor()
+	}
+
+	gz := gzip.NewWriter(writer)
+	archive := tar.NewWriter(gz)
+	logger := logging.FromContext(ctx)
+
+	var (
+		count int
+		total int64
+	)
+
+	paths := files()
+
+	for _, name := range slices.Sorted(maps.Keys(paths)) {
+		path := paths[name]
+
+		if ctx.Err() != nil {
+			return count, fmt.Errorf("capture canceled: %w", ctx.Err())
+		}
+
+		contents, err := readFile(path, options.MaxFileSize)
+		if err != nil {
+			logger.Debug("Skipping file.", slog.String("path", path), slog.Any("error", err))
+
+			continue
+		}
+
+		if redact != nil {
+			contents = redact.redact(name, contents)
+		}
+
+		total += int64(len(contents))
+		if total > options.MaxTotalSize {
+			return count, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, options.MaxTotalSize)
+		}
+
+		header := &tar.Header{
+			Name:    name,
+			Mode:    filePerms,
+			Size:    int64(len(contents)),
+			ModTime: time.Now(),
+		}
+
+		if err := archive.WriteHeader(header); err != nil {
+			return count, fmt.Errorf("could not write %s: %w", name, err)
+		}
+
+		if _, err := archive.Write(contents); err != nil {
+			return count, fmt.Errorf("could not write %s: %w", name, err)
+		}
+
+		count++
+	}
+
+	if err := errors.Join(archive.Close(), gz.Close()); err != nil {
+		return count, fmt.Errorf("could not write snapshot: %w", err)
+	}
+
+	return count, nil
+}
+
+// files returns the name in the snapshot and path of each file to capture.
+func files() map[string]string {
+	found := make(map[string]string)
+
+	for _, file := range procFiles {
+		found[filepath.Join(procDir, file)] = filepath.Join(linux.ProcFSRoot, file)
+	}
+
+	for _, pattern := range sysFiles {
+		matches, err := filepath.Glob(filepath.Join(linux.SysFSRoot, pattern))
+		if err != nil {
+			continue
+		}
+
+		for _, path := range matches {
+			// Only regular files are captured. Symlinks to directories (like
+			// hwmon device links) are skipped.
+			if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+
+			rel, err := filepath.Rel(linux.SysFSRoot, path)
+			if err != nil {
+				continue
+			}
+
+			found[filepath.Join(sysDir, rel)] = path
+		}
+	}
+
+	return found
+}
+
+// readFile reads the given file, returning an error if it is larger than the
+// given size. The size of procfs and sysfs files is not known until they are
+// read.
+func readFile(path string, maxSize int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file: %w", err)
+	}
+	defer file.Close()
+
+	contents, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("could not read file: %w", err)
+	}
+
+	if int64(len(contents)) > maxSize {
+		return nil, fmt.Errorf("%w: file is larger than %d bytes", ErrTooLarge, maxSize)
+	}
+
+	return contents, nil
+}
+
+// Load points the workers at the snapshot at the given path, which is either a
+// directory containing an extracted snapshot or a snapshot tarball. A tarball
+// is extracted to a temporary directory, which is removed by calling the
+// returned function. The function should be called even if an error is
+// returned. Load must be called before any workers are created.
+func Load(path string) (func() error, error) {
+	cleanup := func() error { return nil }
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return cleanup, fmt.Errorf("could not load snapshot: %w", err)
+	}
+
+	dir := path
+
+	if !info.IsDir() {
+		dir, err = os.MkdirTemp("", "go-hass-agent-snapshot-")
+		if err != nil {
+			return cleanup, fmt.Errorf("could not load snapshot: %w", err)
+		}
+
+		cleanup = func() error { return os.RemoveAll(dir) }
+
+		if err := Extract(path, dir); err != nil {
+			return cleanup, err
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, procDir)); err != nil {
+		return cleanup, fmt.Errorf("%w: no %s directory", ErrInvalidSnapshot, procDir)
+	}
+
+	linux.ProcFSRoot = filepath.Join(dir, procDir)
+	linux.SysFSRoot = filepath.Join(dir, sysDir)
+
+	// Some workers read procfs and sysfs through gopsutil, which uses these
+	// environment variables.
+	if err := errors.Join(
+		os.Setenv("HOST_PROC", linux.ProcFSRoot),
+		os.Setenv("HOST_SYS", linux.SysFSRoot),
+	); err != nil {
+		return cleanup, fmt.Errorf("could not load snapshot: %w", err)
+	}
+
+	return cleanup, nil
+}
+
+// Extract extracts the snapshot tarball at the given path into the given
+// directory.
+func Extract(path, dir string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open snapshot: %w", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+
+	archive := tar.NewReader(gz)
+
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// Only extract files inside the snapshot directories.
+		name := filepath.Clean(header.Name)
+		if !filepath.IsLocal(name) ||
+			(!strings.HasPrefix(name, procDir+string(filepath.Separator)) &&
+				!strings.HasPrefix(name, sysDir+string(filepath.Separator))) {
+			return fmt.Errorf("%w: unexpected file %s", ErrInvalidSnapshot, header.Name)
+		}
+
+		if err := extractFile(archive, filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+}
+
+// extractFile writes the contents of the given reader to a file at the given
+// path, creating any directories as needed.
+func extractFile(reader io.Reader, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), dirPerms); err != nil {
+		return fmt.Errorf("could not extract %s: %w", path, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, filePerms)
+	if err != nil {
+		return fmt.Errorf("could not extract %s: %w", path, err)
+	}
+	defer file.Close()
+
+	// Guard against decompression bombs.
+	if _, err := io.Copy(file, io.LimitReader(reader, DefaultMaxTotalSize)); err != nil {
+		return fmt.Errorf("could not extract %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/linux"
+)
+
+var testMounts = `/dev/nvme0n1p2 / ext4 rw,relatime 0 0
+server:/export /mnt/nfs nfs4 rw,addr=192.168.1.10,clientaddr=192.168.1.2 0 0
+//nas/share /mnt/share cifs rw,username=someone,domain=HOME 0 0
+`
+
+// newTestRoot creates procfs and sysfs trees with some of the files read by
+// the workers, and points the workers at them.
+func newTestRoot(t *testing.T) {
+	t.Helper()
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"proc/stat":    "cpu  1 2 3 4 5 6 7 8 9 10\n",
+		"proc/meminfo": "MemTotal: 32572792 kB\n",
+		"proc/mounts":  testMounts,
+		"proc/kcore":   "not read by workers",
+		"sys/devices/platform/coretemp.0/hwmon/hwmon0/name":        "coretemp\n",
+		"sys/devices/platform/coretemp.0/hwmon/hwmon0/temp1_input": "45000\n",
+		"sys/devices/system/cpu/cpu0/cpufreq/scaling_governor":     strings.Repeat("x", 1000),
+	})
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sys/class/hwmon"), 0o755))
+	require.NoError(t, os.Symlink(filepath.Join(root, "sys/devices/platform/coretemp.0/hwmon/hwmon0"),
+		filepath.Join(root, "sys/class/hwmon/hwmon0")))
+	require.NoError(t, os.Symlink(filepath.Join(root, "sys/devices/platform/coretemp.0"),
+		filepath.Join(root, "sys/devices/platform/coretemp.0/hwmon/hwmon0/device")))
+
+	procFSRoot, sysFSRoot := linux.ProcFSRoot, linux.SysFSRoot
+	linux.ProcFSRoot = filepath.Join(root, "proc")
+	linux.SysFSRoot = filepath.Join(root, "sys")
+
+	t.Cleanup(func() {
+		linux.ProcFSRoot, linux.SysFSRoot = procFSRoot, sysFSRoot
+	})
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	for name, contents := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+	}
+}
+
+func TestCaptureAndLoad(t *testing.T) {
+	newTestRoot(t)
+
+	tarball := filepath.Join(t.TempDir(), "snapshot.tar.gz")
+	snapshotFile, err := os.Create(tarball)
+	require.NoError(t, err)
+
+	count, err := Capture(context.TODO(), snapshotFile, Options{MaxFileSize: 500})
+	require.NoError(t, err)
+	require.NoError(t, snapshotFile.Close())
+	// The cpufreq file is larger than the maximum file size and kcore is not
+	// read by workers.
+	assert.Equal(t, 5, count)
+
+	t.Setenv("HOST_PROC", "")
+	t.Setenv("HOST_SYS", "")
+
+	cleanup, err := Load(tarball)
+	require.NoError(t, err)
+
+	dir := filepath.Dir(linux.ProcFSRoot)
+
+	for name, want := range map[string]string{
+		"proc/stat":                          "cpu  1 2 3 4 5 6 7 8 9 10\n",
+		"sys/class/hwmon/hwmon0/name":        "coretemp\n",
+		"sys/class/hwmon/hwmon0/temp1_input": "45000\n",
+	} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Equal(t, want, string(got))
+	}
+
+	assert.NoFileExists(t, filepath.Join(dir, "proc/kcore"))
+	assert.NoFileExists(t, filepath.Join(dir, "sys/devices/system/cpu/cpu0/cpufreq/scaling_governor"))
+	assert.Equal(t, linux.ProcFSRoot, os.Getenv("HOST_PROC"))
+
+	require.NoError(t, cleanup())
+	assert.NoDirExists(t, dir)
+}
+
+func TestCapture_maxTotalSize(t *testing.T) {
+	newTestRoot(t)
+
+	var snapshot bytes.Buffer
+
+	_, err := Capture(context.TODO(), &snapshot, Options{MaxTotalSize: 64})
+	require.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestLoad_invalid(t *testing.T) {
+	notSnapshot := filepath.Join(t.TempDir(), "notsnapshot")
+	require.NoError(t, os.WriteFile(notSnapshot, []byte("not a snapshot"), 0o600))
+
+	cleanup, err := Load(notSnapshot)
+	require.ErrorIs(t, err, ErrInvalidSnapshot)
+	require.NoError(t, cleanup())
+
+	cleanup, err = Load(t.TempDir())
+	require.ErrorIs(t, err, ErrInvalidSnapshot)
+	require.NoError(t, cleanup())
+}
+
+func Test_redactMounts(t *testing.T) {
+	want := `/dev/nvme0n1p2 / ext4 rw,relatime 0 0
+REDACTED /mnt/nfs nfs4 rw,addr=REDACTED,clientaddr=REDACTED 0 0
+REDACTED /mnt/share cifs rw,username=REDACTED,domain=REDACTED 0 0
+`
+	assert.Equal(t, want, string(redactMounts([]byte(testMounts))))
+}
+
+func Test_redactor_redact(t *testing.T) {
+	redact := &redactor{}
+	redact.add("/root", "/user")
+	redact.add("root", "user")
+	redact.add("myhost", "hostname")
+
+	tests := []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		{
+			name:     "whole words",
+			contents: "root /root/.config myhost.local myhost",
+			want:     "user /user/.config hostname.local hostname",
+		},
+		{
+			name:     "part of words",
+			contents: "rootfs /rootfs chroot myhosts dev-root",
+			want:     "rootfs /rootfs chroot myhosts dev-root",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(redact.redact("proc/stat", []byte(tt.contents))))
+		})
+	}
+}
@@ -9,6 +9,7 @@ package system
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"time"
 
 	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
@@ -21,7 +22,8 @@ const (
 	hwMonInterval = time.Minute
 	hwMonJitter   = 5 * time.Second
 
-	hwmonWorkerID = "hwmon_sensors"
+	// HWMonWorkerID is the ID of the hardware sensors worker.
+	HWMonWorkerID = "hwmon_sensors"
 )
 
 type hwSensor struct {
@@ -99,14 +101,16 @@ func newHWSensor(details *hwmon.Sensor) *hwSensor {
 	return newSensor
 }
 
-type hwMonWorker struct{}
+type hwMonWorker struct {
+	path string
+}
 
 func (w *hwMonWorker) Interval() time.Duration { return hwMonInterval }
 
 func (w *hwMonWorker) Jitter() time.Duration { return hwMonJitter }
 
 func (w *hwMonWorker) Sensors(_ context.Context, _ time.Duration) ([]sensor.Details, error) {
-	hwmonSensors, err := hwmon.GetAllSensors()
+	hwmonSensors, err := hwmon.GetAllSensorsAt(w.path)
 	if err != nil {
 		return nil, fmt.Errorf("could not retrieve hardware sensors: %w", err)
 	}
@@ -122,8 +126,9 @@ func (w *hwMonWorker) Sensors(_ context.Context, _ time.Duration) ([]sensor.Deta
 
 func NewHWMonWorker(_ context.Context) (*linux.SensorWorker, error) {
 	return &linux.SensorWorker{
-			Value:    &hwMonWorker{},
-			WorkerID: hwmonWorkerID,
+			// Read hwmon from the sysfs used by the other workers.
+			Value:    &hwMonWorker{path: filepath.Join(linux.SysFSRoot, "class", "hwmon")},
+			WorkerID: HWMonWorkerID,
 		},
 		nil
 }
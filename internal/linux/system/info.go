@@ -17,7 +17,8 @@ import (
 )
 
 const (
-	infoWorkerID = "system_info_sensors"
+	// InfoWorkerID is the ID of the system info worker.
+	InfoWorkerID = "system_info_sensors"
 )
 
 type infoWorker struct {
@@ -75,9 +76,9 @@ func (w *infoWorker) Sensors(_ context.Context) ([]sensor.Details, error) {
 func NewInfoWorker(ctx context.Context) (*linux.SensorWorker, error) {
 	return &linux.SensorWorker{
 			Value: &infoWorker{
-				logger: logging.FromContext(ctx).With(slog.String("worker", infoWorkerID)),
+				logger: logging.FromContext(ctx).With(slog.String("worker", InfoWorkerID)),
 			},
-			WorkerID: infoWorkerID,
+			WorkerID: InfoWorkerID,
 		},
 		nil
 }
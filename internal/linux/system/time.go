@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 
@@ -25,7 +26,8 @@ const (
 	uptimeInterval = 15 * time.Minute
 	uptimeJitter   = time.Minute
 
-	timeWorkerID = "time_sensors"
+	// TimeWorkerID is the ID of the time worker.
+	TimeWorkerID = "time_sensors"
 )
 
 type timeWorker struct {
@@ -72,7 +74,7 @@ func (w *timeWorker) Sensors(_ context.Context, _ time.Duration) ([]sensor.Detai
 // getUptime retrieve the uptime of the device running Go Hass Agent, in
 // seconds. If the uptime cannot be retrieved, it will return 0.
 func (w *timeWorker) getUptime() float64 {
-	data, err := os.Open(linux.UptimeFile)
+	data, err := os.Open(filepath.Join(linux.ProcFSRoot, "uptime"))
 	if err != nil {
 		w.logger.Debug("Unable to retrieve uptime.", slog.Any("error", err))
 
@@ -108,10 +110,10 @@ func NewTimeWorker(ctx context.Context) (*linux.SensorWorker, error) {
 
 	return &linux.SensorWorker{
 			Value: &timeWorker{
-				logger:   logging.FromContext(ctx).WithGroup(timeWorkerID),
+				logger:   logging.FromContext(ctx).WithGroup(TimeWorkerID),
 				boottime: boottime,
 			},
-			WorkerID: timeWorkerID,
+			WorkerID: TimeWorkerID,
 		},
 		nil
 }
@@ -0,0 +1,216 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package textfile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidMetric = errors.New("invalid metric")
+	ErrInvalidLabels = errors.New("unterminated label set")
+)
+
+// metric is a single sample from a Prometheus exposition format file.
+type metric struct {
+	labels     map[string]string
+	name       string
+	help       string
+	metricType string
+	value      float64
+}
+
+// metricFamily holds the HELP and TYPE metadata for a metric name.
+type metricFamily struct {
+	help       string
+	metricType string
+}
+
+// parse reads metrics in the Prometheus text exposition format. Samples with
+// non-finite values are skipped, as are histogram buckets.
+//
+//nolint:cyclop
+func parse(reader io.Reader) ([]metric, error) {
+	var metrics []metric
+
+	families := make(map[string]*metricFamily)
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#"):
+			parseComment(line, families)
+
+			continue
+		}
+
+		sample, err := parseSample(line)
+		if err != nil {
+			return nil, err
+		}
+
+		if math.IsNaN(sample.value) || math.IsInf(sample.value, 0) {
+			continue
+		}
+
+		family := findFamily(sample.name, families)
+		if family != nil {
+			sample.help = family.help
+			sample.metricType = family.metricType
+		}
+
+		if sample.metricType == "histogram" && strings.HasSuffix(sample.name, "_bucket") {
+			continue
+		}
+
+		metrics = append(metrics, *sample)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read metrics: %w", err)
+	}
+
+	return metrics, nil
+}
+
+// parseComment parses a comment line. If it is a HELP or TYPE line, the
+// metadata is added to the given families.
+func parseComment(line string, families map[string]*metricFamily) {
+	fields := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(line, "#")), " ", 3) //nolint:mnd
+	if len(fields) < 3 {                                                                //nolint:mnd
+		return
+	}
+
+	keyword, name, value := fields[0], fields[1], fields[2]
+
+	if keyword != "HELP" && keyword != "TYPE" {
+		return
+	}
+
+	family, found := families[name]
+	if !found {
+		family = &metricFamily{}
+		families[name] = family
+	}
+
+	switch keyword {
+	case "HELP":
+		family.help = strings.NewReplacer(`\\`, `\`, `\n`, "\n").Replace(value)
+	case "TYPE":
+		family.metricType = strings.TrimSpace(value)
+	}
+}
+
+// findFamily returns the family of a sample. Histogram and summary samples have
+// suffixes that are not part of the family name.
+func findFamily(name string, families map[string]*metricFamily) *metricFamily {
+	if family, found := families[name]; found {
+		return family
+	}
+
+	for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+		if family, found := families[strings.TrimSuffix(name, suffix)]; found && strings.HasSuffix(name, suffix) {
+			return family
+		}
+	}
+
+	return nil
+}
+
+// parseSample parses a sample line of the format
+// name{label="value",...} value [timestamp].
+func parseSample(line string) (*metric, error) {
+	sample := &metric{labels: make(map[string]string)}
+
+	idx := strings.IndexAny(line, "{ \t")
+	if idx == -1 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidMetric, line)
+	}
+
+	sample.name = line[:idx]
+	rest := line[idx:]
+
+	if strings.HasPrefix(rest, "{") {
+		end, err := parseLabels(rest, sample.labels)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrInvalidMetric, line, err)
+		}
+
+		rest = rest[end:]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: %q: no value", ErrInvalidMetric, line)
+	}
+
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", ErrInvalidMetric, line, err)
+	}
+
+	sample.value = value
+
+	return sample, nil
+}
+
+// parseLabels parses the label set at the start of the given string into the
+// given map. It returns the index after the closing brace.
+//
+//nolint:cyclop
+func parseLabels(value string, labels map[string]string) (int, error) {
+	var (
+		name    strings.Builder
+		current strings.Builder
+		inValue bool
+		escaped bool
+	)
+
+	for idx := 1; idx < len(value); idx++ {
+		char := value[idx]
+
+		switch {
+		case inValue && escaped:
+			switch char {
+			case 'n':
+				current.WriteByte('\n')
+			default:
+				current.WriteByte(char)
+			}
+
+			escaped = false
+		case inValue && char == '\\':
+			escaped = true
+		case inValue && char == '"':
+			labels[strings.TrimSpace(name.String())] = current.String()
+			name.Reset()
+			current.Reset()
+
+			inValue = false
+		case inValue:
+			current.WriteByte(char)
+		case char == '"':
+			inValue = true
+		case char == '=' || char == ',' || char == ' ':
+		case char == '}':
+			return idx + 1, nil
+		default:
+			name.WriteByte(char)
+		}
+	}
+
+	return 0, ErrInvalidLabels
+}
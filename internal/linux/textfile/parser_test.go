@@ -0,0 +1,100 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package textfile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parse(t *testing.T) {
+	type args struct {
+		input string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []metric
+		wantErr bool
+	}{
+		{
+			name: "gauge and counter",
+			args: args{input: `# HELP smartctl_temp Drive temperature.
+# TYPE smartctl_temp gauge
+smartctl_temp{device="/dev/sda",model="My \"SSD\""} 35
+# TYPE jobs_total counter
+jobs_total 10 1700000000000
+untyped_metric 1.5
+`},
+			want: []metric{
+				{
+					name:       "smartctl_temp",
+					help:       "Drive temperature.",
+					metricType: "gauge",
+					labels:     map[string]string{"device": "/dev/sda", "model": `My "SSD"`},
+					value:      35,
+				},
+				{
+					name:       "jobs_total",
+					metricType: "counter",
+					labels:     map[string]string{},
+					value:      10,
+				},
+				{
+					name:   "untyped_metric",
+					labels: map[string]string{},
+					value:  1.5,
+				},
+			},
+		},
+		{
+			name: "histogram and non-finite values",
+			args: args{input: `# TYPE req_duration_seconds histogram
+req_duration_seconds_bucket{le="0.5"} 3
+req_duration_seconds_sum 1.2
+req_duration_seconds_count 4
+nan_metric NaN
+`},
+			want: []metric{
+				{
+					name:       "req_duration_seconds_sum",
+					metricType: "histogram",
+					labels:     map[string]string{},
+					value:      1.2,
+				},
+				{
+					name:       "req_duration_seconds_count",
+					metricType: "histogram",
+					labels:     map[string]string{},
+					value:      4,
+				},
+			},
+		},
+		{
+			name:    "invalid value",
+			args:    args{input: `metric abc`},
+			wantErr: true,
+		},
+		{
+			name:    "unterminated labels",
+			args:    args{input: `metric{label="value" 1`},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parse(strings.NewReader(tt.args.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//revive:disable:unused-receiver
+package textfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor/types"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+	"github.com/joshuar/go-hass-agent/internal/logging"
+)
+
+const (
+	textfileUpdateInterval = time.Minute
+	textfileUpdateJitter   = 5 * time.Second
+
+	// WorkerID is the ID of the textfile worker.
+	WorkerID = "textfile_sensors"
+
+	textfileExt  = ".prom"
+	textfileIcon = "mdi:chart-line"
+
+	dataSrcTextfile = "Textfile"
+)
+
+var ErrNoTextfileDir = errors.New("textfile directory not found")
+
+var invalidIDChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// units maps the unit suffixes of metric names, as per the Prometheus naming
+// conventions, to Home Assistant units.
+var units = map[string]string{
+	"_seconds": "s",
+	"_bytes":   "B",
+	"_celsius": "°C",
+	"_percent": "%",
+	"_volts":   "V",
+	"_amperes": "A",
+	"_watts":   "W",
+	"_joules":  "J",
+	"_meters":  "m",
+	"_grams":   "g",
+}
+
+// metricSensor is a sensor generated from a Prometheus metric. The metric labels
+// are shown as attributes.
+type metricSensor struct {
+	labels map[string]string
+	linux.Sensor
+}
+
+func (s *metricSensor) Attributes() map[string]any {
+	attributes := s.Sensor.Attributes()
+
+	for label, value := range s.labels {
+		attributes[label] = value
+	}
+
+	return attributes
+}
+
+type worker struct {
+	logger *slog.Logger
+	path   string
+}
+
+func (w *worker) Interval() time.Duration { return textfileUpdateInterval }
+
+func (w *worker) Jitter() time.Duration { return textfileUpdateJitter }
+
+// Sensors reads all textfile collector files in the directory and returns a
+// sensor for each metric. Files that cannot be parsed are skipped. If the same
+// metric appears in more than one file, the first one found is used.
+func (w *worker) Sensors(ctx context.Context, _ time.Duration) ([]sensor.Details, error) {
+	files, err := filepath.Glob(filepath.Join(w.path, "*"+textfileExt))
+	if err != nil {
+		return nil, fmt.Errorf("could not search for textfiles: %w", err)
+	}
+
+	var sensors []sensor.Details
+
+	seen := make(map[string]struct{})
+
+	for _, file := range files {
+		metrics, err := readTextfile(file)
+		if err != nil {
+			w.logger.Log(ctx, logging.LevelTrace, "Could not read textfile.",
+				slog.String("file", file),
+				slog.Any("error", err))
+
+			continue
+		}
+
+		for _, m := range metrics {
+			details := newMetricSensor(m)
+
+			if _, found := seen[details.ID()]; found {
+				continue
+			}
+
+			seen[details.ID()] = struct{}{}
+
+			sensors = append(sensors, details)
+		}
+	}
+
+	return sensors, nil
+}
+
+// readTextfile reads the metrics from the given textfile.
+func readTextfile(file string) ([]metric, error) {
+	textfile, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not open textfile: %w", err)
+	}
+	defer textfile.Close()
+
+	return parse(textfile)
+}
+
+// newMetricSensor creates a sensor from the given metric. The sensor ID is
+// derived from the metric name and labels, so that it is stable between runs.
+// The HELP text of the metric, if any, is used for the sensor name. Counters
+// are treated as total increasing sensors.
+func newMetricSensor(m metric) *metricSensor {
+	labelNames := slices.Sorted(maps.Keys(m.labels))
+
+	idParts := []string{"textfile", m.name}
+	labelValues := make([]string, 0, len(labelNames))
+
+	for _, label := range labelNames {
+		idParts = append(idParts, label, m.labels[label])
+		labelValues = append(labelValues, label+"="+m.labels[label])
+	}
+
+	name := m.help
+	if name == "" {
+		name = m.name
+	}
+
+	if len(labelValues) > 0 {
+		name += " (" + strings.Join(labelValues, ", ") + ")"
+	}
+
+	metricSensor := &metricSensor{
+		labels: m.labels,
+		Sensor: linux.Sensor{
+			DisplayName:     name,
+			UniqueID:        metricID(idParts...),
+			Value:           m.value,
+			IconString:      textfileIcon,
+			DataSource:      dataSrcTextfile,
+			StateClassValue: types.StateClassMeasurement,
+		},
+	}
+
+	switch m.metricType {
+	case "counter":
+		metricSensor.StateClassValue = types.StateClassTotalIncreasing
+	case "histogram", "summary":
+		if strings.HasSuffix(m.name, "_count") || strings.HasSuffix(m.name, "_sum") {
+			metricSensor.StateClassValue = types.StateClassTotalIncreasing
+		}
+	}
+
+	for suffix, unit := range units {
+		if strings.HasSuffix(strings.TrimSuffix(strings.TrimSuffix(m.name, "_total"), "_sum"), suffix) {
+			metricSensor.UnitsString = unit
+
+			break
+		}
+	}
+
+	return metricSensor
+}
+
+// metricID generates a sensor ID from the given parts.
+func metricID(parts ...string) string {
+	id := strcase.ToSnake(strings.Join(parts, "_"))
+
+	return strings.Trim(invalidIDChars.ReplaceAllString(id, "_"), "_")
+}
+
+// NewTextfileWorker creates a worker that reads Prometheus node_exporter
+// textfile collector files from a directory and reports each metric as a
+// sensor.
+func NewTextfileWorker(ctx context.Context) (*linux.SensorWorker, error) {
+	path, found := linux.CtxGetTextfilePath(ctx)
+	if !found {
+		return nil, fmt.Errorf("%w: no textfile path value", linux.ErrInvalidCtx)
+	}
+
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%w: %s", ErrNoTextfileDir, path)
+	}
+
+	return &linux.SensorWorker{
+			Value: &worker{
+				path:   path,
+				logger: logging.FromContext(ctx).With(slog.String("worker", WorkerID)),
+			},
+			WorkerID: WorkerID,
+		},
+		nil
+}
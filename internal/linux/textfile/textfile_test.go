@@ -0,0 +1,118 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package textfile
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor/types"
+	"github.com/joshuar/go-hass-agent/internal/linux"
+)
+
+func Test_newMetricSensor(t *testing.T) {
+	tests := []struct {
+		name           string
+		metric         metric
+		wantID         string
+		wantName       string
+		wantUnits      string
+		wantStateClass types.StateClass
+	}{
+		{
+			name: "gauge with help and labels",
+			metric: metric{
+				name:       "smartctl_temperature_celsius",
+				help:       "Drive temperature",
+				metricType: "gauge",
+				labels:     map[string]string{"model": "SSD", "device": "/dev/sda"},
+				value:      35,
+			},
+			wantID:         "textfile_smartctl_temperature_celsius_device_dev_sda_model_ssd",
+			wantName:       "Drive temperature (device=/dev/sda, model=SSD)",
+			wantUnits:      "°C",
+			wantStateClass: types.StateClassMeasurement,
+		},
+		{
+			name: "counter without help",
+			metric: metric{
+				name:       "backup_duration_seconds_total",
+				metricType: "counter",
+				value:      120,
+			},
+			wantID:         "textfile_backup_duration_seconds_total",
+			wantName:       "backup_duration_seconds_total",
+			wantUnits:      "s",
+			wantStateClass: types.StateClassTotalIncreasing,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newMetricSensor(tt.metric)
+			assert.Equal(t, tt.wantID, got.ID())
+			assert.Equal(t, tt.wantName, got.Name())
+			assert.Equal(t, tt.wantUnits, got.Units())
+			assert.Equal(t, tt.wantStateClass, got.StateClass())
+			for label, value := range tt.metric.labels {
+				assert.Equal(t, value, got.Attributes()[label])
+			}
+		})
+	}
+}
+
+func Test_worker_Sensors(t *testing.T) {
+	w := &worker{path: "testing/data", logger: slog.Default()}
+
+	got, err := w.Sensors(context.TODO(), 0)
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(got))
+	for _, s := range got {
+		ids = append(ids, s.ID())
+	}
+
+	assert.ElementsMatch(t, []string{
+		"textfile_backup_last_success_timestamp_seconds_job_home",
+		"textfile_backup_runs_total_job_home_result_ok",
+		"textfile_backup_runs_total_job_home_result_failed",
+	}, ids)
+}
+
+func TestNewTextfileWorker(t *testing.T) {
+	tests := []struct {
+		ctx     context.Context
+		name    string
+		wantErr bool
+	}{
+		{
+			name: "valid directory",
+			ctx:  linux.WithTextfilePath(context.TODO(), "testing/data"),
+		},
+		{
+			name:    "missing directory",
+			ctx:     linux.WithTextfilePath(context.TODO(), "does/not/exist"),
+			wantErr: true,
+		},
+		{
+			name:    "no path in context",
+			ctx:     context.TODO(),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTextfileWorker(tt.ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewTextfileWorker() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
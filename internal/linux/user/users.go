@@ -28,7 +28,8 @@ const (
 	sensorUnits = "users"
 	sensorIcon  = "mdi:account"
 
-	usersWorkerID = "users_sensors"
+	// WorkerID is the ID of the users worker.
+	WorkerID = "users_sensors"
 )
 
 type usersSensor struct {
@@ -72,7 +73,7 @@ func (w *worker) Events(ctx context.Context) (chan sensor.Details, error) {
 	sendUpdate := func() {
 		users, err := w.sensor.getUsers()
 		if err != nil {
-			slog.With(slog.String("worker", usersWorkerID)).Debug("Failed to get list of user sessions.", slog.Any("error", err))
+			slog.With(slog.String("worker", WorkerID)).Debug("Failed to get list of user sessions.", slog.Any("error", err))
 		} else {
 			w.sensor.userNames = users
 			sensorCh <- w.sensor
@@ -144,7 +145,7 @@ func NewUserWorker(ctx context.Context) (*linux.SensorWorker, error) {
 				sensor:    usersSensor,
 				triggerCh: triggerCh,
 			},
-			WorkerID: usersWorkerID,
+			WorkerID: WorkerID,
 		},
 		nil
 }
@@ -17,7 +17,10 @@ import (
 	"github.com/joshuar/go-hass-agent/internal/logging"
 )
 
-var ErrUnknownWorker = errors.New("unknown sensor worker type")
+var (
+	ErrUnknownWorker    = errors.New("unknown sensor worker type")
+	ErrNotPollingWorker = errors.New("not a polling sensor worker")
+)
 
 // pollingType interface represents sensors that are generated on some poll interval.
 type pollingType interface {
@@ -46,6 +49,13 @@ type SensorWorker struct {
 	cancelFunc context.CancelFunc
 	logger     *slog.Logger
 	WorkerID   string
+	// errorHandler, if set, is called with any error that occurs while the
+	// worker is sending updates.
+	errorHandler func(err error)
+	// interval and jitter override the values requested by a polling
+	// worker, when non-zero.
+	interval time.Duration
+	jitter   time.Duration
 }
 
 // ID is a name that can be used as an ID to represent the group of sensors
@@ -65,6 +75,47 @@ func (w *SensorWorker) Stop() error {
 	return nil
 }
 
+// SetPollInterval overrides the poll interval and jitter requested by a polling
+// worker. A zero value keeps the value requested by the worker. If the worker
+// is not a polling worker, a non-nil error is returned.
+func (w *SensorWorker) SetPollInterval(interval, jitter time.Duration) error {
+	if _, ok := w.Value.(pollingType); !ok {
+		return ErrNotPollingWorker
+	}
+
+	w.interval = interval
+	w.jitter = jitter
+
+	return nil
+}
+
+// SetErrorHandler sets a function that will be called with any error that
+// occurs while the worker is sending updates. It should be called before
+// Updates.
+func (w *SensorWorker) SetErrorHandler(handler func(err error)) {
+	w.errorHandler = handler
+}
+
+// OneShot returns whether the worker sends its sensors once and then closes
+// its updates channel.
+func (w *SensorWorker) OneShot() bool {
+	switch w.Value.(type) {
+	case pollingType, eventType:
+		return false
+	case oneShotType:
+		return true
+	}
+
+	return false
+}
+
+// reportError passes the given error to the error handler, if any.
+func (w *SensorWorker) reportError(err error) {
+	if w.errorHandler != nil {
+		w.errorHandler(err)
+	}
+}
+
 // Sensors returns the current values of all sensors managed by this
 // SensorWorker. If the values cannot be retrieved, it will return a non-nil
 // error.
@@ -145,6 +196,8 @@ func (w *SensorWorker) handlePolling(ctx context.Context, worker pollingType) ch
 		sensors, err := worker.Sensors(ctx, d)
 		if err != nil {
 			w.logger.Error("Worker error occurred.", slog.Any("error", err))
+			w.reportError(err)
+
 			return
 		}
 
@@ -157,9 +210,18 @@ func (w *SensorWorker) handlePolling(ctx context.Context, worker pollingType) ch
 			outCh <- s
 		}
 	}
+	interval, jitter := worker.Interval(), worker.Jitter()
+	if w.interval > 0 {
+		interval = w.interval
+	}
+
+	if w.jitter > 0 {
+		jitter = w.jitter
+	}
+
 	go func() {
 		defer close(outCh)
-		helpers.PollSensors(ctx, updater, worker.Interval(), worker.Jitter())
+		helpers.PollSensors(ctx, updater, interval, jitter)
 	}()
 
 	return outCh
@@ -175,6 +237,7 @@ func (w *SensorWorker) handleEvents(ctx context.Context, worker eventType) chan
 		eventCh, err := worker.Events(ctx)
 		if err != nil {
 			w.logger.Debug("Unable to retrieve sensor events.", slog.Any("error", err))
+			w.reportError(err)
 
 			return
 		}
@@ -198,6 +261,7 @@ func (w *SensorWorker) handleOneShot(ctx context.Context, worker oneShotType) ch
 		sensors, err := worker.Sensors(ctx)
 		if err != nil {
 			w.logger.Debug("Unable to retrieve sensors.", slog.Any("error", err))
+			w.reportError(err)
 
 			return
 		}
@@ -0,0 +1,314 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// journalSocket is the socket journald listens on for native protocol
+	// messages.
+	journalSocket = "/run/systemd/journal/socket"
+	// journalFieldMaxLength is the longest field name journald accepts.
+	journalFieldMaxLength = 64
+)
+
+// journalReservedFields are the journal fields with a meaning to journald or
+// journalctl. Attributes with these names are prefixed with "ATTR_" so that they
+// do not replace the fields set by the handler or confuse journalctl. See
+// systemd.journal-fields(7).
+var journalReservedFields = map[string]bool{
+	"MESSAGE":            true,
+	"MESSAGE_ID":         true,
+	"PRIORITY":           true,
+	"CODE_FILE":          true,
+	"CODE_LINE":          true,
+	"CODE_FUNC":          true,
+	"ERRNO":              true,
+	"INVOCATION_ID":      true,
+	"USER_INVOCATION_ID": true,
+	"SYSLOG_FACILITY":    true,
+	"SYSLOG_IDENTIFIER":  true,
+	"SYSLOG_PID":         true,
+	"SYSLOG_TIMESTAMP":   true,
+	"SYSLOG_RAW":         true,
+	"DOCUMENTATION":      true,
+	"TID":                true,
+	"UNIT":               true,
+	"USER_UNIT":          true,
+}
+
+// journalHandler is a slog.Handler that sends records to journald using its
+// native protocol, so that attributes are stored as journal fields and can be
+// filtered on with journalctl. Attribute keys are converted to valid journal
+// field names, for example "error" becomes "ERROR", attributes in a group
+// "worker" with key "id" become "WORKER_ID" and "message" becomes
+// "ATTR_MESSAGE".
+type journalHandler struct {
+	conn       *net.UnixConn
+	level      slog.Leveler
+	identifier string
+	prefix     string
+	fields     []byte
+	addSource  bool
+}
+
+// newJournalHandler creates a journalHandler that sends records at or above the
+// given level to the journald socket at the given path.
+func newJournalHandler(socket string, level slog.Leveler, addSource bool) (*journalHandler, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to journal: %w", err)
+	}
+
+	return &journalHandler{
+		conn:       conn,
+		level:      level,
+		identifier: filepath.Base(os.Args[0]),
+		addSource:  addSource,
+	}, nil
+}
+
+// connectedToJournal reports whether stdout of the agent is connected to the
+// journal, as is the case when it is run as a systemd service. See
+// systemd.exec(5) for details of JOURNAL_STREAM.
+func connectedToJournal() bool {
+	stream := os.Getenv("JOURNAL_STREAM")
+	if stream == "" {
+		return false
+	}
+
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+
+	return stream == strconv.FormatUint(stat.Dev, 10)+":"+strconv.FormatUint(stat.Ino, 10)
+}
+
+func (h *journalHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+//nolint:gocritic // slog.Handler requires the record be passed by value.
+func (h *journalHandler) Handle(_ context.Context, record slog.Record) error {
+	var buf bytes.Buffer
+
+	appendJournalField(&buf, "MESSAGE", record.Message)
+	appendJournalField(&buf, "PRIORITY", strconv.Itoa(journalPriority(record.Level)))
+	appendJournalField(&buf, "SYSLOG_IDENTIFIER", h.identifier)
+
+	if h.addSource && record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		appendJournalField(&buf, "CODE_FILE", frame.File)
+		appendJournalField(&buf, "CODE_LINE", strconv.Itoa(frame.Line))
+		appendJournalField(&buf, "CODE_FUNC", frame.Function)
+	}
+
+	buf.Write(h.fields)
+
+	record.Attrs(func(attr slog.Attr) bool {
+		appendJournalAttr(&buf, h.prefix, attr)
+
+		return true
+	})
+
+	_, err := h.conn.Write(buf.Bytes())
+	// Messages too large for a datagram are passed in a memfd instead, as
+	// sd_journal_sendv(3) does.
+	if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+		err = h.writeMemfd(buf.Bytes())
+	}
+
+	if err != nil {
+		return fmt.Errorf("could not write to journal: %w", err)
+	}
+
+	return nil
+}
+
+// writeMemfd sends the given message to journald in a sealed memfd.
+func (h *journalHandler) writeMemfd(msg []byte) error {
+	fd, err := unix.MemfdCreate("journal-message", unix.MFD_ALLOW_SEALING|unix.MFD_CLOEXEC)
+	if err != nil {
+		return fmt.Errorf("could not create memfd: %w", err)
+	}
+
+	file := os.NewFile(uintptr(fd), "journal-message")
+	defer file.Close()
+
+	if _, err := file.Write(msg); err != nil {
+		return fmt.Errorf("could not write memfd: %w", err)
+	}
+
+	// journald only accepts sealed memfds.
+	seals := unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE | unix.F_SEAL_SEAL
+	if _, err := unix.FcntlInt(file.Fd(), unix.F_ADD_SEALS, seals); err != nil {
+		return fmt.Errorf("could not seal memfd: %w", err)
+	}
+
+	// journald expects the memfd in a datagram without any data, which
+	// net.UnixConn will not send.
+	rawConn, err := h.conn.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("could not send memfd: %w", err)
+	}
+
+	var sendErr error
+
+	err = rawConn.Write(func(sock uintptr) bool {
+		sendErr = unix.Sendmsg(int(sock), nil, unix.UnixRights(int(file.Fd())), nil, 0)
+
+		return !errors.Is(sendErr, unix.EAGAIN)
+	})
+	if err = errors.Join(err, sendErr); err != nil {
+		return fmt.Errorf("could not send memfd: %w", err)
+	}
+
+	return nil
+}
+
+func (h *journalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handler := *h
+
+	fields := bytes.NewBuffer(append([]byte{}, h.fields...))
+	for _, attr := range attrs {
+		appendJournalAttr(fields, h.prefix, attr)
+	}
+
+	handler.fields = fields.Bytes()
+
+	return &handler
+}
+
+func (h *journalHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	handler := *h
+	handler.prefix = h.prefix + name + "_"
+
+	return &handler
+}
+
+// journalPriority returns the syslog priority for the given level.
+func journalPriority(level slog.Level) int {
+	switch {
+	case level >= LevelFatal:
+		return 2 // crit
+	case level >= slog.LevelError:
+		return 3 // err
+	case level >= slog.LevelWarn:
+		return 4 // warning
+	case level >= slog.LevelInfo:
+		return 6 // info
+	default:
+		return 7 // debug
+	}
+}
+
+// appendJournalAttr appends the given attribute as journal fields. Groups are
+// flattened, with the group name as a prefix.
+func appendJournalAttr(buf *bytes.Buffer, prefix string, attr slog.Attr) {
+	value := attr.Value.Resolve()
+
+	if value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix = prefix + attr.Key + "_"
+		}
+
+		for _, groupAttr := range value.Group() {
+			appendJournalAttr(buf, prefix, groupAttr)
+		}
+
+		return
+	}
+
+	if attr.Key == "" {
+		return
+	}
+
+	var formatted string
+
+	if err, ok := value.Any().(error); ok && value.Kind() == slog.KindAny {
+		formatted = err.Error()
+	} else {
+		formatted = value.String()
+	}
+
+	appendJournalField(buf, journalFieldName(prefix+attr.Key), formatted)
+}
+
+// appendJournalField appends the given field in the journal native format.
+// Values containing newlines use the binary format.
+func appendJournalField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(name + "=" + value + "\n")
+
+		return
+	}
+
+	buf.WriteString(name + "\n")
+
+	if err := binary.Write(buf, binary.LittleEndian, uint64(len(value))); err != nil {
+		return
+	}
+
+	buf.WriteString(value + "\n")
+}
+
+// journalFieldName converts the given attribute key to a valid journal field
+// name. Journal field names can only contain upper case letters, digits and
+// underscores, and cannot start with an underscore or digit. Names of reserved
+// fields are prefixed, so attributes cannot replace them.
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+
+	name = strings.TrimLeft(name, "_")
+
+	switch {
+	case name == "" || (name[0] >= '0' && name[0] <= '9'):
+		name = "FIELD_" + name
+	case journalReservedFields[name]:
+		name = "ATTR_" + name
+	}
+
+	if len(name) > journalFieldMaxLength {
+		name = name[:journalFieldMaxLength]
+	}
+
+	return name
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// readJournalFields reads a message sent to the given fake journal socket and
+// returns its fields. Messages sent in a memfd are read from the memfd.
+func readJournalFields(t *testing.T, conn *net.UnixConn) map[string]string {
+	t.Helper()
+
+	buf := make([]byte, 65536)
+	oob := make([]byte, unix.CmsgSpace(4))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	require.NoError(t, err)
+
+	data := buf[:n]
+
+	if oobn > 0 {
+		msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+		require.NoError(t, err)
+		require.Len(t, msgs, 1)
+
+		fds, err := unix.ParseUnixRights(&msgs[0])
+		require.NoError(t, err)
+		require.Len(t, fds, 1)
+
+		file := os.NewFile(uintptr(fds[0]), "memfd")
+		defer file.Close()
+
+		// The file offset is shared with the sender, so read from the start.
+		data, err = io.ReadAll(io.NewSectionReader(file, 0, math.MaxInt64))
+		require.NoError(t, err)
+	}
+
+	fields := make(map[string]string)
+
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		require.NotEqual(t, -1, end)
+
+		line := data[:end]
+		data = data[end+1:]
+
+		if name, value, found := bytes.Cut(line, []byte("=")); found {
+			fields[string(name)] = string(value)
+
+			continue
+		}
+
+		// Binary format: the length of the value follows the name.
+		length := binary.LittleEndian.Uint64(data[:8])
+		fields[string(line)] = string(data[8 : 8+length])
+		data = data[8+length+1:]
+	}
+
+	return fields
+}
+
+func TestJournalHandler(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	handler, err := newJournalHandler(socket, slog.LevelInfo, false)
+	require.NoError(t, err)
+
+	logger := slog.New(handler)
+
+	logger.Debug("Not sent.")
+	logger.With(slog.String("worker", "cpu"), slog.String("priority", "high")).
+		WithGroup("sensor").
+		Warn("Update failed.",
+			slog.Any("error", errors.New("first line\nsecond line")),
+			slog.Group("value", slog.Int("raw", 5)),
+			slog.String("9lives", "cat"))
+
+	fields := readJournalFields(t, conn)
+	assert.Equal(t, "Update failed.", fields["MESSAGE"])
+	assert.Equal(t, "4", fields["PRIORITY"])
+	assert.Equal(t, "cpu", fields["WORKER"])
+	assert.Equal(t, "first line\nsecond line", fields["SENSOR_ERROR"])
+	assert.Equal(t, "5", fields["SENSOR_VALUE_RAW"])
+	assert.Equal(t, "cat", fields["SENSOR_9LIVES"])
+	assert.Equal(t, "high", fields["ATTR_PRIORITY"])
+	assert.NotContains(t, fields, "CODE_FILE")
+}
+
+func TestJournalHandler_largeMessage(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	handler, err := newJournalHandler(socket, slog.LevelInfo, false)
+	require.NoError(t, err)
+
+	// Larger than the default socket buffer, so too large for a datagram.
+	large := strings.Repeat("a", 4*1024*1024)
+
+	slog.New(handler).Info("Large message.", slog.String("data", large))
+
+	fields := readJournalFields(t, conn)
+	assert.Equal(t, "Large message.", fields["MESSAGE"])
+	assert.Equal(t, large, fields["DATA"])
+}
+
+func Test_journalFieldName(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "error", want: "ERROR"},
+		{key: "worker.id", want: "WORKER_ID"},
+		{key: "_private", want: "PRIVATE"},
+		{key: "9lives", want: "FIELD_9LIVES"},
+		{key: "_", want: "FIELD_"},
+		{key: "message", want: "ATTR_MESSAGE"},
+		{key: "priority", want: "ATTR_PRIORITY"},
+		{key: "syslog_identifier", want: "ATTR_SYSLOG_IDENTIFIER"},
+		{key: "sensor.message", want: "SENSOR_MESSAGE"},
+		{key: string(bytes.Repeat([]byte("a"), 70)), want: string(bytes.Repeat([]byte("A"), 64))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			assert.Equal(t, tt.want, journalFieldName(tt.key))
+		})
+	}
+}
+
+func Test_journalPriority(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  int
+	}{
+		{level: LevelTrace, want: 7},
+		{level: slog.LevelDebug, want: 7},
+		{level: slog.LevelInfo, want: 6},
+		{level: slog.LevelWarn, want: 4},
+		{level: slog.LevelError, want: 3},
+		{level: LevelFatal, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			assert.Equal(t, tt.want, journalPriority(tt.level))
+		})
+	}
+}
@@ -35,15 +35,7 @@ func New(level string, logFile string) *slog.Logger {
 		handler  slog.Handler
 	)
 
-	// Set the log level.
-	switch level {
-	case "trace":
-		logLevel = LevelTrace
-	case "debug":
-		logLevel = slog.LevelDebug
-	default:
-		logLevel = slog.LevelInfo
-	}
+	logLevel = parseLevel(level)
 
 	// Set the slog handler
 	// Unless no log file was requested, set up file logging.
@@ -55,13 +47,13 @@ func New(level string, logFile string) *slog.Logger {
 				slog.Any("error", err))
 		} else {
 			handler = slogmulti.Fanout(
-				tint.NewHandler(os.Stdout, generateOptions(logLevel, os.Stdout.Fd())),
+				newStdoutHandler(logLevel),
 				tint.NewHandler(logFH, generateOptions(logLevel, logFH.Fd())),
 			)
 		}
 	} else {
 		handler = slogmulti.Fanout(
-			tint.NewHandler(os.Stdout, generateOptions(logLevel, os.Stdout.Fd())),
+			newStdoutHandler(logLevel),
 		)
 	}
 
@@ -72,6 +64,45 @@ func New(level string, logFile string) *slog.Logger {
 	return logger
 }
 
+// newStdoutHandler creates the handler for logging to stdout. When stdout is
+// connected to the journal, records are sent to journald directly instead, so
+// that their attributes are kept as journal fields.
+func newStdoutHandler(level slog.Level) slog.Handler {
+	if connectedToJournal() {
+		handler, err := newJournalHandler(journalSocket, level, level == LevelTrace)
+		if err == nil {
+			return handler
+		}
+
+		slog.Warn("Unable to log to journal.", slog.Any("error", err))
+	}
+
+	return tint.NewHandler(os.Stdout, generateOptions(level, os.Stdout.Fd()))
+}
+
+// NewStderr creates a logger at the given level that only logs to stderr. It is
+// used by commands that write their own output to stdout.
+func NewStderr(level string) *slog.Logger {
+	logger := slog.New(tint.NewHandler(os.Stderr, generateOptions(parseLevel(level), os.Stderr.Fd())))
+
+	slog.SetDefault(logger)
+
+	return logger
+}
+
+// parseLevel returns the log level with the given name. Unknown names are
+// treated as "info".
+func parseLevel(level string) slog.Level {
+	switch level {
+	case "trace":
+		return LevelTrace
+	case "debug":
+		return slog.LevelDebug
+	default:
+		return slog.LevelInfo
+	}
+}
+
 func generateOptions(level slog.Level, fd uintptr) *tint.Options {
 	opts := &tint.Options{
 		Level:   level,
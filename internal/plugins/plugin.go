@@ -0,0 +1,600 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package plugins runs external sensor plugins. A plugin is a long-lived
+// executable that speaks JSON-RPC 2.0 over its stdin and stdout, one message
+// per line. Plugins stream sensor updates to the agent and can declare MQTT
+// entities, whose commands from Home Assistant are sent back to the plugin.
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/iancoleman/strcase"
+
+	mqtthass "github.com/joshuar/go-hass-anything/v11/pkg/hass"
+	mqttapi "github.com/joshuar/go-hass-anything/v11/pkg/mqtt"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/logging"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/scripts"
+)
+
+const (
+	// IDPrefix is the prefix of the worker ID of every plugin, which is
+	// followed by the plugin file name.
+	IDPrefix = "plugin_"
+
+	// initTimeout is how long a plugin has to respond to the initialize
+	// request.
+	initTimeout = 10 * time.Second
+	// commandTimeout is how long a plugin has to respond to an entity.command
+	// request.
+	commandTimeout = 10 * time.Second
+	// msgBufferSize is the number of entity states that can be waiting to be
+	// published to MQTT before further states are dropped.
+	msgBufferSize = 16
+)
+
+var (
+	ErrUnsupportedProtocol = errors.New("unsupported plugin protocol version")
+	ErrInvalidSensor       = errors.New("invalid sensor from plugin")
+	ErrUnknownEntity       = errors.New("unknown entity")
+	ErrNotRunning          = errors.New("plugin is not running")
+)
+
+// Plugin is a Worker for a plugin executable. It also provides the MQTT
+// entities declared by the plugin.
+type Plugin struct {
+	device       *mqtthass.Device
+	logger       *slog.Logger
+	errorHandler func(err error)
+	proc         *process
+	msgCh        chan *mqttapi.Msg
+	// stateTopics maps entity IDs to their MQTT state topic.
+	stateTopics  map[string]string
+	path         string
+	id           string
+	agentVersion string
+	declaration  Declaration
+	buttons      []*mqtthass.ButtonEntity
+	switches     []*mqtthass.SwitchEntity
+	numbers      []*mqtthass.NumberEntity[float64]
+	mu           sync.Mutex
+}
+
+// New creates a Plugin for the executable at the given path. The plugin is run
+// once to find the entities it declares. If device is nil, entities are
+// ignored.
+func New(ctx context.Context, path, agentVersion string, device *mqtthass.Device) (*Plugin, error) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	id := IDPrefix + strcase.ToSnake(name)
+
+	plugin := &Plugin{
+		device:       device,
+		logger:       logging.FromContext(ctx).With(slog.String("plugin", id)),
+		path:         path,
+		id:           id,
+		agentVersion: agentVersion,
+		stateTopics:  make(map[string]string),
+	}
+
+	proc, err := plugin.start(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	declaration, err := plugin.initialize(ctx, proc)
+	if err := errors.Join(err, proc.stop()); err != nil {
+		return nil, fmt.Errorf("could not load plugin %s: %w", path, err)
+	}
+
+	plugin.declaration = declaration
+
+	if device != nil {
+		plugin.msgCh = make(chan *mqttapi.Msg, msgBufferSize)
+		plugin.generateEntities(declaration.Entities)
+	}
+
+	return plugin, nil
+}
+
+// ID returns the ID of the plugin, derived from its file name.
+func (p *Plugin) ID() string { return p.id }
+
+// Name returns the name the plugin declared for itself.
+func (p *Plugin) Name() string { return p.declaration.Name }
+
+// SetErrorHandler sets the function called with any errors in the messages
+// from the plugin.
+func (p *Plugin) SetErrorHandler(handler func(err error)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.errorHandler = handler
+}
+
+// Updates starts the plugin and returns a channel of the sensor updates it
+// sends. The channel is closed when the plugin exits or the context is
+// canceled.
+func (p *Plugin) Updates(ctx context.Context) (<-chan sensor.Details, error) {
+	sensorCh := make(chan sensor.Details)
+
+	proc, err := p.start(ctx, sensorCh)
+	if err != nil {
+		close(sensorCh)
+
+		return sensorCh, err
+	}
+
+	p.mu.Lock()
+	previous := p.proc
+	p.proc = proc
+	p.mu.Unlock()
+
+	if previous != nil {
+		if err := previous.stop(); err != nil {
+			p.logger.Debug("Could not stop previous plugin process.", slog.Any("error", err))
+		}
+	}
+
+	// Initialize in the background, as the plugin may send sensor updates
+	// before it responds.
+	go func() {
+		if _, err := p.initialize(ctx, proc); err != nil {
+			p.reportError(err)
+
+			if err := proc.stop(); err != nil {
+				p.logger.Debug("Could not stop plugin.", slog.Any("error", err))
+			}
+		}
+	}()
+
+	go func() {
+		defer close(sensorCh)
+
+		select {
+		case <-proc.done:
+		case <-ctx.Done():
+			if err := proc.stop(); err != nil {
+				p.logger.Debug("Could not stop plugin.", slog.Any("error", err))
+			}
+		}
+	}()
+
+	return sensorCh, nil
+}
+
+// Sensors returns the current value of all sensors of the plugin. If the
+// plugin is not running, it is started just for the request.
+func (p *Plugin) Sensors(ctx context.Context) ([]sensor.Details, error) {
+	p.mu.Lock()
+	proc := p.proc
+	p.mu.Unlock()
+
+	if proc == nil || proc.exited() {
+		var err error
+
+		proc, err = p.start(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		defer proc.stop() //nolint:errcheck
+
+		if _, err := p.initialize(ctx, proc); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &sensorsResult{}
+
+	if err := proc.conn.call(ctx, methodSensors, nil, result); err != nil {
+		return nil, fmt.Errorf("could not get sensors: %w", err)
+	}
+
+	sensors := make([]sensor.Details, 0, len(result.Sensors))
+
+	for _, details := range result.Sensors {
+		if details == nil || details.ID() == "" {
+			p.reportError(fmt.Errorf("%w: sensor has no name or ID", ErrInvalidSensor))
+
+			continue
+		}
+
+		sensors = append(sensors, details)
+	}
+
+	return sensors, nil
+}
+
+// Stop stops the plugin.
+func (p *Plugin) Stop() error {
+	p.mu.Lock()
+	proc := p.proc
+	p.proc = nil
+	p.mu.Unlock()
+
+	if proc == nil {
+		return nil
+	}
+
+	return proc.stop()
+}
+
+// start starts a new process of the plugin. Sensor updates from the process are
+// sent on sensorCh, or dropped if it is nil.
+func (p *Plugin) start(ctx context.Context, sensorCh chan<- sensor.Details) (*process, error) {
+	handler := func(proc *process, msg *message) {
+		p.handle(ctx, proc, sensorCh, msg)
+	}
+
+	proc, err := startProcess(p.path, p.logger, handler, p.reportError)
+	if err != nil {
+		return nil, err
+	}
+
+	return proc, nil
+}
+
+// initialize sends the initialize request to the given process and returns the
+// declaration of the plugin.
+func (p *Plugin) initialize(ctx context.Context, proc *process) (Declaration, error) {
+	ctx, cancelFunc := context.WithTimeout(ctx, initTimeout)
+	defer cancelFunc()
+
+	var declaration Declaration
+
+	params := &initializeParams{
+		AgentVersion:    p.agentVersion,
+		ProtocolVersion: protocolVersion,
+	}
+
+	if err := proc.conn.call(ctx, methodInitialize, params, &declaration); err != nil {
+		return declaration, fmt.Errorf("could not initialize plugin: %w", err)
+	}
+
+	if declaration.ProtocolVersion != protocolVersion {
+		return declaration, fmt.Errorf("%w: %d", ErrUnsupportedProtocol, declaration.ProtocolVersion)
+	}
+
+	return declaration, nil
+}
+
+// handle handles a request or notification from the plugin.
+func (p *Plugin) handle(ctx context.Context, proc *process, sensorCh chan<- sensor.Details, msg *message) {
+	var err error
+
+	switch msg.Method {
+	case methodSensorUpdate:
+		err = p.handleSensorUpdate(ctx, sensorCh, msg.Params)
+	case methodEntityState:
+		err = p.handleEntityState(msg.Params)
+	case methodLog:
+		err = p.handleLog(ctx, msg.Params)
+	default:
+		err = fmt.Errorf("%w: unknown method %q", ErrInvalidMessage, msg.Method)
+
+		if msg.ID != nil {
+			if replyErr := proc.conn.reply(msg.ID, nil, &RPCError{Code: codeMethodNotFound, Message: err.Error()}); replyErr != nil {
+				p.logger.Debug("Could not reply to plugin.", slog.Any("error", replyErr))
+			}
+		}
+
+		p.reportError(err)
+
+		return
+	}
+
+	if err != nil {
+		p.reportError(err)
+	}
+
+	// Acknowledge requests, so that plugins can wait for a message to be
+	// handled.
+	if msg.ID != nil {
+		if replyErr := proc.conn.reply(msg.ID, err == nil, nil); replyErr != nil {
+			p.logger.Debug("Could not reply to plugin.", slog.Any("error", replyErr))
+		}
+	}
+}
+
+func (p *Plugin) handleSensorUpdate(ctx context.Context, sensorCh chan<- sensor.Details, params json.RawMessage) error {
+	details := &scripts.ScriptSensor{}
+
+	if err := json.Unmarshal(params, details); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSensor, err)
+	}
+
+	if details.ID() == "" {
+		return fmt.Errorf("%w: sensor has no name or ID", ErrInvalidSensor)
+	}
+
+	if sensorCh == nil {
+		return nil
+	}
+
+	select {
+	case sensorCh <- details:
+	case <-ctx.Done():
+	}
+
+	return nil
+}
+
+func (p *Plugin) handleEntityState(params json.RawMessage) error {
+	state := &stateParams{}
+
+	if err := json.Unmarshal(params, state); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+	}
+
+	// Entities are ignored without MQTT.
+	if p.msgCh == nil {
+		return nil
+	}
+
+	topic, found := p.stateTopics[state.ID]
+	if !found {
+		return fmt.Errorf("%w: %s", ErrUnknownEntity, state.ID)
+	}
+
+	payload := []byte(state.State)
+
+	var value string
+	if err := json.Unmarshal(state.State, &value); err == nil {
+		payload = []byte(value)
+	}
+
+	select {
+	case p.msgCh <- mqttapi.NewMsg(topic, payload).Retain():
+	default:
+		p.logger.Debug("Dropping entity state, too many waiting to be published.", slog.String("entity", state.ID))
+	}
+
+	return nil
+}
+
+func (p *Plugin) handleLog(ctx context.Context, params json.RawMessage) error {
+	entry := &logParams{}
+
+	if err := json.Unmarshal(params, entry); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+	}
+
+	level := slog.LevelInfo
+	if err := level.UnmarshalText([]byte(entry.Level)); err != nil {
+		level = slog.LevelInfo
+	}
+
+	p.logger.Log(ctx, level, entry.Message)
+
+	return nil
+}
+
+// reportError logs the given error and passes it to the error handler, if
+// set.
+func (p *Plugin) reportError(err error) {
+	p.logger.Warn("Plugin error.", slog.Any("error", err))
+
+	p.mu.Lock()
+	handler := p.errorHandler
+	p.mu.Unlock()
+
+	if handler != nil {
+		handler(err)
+	}
+}
+
+// Subscriptions are the MQTT subscriptions for the commands of the entities of
+// the plugin.
+func (p *Plugin) Subscriptions() []*mqttapi.Subscription {
+	subs := make([]*mqttapi.Subscription, 0, len(p.buttons)+len(p.switches)+len(p.numbers))
+
+	for _, entity := range p.buttons {
+		subs = appendIfValid(subs, entity.MarshalSubscription, p.logger)
+	}
+
+	for _, entity := range p.switches {
+		subs = appendIfValid(subs, entity.MarshalSubscription, p.logger)
+	}
+
+	for _, entity := range p.numbers {
+		subs = appendIfValid(subs, entity.MarshalSubscription, p.logger)
+	}
+
+	return subs
+}
+
+// Configs are the MQTT configurations for the entities of the plugin.
+func (p *Plugin) Configs() []*mqttapi.Msg {
+	configs := make([]*mqttapi.Msg, 0, len(p.buttons)+len(p.switches)+len(p.numbers))
+
+	for _, entity := range p.buttons {
+		configs = appendIfValid(configs, entity.MarshalConfig, p.logger)
+	}
+
+	for _, entity := range p.switches {
+		configs = appendIfValid(configs, entity.MarshalConfig, p.logger)
+	}
+
+	for _, entity := range p.numbers {
+		configs = appendIfValid(configs, entity.MarshalConfig, p.logger)
+	}
+
+	return configs
+}
+
+// Msgs returns a channel of the entity states sent by the plugin.
+func (p *Plugin) Msgs() chan *mqttapi.Msg {
+	return p.msgCh
+}
+
+// generateEntities creates the MQTT entities declared by the plugin.
+func (p *Plugin) generateEntities(entities []Entity) {
+	for _, declared := range entities {
+		if declared.ID == "" || declared.Name == "" {
+			p.logger.Warn("Ignoring entity without an ID or name.", slog.String("entity", declared.ID))
+
+			continue
+		}
+
+		id := strcase.ToSnake(p.device.Name + "_" + declared.ID)
+
+		entity := mqtthass.NewEntity(preferences.AppName, declared.Name, id).
+			WithOriginInfo(preferences.MQTTOrigin()).
+			WithDeviceInfo(p.device).
+			WithIcon(entityIcon(declared)).
+			WithCommandCallback(p.commandCallback(declared.ID))
+
+		switch declared.Type {
+		case EntityButton:
+			button := mqtthass.AsButton(entity)
+			p.buttons = append(p.buttons, button)
+			p.stateTopics[declared.ID] = button.GetTopics().State
+		case EntitySwitch:
+			sw := mqtthass.AsSwitch(entity, true)
+			p.switches = append(p.switches, sw)
+			p.stateTopics[declared.ID] = sw.GetTopics().State
+		case EntityNumber:
+			if declared.Max == 0 {
+				declared.Max = 100
+			}
+
+			if declared.Step == 0 {
+				declared.Step = 1
+			}
+
+			number := mqtthass.AsNumber(entity, declared.Step, declared.Min, declared.Max, numberDisplay(declared.Display))
+			p.numbers = append(p.numbers, number)
+			p.stateTopics[declared.ID] = number.GetTopics().State
+		default:
+			p.logger.Warn("Ignoring entity of unknown type.",
+				slog.String("entity", declared.ID),
+				slog.String("type", declared.Type))
+		}
+	}
+}
+
+// commandCallback returns a callback that sends commands for the entity with
+// the given ID to the running plugin.
+func (p *Plugin) commandCallback(id string) func(msg *paho.Publish) {
+	return func(msg *paho.Publish) {
+		p.mu.Lock()
+		proc := p.proc
+		p.mu.Unlock()
+
+		if proc == nil {
+			p.logger.Warn("Cannot send command to plugin.", slog.String("entity", id), slog.Any("error", ErrNotRunning))
+
+			return
+		}
+
+		ctx, cancelFunc := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancelFunc()
+
+		if err := proc.conn.call(ctx, methodCommand, &commandParams{ID: id, Payload: string(msg.Payload)}, nil); err != nil {
+			p.logger.Warn("Plugin command failed.", slog.String("entity", id), slog.Any("error", err))
+		}
+	}
+}
+
+// entityIcon returns the icon of the given entity, or a default icon for its
+// type.
+func entityIcon(entity Entity) string {
+	switch {
+	case entity.Icon != "":
+		return entity.Icon
+	case entity.Type == EntitySwitch:
+		return "mdi:toggle-switch"
+	case entity.Type == EntityNumber:
+		return "mdi:knob"
+	default:
+		return "mdi:button-pointer"
+	}
+}
+
+// numberDisplay returns the display mode of a number entity.
+func numberDisplay(display string) mqtthass.NumberMode {
+	switch display {
+	case "box":
+		return mqtthass.NumberBox
+	case "slider":
+		return mqtthass.NumberSlider
+	default:
+		return mqtthass.NumberAuto
+	}
+}
+
+// appendIfValid appends the result of the given marshal function to the given
+// slice, logging any error.
+func appendIfValid[T any](items []*T, marshal func() (*T, error), logger *slog.Logger) []*T {
+	item, err := marshal()
+	if err != nil {
+		logger.Warn("Could not create entity config.", slog.Any("error", err))
+
+		return items
+	}
+
+	return append(items, item)
+}
+
+// Discover finds the plugins in the given directory and loads them. If the
+// directory does not exist, no plugins are returned. Plugins that cannot be
+// loaded are skipped and returned as a combined error. Plugins and the
+// directory must have the same ownership and permissions as scripts.
+func Discover(ctx context.Context, dir, agentVersion string, device *mqtthass.Device) ([]*Plugin, error) {
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err := scripts.CheckPermissions(dir); err != nil {
+		return nil, fmt.Errorf("could not search for plugins: %w", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		return nil, fmt.Errorf("could not search for plugins: %w", err)
+	}
+
+	var (
+		plugins []*Plugin
+		errs    error
+	)
+
+	for _, file := range files {
+		if !scripts.IsExecutable(file) {
+			continue
+		}
+
+		if err := scripts.CheckPermissions(file); err != nil {
+			errs = errors.Join(errs, err)
+
+			continue
+		}
+
+		plugin, err := New(ctx, file, agentVersion, device)
+		if err != nil {
+			errs = errors.Join(errs, err)
+
+			continue
+		}
+
+		plugins = append(plugins, plugin)
+	}
+
+	return plugins, errs
+}
@@ -0,0 +1,302 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package plugins
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mqtthass "github.com/joshuar/go-hass-anything/v11/pkg/hass"
+
+	"github.com/joshuar/go-hass-agent/internal/scripts"
+)
+
+// testPluginEnv is set when the test binary is run as a plugin. Its value is
+// the behavior of the plugin.
+const testPluginEnv = "GO_HASS_AGENT_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(testPluginEnv); mode != "" {
+		runTestPlugin(mode)
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// runTestPlugin is a plugin that declares a switch, sends a sensor update once
+// initialized and reports the state of the switch when commanded. In crash
+// mode, it exits after sending the sensor update. In stuck mode, it never
+// reads its input.
+//
+//nolint:errcheck
+func runTestPlugin(mode string) {
+	if mode == "stuck" {
+		time.Sleep(time.Hour)
+
+		return
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	scanner := bufio.NewScanner(os.Stdin)
+	testSensor := map[string]any{"sensor_name": "Test Sensor", "sensor_state": 1}
+
+	for scanner.Scan() {
+		msg := &message{}
+		if err := json.Unmarshal(scanner.Bytes(), msg); err != nil {
+			continue
+		}
+
+		switch msg.Method {
+		case methodInitialize:
+			encoder.Encode(map[string]any{"jsonrpc": jsonrpcVersion, "id": msg.ID, "result": &Declaration{
+				Name:            "Test",
+				ProtocolVersion: protocolVersion,
+				Entities:        []Entity{{ID: "toggle", Name: "Toggle", Type: EntitySwitch}},
+			}})
+			encoder.Encode(map[string]any{"jsonrpc": jsonrpcVersion, "method": methodSensorUpdate, "params": testSensor})
+
+			if mode == "crash" {
+				os.Exit(1)
+			}
+		case methodSensors:
+			encoder.Encode(map[string]any{"jsonrpc": jsonrpcVersion, "id": msg.ID, "result": map[string]any{
+				"sensors": []any{testSensor, map[string]any{"sensor_state": 2}},
+			}})
+		case methodCommand:
+			params := &commandParams{}
+			json.Unmarshal(msg.Params, params)
+			encoder.Encode(map[string]any{"jsonrpc": jsonrpcVersion, "method": methodEntityState, "params": &stateParams{
+				ID:    params.ID,
+				State: json.RawMessage(fmt.Sprintf("%q", params.Payload)),
+			}})
+			encoder.Encode(map[string]any{"jsonrpc": jsonrpcVersion, "id": msg.ID, "result": true})
+		case methodShutdown:
+			return
+		default:
+			encoder.Encode(map[string]any{"jsonrpc": jsonrpcVersion, "method": "unknown"})
+		}
+	}
+}
+
+func newTestPlugin(t *testing.T, mode string, device *mqtthass.Device) *Plugin {
+	t.Helper()
+	t.Setenv(testPluginEnv, mode)
+
+	plugin, err := New(context.TODO(), os.Args[0], "test", device)
+	require.NoError(t, err)
+
+	return plugin
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		device       *mqtthass.Device
+		name         string
+		wantConfigs  int
+		wantMsgsChan bool
+	}{
+		{
+			name: "without mqtt",
+		},
+		{
+			name:         "with mqtt",
+			device:       &mqtthass.Device{Name: "test"},
+			wantConfigs:  1,
+			wantMsgsChan: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newTestPlugin(t, "ok", tt.device)
+
+			assert.Equal(t, "Test", plugin.Name())
+			assert.Len(t, plugin.Configs(), tt.wantConfigs)
+			assert.Len(t, plugin.Subscriptions(), tt.wantConfigs)
+			assert.Equal(t, tt.wantMsgsChan, plugin.Msgs() != nil)
+		})
+	}
+}
+
+func TestPlugin_Updates(t *testing.T) {
+	plugin := newTestPlugin(t, "ok", nil)
+
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancelFunc()
+
+	sensorCh, err := plugin.Updates(ctx)
+	require.NoError(t, err)
+
+	details := <-sensorCh
+	require.NotNil(t, details)
+	assert.Equal(t, "test_sensor", details.ID())
+	assert.InDelta(t, 1, details.State(), 0)
+
+	require.NoError(t, plugin.Stop())
+
+	_, open := <-sensorCh
+	assert.False(t, open, "channel should be closed when the plugin is stopped")
+}
+
+func TestPlugin_crash(t *testing.T) {
+	plugin := newTestPlugin(t, "ok", nil)
+	// Crash from the second run, after the plugin has been loaded.
+	t.Setenv(testPluginEnv, "crash")
+
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancelFunc()
+
+	sensorCh, err := plugin.Updates(ctx)
+	require.NoError(t, err)
+
+	var updates int
+	for range sensorCh {
+		updates++
+	}
+
+	assert.Equal(t, 1, updates, "channel should be closed when the plugin exits")
+	assert.NoError(t, plugin.Stop())
+}
+
+func TestPlugin_Sensors(t *testing.T) {
+	plugin := newTestPlugin(t, "ok", nil)
+
+	var errs []error
+	plugin.SetErrorHandler(func(err error) { errs = append(errs, err) })
+
+	// Not running, so the plugin is started for the request.
+	sensors, err := plugin.Sensors(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, sensors, 1)
+	assert.Equal(t, "test_sensor", sensors[0].ID())
+	// The sensor without a name is reported.
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], ErrInvalidSensor)
+}
+
+func TestPlugin_command(t *testing.T) {
+	plugin := newTestPlugin(t, "ok", &mqtthass.Device{Name: "test"})
+
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancelFunc()
+
+	sensorCh, err := plugin.Updates(ctx)
+	require.NoError(t, err)
+	<-sensorCh
+
+	subs := plugin.Subscriptions()
+	require.Len(t, subs, 1)
+
+	subs[0].Callback(&paho.Publish{Payload: []byte("ON")})
+
+	select {
+	case msg := <-plugin.Msgs():
+		assert.Equal(t, plugin.stateTopics["toggle"], msg.Topic)
+		assert.Equal(t, []byte("ON"), msg.Message)
+		assert.True(t, msg.Retained)
+	case <-ctx.Done():
+		t.Fatal("no state published")
+	}
+
+	require.NoError(t, plugin.Stop())
+}
+
+func TestDiscover(t *testing.T) {
+	emptyDir := t.TempDir()
+
+	insecureDir := t.TempDir()
+	require.NoError(t, os.Chmod(insecureDir, 0o777)) //nolint:gosec
+
+	pluginDir := t.TempDir()
+	require.NoError(t, os.Symlink(os.Args[0], filepath.Join(pluginDir, "test-plugin")))
+	require.NoError(t, os.WriteFile(filepath.Join(pluginDir, "README"), []byte("not a plugin"), 0o600))
+
+	t.Setenv(testPluginEnv, "ok")
+
+	tests := []struct {
+		name    string
+		dir     string
+		wantIDs []string
+		wantErr bool
+	}{
+		{
+			name: "nonexistent dir",
+			dir:  filepath.Join(emptyDir, "missing"),
+		},
+		{
+			name: "empty dir",
+			dir:  emptyDir,
+		},
+		{
+			name:    "insecure dir",
+			dir:     insecureDir,
+			wantErr: true,
+		},
+		{
+			name:    "plugins",
+			dir:     pluginDir,
+			wantIDs: []string{"plugin_test_plugin"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugins, err := Discover(context.TODO(), tt.dir, "test", nil)
+			if tt.wantErr {
+				require.ErrorIs(t, err, scripts.ErrInsecurePermissions)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			ids := make([]string, 0, len(plugins))
+			for _, plugin := range plugins {
+				ids = append(ids, plugin.ID())
+			}
+
+			assert.ElementsMatch(t, tt.wantIDs, ids)
+		})
+	}
+}
+
+func TestProcess_stop_stuck(t *testing.T) {
+	t.Setenv(testPluginEnv, "stuck")
+
+	proc, err := startProcess(os.Args[0], slog.Default(), func(_ *process, _ *message) {}, func(_ error) {})
+	require.NoError(t, err)
+
+	// Fill the input of the plugin, so that writes to it block.
+	go proc.conn.notify(methodSensors, strings.Repeat("x", 1<<20)) //nolint:errcheck
+
+	time.Sleep(100 * time.Millisecond)
+
+	// The plugin is killed, even though the shutdown notification cannot be
+	// sent.
+	stopped := make(chan error)
+
+	go func() { stopped <- proc.stop() }()
+
+	select {
+	case err := <-stopped:
+		require.NoError(t, err)
+	case <-time.After(2 * stopTimeout):
+		t.Fatal("plugin was not stopped")
+	}
+
+	assert.True(t, proc.exited())
+}
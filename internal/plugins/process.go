@@ -0,0 +1,134 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package plugins
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// stopTimeout is how long a plugin has to exit after being asked to shut down,
+// before it is killed.
+const stopTimeout = 5 * time.Second
+
+// process is a running plugin executable.
+type process struct {
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	conn     *conn
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// startProcess starts the plugin executable at the given path. Requests and
+// notifications from the plugin are passed to handler. Anything the plugin
+// writes to stderr is logged.
+func startProcess(path string, logger *slog.Logger, handler func(proc *process, msg *message), errorHandler func(err error)) (*process, error) {
+	cmd := exec.Command(path)
+	cmd.Stderr = &stderrLogger{logger: logger}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("could not start plugin: %w", err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("could not start plugin: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("could not start plugin: %w", err)
+	}
+
+	proc := &process{
+		cmd:   cmd,
+		stdin: stdin,
+		done:  make(chan struct{}),
+	}
+	proc.conn = newConn(stdin, func(msg *message) { handler(proc, msg) })
+
+	go func() {
+		defer close(proc.done)
+
+		if err := proc.conn.serve(stdout, errorHandler); err != nil {
+			errorHandler(err)
+		}
+
+		if err := cmd.Wait(); err != nil {
+			logger.Debug("Plugin exited.", slog.Any("error", err))
+		}
+	}()
+
+	return proc, nil
+}
+
+// stop asks the plugin to shut down and waits for it to exit. If it does not
+// exit within stopTimeout, it is killed. It is safe to call stop more than
+// once.
+func (p *process) stop() error {
+	var err error
+
+	p.stopOnce.Do(func() {
+		timer := time.NewTimer(stopTimeout)
+		defer timer.Stop()
+
+		// A plugin that is not reading its input would block sending the
+		// shutdown notification, so it is sent in the background. Errors
+		// are ignored, as the plugin may have already exited.
+		go func() {
+			p.conn.notify(methodShutdown, nil) //nolint:errcheck
+			p.stdin.Close()
+		}()
+
+		select {
+		case <-p.done:
+			return
+		case <-timer.C:
+		}
+
+		// Closing the input fails any blocked writes to the plugin.
+		p.stdin.Close()
+
+		if killErr := p.cmd.Process.Kill(); killErr != nil {
+			err = fmt.Errorf("could not kill plugin: %w", killErr)
+		}
+
+		<-p.done
+	})
+
+	return err
+}
+
+// exited returns whether the plugin has exited.
+func (p *process) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// stderrLogger logs each line written by a plugin to stderr.
+type stderrLogger struct {
+	logger *slog.Logger
+}
+
+func (l *stderrLogger) Write(data []byte) (int, error) {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			l.logger.Debug("Plugin output.", slog.String("stderr", string(line)))
+		}
+	}
+
+	return len(data), nil
+}
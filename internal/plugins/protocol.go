@@ -0,0 +1,109 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:tagalign
+package plugins
+
+import (
+	"encoding/json"
+
+	"github.com/joshuar/go-hass-agent/internal/scripts"
+)
+
+// protocolVersion is the version of the plugin protocol spoken by the agent.
+const protocolVersion = 1
+
+// Methods called by the agent.
+const (
+	// methodInitialize is called when the plugin is started. The plugin
+	// responds with its Declaration.
+	methodInitialize = "initialize"
+	// methodSensors is called to fetch the current value of all sensors of
+	// the plugin.
+	methodSensors = "sensors.get"
+	// methodCommand is called when Home Assistant sends a command to an
+	// entity of the plugin.
+	methodCommand = "entity.command"
+	// methodShutdown is sent before the plugin is stopped.
+	methodShutdown = "shutdown"
+)
+
+// Methods called by plugins.
+const (
+	// methodSensorUpdate sends an update of a sensor.
+	methodSensorUpdate = "sensor.update"
+	// methodEntityState sends the state of an entity.
+	methodEntityState = "entity.state"
+	// methodLog writes a message to the agent log.
+	methodLog = "log"
+)
+
+// Entity types that plugins can declare.
+const (
+	EntityButton = "button"
+	EntitySwitch = "switch"
+	EntityNumber = "number"
+)
+
+// initializeParams are the params of the initialize request.
+type initializeParams struct {
+	AgentVersion    string `json:"agent_version"`
+	ProtocolVersion int    `json:"protocol_version"`
+}
+
+// Declaration is what a plugin provides, returned in response to the
+// initialize request.
+type Declaration struct {
+	// Name is a display name for the plugin.
+	Name string `json:"name"`
+	// Entities are the MQTT entities of the plugin.
+	Entities []Entity `json:"entities,omitempty"`
+	// ProtocolVersion is the version of the protocol spoken by the plugin.
+	ProtocolVersion int `json:"protocol_version"`
+}
+
+// Entity is an MQTT entity declared by a plugin. Commands from Home Assistant
+// for the entity are sent to the plugin with an entity.command request.
+type Entity struct {
+	// ID is the ID of the entity, unique to the plugin.
+	ID string `json:"id"`
+	// Name is the display name of the entity.
+	Name string `json:"name"`
+	// Type is one of button, switch or number.
+	Type string `json:"type"`
+	// Icon is a material design icon for the entity.
+	Icon string `json:"icon,omitempty"`
+	// Display is how a number is shown, either box or slider.
+	Display string `json:"display,omitempty"`
+	// Min, Max and Step are the range of a number.
+	Min  float64 `json:"min,omitempty"`
+	Max  float64 `json:"max,omitempty"`
+	Step float64 `json:"step,omitempty"`
+}
+
+// sensorsResult is the result of the sensors.get request. Sensors have the same
+// fields as sensors output by scripts.
+type sensorsResult struct {
+	Sensors []*scripts.ScriptSensor `json:"sensors"`
+}
+
+// commandParams are the params of the entity.command request.
+type commandParams struct {
+	ID      string `json:"id"`
+	Payload string `json:"payload"`
+}
+
+// stateParams are the params of the entity.state notification. A string state
+// is published as-is, any other state as JSON.
+type stateParams struct {
+	ID    string          `json:"id"`
+	State json.RawMessage `json:"state"`
+}
+
+// logParams are the params of the log notification.
+type logParams struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package plugins
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+const (
+	jsonrpcVersion = "2.0"
+
+	// maxMessageSize is the maximum size of a single message from a plugin.
+	maxMessageSize = 1 << 20
+
+	codeMethodNotFound = -32601
+)
+
+var (
+	ErrClosed         = errors.New("plugin connection closed")
+	ErrInvalidMessage = errors.New("invalid message from plugin")
+)
+
+// message is a JSON-RPC 2.0 request, notification or response. Messages are
+// sent one per line.
+type message struct {
+	ID      json.RawMessage `json:"id,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method,omitempty"`
+}
+
+// isResponse returns whether the message is a response to a request.
+func (m *message) isResponse() bool {
+	return m.Method == "" && m.ID != nil
+}
+
+// RPCError is an error returned by a plugin in response to a request.
+type RPCError struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("plugin error %d: %s", e.Code, e.Message)
+}
+
+// conn is a JSON-RPC connection to a plugin.
+type conn struct {
+	writer  io.Writer
+	pending map[string]chan *message
+	// handler is called with each request and notification from the plugin.
+	handler func(msg *message)
+	nextID  int64
+	closed  bool
+	mu      sync.Mutex
+}
+
+func newConn(writer io.Writer, handler func(msg *message)) *conn {
+	return &conn{
+		writer:  writer,
+		handler: handler,
+		pending: make(map[string]chan *message),
+	}
+}
+
+// call sends a request with the given method and params and waits for the
+// response. The result of the response is unmarshaled into result, if not nil.
+func (c *conn) call(ctx context.Context, method string, params, result any) error {
+	c.mu.Lock()
+
+	if c.closed {
+		c.mu.Unlock()
+
+		return ErrClosed
+	}
+
+	c.nextID++
+	id := strconv.FormatInt(c.nextID, 10)
+	responseCh := make(chan *message, 1)
+	c.pending[id] = responseCh
+
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.send(&message{ID: json.RawMessage(id), Method: method}, params); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", method, ctx.Err())
+	case response, ok := <-responseCh:
+		if !ok {
+			return ErrClosed
+		}
+
+		if response.Error != nil {
+			return fmt.Errorf("%s: %w", method, response.Error)
+		}
+
+		if result != nil && response.Result != nil {
+			if err := json.Unmarshal(response.Result, result); err != nil {
+				return fmt.Errorf("%w: could not parse %s result: %w", ErrInvalidMessage, method, err)
+			}
+		}
+
+		return nil
+	}
+}
+
+// notify sends a notification with the given method and params.
+func (c *conn) notify(method string, params any) error {
+	return c.send(&message{Method: method}, params)
+}
+
+// reply sends a response to the request with the given ID. If rpcErr is not
+// nil, it is sent instead of the result.
+func (c *conn) reply(id json.RawMessage, result any, rpcErr *RPCError) error {
+	if rpcErr != nil {
+		return c.send(&message{ID: id, Error: rpcErr}, nil)
+	}
+
+	return c.send(&message{ID: id}, result)
+}
+
+func (c *conn) send(msg *message, params any) error {
+	msg.JSONRPC = jsonrpcVersion
+
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("could not encode message: %w", err)
+		}
+
+		if msg.Method != "" {
+			msg.Params = data
+		} else {
+			msg.Result = data
+		}
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("could not encode message: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+
+	if _, err := c.writer.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("could not send message: %w", err)
+	}
+
+	return nil
+}
+
+// serve reads messages from the given reader until it is closed. Responses are
+// passed to the waiting call, all other messages to the handler. Messages that
+// cannot be parsed are passed to errorHandler. When serve returns, any waiting
+// calls fail with ErrClosed.
+func (c *conn) serve(reader io.Reader, errorHandler func(err error)) error {
+	defer c.close()
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxMessageSize)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		msg := &message{}
+
+		if err := json.Unmarshal(scanner.Bytes(), msg); err != nil {
+			errorHandler(fmt.Errorf("%w: %w", ErrInvalidMessage, err))
+
+			continue
+		}
+
+		if !msg.isResponse() {
+			c.handler(msg)
+
+			continue
+		}
+
+		c.mu.Lock()
+		responseCh, found := c.pending[string(msg.ID)]
+		c.mu.Unlock()
+
+		if found {
+			responseCh <- msg
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("could not read from plugin: %w", err)
+	}
+
+	return nil
+}
+
+// close marks the connection as closed and fails any waiting calls.
+func (c *conn) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+
+	for id, responseCh := range c.pending {
+		close(responseCh)
+		delete(c.pending, id)
+	}
+}
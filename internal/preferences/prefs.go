@@ -11,8 +11,10 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sync"
 
 	"github.com/pelletier/go-toml/v2"
@@ -42,6 +44,14 @@ var (
 	AppVersion                                     = gitVersion
 )
 
+// Names of the preferences sections that can be changed while the agent is
+// running. See Reload.
+const (
+	MQTTSection    = "mqtt"
+	ScriptsSection = "scripts"
+	SensorsSection = "sensors"
+)
+
 var (
 	ErrNoPreferences = errors.New("no preferences file found, using defaults")
 	ErrFileContents  = errors.New("could not read file contents")
@@ -49,14 +59,24 @@ var (
 
 //nolint:tagalign
 type Preferences struct {
-	mu           *sync.Mutex
-	MQTT         *MQTT         `toml:"mqtt,omitempty"`
-	Registration *Registration `toml:"registration"`
-	Hass         *Hass         `toml:"hass"`
-	Device       *Device       `toml:"device"`
-	Version      string        `toml:"version" validate:"required"`
-	file         string
-	Registered   bool `toml:"registered" validate:"boolean"`
+	// mu guards the sections that can be replaced by Reload.
+	mu           sync.RWMutex
+	MQTT         *MQTT              `toml:"mqtt,omitempty"`
+	Registration *Registration      `toml:"registration"`
+	Hass         *Hass              `toml:"hass"`
+	Device       *Device            `toml:"device"`
+	Textfile     *Textfile          `toml:"textfile,omitempty"`
+	Scripts      *Scripts           `toml:"scripts,omitempty"`
+	Sensors      *Sensors           `toml:"sensors,omitempty"`
+	Push         *Push              `toml:"push,omitempty"`
+	Workers      map[string]*Worker `toml:"workers,omitempty" validate:"omitempty,dive"`
+	Servers      []*Server          `toml:"servers,omitempty" validate:"omitempty,unique=Name,dive"`
+	Version      string             `toml:"version" validate:"required"`
+	// saved are the sections of the preferences as they were last loaded or
+	// saved. It is used by Save to find the sections that have changed.
+	saved      map[string]any
+	file       string
+	Registered bool `toml:"registered" validate:"boolean"`
 }
 
 type MQTT struct {
@@ -77,9 +97,16 @@ func (p *Preferences) Validate() error {
 }
 
 // Save will save the new values of the specified preferences to the existing
-// preferences file. NOTE: if the preferences file does not exist, Save will
+// preferences file. Only the sections of the preferences that have changed
+// since they were loaded or last saved are written, any other sections are
+// kept as they are in the file, so that changes made to the file while the
+// agent is running are not lost. If the file has changed and is not valid, it
+// is not overwritten. NOTE: if the preferences file does not exist, Save will
 // return an error. Use New if saving preferences for the first time.
 func (p *Preferences) Save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if err := p.Validate(); err != nil {
 		return err
 	}
@@ -88,7 +115,23 @@ func (p *Preferences) Save() error {
 		return err
 	}
 
-	b, err := toml.Marshal(p)
+	current, err := p.sections()
+	if err != nil {
+		return err
+	}
+
+	sections := current
+
+	onDisk, err := readSections(p.file)
+
+	switch {
+	case err == nil && p.saved != nil:
+		sections = mergeSections(onDisk, current, p.saved)
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("not overwriting preferences file: %w", err)
+	}
+
+	b, err := toml.Marshal(sections)
 	if err != nil {
 		return fmt.Errorf("unable to format preferences: %w", err)
 	}
@@ -98,14 +141,91 @@ func (p *Preferences) Save() error {
 		return fmt.Errorf("unable to write preferences file: %w", err)
 	}
 
+	p.saved = current
+
 	return nil
 }
 
+// sections returns the preferences as a map of their top-level sections, as
+// they are written to the preferences file. The preferences must be locked.
+func (p *Preferences) sections() (map[string]any, error) {
+	b, err := toml.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("unable to format preferences: %w", err)
+	}
+
+	var sections map[string]any
+
+	if err := toml.Unmarshal(b, &sections); err != nil {
+		return nil, fmt.Errorf("unable to format preferences: %w", err)
+	}
+
+	return sections, nil
+}
+
+// readSections reads the top-level sections of the preferences file.
+func readSections(file string) (map[string]any, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read preferences file: %w", err)
+	}
+
+	var sections map[string]any
+
+	if err := toml.Unmarshal(b, &sections); err != nil {
+		return nil, errors.Join(ErrFileContents, err)
+	}
+
+	return sections, nil
+}
+
+// mergeSections returns the sections on disk, with any sections that are
+// different in current from the last saved sections replaced by those in
+// current.
+func mergeSections(onDisk, current, saved map[string]any) map[string]any {
+	merged := maps.Clone(onDisk)
+
+	for name, section := range current {
+		if !reflect.DeepEqual(section, saved[name]) {
+			merged[name] = section
+		}
+	}
+
+	// Sections that have been removed.
+	for name := range saved {
+		if _, found := current[name]; !found {
+			delete(merged, name)
+		}
+	}
+
+	return merged
+}
+
 // GetMQTTPreferences returns the subset of MQTT preferences.
 func (p *Preferences) GetMQTTPreferences() *MQTT {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	return p.MQTT
 }
 
+// SetMQTTPreferences replaces the MQTT preferences.
+func (p *Preferences) SetMQTTPreferences(mqtt *MQTT) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.MQTT = mqtt
+}
+
+// SensorPreferences returns the sensors section of the preferences, which
+// might be nil.
+func (p *Preferences) SensorPreferences() *Sensors {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.Sensors
+}
+
 // Load will retrieve the current preferences from the preference file on disk.
 // If there is a problem during retrieval, an error will be returned.
 func Load(path string) (*Preferences, error) {
@@ -122,9 +242,73 @@ func Load(path string) (*Preferences, error) {
 		return prefs, errors.Join(ErrFileContents, err)
 	}
 
+	if prefs.saved, err = prefs.sections(); err != nil {
+		return prefs, err
+	}
+
 	return prefs, nil
 }
 
+// Reload will re-read the preferences file and apply any changes to the
+// preferences that can be changed while the agent is running (the MQTT, scripts
+// and sensors sections). The names of the sections that changed are returned.
+// Changes to any other preferences are ignored until the agent is restarted.
+// If the file cannot be read or is not valid, no preferences are changed.
+func (p *Preferences) Reload() ([]string, error) {
+	b, err := os.ReadFile(p.file)
+	if err != nil {
+		return nil, errors.Join(ErrNoPreferences, err)
+	}
+
+	newPrefs := &Preferences{}
+
+	if err = toml.Unmarshal(b, newPrefs); err != nil {
+		return nil, errors.Join(ErrFileContents, err)
+	}
+
+	sections, err := newPrefs.sections()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = newPrefs.Validate(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var changed []string
+
+	if !reflect.DeepEqual(p.MQTT, newPrefs.MQTT) {
+		p.MQTT = newPrefs.MQTT
+		changed = append(changed, MQTTSection)
+	}
+
+	if !reflect.DeepEqual(p.Scripts, newPrefs.Scripts) {
+		p.Scripts = newPrefs.Scripts
+		changed = append(changed, ScriptsSection)
+	}
+
+	if !reflect.DeepEqual(p.Sensors, newPrefs.Sensors) {
+		p.Sensors = newPrefs.Sensors
+		changed = append(changed, SensorsSection)
+	}
+
+	// The reloaded sections are now the same as in the file.
+	if p.saved != nil {
+		for _, name := range []string{MQTTSection, ScriptsSection, SensorsSection} {
+			if section, found := sections[name]; found {
+				p.saved[name] = section
+			} else {
+				delete(p.saved, name)
+			}
+		}
+	}
+
+	return changed, nil
+}
+
 // Reset will remove the preferences directory.
 func Reset(path string) error {
 	file := filepath.Join(path, preferencesFile)
@@ -170,7 +354,6 @@ func DefaultPreferences(file string) *Preferences {
 		},
 		MQTT:   &MQTT{MQTTEnabled: false},
 		Device: device,
-		mu:     &sync.Mutex{},
 		file:   file,
 	}
 }
@@ -256,6 +439,33 @@ func (p *Preferences) WebhookID() string {
 	return ""
 }
 
+// TextfilePath returns the directory to search for Prometheus node_exporter
+// textfile collector files.
+func (p *Preferences) TextfilePath() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.Textfile != nil && p.Textfile.Path != "" {
+		return p.Textfile.Path
+	}
+
+	return DefaultTextfilePath
+}
+
+// ScriptDirs returns the directories to search for scripts. If none have been
+// configured, the system-wide directory followed by the given per-user
+// directory is returned.
+func (p *Preferences) ScriptDirs(userDir string) []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.Scripts != nil && len(p.Scripts.Dirs) > 0 {
+		return p.Scripts.Dirs
+	}
+
+	return []string{DefaultSystemScriptsPath, userDir}
+}
+
 func (p *Preferences) Token() string {
 	if p.Registration != nil {
 		return p.Registration.Token
@@ -11,9 +11,10 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
-	"sync"
+	"strconv"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -77,7 +78,6 @@ func TestPreferences_Validate(t *testing.T) {
 	validPrefs := DefaultPreferences(filepath.Join(t.TempDir(), preferencesFile))
 
 	type fields struct {
-		mu           *sync.Mutex
 		MQTT         *MQTT
 		Registration *Registration
 		Hass         *Hass
@@ -126,7 +126,6 @@ func TestPreferences_Validate(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p := &Preferences{
-				mu:           tt.fields.mu,
 				MQTT:         tt.fields.MQTT,
 				Registration: tt.fields.Registration,
 				Hass:         tt.fields.Hass,
@@ -146,7 +145,6 @@ func TestPreferences_Save(t *testing.T) {
 	validPrefs := DefaultPreferences(filepath.Join(t.TempDir(), preferencesFile))
 
 	type fields struct {
-		mu           *sync.Mutex
 		MQTT         *MQTT
 		Registration *Registration
 		Hass         *Hass
@@ -202,7 +200,6 @@ func TestPreferences_Save(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p := &Preferences{
-				mu:           tt.fields.mu,
 				MQTT:         tt.fields.MQTT,
 				Registration: tt.fields.Registration,
 				Hass:         tt.fields.Hass,
@@ -218,11 +215,46 @@ func TestPreferences_Save(t *testing.T) {
 	}
 }
 
+func TestPreferences_Save_merge(t *testing.T) {
+	file := filepath.Join(t.TempDir(), preferencesFile)
+	require.NoError(t, DefaultPreferences(file).Save())
+
+	prefs, err := Load(filepath.Dir(file))
+	require.NoError(t, err)
+
+	// The file is changed while the agent is running.
+	onDisk, err := Load(filepath.Dir(file))
+	require.NoError(t, err)
+	onDisk.Textfile = &Textfile{Path: "/some/textfiles"}
+	onDisk.Hass.RestAPIURL = "http://some.host:8123"
+	require.NoError(t, onDisk.Save())
+
+	// Only the sections changed by the agent are saved.
+	prefs.Registered = true
+	prefs.Hass.WebhookID = "changed"
+	require.NoError(t, prefs.Save())
+
+	saved, err := Load(filepath.Dir(file))
+	require.NoError(t, err)
+	assert.True(t, saved.Registered)
+	assert.Equal(t, "changed", saved.Hass.WebhookID)
+	assert.Equal(t, defaultServer, saved.Hass.RestAPIURL)
+	assert.Equal(t, "/some/textfiles", saved.TextfilePath())
+
+	// An invalid file is not overwritten.
+	require.NoError(t, os.WriteFile(file, []byte(`invalid`), 0o600))
+	prefs.Registered = false
+	require.ErrorIs(t, prefs.Save(), ErrFileContents)
+
+	b, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "invalid", string(b))
+}
+
 func TestPreferences_GetMQTTPreferences(t *testing.T) {
 	validPrefs := DefaultPreferences(filepath.Join(t.TempDir(), preferencesFile))
 
 	type fields struct {
-		mu           *sync.Mutex
 		MQTT         *MQTT
 		Registration *Registration
 		Hass         *Hass
@@ -247,7 +279,6 @@ func TestPreferences_GetMQTTPreferences(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			p := &Preferences{
-				mu:           tt.fields.mu,
 				MQTT:         tt.fields.MQTT,
 				Registration: tt.fields.Registration,
 				Hass:         tt.fields.Hass,
@@ -317,6 +348,79 @@ func TestLoad(t *testing.T) {
 	}
 }
 
+func TestPreferences_Reload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), preferencesFile)
+	prefs := DefaultPreferences(file)
+	require.NoError(t, prefs.Save())
+
+	// No changes.
+	changed, err := prefs.Reload()
+	require.NoError(t, err)
+	require.Empty(t, changed)
+
+	// Change reloadable and non-reloadable preferences on disk.
+	onDisk, err := Load(filepath.Dir(file))
+	require.NoError(t, err)
+	onDisk.MQTT = &MQTT{MQTTEnabled: true, MQTTServer: "tcp://localhost:1883"}
+	onDisk.Scripts = &Scripts{Dirs: []string{"/some/scripts"}}
+	onDisk.Sensors = &Sensors{Exclude: []string{"*_rate"}}
+	onDisk.Textfile = &Textfile{Path: "/some/textfiles"}
+	onDisk.Hass.RestAPIURL = "http://some.host:8123"
+	require.NoError(t, onDisk.Save())
+
+	changed, err = prefs.Reload()
+	require.NoError(t, err)
+	require.Equal(t, []string{MQTTSection, ScriptsSection, SensorsSection}, changed)
+	require.Equal(t, onDisk.MQTT, prefs.MQTT)
+	require.Equal(t, []string{"/some/scripts"}, prefs.ScriptDirs("/user/scripts"))
+	require.Equal(t, defaultServer, prefs.RestAPIURL())
+	require.Equal(t, DefaultTextfilePath, prefs.TextfilePath())
+
+	// Invalid file.
+	require.NoError(t, os.WriteFile(file, []byte(`invalid`), 0o600))
+
+	_, err = prefs.Reload()
+	require.ErrorIs(t, err, ErrFileContents)
+	require.Equal(t, onDisk.MQTT, prefs.MQTT)
+}
+
+func TestPreferences_Reload_concurrent(t *testing.T) {
+	file := filepath.Join(t.TempDir(), preferencesFile)
+	prefs := DefaultPreferences(file)
+	require.NoError(t, prefs.Save())
+
+	onDisk, err := Load(filepath.Dir(file))
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := range 20 {
+			onDisk.Sensors = &Sensors{Exclude: []string{strconv.Itoa(i)}}
+			onDisk.Scripts = &Scripts{Dirs: []string{"/scripts/" + strconv.Itoa(i)}}
+			assert.NoError(t, onDisk.Save())
+
+			_, err := prefs.Reload()
+			assert.NoError(t, err)
+		}
+	}()
+
+	// Reading the reloadable sections while they are reloaded is safe.
+	for {
+		select {
+		case <-done:
+			return
+		default:
+			prefs.ScriptDirs("/user/scripts")
+			prefs.SensorPreferences().OrphanPeriod()
+			prefs.GetMQTTPreferences()
+			prefs.WorkerEnabled("worker")
+		}
+	}
+}
+
 func TestReset(t *testing.T) {
 	existingPrefs := DefaultPreferences(filepath.Join(t.TempDir(), preferencesFile))
 	err := existingPrefs.Save()
@@ -0,0 +1,86 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:tagalign
+package preferences
+
+import (
+	"net"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// DefaultPushListen is the address the push endpoint listens on if none
+	// is set.
+	DefaultPushListen = "127.0.0.1:8124"
+	// DefaultPushNamespace is the prefix added to the IDs of pushed sensors if
+	// none is set.
+	DefaultPushNamespace = "push"
+
+	unixSocketPrefix = "unix:"
+)
+
+// Push contains preferences for the local push endpoint, which accepts sensor
+// requests in the same format as the Home Assistant mobile_app webhook.
+type Push struct {
+	// Listen is the address to listen on. It is either a host:port on the
+	// loopback interface, or unix: followed by the path of a Unix socket.
+	Listen string `toml:"listen,omitempty" validate:"omitempty,local_listen"`
+	// Token is the token clients must send as a bearer token.
+	Token string `toml:"token,omitempty" validate:"required_if=Enabled true,omitempty,min=16"`
+	// Namespace is added as a prefix to the IDs of pushed sensors, so that
+	// they cannot replace the agent's own sensors.
+	Namespace string `toml:"namespace,omitempty" validate:"omitempty,alphanum,lowercase"`
+	// Enabled is whether the push endpoint is started.
+	Enabled bool `toml:"enabled" validate:"boolean"`
+}
+
+// Address returns the network ("tcp" or "unix") and address the push endpoint
+// listens on.
+func (p *Push) Address() (network, address string) {
+	switch {
+	case p.Listen == "":
+		return "tcp", DefaultPushListen
+	case strings.HasPrefix(p.Listen, unixSocketPrefix):
+		return "unix", strings.TrimPrefix(p.Listen, unixSocketPrefix)
+	default:
+		return "tcp", p.Listen
+	}
+}
+
+// IDPrefix returns the prefix added to the IDs of pushed sensors.
+func (p *Push) IDPrefix() string {
+	if p.Namespace == "" {
+		return DefaultPushNamespace + "_"
+	}
+
+	return p.Namespace + "_"
+}
+
+// validateLocalListen checks that a string field is an address that can only
+// be reached from the local device: a Unix socket path or a host:port on the
+// loopback interface.
+func validateLocalListen(fl validator.FieldLevel) bool {
+	listen := fl.Field().String()
+
+	if path, found := strings.CutPrefix(listen, unixSocketPrefix); found {
+		return strings.HasPrefix(path, "/")
+	}
+
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
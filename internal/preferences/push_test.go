@@ -0,0 +1,66 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package preferences
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPushToken = "a-long-enough-token"
+
+func TestPush_Validate(t *testing.T) {
+	tests := []struct {
+		push    *Push
+		name    string
+		wantErr bool
+	}{
+		{name: "disabled", push: &Push{}},
+		{name: "enabled with defaults", push: &Push{Enabled: true, Token: testPushToken}},
+		{name: "enabled without token", push: &Push{Enabled: true}, wantErr: true},
+		{name: "short token", push: &Push{Enabled: true, Token: "short"}, wantErr: true},
+		{name: "loopback address", push: &Push{Enabled: true, Token: testPushToken, Listen: "[::1]:9000"}},
+		{name: "localhost", push: &Push{Enabled: true, Token: testPushToken, Listen: "localhost:9000"}},
+		{name: "unix socket", push: &Push{Enabled: true, Token: testPushToken, Listen: "unix:/run/user/1000/push.sock"}},
+		{name: "relative unix socket", push: &Push{Enabled: true, Token: testPushToken, Listen: "unix:push.sock"}, wantErr: true},
+		{name: "all interfaces", push: &Push{Enabled: true, Token: testPushToken, Listen: ":9000"}, wantErr: true},
+		{name: "other host", push: &Push{Enabled: true, Token: testPushToken, Listen: "192.168.1.2:9000"}, wantErr: true},
+		{name: "invalid namespace", push: &Push{Enabled: true, Token: testPushToken, Namespace: "My Apps"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefs := DefaultPreferences(filepath.Join(t.TempDir(), preferencesFile))
+			prefs.Push = tt.push
+
+			if err := prefs.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPush_Address(t *testing.T) {
+	tests := []struct {
+		name        string
+		listen      string
+		wantNetwork string
+		wantAddress string
+	}{
+		{name: "default", wantNetwork: "tcp", wantAddress: DefaultPushListen},
+		{name: "tcp", listen: "localhost:9000", wantNetwork: "tcp", wantAddress: "localhost:9000"},
+		{name: "unix", listen: "unix:/run/push.sock", wantNetwork: "unix", wantAddress: "/run/push.sock"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			network, address := (&Push{Listen: tt.listen}).Address()
+			assert.Equal(t, tt.wantNetwork, network)
+			assert.Equal(t, tt.wantAddress, address)
+		})
+	}
+}
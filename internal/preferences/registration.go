@@ -13,6 +13,9 @@ import (
 type Registration struct {
 	Server string `toml:"server" validate:"required,http_url"`
 	Token  string `toml:"token" validate:"required"`
+	// Reregister is whether the agent registers again with the token, without
+	// asking, if the mobile_app integration is removed from Home Assistant.
+	Reregister bool `toml:"reregister,omitempty" validate:"boolean"`
 }
 
 func (p *Registration) Validate() error {
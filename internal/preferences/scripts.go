@@ -0,0 +1,18 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:tagalign
+package preferences
+
+// DefaultSystemScriptsPath is the system-wide directory searched for script
+// sensors. It can be used to provide scripts to all users of a device.
+const DefaultSystemScriptsPath = "/etc/go-hass-agent/scripts"
+
+// Scripts contains preferences for script sensors.
+type Scripts struct {
+	// Dirs is the list of directories to search for scripts. Scripts in later
+	// directories override scripts with the same name in earlier ones.
+	Dirs []string `toml:"dirs,omitempty" validate:"omitempty,dive,startswith=/"`
+}
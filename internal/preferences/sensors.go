@@ -0,0 +1,49 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:tagalign
+package preferences
+
+import "time"
+
+// DefaultOrphanAfter is how long a registered sensor can go without being sent
+// before it is considered orphaned, if not set in the preferences.
+const DefaultOrphanAfter = 30 * 24 * time.Hour
+
+// Sensors contains preferences for filtering which sensors are sent to Home
+// Assistant. Patterns are shell-style globs (e.g., "cpu_*"). A sensor is sent
+// if there are no include rules or it matches any include rule, and it does
+// not match any exclude rule.
+type Sensors struct {
+	// Include is a list of patterns matched against sensor IDs.
+	Include []string `toml:"include,omitempty" validate:"omitempty,dive,glob"`
+	// Exclude is a list of patterns matched against sensor IDs.
+	Exclude []string `toml:"exclude,omitempty" validate:"omitempty,dive,glob"`
+	// IncludeAttributes maps sensor attribute names to patterns matched
+	// against the attribute value.
+	IncludeAttributes map[string]string `toml:"include_attributes,omitempty" validate:"omitempty,dive,glob"`
+	// ExcludeAttributes maps sensor attribute names to patterns matched
+	// against the attribute value.
+	ExcludeAttributes map[string]string `toml:"exclude_attributes,omitempty" validate:"omitempty,dive,glob"`
+	// OrphanAfter is how long a registered sensor can go without being sent
+	// before it is considered orphaned, as a duration (e.g., "720h").
+	OrphanAfter string `toml:"orphan_after,omitempty" validate:"omitempty,duration"`
+}
+
+// OrphanPeriod returns how long a registered sensor can go without being sent
+// before it is considered orphaned.
+func (p *Sensors) OrphanPeriod() time.Duration {
+	if p == nil || p.OrphanAfter == "" {
+		return DefaultOrphanAfter
+	}
+
+	// Durations are validated when the preferences are loaded.
+	period, _ := time.ParseDuration(p.OrphanAfter) //nolint:errcheck
+	if period == 0 {
+		return DefaultOrphanAfter
+	}
+
+	return period
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package preferences
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSensors_Validate(t *testing.T) {
+	tests := []struct {
+		sensors *Sensors
+		name    string
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			sensors: &Sensors{Include: []string{"cpu_*"}, ExcludeAttributes: map[string]string{"device": "loop?"}},
+		},
+		{
+			name:    "invalid id pattern",
+			sensors: &Sensors{Exclude: []string{"cpu_["}},
+			wantErr: true,
+		},
+		{
+			name:    "valid orphan period",
+			sensors: &Sensors{OrphanAfter: "168h"},
+		},
+		{
+			name:    "invalid orphan period",
+			sensors: &Sensors{OrphanAfter: "a week"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid attribute pattern",
+			sensors: &Sensors{IncludeAttributes: map[string]string{"device": "sd["}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefs := DefaultPreferences(filepath.Join(t.TempDir(), preferencesFile))
+			prefs.Sensors = tt.sensors
+
+			if err := prefs.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSensors_OrphanPeriod(t *testing.T) {
+	tests := []struct {
+		sensors *Sensors
+		name    string
+		want    time.Duration
+	}{
+		{name: "no sensors section", want: DefaultOrphanAfter},
+		{name: "not set", sensors: &Sensors{}, want: DefaultOrphanAfter},
+		{name: "set", sensors: &Sensors{OrphanAfter: "168h"}, want: 168 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.sensors.OrphanPeriod())
+		})
+	}
+}
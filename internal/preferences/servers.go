@@ -0,0 +1,112 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:tagalign
+package preferences
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultServerName is the name of the server whose details are in the
+// registration and hass sections of the preferences.
+const DefaultServerName = "default"
+
+var ErrUnknownServer = errors.New("no active server with that name")
+
+// Server is a profile for a Home Assistant server the agent reports to, in
+// addition to the default server. Each server has its own registration,
+// webhook, sensor registry and notifications.
+type Server struct {
+	// Registration contains the details used to register with the server.
+	Registration *Registration `toml:"registration" validate:"required"`
+	// Hass contains the details returned by the server on registration.
+	Hass *Hass `toml:"hass,omitempty"`
+	// Sensors filters which sensors are sent to the server. If not set, the
+	// sensors section of the preferences is used.
+	Sensors *Sensors `toml:"sensors,omitempty"`
+	// Name identifies the server. It must be unique.
+	Name string `toml:"name" validate:"required,ne=default,excludesall=/\\ "`
+	// Registered is whether the agent has registered with the server.
+	Registered bool `toml:"registered" validate:"boolean"`
+	// Disabled servers are not registered with or sent sensor updates.
+	Disabled bool `toml:"disabled,omitempty" validate:"boolean"`
+}
+
+// DefaultServer returns a profile for the default server. Changes to its
+// registration details are reflected in the preferences, other changes are
+// not.
+func (p *Preferences) DefaultServer() *Server {
+	return &Server{
+		Name:         DefaultServerName,
+		Registration: p.Registration,
+		Hass:         p.Hass,
+		Sensors:      p.SensorPreferences(),
+		Registered:   p.Registered,
+	}
+}
+
+// ActiveServers returns the default server and any other servers that are not
+// disabled.
+func (p *Preferences) ActiveServers() []*Server {
+	servers := []*Server{p.DefaultServer()}
+
+	for _, server := range p.Servers {
+		if server != nil && !server.Disabled {
+			servers = append(servers, server)
+		}
+	}
+
+	return servers
+}
+
+func (s *Server) RestAPIURL() string {
+	if s.Hass != nil {
+		return s.Hass.RestAPIURL
+	}
+
+	return ""
+}
+
+func (s *Server) WebsocketURL() string {
+	if s.Hass != nil {
+		return s.Hass.WebsocketURL
+	}
+
+	return ""
+}
+
+func (s *Server) WebhookID() string {
+	if s.Hass != nil {
+		return s.Hass.WebhookID
+	}
+
+	return ""
+}
+
+func (s *Server) Token() string {
+	if s.Registration != nil {
+		return s.Registration.Token
+	}
+
+	return ""
+}
+
+// Server returns the active server with the given name. An empty name is the
+// default server.
+func (p *Preferences) Server(name string) (*Server, error) {
+	if name == "" {
+		name = DefaultServerName
+	}
+
+	for _, server := range p.ActiveServers() {
+		if server.Name == name {
+			return server, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownServer, name)
+}
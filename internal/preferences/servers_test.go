@@ -0,0 +1,152 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package preferences
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreferences_ActiveServers(t *testing.T) {
+	prefs := DefaultPreferences(filepath.Join(t.TempDir(), preferencesFile))
+	prefs.Registered = true
+	prefs.Servers = []*Server{
+		{Name: "lab", Registration: &Registration{Server: "http://lab:8123", Token: "token"}},
+		{Name: "old", Registration: &Registration{Server: "http://old:8123", Token: "token"}, Disabled: true},
+	}
+	require.NoError(t, prefs.Validate())
+
+	servers := prefs.ActiveServers()
+	require.Len(t, servers, 2)
+
+	assert.Equal(t, DefaultServerName, servers[0].Name)
+	assert.True(t, servers[0].Registered)
+	assert.Equal(t, prefs.RestAPIURL(), servers[0].RestAPIURL())
+	assert.Equal(t, prefs.Token(), servers[0].Token())
+
+	assert.Equal(t, "lab", servers[1].Name)
+	assert.False(t, servers[1].Registered)
+	assert.Empty(t, servers[1].RestAPIURL())
+}
+
+func TestServer_Validate(t *testing.T) {
+	registration := &Registration{Server: "http://lab:8123", Token: "token"}
+
+	tests := []struct {
+		name    string
+		servers []*Server
+		wantErr bool
+	}{
+		{name: "valid", servers: []*Server{{Name: "lab", Registration: registration}}},
+		{name: "no name", servers: []*Server{{Registration: registration}}, wantErr: true},
+		{name: "default name", servers: []*Server{{Name: DefaultServerName, Registration: registration}}, wantErr: true},
+		{name: "path in name", servers: []*Server{{Name: "../lab", Registration: registration}}, wantErr: true},
+		{name: "no registration", servers: []*Server{{Name: "lab"}}, wantErr: true},
+		{
+			name:    "invalid registration",
+			servers: []*Server{{Name: "lab", Registration: &Registration{Server: "notaurl", Token: "token"}}},
+			wantErr: true,
+		},
+		{
+			name: "duplicate names",
+			servers: []*Server{
+				{Name: "lab", Registration: registration},
+				{Name: "lab", Registration: registration},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefs := DefaultPreferences(filepath.Join(t.TempDir(), preferencesFile))
+			prefs.Servers = tt.servers
+			if err := prefs.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Preferences.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_servers(t *testing.T) {
+	dir := t.TempDir()
+	contents := `version = "test"
+registered = true
+
+[registration]
+server = "http://home:8123"
+token = "home"
+
+[hass]
+webhook_id = "home"
+apiurl = "http://home:8123/api/webhook/home"
+websocketurl = "ws://home:8123/api/websocket"
+
+[[servers]]
+name = "lab"
+
+[servers.registration]
+server = "http://lab:8123"
+token = "lab"
+
+[servers.sensors]
+include = ["cpu_*"]
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, preferencesFile), []byte(contents), 0o600))
+
+	prefs, err := Load(dir)
+	require.NoError(t, err)
+	require.NoError(t, prefs.Validate())
+	require.Len(t, prefs.Servers, 1)
+
+	lab := prefs.Servers[0]
+	assert.Equal(t, "lab", lab.Name)
+	assert.Equal(t, "http://lab:8123", lab.Registration.Server)
+	assert.Equal(t, []string{"cpu_*"}, lab.Sensors.Include)
+	assert.False(t, lab.Registered)
+}
+
+func TestPreferences_Server(t *testing.T) {
+	prefs := DefaultPreferences(filepath.Join(t.TempDir(), preferencesFile))
+	prefs.Servers = []*Server{
+		{Name: "lab", Registration: &Registration{Server: "http://lab:8123", Token: "token"}},
+		{Name: "old", Registration: &Registration{Server: "http://old:8123", Token: "token"}, Disabled: true},
+	}
+
+	tests := []struct {
+		name    string
+		server  string
+		wantErr bool
+	}{
+		{name: "default", server: DefaultServerName},
+		{name: "empty is default", server: ""},
+		{name: "additional", server: "lab"},
+		{name: "disabled", server: "old", wantErr: true},
+		{name: "unknown", server: "missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, err := prefs.Server(tt.server)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrUnknownServer)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			if tt.server == "" {
+				assert.Equal(t, DefaultServerName, server.Name)
+			} else {
+				assert.Equal(t, tt.server, server.Name)
+			}
+		})
+	}
+}
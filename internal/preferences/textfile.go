@@ -0,0 +1,18 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:tagalign
+package preferences
+
+// DefaultTextfilePath is the default directory searched for Prometheus
+// node_exporter textfile collector (.prom) files. It matches the directory used
+// by the node_exporter packages of most distributions.
+const DefaultTextfilePath = "/var/lib/prometheus/node-exporter"
+
+// Textfile contains preferences for reading Prometheus node_exporter textfile
+// collector files as sensors.
+type Textfile struct {
+	Path string `toml:"path,omitempty" validate:"omitempty,startswith=/"`
+}
@@ -7,7 +7,9 @@ package preferences
 
 import (
 	"errors"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -18,6 +20,33 @@ var ErrValidationFailed = errors.New("validation failed")
 
 func init() {
 	validate = validator.New(validator.WithRequiredStructEnabled())
+
+	if err := validate.RegisterValidation("duration", validateDuration); err != nil {
+		panic(err)
+	}
+
+	if err := validate.RegisterValidation("glob", validateGlob); err != nil {
+		panic(err)
+	}
+
+	if err := validate.RegisterValidation("local_listen", validateLocalListen); err != nil {
+		panic(err)
+	}
+}
+
+// validateDuration checks that a string field is a valid, non-negative,
+// duration.
+func validateDuration(fl validator.FieldLevel) bool {
+	duration, err := time.ParseDuration(fl.Field().String())
+
+	return err == nil && duration >= 0
+}
+
+// validateGlob checks that a string field is a valid glob pattern.
+func validateGlob(fl validator.FieldLevel) bool {
+	_, err := path.Match(fl.Field().String(), "")
+
+	return err == nil
 }
 
 //nolint:errorlint
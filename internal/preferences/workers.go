@@ -0,0 +1,55 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:tagalign
+package preferences
+
+import "time"
+
+// Worker contains preferences for a single sensor worker, keyed by the worker
+// ID in the workers section of the preferences.
+type Worker struct {
+	// Enabled controls whether the worker is started. Workers are enabled
+	// unless explicitly disabled.
+	Enabled *bool `toml:"enabled,omitempty"`
+	// Interval overrides the default poll interval of a polling worker, as a
+	// duration string (e.g., "30s").
+	Interval string `toml:"interval,omitempty" validate:"omitempty,duration"`
+	// Jitter overrides the default poll jitter of a polling worker, as a
+	// duration string (e.g., "5s").
+	Jitter string `toml:"jitter,omitempty" validate:"omitempty,duration"`
+}
+
+// WorkerEnabled returns whether the worker with the given ID is enabled.
+func (p *Preferences) WorkerEnabled(id string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	worker, found := p.Workers[id]
+	if !found || worker == nil || worker.Enabled == nil {
+		return true
+	}
+
+	return *worker.Enabled
+}
+
+// WorkerInterval returns any overrides for the poll interval and jitter of the
+// worker with the given ID. A zero value means the worker default should be
+// used.
+func (p *Preferences) WorkerInterval(id string) (interval, jitter time.Duration) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	worker, found := p.Workers[id]
+	if !found || worker == nil {
+		return 0, 0
+	}
+
+	// Durations are validated when the preferences are loaded.
+	interval, _ = time.ParseDuration(worker.Interval) //nolint:errcheck
+	jitter, _ = time.ParseDuration(worker.Jitter)     //nolint:errcheck
+
+	return interval, jitter
+}
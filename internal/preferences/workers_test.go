@@ -0,0 +1,71 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package preferences
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreferences_Workers(t *testing.T) {
+	disabled := false
+	enabled := true
+
+	prefs := DefaultPreferences(filepath.Join(t.TempDir(), preferencesFile))
+	prefs.Workers = map[string]*Worker{
+		"disabled": {Enabled: &disabled},
+		"enabled":  {Enabled: &enabled, Interval: "30s"},
+		"interval": {Interval: "1m", Jitter: "5s"},
+	}
+	require.NoError(t, prefs.Validate())
+
+	tests := []struct {
+		name         string
+		id           string
+		wantEnabled  bool
+		wantInterval time.Duration
+		wantJitter   time.Duration
+	}{
+		{name: "disabled", id: "disabled", wantEnabled: false},
+		{name: "enabled", id: "enabled", wantEnabled: true, wantInterval: 30 * time.Second},
+		{name: "interval only", id: "interval", wantEnabled: true, wantInterval: time.Minute, wantJitter: 5 * time.Second},
+		{name: "no preferences", id: "unknown", wantEnabled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantEnabled, prefs.WorkerEnabled(tt.id))
+			interval, jitter := prefs.WorkerInterval(tt.id)
+			assert.Equal(t, tt.wantInterval, interval)
+			assert.Equal(t, tt.wantJitter, jitter)
+		})
+	}
+}
+
+func TestWorker_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		worker  *Worker
+		wantErr bool
+	}{
+		{name: "valid", worker: &Worker{Interval: "10s", Jitter: "0s"}},
+		{name: "invalid interval", worker: &Worker{Interval: "often"}, wantErr: true},
+		{name: "negative jitter", worker: &Worker{Jitter: "-5s"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefs := DefaultPreferences(filepath.Join(t.TempDir(), preferencesFile))
+			prefs.Workers = map[string]*Worker{"worker": tt.worker}
+			if err := prefs.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Preferences.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package push
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/scripts"
+)
+
+const (
+	requestTypeRegister = "register_sensor"
+	requestTypeUpdate   = "update_sensor_states"
+
+	binarySensorType = "binary_sensor"
+)
+
+var (
+	ErrInvalidRequest = errors.New("invalid request")
+	ErrNotRegistered  = errors.New("entity is not registered")
+)
+
+// request is a webhook request. Only the sensor request types are supported.
+type request struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// sensorState is the data of a sensor update, and the common data of a sensor
+// registration.
+type sensorState struct {
+	State      any            `json:"state"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	UniqueID   string         `json:"unique_id"`
+	Type       string         `json:"type"`
+	Icon       string         `json:"icon,omitempty"`
+}
+
+// sensorRegistration is the data of a sensor registration.
+type sensorRegistration struct {
+	sensorState
+	Name              string `json:"name"`
+	DeviceClass       string `json:"device_class,omitempty"`
+	UnitOfMeasurement string `json:"unit_of_measurement,omitempty"`
+	StateClass        string `json:"state_class,omitempty"`
+	EntityCategory    string `json:"entity_category,omitempty"`
+}
+
+// updateResult is the result of updating a single sensor, as returned by Home
+// Assistant.
+type updateResult struct {
+	Error   *resultError `json:"error,omitempty"`
+	Success bool         `json:"success"`
+}
+
+type resultError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// errorResponse is the body of a response to a failed request.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// handleRequest handles a webhook request.
+func (s *Server) handleRequest(res http.ResponseWriter, req *http.Request) {
+	if !s.authorized(req) {
+		writeJSON(res, http.StatusUnauthorized, &errorResponse{Error: "unauthorized"})
+
+		return
+	}
+
+	var body request
+
+	decoder := json.NewDecoder(http.MaxBytesReader(res, req.Body, maxRequestSize))
+	if err := decoder.Decode(&body); err != nil {
+		writeJSON(res, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("%v: %v", ErrInvalidRequest, err)})
+
+		return
+	}
+
+	switch body.Type {
+	case requestTypeRegister:
+		s.handleRegistration(res, body.Data)
+	case requestTypeUpdate:
+		s.handleUpdates(res, body.Data)
+	default:
+		writeJSON(res, http.StatusBadRequest,
+			&errorResponse{Error: fmt.Sprintf("%v: unsupported type %q", ErrInvalidRequest, body.Type)})
+	}
+}
+
+// handleRegistration registers the sensor in the request and publishes it.
+func (s *Server) handleRegistration(res http.ResponseWriter, data json.RawMessage) {
+	var registration sensorRegistration
+
+	if err := json.Unmarshal(data, &registration); err != nil {
+		writeJSON(res, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("%v: %v", ErrInvalidRequest, err)})
+
+		return
+	}
+
+	if registration.UniqueID == "" || registration.Name == "" || registration.State == nil {
+		writeJSON(res, http.StatusBadRequest,
+			&errorResponse{Error: fmt.Sprintf("%v: unique_id, name and state are required", ErrInvalidRequest)})
+
+		return
+	}
+
+	details := &scripts.ScriptSensor{
+		SensorID:          s.prefix + registration.UniqueID,
+		SensorName:        registration.Name,
+		SensorState:       registration.State,
+		SensorAttributes:  registration.Attributes,
+		SensorIcon:        registration.Icon,
+		SensorDeviceClass: registration.DeviceClass,
+		SensorStateClass:  registration.StateClass,
+		SensorUnits:       registration.UnitOfMeasurement,
+		SensorCategory:    registration.EntityCategory,
+	}
+
+	if registration.Type == binarySensorType {
+		details.SensorStateType = "binary"
+	}
+
+	s.mu.Lock()
+	s.sensors[details.ID()] = details
+	s.mu.Unlock()
+
+	if err := s.publish(details); err != nil {
+		writeJSON(res, http.StatusServiceUnavailable, &errorResponse{Error: err.Error()})
+
+		return
+	}
+
+	s.logger.Debug("Sensor registered through push endpoint.", slog.String("sensor", details.ID()))
+
+	writeJSON(res, http.StatusCreated, &updateResult{Success: true})
+}
+
+// handleUpdates updates the state of the sensors in the request and publishes
+// them. As with Home Assistant, the request can contain a single update or a
+// list of updates, and the result of each update is returned by sensor.
+func (s *Server) handleUpdates(res http.ResponseWriter, data json.RawMessage) {
+	var updates []sensorState
+
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		data = append(append([]byte{'['}, data...), ']')
+	}
+
+	if err := json.Unmarshal(data, &updates); err != nil {
+		writeJSON(res, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("%v: %v", ErrInvalidRequest, err)})
+
+		return
+	}
+
+	results := make(map[string]*updateResult, len(updates))
+
+	for _, update := range updates {
+		details, err := s.update(&update)
+		if err == nil {
+			err = s.publish(details)
+		}
+
+		if err != nil {
+			code := "error"
+			if errors.Is(err, ErrNotRegistered) {
+				code = "not_registered"
+			}
+
+			results[update.UniqueID] = &updateResult{Error: &resultError{Code: code, Message: err.Error()}}
+
+			continue
+		}
+
+		results[update.UniqueID] = &updateResult{Success: true}
+	}
+
+	writeJSON(res, http.StatusOK, results)
+}
+
+// update applies the given update to the registered sensor and returns the
+// updated sensor.
+func (s *Server) update(update *sensorState) (sensor.Details, error) {
+	if update.State == nil {
+		return nil, fmt.Errorf("%w: state is required", ErrInvalidRequest)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := (&scripts.ScriptSensor{SensorID: s.prefix + update.UniqueID}).ID()
+
+	existing, found := s.sensors[id]
+	if !found {
+		return nil, ErrNotRegistered
+	}
+
+	details := *existing
+	details.SensorState = update.State
+	details.SensorAttributes = update.Attributes
+
+	if update.Icon != "" {
+		details.SensorIcon = update.Icon
+	}
+
+	s.sensors[id] = &details
+
+	return &details, nil
+}
+
+// writeJSON writes the given value as the JSON body of the response.
+func writeJSON(res http.ResponseWriter, code int, value any) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(code)
+
+	_ = json.NewEncoder(res).Encode(value) //nolint:errcheck // the client has gone away
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package push provides a local HTTP endpoint that accepts sensor requests in
+// the same format as the Home Assistant mobile_app webhook. Tools on the same
+// device can use it to send sensors through the agent, which handles their
+// registration with Home Assistant.
+package push
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joshuar/go-hass-agent/internal/control"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/logging"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+	"github.com/joshuar/go-hass-agent/internal/scripts"
+)
+
+const (
+	// WorkerID is the ID of the worker for the push endpoint.
+	WorkerID = "push_api"
+
+	readHeaderTimeout = 5 * time.Second
+	shutdownTimeout   = 5 * time.Second
+	// maxRequestSize is the largest request body accepted.
+	maxRequestSize = 1 << 20
+)
+
+var (
+	ErrRunning    = errors.New("push endpoint already running")
+	ErrNotRunning = errors.New("push endpoint not running")
+)
+
+// Server is a worker that serves the push endpoint while it is running.
+// Sensors sent to the endpoint are published as updates of the worker.
+type Server struct {
+	logger  *slog.Logger
+	network string
+	address string
+	token   string
+	prefix  string
+	// sensors are the registered sensors, by their ID with the prefix.
+	sensors    map[string]*scripts.ScriptSensor
+	updates    chan sensor.Details
+	ctx        context.Context //nolint:containedctx
+	cancelFunc context.CancelFunc
+	// senders tracks updates being sent, so that the updates channel is only
+	// closed once they are done.
+	senders sync.WaitGroup
+	mu      sync.Mutex
+}
+
+// New creates a push endpoint with the given preferences.
+func New(ctx context.Context, prefs *preferences.Push) *Server {
+	network, address := prefs.Address()
+
+	return &Server{
+		logger:  logging.FromContext(ctx).With(slog.String("worker", WorkerID)),
+		network: network,
+		address: address,
+		token:   prefs.Token,
+		prefix:  prefs.IDPrefix(),
+		sensors: make(map[string]*scripts.ScriptSensor),
+	}
+}
+
+func (s *Server) ID() string {
+	return WorkerID
+}
+
+// Sensors returns the current value of all sensors that have been sent to the
+// endpoint.
+func (s *Server) Sensors(_ context.Context) ([]sensor.Details, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sensors := make([]sensor.Details, 0, len(s.sensors))
+	for _, details := range s.sensors {
+		sensors = append(sensors, details)
+	}
+
+	return sensors, nil
+}
+
+// Updates starts serving the endpoint. The returned channel is closed once the
+// context is canceled or Stop is called, after the endpoint has stopped.
+func (s *Server) Updates(ctx context.Context) (<-chan sensor.Details, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.updates != nil {
+		return nil, ErrRunning
+	}
+
+	listener, err := s.listen(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.ctx, s.cancelFunc = context.WithCancel(ctx)
+	s.updates = make(chan sensor.Details)
+
+	go s.serve(s.ctx, listener)
+
+	return s.updates, nil
+}
+
+// listen creates the listener for the endpoint. Unix sockets are created in
+// the same way as the control API socket.
+func (s *Server) listen(ctx context.Context) (net.Listener, error) {
+	if s.network == "unix" {
+		listener, err := control.Listen(ctx, s.address)
+		if err != nil {
+			return nil, fmt.Errorf("could not start push endpoint: %w", err)
+		}
+
+		return listener, nil
+	}
+
+	var listenConfig net.ListenConfig
+
+	listener, err := listenConfig.Listen(ctx, s.network, s.address)
+	if err != nil {
+		return nil, fmt.Errorf("could not start push endpoint: %w", err)
+	}
+
+	return listener, nil
+}
+
+// serve serves the endpoint until the context is canceled.
+func (s *Server) serve(ctx context.Context, listener net.Listener) {
+	mux := http.NewServeMux()
+	// Clients written for Home Assistant can use the endpoint by changing
+	// the server, so accept any webhook ID.
+	mux.HandleFunc("POST /api/webhook/{id}", s.handleRequest)
+	mux.HandleFunc("POST /{$}", s.handleRequest)
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+		BaseContext:       func(_ net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancelFunc := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancelFunc()
+
+		if err := server.Shutdown(shutdownCtx); err != nil { //nolint:contextcheck
+			s.logger.Debug("Could not cleanly shut down push endpoint.", slog.Any("error", err))
+		}
+	}()
+
+	s.logger.Debug("Serving push endpoint.", slog.String("address", listener.Addr().String()))
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.logger.Warn("Push endpoint stopped.", slog.Any("error", err))
+	}
+
+	// Wait for the context to be canceled if serving failed, so the channel
+	// is closed when the worker is stopped.
+	<-ctx.Done()
+
+	s.mu.Lock()
+	updates := s.updates
+	s.updates = nil
+	s.mu.Unlock()
+
+	s.senders.Wait()
+	close(updates)
+}
+
+// Stop stops serving the endpoint.
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancelFunc != nil {
+		s.cancelFunc()
+	}
+
+	return nil
+}
+
+// authorized reports whether the request has the endpoint token as its bearer
+// token.
+func (s *Server) authorized(req *http.Request) bool {
+	token, found := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+
+	return found && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// publish sends the given sensor as an update of the worker. It blocks until
+// the update is accepted by the agent.
+func (s *Server) publish(details sensor.Details) error {
+	s.mu.Lock()
+
+	if s.updates == nil {
+		s.mu.Unlock()
+
+		return ErrNotRunning
+	}
+
+	updates, ctx := s.updates, s.ctx
+
+	s.senders.Add(1)
+	defer s.senders.Done()
+
+	s.mu.Unlock()
+
+	select {
+	case updates <- details:
+		return nil
+	case <-ctx.Done():
+		return ErrNotRunning
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor/types"
+	"github.com/joshuar/go-hass-agent/internal/preferences"
+)
+
+const testToken = "a-long-enough-token"
+
+// testClient sends requests to a push endpoint on a Unix socket.
+type testClient struct {
+	client *http.Client
+}
+
+func newTestClient(socket string) *testClient {
+	return &testClient{
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var dialer net.Dialer
+
+					return dialer.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+// post sends the given request body and returns the response status and the
+// decoded response body.
+func (c *testClient) post(t *testing.T, path, token, body string) (int, map[string]any) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.TODO(), http.MethodPost, "http://push"+path, strings.NewReader(body))
+	require.NoError(t, err)
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := c.client.Do(req)
+	require.NoError(t, err)
+
+	defer res.Body.Close()
+
+	var response map[string]any
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+
+	return res.StatusCode, response
+}
+
+//revive:disable:function-length
+func TestServer(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+
+	socket := filepath.Join(t.TempDir(), "push", "push.sock")
+	server := New(ctx, &preferences.Push{Listen: "unix:" + socket, Token: testToken, Namespace: "ci", Enabled: true})
+
+	updates, err := server.Updates(ctx)
+	require.NoError(t, err)
+
+	_, err = server.Updates(ctx)
+	require.ErrorIs(t, err, ErrRunning)
+
+	client := newTestClient(socket)
+
+	// Requests must have the token.
+	status, _ := client.post(t, "/", "", `{"type":"register_sensor","data":{}}`)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, _ = client.post(t, "/", "not-the-token", `{"type":"register_sensor","data":{}}`)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	// Only sensor requests are supported.
+	status, _ = client.post(t, "/", testToken, `{"type":"get_config"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = client.post(t, "/", testToken, `{"type":"register_sensor","data":{"unique_id":"build_status"}}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// Sensors must be registered before they can be updated.
+	_, response := client.post(t, "/", testToken,
+		`{"type":"update_sensor_states","data":{"unique_id":"build_status","state":"passing"}}`)
+	assert.Equal(t, map[string]any{
+		"build_status": map[string]any{
+			"success": false,
+			"error":   map[string]any{"code": "not_registered", "message": ErrNotRegistered.Error()},
+		},
+	}, response)
+
+	// Registered sensors are published with the namespace prefix.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		status, response := client.post(t, "/api/webhook/anything", testToken,
+			`{"type":"register_sensor","data":{"unique_id":"build_status","name":"Build Status","state":"running","type":"binary_sensor","icon":"mdi:hammer"}}`)
+		assert.Equal(t, http.StatusCreated, status)
+		assert.Equal(t, map[string]any{"success": true}, response)
+	}()
+
+	details := <-updates
+	assert.Equal(t, "ci_build_status", details.ID())
+	assert.Equal(t, "Build Status", details.Name())
+	assert.Equal(t, "mdi:hammer", details.Icon())
+	assert.Equal(t, "running", details.State())
+	assert.Equal(t, types.BinarySensor, details.SensorType())
+	<-done
+
+	// Updates can be a list, and keep the registration details.
+	done = make(chan struct{})
+	go func() {
+		defer close(done)
+
+		_, response := client.post(t, "/", testToken,
+			`{"type":"update_sensor_states","data":[{"unique_id":"build_status","state":"passing","attributes":{"jobs":3}}]}`)
+		assert.Equal(t, map[string]any{"build_status": map[string]any{"success": true}}, response)
+	}()
+
+	details = <-updates
+	assert.Equal(t, "ci_build_status", details.ID())
+	assert.Equal(t, "Build Status", details.Name())
+	assert.Equal(t, "mdi:hammer", details.Icon())
+	assert.Equal(t, "passing", details.State())
+	assert.Equal(t, map[string]any{"jobs": float64(3)}, details.Attributes())
+	<-done
+
+	sensors, err := server.Sensors(ctx)
+	require.NoError(t, err)
+	assert.Len(t, sensors, 1)
+
+	// Once stopped, the updates channel is closed.
+	require.NoError(t, server.Stop())
+
+	_, open := <-updates
+	assert.False(t, open)
+}
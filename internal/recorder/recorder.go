@@ -0,0 +1,271 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package recorder records streams of sensor updates to a file and replays
+// them. Recordings are gzip-compressed JSON lines: a header followed by one
+// line per sensor update.
+//
+//nolint:tagalign
+package recorder
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor/types"
+)
+
+const (
+	formatVersion = 1
+
+	filePerms = 0o600
+
+	// flushEvery is the number of records after which the recording is
+	// flushed to disk, so that little is lost if the agent exits uncleanly.
+	flushEvery = 50
+)
+
+var (
+	ErrInvalidRecording = errors.New("invalid recording")
+	ErrUnknownVersion   = errors.New("unsupported recording version")
+)
+
+// Header is the first line of a recording.
+type Header struct {
+	Started      time.Time `json:"started"`
+	AgentVersion string    `json:"agent_version,omitempty"`
+	Version      int       `json:"version"`
+}
+
+// Record is a single recorded sensor update. It satisfies sensor.Details, so
+// it can be processed like the original update.
+type Record struct {
+	Time              time.Time               `json:"time"`
+	SensorState       any                     `json:"state,omitempty"`
+	SensorLocation    *sensor.LocationRequest `json:"location,omitempty"`
+	SensorAttributes  map[string]any          `json:"attributes,omitempty"`
+	SensorID          string                  `json:"id"`
+	SensorName        string                  `json:"name,omitempty"`
+	SensorIcon        string                  `json:"icon,omitempty"`
+	SensorUnits       string                  `json:"units,omitempty"`
+	SensorCategory    string                  `json:"category,omitempty"`
+	SensorClass       types.SensorClass       `json:"type,omitempty"`
+	SensorDeviceClass types.DeviceClass       `json:"device_class,omitempty"`
+	SensorStateClass  types.StateClass        `json:"state_class,omitempty"`
+}
+
+// NewRecord creates a new Record of the given sensor update at the given time.
+func NewRecord(details sensor.Details, at time.Time) *Record {
+	record := &Record{
+		Time:              at,
+		SensorAttributes:  details.Attributes(),
+		SensorID:          details.ID(),
+		SensorName:        details.Name(),
+		SensorIcon:        details.Icon(),
+		SensorUnits:       details.Units(),
+		SensorCategory:    details.Category(),
+		SensorClass:       details.SensorType(),
+		SensorDeviceClass: details.DeviceClass(),
+		SensorStateClass:  details.StateClass(),
+	}
+
+	if location, ok := details.State().(*sensor.LocationRequest); ok {
+		record.SensorLocation = location
+	} else {
+		record.SensorState = details.State()
+	}
+
+	return record
+}
+
+func (r *Record) ID() string                     { return r.SensorID }
+func (r *Record) Name() string                   { return r.SensorName }
+func (r *Record) Icon() string                   { return r.SensorIcon }
+func (r *Record) Units() string                  { return r.SensorUnits }
+func (r *Record) Category() string               { return r.SensorCategory }
+func (r *Record) Attributes() map[string]any     { return r.SensorAttributes }
+func (r *Record) SensorType() types.SensorClass  { return r.SensorClass }
+func (r *Record) DeviceClass() types.DeviceClass { return r.SensorDeviceClass }
+func (r *Record) StateClass() types.StateClass   { return r.SensorStateClass }
+
+// State returns the recorded state. For a location update, this is the
+// recorded *sensor.LocationRequest.
+func (r *Record) State() any {
+	if r.SensorLocation != nil {
+		return r.SensorLocation
+	}
+
+	return r.SensorState
+}
+
+// Recorder records sensor updates to a file.
+type Recorder struct {
+	file    *os.File
+	gzip    *gzip.Writer
+	buf     *bufio.Writer
+	encoder *json.Encoder
+	mu      sync.Mutex
+	count   int
+}
+
+// New creates a new Recorder that records to the file at the given path. Any
+// existing file is replaced.
+func New(path, agentVersion string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, filePerms)
+	if err != nil {
+		return nil, fmt.Errorf("could not create recording: %w", err)
+	}
+
+	recorder := &Recorder{file: file}
+	recorder.gzip = gzip.NewWriter(file)
+	recorder.buf = bufio.NewWriter(recorder.gzip)
+	recorder.encoder = json.NewEncoder(recorder.buf)
+
+	header := &Header{
+		Version:      formatVersion,
+		Started:      time.Now(),
+		AgentVersion: agentVersion,
+	}
+
+	if err := recorder.encoder.Encode(header); err != nil {
+		return nil, errors.Join(fmt.Errorf("could not write recording header: %w", err), file.Close())
+	}
+
+	return recorder, nil
+}
+
+// Record records the given sensor update.
+func (r *Recorder) Record(details sensor.Details) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.encoder.Encode(NewRecord(details, time.Now())); err != nil {
+		return fmt.Errorf("could not record sensor %s: %w", details.ID(), err)
+	}
+
+	r.count++
+
+	if r.count%flushEvery == 0 {
+		return r.flush()
+	}
+
+	return nil
+}
+
+// flush writes any buffered records to disk.
+func (r *Recorder) flush() error {
+	if err := r.buf.Flush(); err != nil {
+		return fmt.Errorf("could not flush recording: %w", err)
+	}
+
+	if err := r.gzip.Flush(); err != nil {
+		return fmt.Errorf("could not flush recording: %w", err)
+	}
+
+	return nil
+}
+
+// Close flushes any buffered records and closes the recording.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return errors.Join(r.buf.Flush(), r.gzip.Close(), r.file.Close())
+}
+
+// Player replays a recording.
+type Player struct {
+	file    *os.File
+	decoder *json.Decoder
+	Header  Header
+}
+
+// Open opens the recording at the given path for replay.
+func Open(path string) (*Player, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open recording: %w", err)
+	}
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("%w: %w", ErrInvalidRecording, err), file.Close())
+	}
+
+	player := &Player{
+		file:    file,
+		decoder: json.NewDecoder(gz),
+	}
+
+	if err := player.decoder.Decode(&player.Header); err != nil {
+		return nil, errors.Join(fmt.Errorf("%w: could not read header: %w", ErrInvalidRecording, err), file.Close())
+	}
+
+	if player.Header.Version != formatVersion {
+		return nil, errors.Join(fmt.Errorf("%w: %d", ErrUnknownVersion, player.Header.Version), file.Close())
+	}
+
+	return player, nil
+}
+
+// Play calls the given function with each record, in order. Records are
+// played at the original speed multiplied by the given speed. A speed of 0 or
+// less plays records as fast as possible. Play returns when all records have
+// been played or the context is canceled. Any errors returned by the function
+// are combined and returned.
+func (p *Player) Play(ctx context.Context, speed float64, playFunc func(record *Record) error) error {
+	var (
+		errs error
+		last time.Time
+	)
+
+	for {
+		record := &Record{}
+
+		if err := p.decoder.Decode(record); err != nil {
+			if errors.Is(err, io.EOF) {
+				return errs
+			}
+
+			return errors.Join(errs, fmt.Errorf("%w: %w", ErrInvalidRecording, err))
+		}
+
+		if speed > 0 && !last.IsZero() {
+			delay := time.Duration(float64(record.Time.Sub(last)) / speed)
+
+			select {
+			case <-ctx.Done():
+				return errs
+			case <-time.After(delay):
+			}
+		} else if ctx.Err() != nil {
+			return errs
+		}
+
+		last = record.Time
+
+		if err := playFunc(record); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+}
+
+// Close closes the recording.
+func (p *Player) Close() error {
+	if err := p.file.Close(); err != nil {
+		return fmt.Errorf("could not close recording: %w", err)
+	}
+
+	return nil
+}
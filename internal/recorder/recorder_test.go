@@ -0,0 +1,164 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package recorder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor/types"
+)
+
+func TestRecordAndPlay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.gz")
+
+	updates := []sensor.Details{
+		&Record{
+			SensorID:         "cpu_usage",
+			SensorName:       "CPU Usage",
+			SensorState:      12.5,
+			SensorUnits:      "%",
+			SensorIcon:       "mdi:cpu-64-bit",
+			SensorStateClass: types.StateClassMeasurement,
+			SensorCategory:   "diagnostic",
+			SensorAttributes: map[string]any{"data_source": "procfs"},
+		},
+		&Record{
+			SensorID:       "location",
+			SensorLocation: &sensor.LocationRequest{Gps: []float64{-33.8, 151.2}, GpsAccuracy: 10},
+		},
+		&Record{SensorID: "online", SensorState: true, SensorClass: types.BinarySensor},
+	}
+
+	rec, err := New(path, "v1.0.0")
+	require.NoError(t, err)
+
+	for _, details := range updates {
+		require.NoError(t, rec.Record(details))
+	}
+
+	require.NoError(t, rec.Close())
+
+	player, err := Open(path)
+	require.NoError(t, err)
+
+	defer player.Close()
+
+	assert.Equal(t, "v1.0.0", player.Header.AgentVersion)
+
+	var played []*Record
+
+	err = player.Play(context.TODO(), 0, func(record *Record) error {
+		played = append(played, record)
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, played, len(updates))
+
+	for idx, record := range played {
+		assert.Equal(t, updates[idx].ID(), record.ID())
+		assert.Equal(t, updates[idx].Name(), record.Name())
+		assert.Equal(t, updates[idx].Units(), record.Units())
+		assert.Equal(t, updates[idx].Icon(), record.Icon())
+		assert.Equal(t, updates[idx].SensorType(), record.SensorType())
+		assert.Equal(t, updates[idx].StateClass(), record.StateClass())
+		assert.Equal(t, updates[idx].Category(), record.Category())
+		assert.Equal(t, updates[idx].Attributes(), record.Attributes())
+		assert.Equal(t, updates[idx].State(), record.State())
+	}
+}
+
+func TestPlayer_Play(t *testing.T) {
+	start := time.Now()
+
+	tests := []struct {
+		name        string
+		speed       float64
+		minDuration time.Duration
+		maxDuration time.Duration
+	}{
+		{
+			name:        "original speed",
+			speed:       1,
+			minDuration: 200 * time.Millisecond,
+			maxDuration: time.Second,
+		},
+		{
+			name:        "accelerated",
+			speed:       4,
+			minDuration: 50 * time.Millisecond,
+			maxDuration: 150 * time.Millisecond,
+		},
+		{
+			name:        "as fast as possible",
+			maxDuration: 50 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "recording.gz")
+			writeRecording(t, path,
+				NewRecord(&Record{SensorID: "a"}, start),
+				NewRecord(&Record{SensorID: "b"}, start.Add(100*time.Millisecond)),
+				NewRecord(&Record{SensorID: "c"}, start.Add(200*time.Millisecond)),
+			)
+
+			player, err := Open(path)
+			require.NoError(t, err)
+
+			defer player.Close()
+
+			var ids []string
+
+			begin := time.Now()
+			err = player.Play(context.TODO(), tt.speed, func(record *Record) error {
+				ids = append(ids, record.ID())
+
+				return nil
+			})
+			elapsed := time.Since(begin)
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{"a", "b", "c"}, ids)
+			assert.GreaterOrEqual(t, elapsed, tt.minDuration)
+			assert.Less(t, elapsed, tt.maxDuration)
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	notRecording := filepath.Join(t.TempDir(), "notrecording")
+	require.NoError(t, os.WriteFile(notRecording, []byte("not a recording"), 0o600))
+
+	_, err := Open(notRecording)
+	require.ErrorIs(t, err, ErrInvalidRecording)
+
+	_, err = Open(filepath.Join(t.TempDir(), "missing"))
+	require.Error(t, err)
+}
+
+// writeRecording writes the given records to a recording at the given path.
+func writeRecording(t *testing.T, path string, records ...*Record) {
+	t.Helper()
+
+	rec, err := New(path, "")
+	require.NoError(t, err)
+
+	for _, record := range records {
+		require.NoError(t, rec.encoder.Encode(record))
+	}
+
+	require.NoError(t, rec.Close())
+}
@@ -14,6 +14,7 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"sync"
 
 	"github.com/robfig/cron/v3"
 
@@ -26,10 +27,14 @@ var (
 	ErrAlreadyStarted   = errors.New("script already started")
 	ErrAlreadyStopped   = errors.New("script already stopped")
 	ErrSchedulingFailed = errors.New("failed to schedule script")
+	ErrSensorCollision  = errors.New("sensor ID already used by another script")
 )
 
 type job struct {
 	logAttrs slog.Attr
+	// done is closed when the job is stopped, so that a running script does
+	// not block sending its sensors.
+	done chan struct{}
 	Script
 	ID cron.EntryID
 }
@@ -37,10 +42,21 @@ type job struct {
 type Controller struct {
 	scheduler *cron.Cron
 	logger    *slog.Logger
-	jobs      []job
+	// owners maps sensor IDs to the path of the script that first output
+	// them. It is used to detect sensor ID collisions between scripts.
+	owners map[string]string
+	jobs   []job
+	// dirs are the directories searched for scripts.
+	dirs []string
+	mu   sync.Mutex
+	// jobsMu guards jobs and dirs.
+	jobsMu sync.Mutex
 }
 
 func (c *Controller) ActiveWorkers() []string {
+	c.jobsMu.Lock()
+	defer c.jobsMu.Unlock()
+
 	var activeScripts []string
 
 	for _, job := range c.jobs {
@@ -53,6 +69,9 @@ func (c *Controller) ActiveWorkers() []string {
 }
 
 func (c *Controller) InactiveWorkers() []string {
+	c.jobsMu.Lock()
+	defer c.jobsMu.Unlock()
+
 	var inactiveScripts []string
 
 	for _, job := range c.jobs {
@@ -64,7 +83,10 @@ func (c *Controller) InactiveWorkers() []string {
 	return inactiveScripts
 }
 
-func (c *Controller) Start(_ context.Context, name string) (<-chan sensor.Details, error) {
+func (c *Controller) Start(ctx context.Context, name string) (<-chan sensor.Details, error) {
+	c.jobsMu.Lock()
+	defer c.jobsMu.Unlock()
+
 	found := slices.IndexFunc(c.jobs, func(j job) bool { return j.path == name })
 
 	// If the script was not found, return an error.
@@ -79,34 +101,51 @@ func (c *Controller) Start(_ context.Context, name string) (<-chan sensor.Detail
 	sensorCh := make(chan sensor.Details)
 
 	// Schedule the script.
-	id, err := c.scheduler.AddFunc(c.jobs[found].Schedule(), func() {
-		sensors, err := c.jobs[found].Execute()
-		if err != nil {
-			c.logger.Warn("Could not execute script.",
-				c.jobs[found].logAttrs,
-				slog.Any("error", err))
-
-			return
-		}
-
-		for _, o := range sensors {
-			sensorCh <- o
-		}
-	})
-	if err != nil {
+	if err := c.schedule(ctx, found, sensorCh); err != nil {
 		close(sensorCh)
 
 		return nil, ErrSchedulingFailed
 	}
 
-	// Update the job id.
-	c.jobs[found].ID = id
+	// Make sure the scheduler is running, in case no other scripts have been
+	// started.
+	c.scheduler.Start()
 
 	// Return the new sensor channel for the script.
 	return sensorCh, nil
 }
 
+// WorkerSensors runs the named script once and returns its sensors.
+func (c *Controller) WorkerSensors(_ context.Context, name string) ([]sensor.Details, error) {
+	c.jobsMu.Lock()
+	found := slices.IndexFunc(c.jobs, func(j job) bool { return j.path == name })
+
+	if found == -1 {
+		c.jobsMu.Unlock()
+
+		return nil, ErrUnknownScript
+	}
+
+	script := c.jobs[found].Script
+	c.jobsMu.Unlock()
+
+	sensors, err := script.Execute()
+	if err != nil {
+		return nil, fmt.Errorf("could not execute script: %w", err)
+	}
+
+	return sensors, nil
+}
+
 func (c *Controller) Stop(name string) error {
+	c.jobsMu.Lock()
+	defer c.jobsMu.Unlock()
+
+	return c.stop(name)
+}
+
+// stop stops the job of the named script. jobsMu must be held.
+func (c *Controller) stop(name string) error {
 	found := slices.IndexFunc(c.jobs, func(j job) bool { return j.path == name })
 
 	// If the script was not found, return an error.
@@ -118,14 +157,48 @@ func (c *Controller) Stop(name string) error {
 		return ErrAlreadyStopped
 	}
 
-	c.scheduler.Remove(c.jobs[found].ID)
+	c.unschedule(found)
+
+	return nil
+}
+
+// schedule adds the job at the given index to the scheduler, sending the
+// sensors of its script on the given channel. jobsMu must be held.
+func (c *Controller) schedule(ctx context.Context, idx int, sensorCh chan sensor.Details) error {
+	done := make(chan struct{})
 
-	c.jobs[found].ID = 0
+	id, err := c.scheduler.AddFunc(c.jobs[idx].schedule, c.runFunc(ctx, c.jobs[idx], done, sensorCh))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSchedulingFailed, err)
+	}
+
+	c.jobs[idx].ID = id
+	c.jobs[idx].done = done
 
 	return nil
 }
 
-func (c *Controller) StartAll(_ context.Context) (<-chan sensor.Details, error) {
+// unschedule removes the job at the given index from the scheduler. Any run of
+// its script that is in progress stops sending sensors. jobsMu must be held.
+func (c *Controller) unschedule(idx int) {
+	if c.jobs[idx].ID == 0 {
+		return
+	}
+
+	c.scheduler.Remove(c.jobs[idx].ID)
+
+	if c.jobs[idx].done != nil {
+		close(c.jobs[idx].done)
+	}
+
+	c.jobs[idx].ID = 0
+	c.jobs[idx].done = nil
+}
+
+func (c *Controller) StartAll(ctx context.Context) (<-chan sensor.Details, error) {
+	c.jobsMu.Lock()
+	defer c.jobsMu.Unlock()
+
 	sensorCh := make(chan sensor.Details)
 
 	// If there are no jobs, exit.
@@ -142,33 +215,22 @@ func (c *Controller) StartAll(_ context.Context) (<-chan sensor.Details, error)
 			continue
 		}
 
-		// Create a closure to run the script on it's schedule.
-		runFunc := func() {
-			sensors, err := job.Script.Execute()
-			if err != nil {
-				c.logger.Warn("Could not execute script.",
-					job.logAttrs,
-					slog.Any("error", err))
+		if !job.Enabled() {
+			c.logger.Debug("Script disabled, not scheduling.", job.logAttrs)
 
-				return
-			}
-
-			for _, o := range sensors {
-				sensorCh <- o
-			}
+			continue
 		}
-		// Add the script to the cron scheduler to run the closure on it's
-		// defined schedule.
-		id, err := c.scheduler.AddFunc(job.schedule, runFunc)
-		if err != nil {
+
+		// Add the script to the cron scheduler to run on it's defined
+		// schedule.
+		if err := c.schedule(ctx, idx, sensorCh); err != nil {
 			c.logger.Warn("Unable to schedule script",
 				job.logAttrs,
 				slog.Any("error", err))
 		} else {
-			c.jobs[idx].ID = id
 			c.logger.Debug("Added cron job.",
 				job.logAttrs,
-				slog.Any("job_id", id))
+				slog.Any("job_id", c.jobs[idx].ID))
 		}
 	}
 
@@ -178,12 +240,74 @@ func (c *Controller) StartAll(_ context.Context) (<-chan sensor.Details, error)
 	return sensorCh, nil
 }
 
+// runFunc creates a closure that will run the script of the given job and send
+// its sensors on the given channel, until the job is stopped or the context is
+// canceled.
+func (c *Controller) runFunc(ctx context.Context, job job, done chan struct{}, sensorCh chan sensor.Details) func() {
+	return func() {
+		sensors, err := job.Script.Execute()
+		if err != nil {
+			c.logger.Warn("Could not execute script.",
+				job.logAttrs,
+				slog.Any("error", err))
+
+			return
+		}
+
+		for _, o := range c.claimSensors(job.path, sensors) {
+			select {
+			case sensorCh <- o:
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// claimSensors returns the sensors from the given list that are owned by the
+// given script. A sensor is owned by the first script that outputs it. Any
+// sensors with an ID already owned by another script are reported and dropped.
+func (c *Controller) claimSensors(script string, sensors []sensor.Details) []sensor.Details {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.owners == nil {
+		c.owners = make(map[string]string)
+	}
+
+	claimed := make([]sensor.Details, 0, len(sensors))
+
+	for _, details := range sensors {
+		owner, found := c.owners[details.ID()]
+
+		switch {
+		case !found:
+			c.owners[details.ID()] = script
+		case owner != script:
+			c.logger.Warn("Ignoring sensor from script.",
+				slog.String("script", script),
+				slog.String("sensor_id", details.ID()),
+				slog.String("owner", owner),
+				slog.Any("error", ErrSensorCollision))
+
+			continue
+		}
+
+		claimed = append(claimed, details)
+	}
+
+	return claimed
+}
+
 func (c *Controller) StopAll() error {
+	c.jobsMu.Lock()
 	for idx, job := range c.jobs {
 		c.logger.Debug("Removing cron job.", job.logAttrs)
-		c.scheduler.Remove(job.ID)
-		c.jobs[idx].ID = 0
+		c.unschedule(idx)
 	}
+	c.jobsMu.Unlock()
 
 	c.logger.Debug("Stopping cron scheduler.")
 	waitCtx := c.scheduler.Stop()
@@ -194,58 +318,187 @@ func (c *Controller) StopAll() error {
 	return nil
 }
 
-// NewScriptController creates a new sensor controller for scripts.
-func NewScriptsController(ctx context.Context, path string) (*Controller, error) {
+// NewScriptController creates a new sensor controller for scripts. Scripts are
+// found in the given directories, in order. See DiscoverDirs for how scripts in
+// multiple directories are handled.
+func NewScriptsController(ctx context.Context, dirs ...string) (*Controller, error) {
 	controller := &Controller{
 		scheduler: cron.New(),
 		logger:    logging.FromContext(ctx).With(slog.String("controller", "scripts")),
 	}
 
-	scripts, err := findScripts(path)
+	controller.dirs = dirs
+
+	scripts, err := findScripts(dirs...)
 	if err != nil {
-		return nil, fmt.Errorf("could not find scripts: %w", err)
+		// Problems with individual scripts are not fatal, just report them.
+		controller.logger.Warn("Some scripts could not be added.", slog.Any("error", err))
 	}
 
 	controller.jobs = make([]job, 0, len(scripts))
 
 	for _, s := range scripts {
-		logAttrs := slog.Group("job", slog.String("script", s.path), slog.String("schedule", s.schedule))
-		controller.jobs = append(controller.jobs, job{Script: *s, logAttrs: logAttrs})
+		controller.jobs = append(controller.jobs, newJob(s))
 	}
 
 	return controller, nil
 }
 
-// findScripts locates scripts and returns a slice of scripts that the agent can
-// run.
-func findScripts(path string) ([]*Script, error) {
-	var sensorScripts []*Script
+// SetDirs changes the directories searched for scripts. The change takes
+// effect on the next Reload.
+func (c *Controller) SetDirs(dirs ...string) {
+	c.jobsMu.Lock()
+	defer c.jobsMu.Unlock()
+
+	c.dirs = dirs
+}
+
+// Reload will search for scripts again and compare them against the current
+// jobs. Jobs for scripts that have been removed or whose definition has changed
+// are stopped and removed. Jobs for new or changed scripts are added, but not
+// started. The paths of the added and removed scripts are returned. Added
+// scripts that are enabled can be started with Start. Any problems finding
+// scripts are returned as an error, but do not prevent the reload.
+func (c *Controller) Reload(_ context.Context) (added, removed []string, err error) {
+	c.jobsMu.Lock()
+	defer c.jobsMu.Unlock()
+
+	scripts, err := findScripts(c.dirs...)
+
+	jobs := make([]job, 0, len(scripts))
+
+	for _, current := range c.jobs {
+		idx := slices.IndexFunc(scripts, func(s *Script) bool { return s.path == current.path })
+		if idx != -1 && scripts[idx].equal(&current.Script) {
+			jobs = append(jobs, current)
+
+			continue
+		}
+
+		if current.ID > 0 {
+			if err := c.stop(current.path); err != nil {
+				c.logger.Warn("Could not stop script.", current.logAttrs, slog.Any("error", err))
+			}
+		}
+
+		c.releaseSensors(current.path)
+
+		removed = append(removed, current.path)
+	}
+
+	for _, script := range scripts {
+		if slices.ContainsFunc(jobs, func(j job) bool { return j.path == script.path }) {
+			continue
+		}
+
+		jobs = append(jobs, newJob(script))
+
+		if script.Enabled() {
+			added = append(added, script.path)
+		}
+	}
 
-	var errs error
+	c.jobs = jobs
+
+	return added, removed, err
+}
+
+// releaseSensors removes the given script as the owner of any sensors, so that
+// they can be claimed by other scripts.
+func (c *Controller) releaseSensors(script string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, owner := range c.owners {
+		if owner == script {
+			delete(c.owners, id)
+		}
+	}
+}
+
+// newJob creates a new (unscheduled) job for the given script.
+func newJob(script *Script) job {
+	logAttrs := slog.Group("job",
+		slog.String("script", script.path),
+		slog.String("schedule", script.schedule),
+		slog.String("source", script.source))
+
+	return job{Script: *script, logAttrs: logAttrs}
+}
+
+// Discovery is the result of discovering a single script. If the script could
+// not be added, Err will be non-nil and Script will be nil.
+type Discovery struct {
+	Script *Script
+	Err    error
+	Path   string
+	// Overrides is the path of any script with the same name in another
+	// directory that this script replaces.
+	Overrides string
+}
+
+// Discover locates all scripts in the given path and returns the result of
+// discovering each of them. Any sidecar files are not considered scripts. If
+// the path does not exist, no scripts are returned. If the path has insecure
+// ownership or permissions, an error is returned. Scripts with insecure
+// ownership or permissions are not run and are reported with an error.
+func Discover(path string) ([]Discovery, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err := CheckPermissions(path); err != nil {
+		return nil, fmt.Errorf("could not search for scripts: %w", err)
+	}
 
 	files, err := filepath.Glob(path + "/*")
 	if err != nil {
 		return nil, fmt.Errorf("could not search for scripts: %w", err)
 	}
 
+	results := make([]Discovery, 0, len(files))
+
 	for _, scriptFile := range files {
-		if isExecutable(scriptFile) {
-			script, err := NewScript(scriptFile)
-			if err != nil {
-				errs = errors.Join(errs, err)
+		if filepath.Ext(scriptFile) == sidecarExt || !IsExecutable(scriptFile) {
+			continue
+		}
 
-				continue
-			}
+		if err := CheckPermissions(scriptFile); err != nil {
+			results = append(results, Discovery{Path: scriptFile, Err: err})
 
-			sensorScripts = append(sensorScripts, script)
+			continue
 		}
+
+		script, err := NewScript(scriptFile)
+		results = append(results, Discovery{Path: scriptFile, Script: script, Err: err})
+	}
+
+	return results, nil
+}
+
+// findScripts locates scripts in the given directories and returns a slice of
+// scripts that the agent can run. Any scripts or directories that could not be
+// added are returned as a combined error.
+func findScripts(dirs ...string) ([]*Script, error) {
+	var sensorScripts []*Script
+
+	results, errs := DiscoverDirs(dirs...)
+
+	for _, result := range results {
+		if result.Err != nil {
+			errs = errors.Join(errs, result.Err)
+
+			continue
+		}
+
+		sensorScripts = append(sensorScripts, result.Script)
 	}
 
-	return sensorScripts, nil
+	return sensorScripts, errs
 }
 
-// isExecutable is helper to determine if a (script) file is executable.
-func isExecutable(filename string) bool {
+// IsExecutable is helper to determine if a (script) file is executable.
+func IsExecutable(filename string) bool {
 	fi, err := os.Stat(filename)
 	if err != nil {
 		return false
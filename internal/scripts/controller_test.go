@@ -10,8 +10,11 @@ package scripts
 import (
 	"context"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/robfig/cron/v3"
 	"github.com/stretchr/testify/assert"
@@ -102,7 +105,6 @@ func TestController_Start(t *testing.T) {
 		jobs      []job
 	}
 	type args struct {
-		in0  context.Context
 		name string
 	}
 	tests := []struct {
@@ -133,6 +135,7 @@ func TestController_Start(t *testing.T) {
 			args: args{name: "echo start"},
 			fields: fields{
 				scheduler: cron.New(),
+				logger:    slog.Default(),
 				jobs:      []job{{Script: Script{path: "echo start", schedule: "@every 1s"}}, {Script: Script{path: "echo false"}}},
 			},
 		},
@@ -144,7 +147,11 @@ func TestController_Start(t *testing.T) {
 				logger:    tt.fields.logger,
 				jobs:      tt.fields.jobs,
 			}
-			_, err := c.Start(tt.args.in0, tt.args.name)
+			if c.scheduler != nil {
+				t.Cleanup(func() { c.StopAll() }) //nolint:errcheck
+			}
+
+			_, err := c.Start(context.TODO(), tt.args.name)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Controller.Start() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -224,11 +231,7 @@ func TestController_StartAll(t *testing.T) {
 		logger    *slog.Logger
 		jobs      []job
 	}
-	type args struct {
-		in0 context.Context
-	}
 	tests := []struct {
-		args    args
 		want    <-chan sensor.Details
 		name    string
 		fields  fields
@@ -250,7 +253,9 @@ func TestController_StartAll(t *testing.T) {
 				logger:    tt.fields.logger,
 				jobs:      tt.fields.jobs,
 			}
-			_, err := c.StartAll(tt.args.in0)
+			t.Cleanup(func() { c.StopAll() }) //nolint:errcheck
+
+			_, err := c.StartAll(context.TODO())
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Controller.StartAll() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -342,6 +347,81 @@ func TestNewScriptsController(t *testing.T) {
 	}
 }
 
+func TestController_claimSensors(t *testing.T) {
+	sensorA := &ScriptSensor{SensorName: "Sensor A"}
+	sensorB := &ScriptSensor{SensorName: "Sensor B"}
+
+	c := &Controller{
+		logger: slog.Default(),
+		owners: map[string]string{sensorB.ID(): "other"},
+	}
+
+	type args struct {
+		script  string
+		sensors []sensor.Details
+	}
+	tests := []struct {
+		name string
+		args args
+		want []sensor.Details
+	}{
+		{
+			name: "new and colliding sensors",
+			args: args{script: "script", sensors: []sensor.Details{sensorA, sensorB}},
+			want: []sensor.Details{sensorA},
+		},
+		{
+			name: "owned sensors",
+			args: args{script: "other", sensors: []sensor.Details{sensorB}},
+			want: []sensor.Details{sensorB},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.claimSensors(tt.args.script, tt.args.sensors); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Controller.claimSensors() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	type args struct {
+		path string
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantPaths []string
+		wantErr   bool
+	}{
+		{
+			name:      "with scripts",
+			args:      args{path: "testing/data"},
+			wantPaths: []string{"testing/data/jsonTestScript.sh", "testing/data/sidecarTestScript.sh"},
+		},
+		{
+			name: "without scripts",
+			args: args{path: "foo/bar"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Discover(tt.args.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Discover() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			paths := make([]string, 0, len(got))
+			for _, result := range got {
+				require.NoError(t, result.Err)
+				paths = append(paths, result.Path)
+			}
+			assert.ElementsMatch(t, tt.wantPaths, paths)
+		})
+	}
+}
+
 func Test_findScripts(t *testing.T) {
 	script, err := NewScript("testing/data/jsonTestScript.sh")
 	require.NoError(t, err)
@@ -403,9 +483,84 @@ func Test_isExecutable(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := isExecutable(tt.args.filename); got != tt.want {
-				t.Errorf("isExecutable() = %v, want %v", got, tt.want)
+			if got := IsExecutable(tt.args.filename); got != tt.want {
+				t.Errorf("IsExecutable() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestController_Reload(t *testing.T) {
+	dir := t.TempDir()
+	unchanged := writeTestScript(t, dir, "unchanged.sh", 0o755)
+	changed := writeTestScript(t, dir, "changed.sh", 0o755)
+
+	controller, err := NewScriptsController(context.TODO(), dir)
+	require.NoError(t, err)
+	_, err = controller.Start(context.TODO(), unchanged)
+	require.NoError(t, err)
+	_, err = controller.Start(context.TODO(), changed)
+	require.NoError(t, err)
+
+	// Change the schedule of a script and add a new script.
+	require.NoError(t, os.WriteFile(changed,
+		[]byte("#!/bin/sh\necho '{\"schedule\":\"@every 10s\",\"sensors\":[]}'\n"), 0o755))
+	newScript := writeTestScript(t, dir, "new.sh", 0o755)
+
+	added, removed, err := controller.Reload(context.TODO())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{changed, newScript}, added)
+	assert.Equal(t, []string{changed}, removed)
+	assert.Equal(t, []string{unchanged}, controller.ActiveWorkers())
+	assert.ElementsMatch(t, []string{changed, newScript}, controller.InactiveWorkers())
+
+	// Remove a script.
+	require.NoError(t, os.Remove(newScript))
+
+	added, removed, err = controller.Reload(context.TODO())
+	require.NoError(t, err)
+	assert.Empty(t, added)
+	assert.Equal(t, []string{newScript}, removed)
+}
+
+func TestController_runFunc(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "sensor.sh")
+	require.NoError(t, os.WriteFile(script,
+		[]byte("#!/bin/sh\necho '{\"schedule\":\"@every 5s\",\"sensors\":[{\"sensor_name\":\"Sensor\",\"sensor_state\":1}]}'\n"), 0o755))
+
+	controller, err := NewScriptsController(context.TODO(), dir)
+	require.NoError(t, err)
+	require.Len(t, controller.jobs, 1)
+
+	// A run that cannot send its sensors returns once the job is stopped or
+	// the context is canceled.
+	run := func(t *testing.T, ctx context.Context, done chan struct{}, stop func()) {
+		t.Helper()
+
+		finished := make(chan struct{})
+
+		go func() {
+			defer close(finished)
+			controller.runFunc(ctx, controller.jobs[0], done, make(chan sensor.Details))()
+		}()
+
+		stop()
+
+		select {
+		case <-finished:
+		case <-time.After(5 * time.Second):
+			t.Fatal("script run did not return")
+		}
+	}
+
+	t.Run("stopped", func(t *testing.T) {
+		done := make(chan struct{})
+		run(t, context.TODO(), done, func() { close(done) })
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancelFunc := context.WithCancel(context.TODO())
+		run(t, ctx, make(chan struct{}), cancelFunc)
+	})
+}
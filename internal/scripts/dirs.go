@@ -0,0 +1,106 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package scripts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+var ErrInsecurePermissions = errors.New("insecure ownership or permissions")
+
+const (
+	// worldWritable is the permission bit for a file writable by any user.
+	worldWritable = 0o002
+	// groupWritable is the permission bit for a file writable by the users in
+	// its group.
+	groupWritable = 0o020
+)
+
+// DiscoverDirs discovers the scripts in each of the given directories, in
+// order. A script in a later directory overrides any script with the same file
+// name in an earlier directory. Directories that do not exist are ignored.
+// Directories with insecure ownership or permissions are skipped and returned
+// as a combined error.
+func DiscoverDirs(dirs ...string) ([]Discovery, error) {
+	var (
+		results []Discovery
+		errs    error
+	)
+
+	found := make(map[string]int)
+
+	for _, dir := range dirs {
+		dirResults, err := Discover(dir)
+		if err != nil {
+			errs = errors.Join(errs, err)
+
+			continue
+		}
+
+		for _, result := range dirResults {
+			name := filepath.Base(result.Path)
+
+			if idx, ok := found[name]; ok {
+				result.Overrides = results[idx].Path
+				results[idx] = result
+
+				continue
+			}
+
+			found[name] = len(results)
+			results = append(results, result)
+		}
+	}
+
+	return results, errs
+}
+
+// CheckPermissions checks that the given path, either a file or a directory, is
+// owned by either root or the user running the agent and is not writable by
+// other users. It may only be writable by its group if that is the private group
+// of the user running the agent.
+func CheckPermissions(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("could not check permissions: %w", err)
+	}
+
+	if info.Mode().Perm()&worldWritable != 0 {
+		return fmt.Errorf("%w: %s is writable by other users", ErrInsecurePermissions, path)
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		if stat.Uid != 0 && int(stat.Uid) != os.Getuid() {
+			return fmt.Errorf("%w: %s is owned by another user (uid %d)", ErrInsecurePermissions, path, stat.Uid)
+		}
+
+		if info.Mode().Perm()&groupWritable != 0 && !isPrivateGroup(stat.Gid) {
+			return fmt.Errorf("%w: %s is writable by group %d", ErrInsecurePermissions, path, stat.Gid)
+		}
+	}
+
+	return nil
+}
+
+// isPrivateGroup reports whether the given group is the private group of the
+// user running the agent, that is, their primary group with the same name as
+// the user.
+func isPrivateGroup(gid uint32) bool {
+	current, err := user.Current()
+	if err != nil || current.Gid != strconv.FormatUint(uint64(gid), 10) {
+		return false
+	}
+
+	group, err := user.LookupGroupId(current.Gid)
+
+	return err == nil && group.Name == current.Username
+}
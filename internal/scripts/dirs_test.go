@@ -0,0 +1,143 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package scripts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testScript = "#!/bin/sh\necho '{\"schedule\":\"@every 5s\",\"sensors\":[]}'\n"
+
+func writeTestScript(t *testing.T, dir, name string, perms os.FileMode) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(testScript), perms))
+	// Explicitly set permissions to avoid umask.
+	require.NoError(t, os.Chmod(path, perms))
+
+	return path
+}
+
+func TestDiscoverDirs(t *testing.T) {
+	systemDir := t.TempDir()
+	userDir := t.TempDir()
+
+	systemOnly := writeTestScript(t, systemDir, "system.sh", 0o755)
+	overridden := writeTestScript(t, systemDir, "common.sh", 0o755)
+	override := writeTestScript(t, userDir, "common.sh", 0o755)
+
+	insecureDir := t.TempDir()
+	require.NoError(t, os.Chmod(insecureDir, 0o777))
+
+	tests := []struct {
+		name      string
+		dirs      []string
+		wantPaths []string
+		overrides map[string]string
+		wantErr   bool
+	}{
+		{
+			name:      "user overrides system",
+			dirs:      []string{systemDir, userDir},
+			wantPaths: []string{systemOnly, override},
+			overrides: map[string]string{override: overridden},
+		},
+		{
+			name:      "missing dir ignored",
+			dirs:      []string{"/does/not/exist", userDir},
+			wantPaths: []string{override},
+		},
+		{
+			name:      "insecure dir",
+			dirs:      []string{insecureDir, userDir},
+			wantPaths: []string{override},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DiscoverDirs(tt.dirs...)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInsecurePermissions)
+			} else {
+				require.NoError(t, err)
+			}
+			paths := make([]string, 0, len(got))
+			for _, result := range got {
+				require.NoError(t, result.Err)
+				paths = append(paths, result.Path)
+				assert.Equal(t, tt.overrides[result.Path], result.Overrides)
+			}
+			assert.ElementsMatch(t, tt.wantPaths, paths)
+		})
+	}
+}
+
+func Test_checkPermissions(t *testing.T) {
+	dir := t.TempDir()
+
+	otherGroupDir := filepath.Join(dir, "other")
+	require.NoError(t, os.Mkdir(otherGroupDir, 0o775))
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr error
+		// otherGroup moves the path to a group other than the user's
+		// private group.
+		otherGroup bool
+	}{
+		{
+			name: "secure script",
+			path: writeTestScript(t, dir, "secure.sh", 0o755),
+		},
+		{
+			name: "group writable script",
+			path: writeTestScript(t, dir, "group.sh", 0o775),
+		},
+		{
+			name:    "world writable script",
+			path:    writeTestScript(t, dir, "insecure.sh", 0o777),
+			wantErr: ErrInsecurePermissions,
+		},
+		{
+			name:       "other group writable script",
+			path:       writeTestScript(t, dir, "other.sh", 0o775),
+			otherGroup: true,
+			wantErr:    ErrInsecurePermissions,
+		},
+		{
+			name:       "other group writable dir",
+			path:       otherGroupDir,
+			otherGroup: true,
+			wantErr:    ErrInsecurePermissions,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.otherGroup {
+				if err := os.Chown(tt.path, -1, 65534); err != nil {
+					t.Skip("Cannot change group of test file.")
+				}
+				// Changing the group can clear the permission bits.
+				require.NoError(t, os.Chmod(tt.path, 0o775))
+			}
+			err := CheckPermissions(tt.path)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
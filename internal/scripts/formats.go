@@ -0,0 +1,472 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package scripts
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+const (
+	// FormatAgent is the default script output format, JSON, YAML or TOML in
+	// the agent's own schema.
+	FormatAgent = "agent"
+	// FormatNagios is the output format of Nagios/Icinga check plugins.
+	FormatNagios = "nagios"
+	// FormatInflux is the InfluxDB line protocol, as used by Telegraf exec
+	// scripts.
+	FormatInflux = "influx"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown script output format")
+	ErrNoOutput      = errors.New("no output from script")
+	ErrInvalidOutput = errors.New("invalid script output")
+)
+
+var invalidIDChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// nagiosStates are the states of a Nagios check, indexed by exit code.
+var nagiosStates = []string{"ok", "warning", "critical", "unknown"}
+
+// nagiosUnits maps the units of Nagios performance data to Home Assistant
+// units.
+var nagiosUnits = map[string]string{
+	"s":  "s",
+	"ms": "ms",
+	"us": "μs",
+	"%":  "%",
+	"B":  "B",
+	"KB": "kB",
+	"MB": "MB",
+	"GB": "GB",
+	"TB": "TB",
+}
+
+// validFormat returns whether the given format is a supported script output
+// format.
+func validFormat(format string) bool {
+	return format == "" || slices.Contains([]string{FormatAgent, FormatNagios, FormatInflux}, format)
+}
+
+// sensorID generates a sensor ID from the given parts.
+func sensorID(parts ...string) string {
+	id := strcase.ToSnake(strings.Join(parts, "_"))
+
+	return strings.Trim(invalidIDChars.ReplaceAllString(id, "_"), "_")
+}
+
+// parseNagios parses the output and exit code of a Nagios plugin. The exit code
+// is used for a status sensor and a binary problem sensor, which are marked as
+// synthetic. Any valid performance data is parsed into numeric sensors.
+func parseNagios(name string, output []byte, exitCode int) ([]ScriptSensor, error) {
+	if exitCode < 0 || exitCode >= len(nagiosStates) {
+		exitCode = len(nagiosStates) - 1
+	}
+
+	text, longText, perfData := splitNagiosOutput(output)
+
+	attributes := map[string]any{
+		"output":    text,
+		"exit_code": exitCode,
+	}
+	if longText != "" {
+		attributes["long_output"] = longText
+	}
+
+	sensors := []ScriptSensor{
+		{
+			SensorID:         sensorID(name, "status"),
+			SensorName:       name + " Status",
+			SensorIcon:       "mdi:list-status",
+			SensorState:      nagiosStates[exitCode],
+			SensorAttributes: attributes,
+			synthetic:        true,
+		},
+		{
+			SensorID:        sensorID(name, "problem"),
+			SensorName:      name + " Problem",
+			SensorIcon:      "mdi:alert-circle",
+			SensorStateType: "binary",
+			SensorState:     exitCode != 0,
+			synthetic:       true,
+		},
+	}
+
+	// Invalid performance data is skipped, so that the status of the check is
+	// still reported.
+	for _, field := range splitNagiosPerfData(perfData) {
+		perfSensor, err := parseNagiosPerfData(name, field)
+		if err != nil {
+			slog.Warn("Ignoring invalid performance data from script.",
+				slog.String("script", name),
+				slog.Any("error", err))
+
+			continue
+		}
+
+		if perfSensor != nil {
+			sensors = append(sensors, *perfSensor)
+		}
+	}
+
+	return sensors, nil
+}
+
+// splitNagiosOutput splits Nagios plugin output into the status text, any long
+// text and performance data. Performance data follows a "|" on the first line
+// and/or any line of the long text.
+func splitNagiosOutput(output []byte) (text, longText, perfData string) {
+	var (
+		longLines []string
+		perfLines []string
+		inPerf    bool
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+
+	for lineNum := 0; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+
+		if lineNum == 0 {
+			before, after, found := strings.Cut(line, "|")
+			text = strings.TrimSpace(before)
+
+			if found {
+				perfLines = append(perfLines, after)
+			}
+
+			continue
+		}
+
+		if inPerf {
+			perfLines = append(perfLines, line)
+
+			continue
+		}
+
+		before, after, found := strings.Cut(line, "|")
+		longLines = append(longLines, before)
+
+		if found {
+			perfLines = append(perfLines, after)
+			inPerf = true
+		}
+	}
+
+	return text, strings.TrimSpace(strings.Join(longLines, "\n")), strings.Join(perfLines, " ")
+}
+
+// splitNagiosPerfData splits performance data into individual fields. Fields
+// are separated by whitespace, but labels can be quoted with single quotes to
+// include whitespace.
+func splitNagiosPerfData(perfData string) []string {
+	var (
+		fields   []string
+		current  strings.Builder
+		inQuotes bool
+	)
+
+	for _, char := range perfData {
+		switch {
+		case char == '\'':
+			inQuotes = !inQuotes
+
+			current.WriteRune(char)
+		case (char == ' ' || char == '\t' || char == '\n') && !inQuotes:
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(char)
+		}
+	}
+
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+
+	return fields
+}
+
+// parseNagiosPerfData parses a single performance data field, of the format
+// 'label'=value[UOM];[warn];[crit];[min];[max], into a sensor. If the value is
+// unknown ("U"), a nil sensor is returned.
+//
+//nolint:mnd
+func parseNagiosPerfData(name, field string) (*ScriptSensor, error) {
+	idx := strings.LastIndex(field, "=")
+	if idx <= 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidOutput, field)
+	}
+
+	label := strings.Trim(field[:idx], "'")
+	label = strings.ReplaceAll(label, "''", "'")
+	values := strings.Split(field[idx+1:], ";")
+
+	if values[0] == "U" {
+		return nil, nil //nolint:nilnil
+	}
+
+	numEnd := strings.IndexFunc(values[0], func(r rune) bool {
+		return !strings.ContainsRune("0123456789.-+eE", r)
+	})
+	if numEnd == -1 {
+		numEnd = len(values[0])
+	}
+
+	value, err := strconv.ParseFloat(values[0][:numEnd], 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", ErrInvalidOutput, field, err)
+	}
+
+	uom := values[0][numEnd:]
+
+	perfSensor := &ScriptSensor{
+		SensorID:         sensorID(name, label),
+		SensorName:       name + " " + label,
+		SensorState:      value,
+		SensorStateClass: "measurement",
+		SensorUnits:      nagiosUnits[uom],
+	}
+
+	if uom == "c" {
+		perfSensor.SensorStateClass = "total_increasing"
+	}
+
+	attributes := make(map[string]any)
+
+	for idx, key := range []string{"warning", "critical", "min", "max"} {
+		if len(values) > idx+1 && values[idx+1] != "" {
+			attributes[key] = values[idx+1]
+		}
+	}
+
+	if len(attributes) > 0 {
+		perfSensor.SensorAttributes = attributes
+	}
+
+	return perfSensor, nil
+}
+
+// parseInflux parses output in the InfluxDB line protocol. Each field of each
+// line becomes a sensor. The tags of the line are used as sensor attributes.
+func parseInflux(output []byte) ([]ScriptSensor, error) {
+	var sensors []ScriptSensor
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		lineSensors, err := parseInfluxLine(line)
+		if err != nil {
+			return nil, err
+		}
+
+		sensors = append(sensors, lineSensors...)
+	}
+
+	if len(sensors) == 0 {
+		return nil, ErrNoOutput
+	}
+
+	return sensors, nil
+}
+
+// parseInfluxLine parses a single line of the InfluxDB line protocol, of the
+// format measurement[,tag=value...] field=value[,field=value...] [timestamp].
+func parseInfluxLine(line string) ([]ScriptSensor, error) {
+	sections := splitUnescaped(line, ' ')
+	if len(sections) < 2 { //nolint:mnd
+		return nil, fmt.Errorf("%w: %q", ErrInvalidOutput, line)
+	}
+
+	key := splitUnescaped(sections[0], ',')
+	measurement := unescapeInflux(key[0])
+
+	tagNames := make([]string, 0, len(key)-1)
+	tags := make(map[string]any, len(key)-1)
+
+	for _, tag := range key[1:] {
+		name, value, found := cutUnescaped(tag, '=')
+		if !found {
+			return nil, fmt.Errorf("%w: invalid tag %q", ErrInvalidOutput, tag)
+		}
+
+		name = unescapeInflux(name)
+		tagNames = append(tagNames, name)
+		tags[name] = unescapeInflux(value)
+	}
+
+	slices.Sort(tagNames)
+
+	fields := splitUnescaped(sections[1], ',')
+	sensors := make([]ScriptSensor, 0, len(fields))
+
+	for _, field := range fields {
+		name, rawValue, found := cutUnescaped(field, '=')
+		if !found {
+			return nil, fmt.Errorf("%w: invalid field %q", ErrInvalidOutput, field)
+		}
+
+		name = unescapeInflux(name)
+
+		value, err := parseInfluxValue(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %q: %w", ErrInvalidOutput, name, err)
+		}
+
+		idParts := []string{measurement}
+		displayTags := make([]string, 0, len(tagNames))
+
+		for _, tag := range tagNames {
+			idParts = append(idParts, tag, fmt.Sprint(tags[tag]))
+			displayTags = append(displayTags, tag+"="+fmt.Sprint(tags[tag]))
+		}
+
+		idParts = append(idParts, name)
+
+		fieldSensor := ScriptSensor{
+			SensorID:    sensorID(idParts...),
+			SensorName:  measurement + " " + name,
+			SensorState: value,
+		}
+
+		if len(displayTags) > 0 {
+			fieldSensor.SensorName += " (" + strings.Join(displayTags, ", ") + ")"
+			fieldSensor.SensorAttributes = tags
+		}
+
+		switch value.(type) {
+		case bool:
+			fieldSensor.SensorStateType = "binary"
+		case string:
+		default:
+			fieldSensor.SensorStateClass = "measurement"
+		}
+
+		sensors = append(sensors, fieldSensor)
+	}
+
+	return sensors, nil
+}
+
+// parseInfluxValue parses a field value of the InfluxDB line protocol.
+//
+//nolint:mnd
+func parseInfluxValue(value string) (any, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		if len(value) < 2 || !strings.HasSuffix(value, `"`) {
+			return nil, ErrInvalidOutput
+		}
+
+		unquoted := value[1 : len(value)-1]
+
+		return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(unquoted), nil
+	case strings.HasSuffix(value, "i"):
+		intValue, err := strconv.ParseInt(strings.TrimSuffix(value, "i"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer: %w", err)
+		}
+
+		return intValue, nil
+	case strings.HasSuffix(value, "u"):
+		uintValue, err := strconv.ParseUint(strings.TrimSuffix(value, "u"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unsigned integer: %w", err)
+		}
+
+		return uintValue, nil
+	}
+
+	switch value {
+	case "t", "T", "true", "True", "TRUE":
+		return true, nil
+	case "f", "F", "false", "False", "FALSE":
+		return false, nil
+	}
+
+	floatValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid float: %w", err)
+	}
+
+	return floatValue, nil
+}
+
+// splitUnescaped splits the given string on any separators that are not escaped
+// with a backslash or inside a double-quoted string.
+func splitUnescaped(value string, sep rune) []string {
+	var (
+		parts    []string
+		current  strings.Builder
+		escaped  bool
+		inQuotes bool
+	)
+
+	for _, char := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case char == '\\':
+			escaped = true
+		case char == '"':
+			inQuotes = !inQuotes
+		case char == sep && !inQuotes:
+			if current.Len() > 0 {
+				parts = append(parts, current.String())
+				current.Reset()
+			}
+
+			continue
+		}
+
+		current.WriteRune(char)
+	}
+
+	if current.Len() > 0 {
+		parts = append(parts, current.String())
+	}
+
+	return parts
+}
+
+// cutUnescaped slices the given string around the first separator that is not
+// escaped with a backslash.
+func cutUnescaped(value string, sep byte) (before, after string, found bool) {
+	for idx := 0; idx < len(value); idx++ {
+		switch value[idx] {
+		case '\\':
+			// Skip the escaped character.
+			idx++
+		case sep:
+			return value[:idx], value[idx+1:], true
+		}
+	}
+
+	return value, "", false
+}
+
+// unescapeInflux removes any escaping from measurement names, tags and field
+// keys.
+func unescapeInflux(value string) string {
+	return strings.NewReplacer(`\,`, `,`, `\ `, ` `, `\=`, `=`, `\\`, `\`).Replace(value)
+}
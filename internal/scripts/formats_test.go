@@ -0,0 +1,253 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package scripts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_sensorID(t *testing.T) {
+	type args struct {
+		parts []string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "simple",
+			args: args{parts: []string{"check_disk", "status"}},
+			want: "check_disk_status",
+		},
+		{
+			name: "invalid characters",
+			args: args{parts: []string{"disk", "path", "/home", "used"}},
+			want: "disk_path_home_used",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sensorID(tt.args.parts...); got != tt.want {
+				t.Errorf("sensorID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseNagios(t *testing.T) {
+	type args struct {
+		name     string
+		output   []byte
+		exitCode int
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []ScriptSensor
+		wantErr bool
+	}{
+		{
+			name: "ok with perfdata",
+			args: args{
+				name:     "check_disk",
+				output:   []byte("DISK OK - free space: / 3326 MB (56%); | /=2643MB;5948;5958;0;5968 'inode usage'=10%;;;0;100\n"),
+				exitCode: 0,
+			},
+			want: []ScriptSensor{
+				{
+					SensorID:         "check_disk_status",
+					SensorName:       "check_disk Status",
+					SensorIcon:       "mdi:list-status",
+					SensorState:      "ok",
+					synthetic:        true,
+					SensorAttributes: map[string]any{"output": "DISK OK - free space: / 3326 MB (56%);", "exit_code": 0},
+				},
+				{
+					SensorID:        "check_disk_problem",
+					SensorName:      "check_disk Problem",
+					SensorIcon:      "mdi:alert-circle",
+					SensorStateType: "binary",
+					SensorState:     false,
+					synthetic:       true,
+				},
+				{
+					SensorID:         "check_disk",
+					SensorName:       "check_disk /",
+					SensorState:      float64(2643),
+					SensorStateClass: "measurement",
+					SensorUnits:      "MB",
+					SensorAttributes: map[string]any{"warning": "5948", "critical": "5958", "min": "0", "max": "5968"},
+				},
+				{
+					SensorID:         "check_disk_inode_usage",
+					SensorName:       "check_disk inode usage",
+					SensorState:      float64(10),
+					SensorStateClass: "measurement",
+					SensorUnits:      "%",
+					SensorAttributes: map[string]any{"min": "0", "max": "100"},
+				},
+			},
+		},
+		{
+			name: "critical with long output",
+			args: args{
+				name:     "check_backup",
+				output:   []byte("BACKUP CRITICAL\nlast run failed\n| runs=5c\n"),
+				exitCode: 2,
+			},
+			want: []ScriptSensor{
+				{
+					SensorID:         "check_backup_status",
+					SensorName:       "check_backup Status",
+					SensorIcon:       "mdi:list-status",
+					SensorState:      "critical",
+					synthetic:        true,
+					SensorAttributes: map[string]any{"output": "BACKUP CRITICAL", "long_output": "last run failed", "exit_code": 2},
+				},
+				{
+					SensorID:        "check_backup_problem",
+					SensorName:      "check_backup Problem",
+					SensorIcon:      "mdi:alert-circle",
+					SensorStateType: "binary",
+					SensorState:     true,
+					synthetic:       true,
+				},
+				{
+					SensorID:         "check_backup_runs",
+					SensorName:       "check_backup runs",
+					SensorState:      float64(5),
+					SensorStateClass: "total_increasing",
+				},
+			},
+		},
+		{
+			name: "invalid perfdata",
+			args: args{
+				name:   "check_invalid",
+				output: []byte("OK | value=abc valid=1"),
+			},
+			want: []ScriptSensor{
+				{
+					SensorID:         "check_invalid_status",
+					SensorName:       "check_invalid Status",
+					SensorIcon:       "mdi:list-status",
+					SensorState:      "ok",
+					synthetic:        true,
+					SensorAttributes: map[string]any{"output": "OK", "exit_code": 0},
+				},
+				{
+					SensorID:        "check_invalid_problem",
+					SensorName:      "check_invalid Problem",
+					SensorIcon:      "mdi:alert-circle",
+					SensorStateType: "binary",
+					SensorState:     false,
+					synthetic:       true,
+				},
+				{
+					SensorID:         "check_invalid_valid",
+					SensorName:       "check_invalid valid",
+					SensorState:      float64(1),
+					SensorStateClass: "measurement",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseNagios(tt.args.name, tt.args.output, tt.args.exitCode)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseNagios() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_parseInflux(t *testing.T) {
+	type args struct {
+		output []byte
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []ScriptSensor
+		wantErr bool
+	}{
+		{
+			name: "valid lines",
+			args: args{output: []byte(`# comment
+backup,job=home\ dir last_success=true,duration=12.5,files=1024i,status="done" 1465839830100400200
+`)},
+			want: []ScriptSensor{
+				{
+					SensorID:         "backup_job_home_dir_last_success",
+					SensorName:       "backup last_success (job=home dir)",
+					SensorState:      true,
+					SensorStateType:  "binary",
+					SensorAttributes: map[string]any{"job": "home dir"},
+				},
+				{
+					SensorID:         "backup_job_home_dir_duration",
+					SensorName:       "backup duration (job=home dir)",
+					SensorState:      12.5,
+					SensorStateClass: "measurement",
+					SensorAttributes: map[string]any{"job": "home dir"},
+				},
+				{
+					SensorID:         "backup_job_home_dir_files",
+					SensorName:       "backup files (job=home dir)",
+					SensorState:      int64(1024),
+					SensorStateClass: "measurement",
+					SensorAttributes: map[string]any{"job": "home dir"},
+				},
+				{
+					SensorID:         "backup_job_home_dir_status",
+					SensorName:       "backup status (job=home dir)",
+					SensorState:      "done",
+					SensorAttributes: map[string]any{"job": "home dir"},
+				},
+			},
+		},
+		{
+			name: "escaped tags",
+			args: args{output: []byte(`disk,path=C:\\data\,old,label=a\=b,k\=1=v used=1i`)},
+			want: []ScriptSensor{
+				{
+					SensorID:         "disk_k_1_v_label_a_b_path_c_data_old_used",
+					SensorName:       `disk used (k=1=v, label=a=b, path=C:\data,old)`,
+					SensorState:      int64(1),
+					SensorStateClass: "measurement",
+					SensorAttributes: map[string]any{"k=1": "v", "label": "a=b", "path": `C:\data,old`},
+				},
+			},
+		},
+		{
+			name:    "invalid line",
+			args:    args{output: []byte(`measurement`)},
+			wantErr: true,
+		},
+		{
+			name:    "no output",
+			args:    args{output: []byte(``)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseInflux(tt.args.output)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseInflux() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -7,11 +7,14 @@
 package scripts
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/pelletier/go-toml/v2"
 	"gopkg.in/yaml.v3"
@@ -19,47 +22,139 @@ import (
 	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
 )
 
-var ErrParseCmd = errors.New("could not parse script command")
+// discoveryTimeout is the maximum time a script without a sidecar file is
+// allowed to run when being executed to discover its schedule.
+const discoveryTimeout = 30 * time.Second
+
+var (
+	ErrParseCmd    = errors.New("could not parse script command")
+	ErrNoSchedule  = errors.New("no schedule defined for script")
+	ErrNonZeroExit = errors.New("script exited with non-zero status")
+)
 
 type Script struct {
+	defaults *SensorDefaults
 	path     string
+	name     string
 	schedule string
+	source   string
+	format   string
+	timeout  time.Duration
+	disabled bool
 }
 
 func (s *Script) Schedule() string {
 	return s.schedule
 }
 
+// Path returns the path to the script.
+func (s *Script) Path() string {
+	return s.path
+}
+
+// Source returns where the script metadata was discovered from, either
+// SourceSidecar or SourceOutput.
+func (s *Script) Source() string {
+	return s.source
+}
+
+// Enabled returns whether the script should be scheduled by the agent.
+func (s *Script) Enabled() bool {
+	return !s.disabled
+}
+
+// Timeout returns the maximum time the script is allowed to run. A zero value
+// means there is no timeout.
+func (s *Script) Timeout() time.Duration {
+	return s.timeout
+}
+
+// Format returns the output format of the script.
+func (s *Script) Format() string {
+	if s.format == "" {
+		return FormatAgent
+	}
+
+	return s.format
+}
+
+// Name returns the name of the script. This is used as a prefix for the names
+// of sensors created from Nagios format output. Unless set in the sidecar file,
+// it is the script file name without any extension.
+func (s *Script) Name() string {
+	if s.name != "" {
+		return s.name
+	}
+
+	base := filepath.Base(strings.Split(s.path, " ")[0])
+
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// equal returns whether the given script has the same definition as this one.
+func (s *Script) equal(other *Script) bool {
+	if (s.defaults == nil) != (other.defaults == nil) {
+		return false
+	}
+
+	if s.defaults != nil && *s.defaults != *other.defaults {
+		return false
+	}
+
+	return s.path == other.path &&
+		s.name == other.name &&
+		s.schedule == other.schedule &&
+		s.source == other.source &&
+		s.format == other.format &&
+		s.timeout == other.timeout &&
+		s.disabled == other.disabled
+}
+
 func (s *Script) Execute() ([]sensor.Details, error) {
-	output, err := s.parse()
+	output, err := s.parse(s.timeout)
 	if err != nil {
 		return nil, fmt.Errorf("error running script: %w", err)
 	}
 
-	sensors := make([]sensor.Details, 0, len(output.Sensors))
+	sensors := make([]sensor.Details, 0, len(output.Sensors)+1)
 
-	for _, s := range output.Sensors {
-		sensors = append(sensors, sensor.Details(&s))
+	if output.Location != nil {
+		output.Location.script = s.Name()
+		sensors = append(sensors, output.Location)
 	}
 
-	return sensors, nil
-}
-
-func (s *Script) parse() (*scriptOutput, error) {
-	cmdElems := strings.Split(s.path, " ")
+	for _, scriptSensor := range output.Sensors {
+		if s.defaults != nil && !scriptSensor.synthetic {
+			s.defaults.apply(&scriptSensor)
+		}
 
-	if len(cmdElems) == 0 {
-		return nil, ErrParseCmd
+		sensors = append(sensors, sensor.Details(&scriptSensor))
 	}
 
-	out, err := exec.Command(cmdElems[0], cmdElems[1:]...).Output()
+	return sensors, nil
+}
+
+func (s *Script) parse(timeout time.Duration) (*scriptOutput, error) {
+	out, exitCode, err := s.run(timeout)
 	if err != nil {
 		return nil, fmt.Errorf("could not execute script: %w", err)
 	}
 
 	output := &scriptOutput{}
 
-	err = output.Unmarshal(out)
+	switch s.Format() {
+	case FormatNagios:
+		output.Sensors, err = parseNagios(s.Name(), out, exitCode)
+	case FormatInflux:
+		output.Sensors, err = parseInflux(out)
+	default:
+		if exitCode != 0 {
+			return nil, fmt.Errorf("could not execute script: %w (exit code %d)", ErrNonZeroExit, exitCode)
+		}
+
+		err = output.Unmarshal(out)
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("could not parse script output: %w", err)
 	}
@@ -67,20 +162,102 @@ func (s *Script) parse() (*scriptOutput, error) {
 	return output, nil
 }
 
+// run executes the script and returns its output and exit code. A non-nil error
+// is returned if the script could not be executed or did not complete within
+// the timeout.
+func (s *Script) run(timeout time.Duration) ([]byte, int, error) {
+	cmdElems := strings.Split(s.path, " ")
+
+	if len(cmdElems) == 0 {
+		return nil, 0, ErrParseCmd
+	}
+
+	ctx := context.Background()
+
+	if timeout > 0 {
+		var cancelFunc context.CancelFunc
+
+		ctx, cancelFunc = context.WithTimeout(ctx, timeout)
+		defer cancelFunc()
+	}
+
+	out, err := exec.CommandContext(ctx, cmdElems[0], cmdElems[1:]...).Output()
+	if ctx.Err() != nil {
+		return nil, 0, ctx.Err()
+	}
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return out, exitErr.ExitCode(), nil
+		}
+
+		return nil, 0, err //nolint:wrapcheck
+	}
+
+	return out, 0, nil
+}
+
 // NewScript returns a new script object that can scheduled with the job
-// scheduler by the agent.
+// scheduler by the agent. If the script has a sidecar file, the script metadata
+// is read from that. Otherwise, or if the sidecar file does not define a
+// schedule, the script is executed once to discover its schedule.
 func NewScript(path string) (*Script, error) {
 	script := &Script{
 		path:     path,
 		schedule: "",
 	}
 
-	scriptOutput, err := script.parse()
+	sidecar, err := loadSidecar(path)
 	if err != nil {
 		return nil, fmt.Errorf("cannot add script %s: %w", path, err)
 	}
 
+	if sidecar != nil {
+		script.defaults = &sidecar.Sensor
+		script.timeout = sidecar.timeout()
+		script.disabled = !sidecar.isEnabled()
+		script.format = sidecar.Format
+		script.name = sidecar.Name
+
+		if sidecar.Schedule != "" {
+			script.schedule = sidecar.Schedule
+			script.source = SourceSidecar
+
+			return script, nil
+		}
+		// A disabled script does not need to be executed to discover its
+		// schedule.
+		if script.disabled {
+			script.source = SourceSidecar
+
+			return script, nil
+		}
+	}
+
+	// Only scripts using the agent format can have their schedule discovered
+	// from their output.
+	if script.Format() != FormatAgent {
+		return nil, fmt.Errorf("cannot add script %s: %w: a schedule must be set in the sidecar file for %s format scripts",
+			path, ErrNoSchedule, script.Format())
+	}
+
+	timeout := discoveryTimeout
+	if script.timeout > 0 {
+		timeout = script.timeout
+	}
+
+	scriptOutput, err := script.parse(timeout)
+	if err != nil {
+		return nil, fmt.Errorf("cannot add script %s: %w", path, err)
+	}
+
+	if scriptOutput.Schedule == "" {
+		return nil, fmt.Errorf("cannot add script %s: %w", path, ErrNoSchedule)
+	}
+
 	script.schedule = scriptOutput.Schedule
+	script.source = SourceOutput
 
 	return script, nil
 }
@@ -91,8 +268,9 @@ func NewScript(path string) (*Script, error) {
 //
 //nolint:tagalign
 type scriptOutput struct {
-	Schedule string         `json:"schedule" yaml:"schedule"`
-	Sensors  []ScriptSensor `json:"sensors" yaml:"sensors"`
+	Location *ScriptLocation `json:"location,omitempty" yaml:"location,omitempty" toml:"location,omitempty"`
+	Schedule string          `json:"schedule" yaml:"schedule"`
+	Sensors  []ScriptSensor  `json:"sensors" yaml:"sensors"`
 }
 
 // Unmarshal will attempt to take the raw output from a script execution and
@@ -9,6 +9,8 @@ package scripts
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -16,6 +18,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor/types"
 )
 
 const (
@@ -109,6 +112,15 @@ func TestNewScript(t *testing.T) {
 				schedule: "@every 5s",
 			},
 		},
+		{
+			name: "script with sidecar",
+			args: args{path: "testing/data/sidecarTestScript.sh"},
+			want: &Script{
+				path:     "testing/data/sidecarTestScript.sh",
+				schedule: "@every 10s",
+				source:   SourceSidecar,
+			},
+		},
 		{
 			name:    "invalid script",
 			args:    args{path: "/does/not/exist"},
@@ -125,6 +137,9 @@ func TestNewScript(t *testing.T) {
 			if err == nil {
 				assert.Equal(t, tt.want.path, got.path)
 				assert.Equal(t, tt.want.Schedule(), got.Schedule())
+				if tt.want.source != "" {
+					assert.Equal(t, tt.want.source, got.Source())
+				}
 			}
 		})
 	}
@@ -210,3 +225,29 @@ func TestScript_Execute(t *testing.T) {
 		})
 	}
 }
+
+func TestScript_Execute_nagiosDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "check_disk")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\necho 'DISK OK | /=2643MB'\n"), 0o700)) //nolint:gosec
+
+	s := &Script{
+		path:     path,
+		format:   FormatNagios,
+		defaults: &SensorDefaults{Icon: "mdi:harddisk", DeviceClass: "data_size"},
+	}
+
+	got, err := s.Execute()
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+
+	// The defaults are not applied to the status and problem sensors.
+	assert.Equal(t, "mdi:list-status", got[0].Icon())
+	assert.Empty(t, got[0].DeviceClass())
+	assert.Equal(t, "mdi:alert-circle", got[1].Icon())
+	assert.Empty(t, got[1].DeviceClass())
+
+	// They are applied to the performance data sensors.
+	assert.Equal(t, "mdi:harddisk", got[2].Icon())
+	assert.Equal(t, types.DeviceClassDataSize, got[2].DeviceClass())
+	assert.Equal(t, "MB", got[2].Units())
+}
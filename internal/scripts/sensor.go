@@ -7,28 +7,48 @@
 package scripts
 
 import (
+	"maps"
+
 	"github.com/iancoleman/strcase"
 
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
 	"github.com/joshuar/go-hass-agent/internal/hass/sensor/types"
 )
 
+const (
+	locationSensorName = "Location"
+	locationSensorID   = "location"
+)
+
 //nolint:tagalign
 type ScriptSensor struct {
 	SensorState       any    `json:"sensor_state" yaml:"sensor_state" toml:"sensor_state"`
 	SensorAttributes  any    `json:"sensor_attributes,omitempty" yaml:"sensor_attributes,omitempty" toml:"sensor_attributes,omitempty"`
+	SensorID          string `json:"sensor_id,omitempty" yaml:"sensor_id,omitempty" toml:"sensor_id,omitempty"`
 	SensorName        string `json:"sensor_name" yaml:"sensor_name" toml:"sensor_name"`
+	SensorCategory    string `json:"sensor_category,omitempty" yaml:"sensor_category,omitempty" toml:"sensor_category,omitempty"`
 	SensorIcon        string `json:"sensor_icon,omitempty" yaml:"sensor_icon,omitempty" toml:"sensor_icon,omitempty"`
 	SensorDeviceClass string `json:"sensor_device_class,omitempty" yaml:"sensor_device_class,omitempty" toml:"sensor_device_class,omitempty"`
 	SensorStateClass  string `json:"sensor_state_class,omitempty" yaml:"sensor_state_class,omitempty" toml:"sensor_state_class,omitempty"`
 	SensorStateType   string `json:"sensor_type,omitempty" yaml:"sensor_type,omitempty" toml:"sensor_type,omitempty"`
 	SensorUnits       string `json:"sensor_units,omitempty" yaml:"sensor_units,omitempty" toml:"sensor_units,omitempty"`
+	// synthetic is set for sensors created by the agent rather than from a
+	// value output by the script, such as the status of a Nagios check. The
+	// sensor defaults of a sidecar file do not apply to them.
+	synthetic bool
 }
 
 func (s *ScriptSensor) Name() string {
 	return s.SensorName
 }
 
+// ID returns the unique ID of the sensor. If the script specified an ID for the
+// sensor, that is used as is. Otherwise, an ID is derived from the sensor name.
 func (s *ScriptSensor) ID() string {
+	if s.SensorID != "" {
+		return s.SensorID
+	}
+
 	return strcase.ToSnake(s.SensorName)
 }
 
@@ -80,16 +100,86 @@ func (s *ScriptSensor) Units() string {
 	return s.SensorUnits
 }
 
+// Category returns the entity category of the sensor. Home Assistant only
+// supports "diagnostic" as a category for sensors, any other value is ignored.
 func (s *ScriptSensor) Category() string {
+	if s.SensorCategory == sensor.CategoryDiagnostic {
+		return sensor.CategoryDiagnostic
+	}
+
 	return ""
 }
 
+// Attributes returns the attributes of the sensor. If the script specified the
+// attributes as a map, they are used as-is. Any other value is nested under an
+// "extra_attributes" attribute.
 func (s *ScriptSensor) Attributes() map[string]any {
 	attributes := make(map[string]any)
 
-	if s.SensorAttributes != nil {
-		attributes["extra_attributes"] = s.SensorAttributes
+	switch attrs := s.SensorAttributes.(type) {
+	case nil:
+	case map[string]any:
+		maps.Copy(attributes, attrs)
+	default:
+		attributes["extra_attributes"] = attrs
 	}
 
 	return attributes
 }
+
+// ScriptLocation represents a location update output by a script. It will be
+// sent to Home Assistant as a location update for the device.
+//
+//nolint:tagalign
+type ScriptLocation struct {
+	Latitude         float64 `json:"latitude" yaml:"latitude" toml:"latitude"`
+	Longitude        float64 `json:"longitude" yaml:"longitude" toml:"longitude"`
+	GPSAccuracy      int     `json:"gps_accuracy,omitempty" yaml:"gps_accuracy,omitempty" toml:"gps_accuracy,omitempty"`
+	Battery          int     `json:"battery,omitempty" yaml:"battery,omitempty" toml:"battery,omitempty"`
+	Speed            int     `json:"speed,omitempty" yaml:"speed,omitempty" toml:"speed,omitempty"`
+	Altitude         int     `json:"altitude,omitempty" yaml:"altitude,omitempty" toml:"altitude,omitempty"`
+	Course           int     `json:"course,omitempty" yaml:"course,omitempty" toml:"course,omitempty"`
+	VerticalAccuracy int     `json:"vertical_accuracy,omitempty" yaml:"vertical_accuracy,omitempty" toml:"vertical_accuracy,omitempty"`
+	// script is the name of the script that output the location.
+	script string
+}
+
+func (l *ScriptLocation) Name() string { return locationSensorName }
+
+// ID returns the ID of the location, which includes the name of the script, so
+// that locations from different scripts do not collide.
+func (l *ScriptLocation) ID() string {
+	if l.script == "" {
+		return locationSensorID
+	}
+
+	return sensorID(l.script, locationSensorID)
+}
+
+func (l *ScriptLocation) Icon() string { return "mdi:map-marker" }
+
+func (l *ScriptLocation) SensorType() types.SensorClass { return types.Sensor }
+
+func (l *ScriptLocation) DeviceClass() types.DeviceClass { return 0 }
+
+func (l *ScriptLocation) StateClass() types.StateClass { return 0 }
+
+func (l *ScriptLocation) Units() string { return "" }
+
+func (l *ScriptLocation) Category() string { return "" }
+
+func (l *ScriptLocation) Attributes() map[string]any { return nil }
+
+// State returns the location as a sensor.LocationRequest, which will be
+// handled as a location update by the agent.
+func (l *ScriptLocation) State() any {
+	return &sensor.LocationRequest{
+		Gps:              []float64{l.Latitude, l.Longitude},
+		GpsAccuracy:      l.GPSAccuracy,
+		Battery:          l.Battery,
+		Speed:            l.Speed,
+		Altitude:         l.Altitude,
+		Course:           l.Course,
+		VerticalAccuracy: l.VerticalAccuracy,
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/joshuar/go-hass-agent/internal/hass/sensor"
 	"github.com/joshuar/go-hass-agent/internal/hass/sensor/types"
 )
 
@@ -58,6 +59,7 @@ func Test_scriptSensor_ID(t *testing.T) {
 	type fields struct {
 		SensorState       any
 		SensorAttributes  any
+		SensorID          string
 		SensorName        string
 		SensorIcon        string
 		SensorDeviceClass string
@@ -75,12 +77,23 @@ func Test_scriptSensor_ID(t *testing.T) {
 			fields: fields{SensorName: "Script"},
 			want:   "script",
 		},
+		{
+			name:   "with sensor id",
+			fields: fields{SensorName: "Script", SensorID: "my_script_sensor"},
+			want:   "my_script_sensor",
+		},
+		{
+			name:   "sensor id used as is",
+			fields: fields{SensorName: "Script", SensorID: "myScriptSensor2"},
+			want:   "myScriptSensor2",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := &ScriptSensor{
 				SensorState:       tt.fields.SensorState,
 				SensorAttributes:  tt.fields.SensorAttributes,
+				SensorID:          tt.fields.SensorID,
 				SensorName:        tt.fields.SensorName,
 				SensorIcon:        tt.fields.SensorIcon,
 				SensorDeviceClass: tt.fields.SensorDeviceClass,
@@ -381,6 +394,11 @@ func Test_scriptSensor_Attributes(t *testing.T) {
 			fields: fields{SensorAttributes: attrs},
 			want:   map[string]any{"extra_attributes": attrs},
 		},
+		{
+			name:   "with attributes map",
+			fields: fields{SensorAttributes: map[string]any{"attribute": "value"}},
+			want:   map[string]any{"attribute": "value"},
+		},
 		{
 			name: "without attributes",
 			want: make(map[string]any),
@@ -404,3 +422,81 @@ func Test_scriptSensor_Attributes(t *testing.T) {
 		})
 	}
 }
+
+func TestScriptSensor_Category(t *testing.T) {
+	tests := []struct {
+		name   string
+		sensor *ScriptSensor
+		want   string
+	}{
+		{
+			name:   "diagnostic",
+			sensor: &ScriptSensor{SensorCategory: "diagnostic"},
+			want:   "diagnostic",
+		},
+		{
+			name:   "unsupported",
+			sensor: &ScriptSensor{SensorCategory: "config"},
+			want:   "",
+		},
+		{
+			name:   "none",
+			sensor: &ScriptSensor{},
+			want:   "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sensor.Category(); got != tt.want {
+				t.Errorf("ScriptSensor.Category() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScriptLocation_State(t *testing.T) {
+	tests := []struct {
+		location *ScriptLocation
+		want     any
+		name     string
+	}{
+		{
+			name:     "location",
+			location: &ScriptLocation{Latitude: -33.86, Longitude: 151.21, GPSAccuracy: 10},
+			want:     &sensor.LocationRequest{Gps: []float64{-33.86, 151.21}, GpsAccuracy: 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.location.State(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ScriptLocation.State() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScriptLocation_ID(t *testing.T) {
+	tests := []struct {
+		location *ScriptLocation
+		name     string
+		want     string
+	}{
+		{
+			name:     "from script",
+			location: &ScriptLocation{script: "gps.reader"},
+			want:     "gps_reader_location",
+		},
+		{
+			name:     "no script",
+			location: &ScriptLocation{},
+			want:     "location",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.location.ID(); got != tt.want {
+				t.Errorf("ScriptLocation.ID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:tagalign
+package scripts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+const (
+	sidecarExt = ".toml"
+
+	// SourceSidecar indicates the script metadata was read from a sidecar
+	// file.
+	SourceSidecar = "sidecar"
+	// SourceOutput indicates the script metadata was discovered by executing
+	// the script and parsing its output.
+	SourceOutput = "output"
+)
+
+var ErrInvalidSidecar = errors.New("invalid script sidecar file")
+
+// Sidecar represents the optional metadata file that can accompany a script.
+// It has the name of the script with .toml added (i.e., myscript.sh ->
+// myscript.sh.toml), so that scripts differing only in their extension have
+// their own sidecar files. When a sidecar file defines a schedule, the script
+// does not need to be executed to discover it.
+type Sidecar struct {
+	// Enabled controls whether the script will be scheduled by the agent. If
+	// not specified, the script is enabled.
+	Enabled *bool `toml:"enabled,omitempty"`
+	// Sensor contains default values that will be applied to any sensors the
+	// script outputs that do not set them.
+	Sensor SensorDefaults `toml:"sensor,omitempty"`
+	// Schedule is the cron-formatted schedule for the script.
+	Schedule string `toml:"schedule,omitempty"`
+	// Format is the output format of the script. Either "agent" (the
+	// default), "nagios" or "influx".
+	Format string `toml:"format,omitempty"`
+	// Name is used as a prefix for the names of sensors created from "nagios"
+	// format output. It defaults to the script file name.
+	Name string `toml:"name,omitempty"`
+	// Timeout is the maximum time the script is allowed to run before it is
+	// killed, as a duration string (e.g., "10s").
+	Timeout string `toml:"timeout,omitempty"`
+}
+
+// SensorDefaults are values applied to any sensors output by a script that do
+// not already have the value set. They are not applied to synthetic sensors.
+type SensorDefaults struct {
+	Icon        string `toml:"sensor_icon,omitempty"`
+	DeviceClass string `toml:"sensor_device_class,omitempty"`
+	StateClass  string `toml:"sensor_state_class,omitempty"`
+	StateType   string `toml:"sensor_type,omitempty"`
+	Units       string `toml:"sensor_units,omitempty"`
+}
+
+// apply will set any unset fields of the given sensor to the defaults.
+func (d *SensorDefaults) apply(s *ScriptSensor) {
+	if s.SensorIcon == "" {
+		s.SensorIcon = d.Icon
+	}
+
+	if s.SensorDeviceClass == "" {
+		s.SensorDeviceClass = d.DeviceClass
+	}
+
+	if s.SensorStateClass == "" {
+		s.SensorStateClass = d.StateClass
+	}
+
+	if s.SensorStateType == "" {
+		s.SensorStateType = d.StateType
+	}
+
+	if s.SensorUnits == "" {
+		s.SensorUnits = d.Units
+	}
+}
+
+// sidecarPath returns the path of the sidecar file for the given script. The
+// extension of the script is kept, so that scripts that differ only by their
+// extension have their own sidecar files.
+func sidecarPath(path string) string {
+	return path + sidecarExt
+}
+
+// loadSidecar will load the sidecar file for the given script. If the script
+// has no sidecar file, a nil Sidecar and nil error are returned.
+func loadSidecar(path string) (*Sidecar, error) {
+	data, err := os.ReadFile(sidecarPath(path))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil //nolint:nilnil
+		}
+
+		return nil, fmt.Errorf("could not read sidecar: %w", err)
+	}
+
+	sidecar := &Sidecar{}
+
+	if err := toml.Unmarshal(data, sidecar); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSidecar, err)
+	}
+
+	if !validFormat(sidecar.Format) {
+		return nil, fmt.Errorf("%w: %w: %s", ErrInvalidSidecar, ErrUnknownFormat, sidecar.Format)
+	}
+
+	if sidecar.Timeout != "" {
+		if _, err := time.ParseDuration(sidecar.Timeout); err != nil {
+			return nil, fmt.Errorf("%w: timeout: %w", ErrInvalidSidecar, err)
+		}
+	}
+
+	return sidecar, nil
+}
+
+// isEnabled returns whether the sidecar has enabled the script. Scripts are
+// enabled unless explicitly disabled.
+func (s *Sidecar) isEnabled() bool {
+	if s.Enabled == nil {
+		return true
+	}
+
+	return *s.Enabled
+}
+
+// timeout returns the parsed timeout of the sidecar, or 0 if there is none.
+func (s *Sidecar) timeout() time.Duration {
+	timeout, err := time.ParseDuration(s.Timeout)
+	if err != nil {
+		return 0
+	}
+
+	return timeout
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package scripts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_sidecarPath(t *testing.T) {
+	type args struct {
+		path string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "with extension",
+			args: args{path: "/path/to/myscript.sh"},
+			want: "/path/to/myscript.sh.toml",
+		},
+		{
+			name: "without extension",
+			args: args{path: "/path/to/myscript"},
+			want: "/path/to/myscript.toml",
+		},
+		{
+			name: "other extension",
+			args: args{path: "/path/to/myscript.py"},
+			want: "/path/to/myscript.py.toml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sidecarPath(tt.args.path); got != tt.want {
+				t.Errorf("sidecarPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_loadSidecar(t *testing.T) {
+	invalidDir := t.TempDir()
+	invalidScript := filepath.Join(invalidDir, "invalid.sh")
+	err := os.WriteFile(sidecarPath(invalidScript), []byte(`timeout = 'notaduration'`), 0o600)
+	require.NoError(t, err)
+
+	type args struct {
+		path string
+	}
+	tests := []struct {
+		want    *Sidecar
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "valid sidecar",
+			args: args{path: "testing/data/sidecarTestScript.sh"},
+			want: &Sidecar{
+				Schedule: "@every 10s",
+				Timeout:  "5s",
+				Sensor:   SensorDefaults{Icon: "mdi:dice-5", StateClass: "measurement"},
+			},
+		},
+		{
+			name: "no sidecar",
+			args: args{path: "testing/data/jsonTestScript.sh"},
+		},
+		{
+			name:    "invalid sidecar",
+			args:    args{path: invalidScript},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadSidecar(tt.args.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("loadSidecar() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSidecar_isEnabled(t *testing.T) {
+	enabled := true
+	disabled := false
+
+	tests := []struct {
+		sidecar *Sidecar
+		name    string
+		want    bool
+	}{
+		{
+			name:    "not specified",
+			sidecar: &Sidecar{},
+			want:    true,
+		},
+		{
+			name:    "enabled",
+			sidecar: &Sidecar{Enabled: &enabled},
+			want:    true,
+		},
+		{
+			name:    "disabled",
+			sidecar: &Sidecar{Enabled: &disabled},
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sidecar.isEnabled(); got != tt.want {
+				t.Errorf("Sidecar.isEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSidecar_timeout(t *testing.T) {
+	tests := []struct {
+		sidecar *Sidecar
+		name    string
+		want    time.Duration
+	}{
+		{
+			name:    "valid timeout",
+			sidecar: &Sidecar{Timeout: "10s"},
+			want:    10 * time.Second,
+		},
+		{
+			name:    "no timeout",
+			sidecar: &Sidecar{},
+			want:    0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sidecar.timeout(); got != tt.want {
+				t.Errorf("Sidecar.timeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSensorDefaults_apply(t *testing.T) {
+	defaults := &SensorDefaults{Icon: "mdi:dice-5", Units: "rolls", StateClass: "measurement"}
+
+	tests := []struct {
+		sensor *ScriptSensor
+		want   *ScriptSensor
+		name   string
+	}{
+		{
+			name:   "unset fields",
+			sensor: &ScriptSensor{SensorName: "dice"},
+			want:   &ScriptSensor{SensorName: "dice", SensorIcon: "mdi:dice-5", SensorUnits: "rolls", SensorStateClass: "measurement"},
+		},
+		{
+			name:   "set fields",
+			sensor: &ScriptSensor{SensorName: "dice", SensorIcon: "mdi:dice-1"},
+			want:   &ScriptSensor{SensorName: "dice", SensorIcon: "mdi:dice-1", SensorUnits: "rolls", SensorStateClass: "measurement"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaults.apply(tt.sensor)
+			assert.Equal(t, tt.want, tt.sensor)
+		})
+	}
+}
@@ -0,0 +1,3 @@
+#!/bin/bash
+
+echo '{"sensors":[{"sensor_name": "sidecar random","sensor_state":'$((1 + $RANDOM % 10))'}]}'
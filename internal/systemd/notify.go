@@ -0,0 +1,85 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+// Package systemd implements the parts of the systemd service notification
+// protocol used by the agent. See sd_notify(3) and sd_watchdog_enabled(3).
+package systemd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Ready tells systemd the service has finished starting up.
+	Ready = "READY=1"
+	// Stopping tells systemd the service is shutting down.
+	Stopping = "STOPPING=1"
+	// Watchdog tells systemd the service is still alive.
+	Watchdog = "WATCHDOG=1"
+)
+
+var ErrInvalidWatchdog = errors.New("invalid watchdog settings")
+
+// Status returns a notification that sets the status of the service shown by
+// systemctl status.
+func Status(status string) string {
+	return "STATUS=" + status
+}
+
+// Enabled reports whether the agent was started by systemd as a notify
+// service.
+func Enabled() bool {
+	return os.Getenv("NOTIFY_SOCKET") != ""
+}
+
+// Notify sends the given notifications to systemd. If the agent was not
+// started by systemd as a notify service, it does nothing.
+func Notify(states ...string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	// Go treats addresses starting with @ as abstract sockets, which is also
+	// how systemd passes them.
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("could not connect to notify socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(strings.Join(states, "\n"))); err != nil {
+		return fmt.Errorf("could not send notification: %w", err)
+	}
+
+	return nil
+}
+
+// WatchdogInterval returns how often systemd expects a Watchdog notification.
+// It returns zero if the watchdog is not enabled for the agent.
+func WatchdogInterval() (time.Duration, error) {
+	usecs := os.Getenv("WATCHDOG_USEC")
+	if usecs == "" {
+		return 0, nil
+	}
+
+	// If a pid is given, the watchdog is only for that process.
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+
+	interval, err := strconv.ParseInt(usecs, 10, 64)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("%w: WATCHDOG_USEC=%s", ErrInvalidWatchdog, usecs)
+	}
+
+	return time.Duration(interval) * time.Microsecond, nil
+}
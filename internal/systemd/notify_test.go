@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package systemd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotify(t *testing.T) {
+	t.Run("not a notify service", func(t *testing.T) {
+		t.Setenv("NOTIFY_SOCKET", "")
+		require.NoError(t, Notify(Ready))
+	})
+
+	t.Run("sends notifications", func(t *testing.T) {
+		socket := filepath.Join(t.TempDir(), "notify")
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		t.Setenv("NOTIFY_SOCKET", socket)
+		require.NoError(t, Notify(Ready, Status("Connected.")))
+
+		buf := make([]byte, 1024)
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		assert.Equal(t, "READY=1\nSTATUS=Connected.", string(buf[:n]))
+	})
+
+	t.Run("missing socket", func(t *testing.T) {
+		t.Setenv("NOTIFY_SOCKET", filepath.Join(t.TempDir(), "notify"))
+		require.Error(t, Notify(Ready))
+	})
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		usec    string
+		pid     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "not enabled"},
+		{name: "enabled", usec: "30000000", want: 30 * time.Second},
+		{name: "for this process", usec: "30000000", pid: strconv.Itoa(os.Getpid()), want: 30 * time.Second},
+		{name: "for another process", usec: "30000000", pid: "1"},
+		{name: "invalid", usec: "soon", wantErr: true},
+		{name: "zero", usec: "0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WATCHDOG_USEC", tt.usec)
+			t.Setenv("WATCHDOG_PID", tt.pid)
+			got, err := WatchdogInterval()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WatchdogInterval() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -30,6 +30,13 @@ var CLI struct {
 	LogLevel  string            `name:"log-level" enum:"info,debug,trace" default:"info" help:"Set logging level."`
 	Config    cli.ConfigCmd     `cmd:"" help:"Configure Go Hass Agent."`
 	Register  cli.RegisterCmd   `cmd:"" help:"Register with Home Assistant."`
+	Scripts   cli.ScriptsCmd    `cmd:"" help:"Manage script sensors."`
+	Debug     cli.DebugCmd      `cmd:"" help:"Tools for debugging the agent and its sensors."`
+	Replay    cli.ReplayCmd     `cmd:"" help:"Replay recorded sensor updates to Home Assistant."`
+	Status    cli.StatusCmd     `cmd:"" help:"Show the status of the running agent."`
+	Sensors   cli.SensorsCmd    `cmd:"" help:"Show the sensors of the running agent."`
+	Workers   cli.WorkersCmd    `cmd:"" help:"Show and control the sensor workers of the running agent."`
+	Registry  cli.RegistryCmd   `cmd:"" help:"Show and manage the sensors registered with Home Assistant."`
 	NoLogFile bool              `help:"Don't write to a log file."`
 }
 
@@ -0,0 +1,154 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+package dbusx
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+var (
+	ErrNameTaken       = errors.New("name is owned by another connection")
+	ErrUnknownProperty = errors.New("unknown property")
+	ErrPropertyType    = errors.New("wrong type for property")
+)
+
+// ExportedObject is an object exported on the bus by the agent, which other
+// applications can call methods on and read properties of.
+type ExportedObject struct {
+	bus   *Bus
+	props *prop.Properties
+	path  dbus.ObjectPath
+	intr  string
+}
+
+// RequestName requests the given well-known name on the bus, so that other
+// applications can find objects exported by the agent. If another connection
+// already owns the name, ErrNameTaken is returned.
+func (b *Bus) RequestName(name string) error {
+	b.traceLog("Requesting name.", slog.String("name", name))
+
+	reply, err := b.conn.RequestName(name, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return fmt.Errorf("%s: unable to request name %s: %w", b.busType.String(), name, err)
+	}
+
+	if reply != dbus.RequestNameReplyPrimaryOwner && reply != dbus.RequestNameReplyAlreadyOwner {
+		return fmt.Errorf("%s: unable to request name %s: %w", b.busType.String(), name, ErrNameTaken)
+	}
+
+	return nil
+}
+
+// ReleaseName releases the given well-known name on the bus.
+func (b *Bus) ReleaseName(name string) error {
+	b.traceLog("Releasing name.", slog.String("name", name))
+
+	if _, err := b.conn.ReleaseName(name); err != nil {
+		return fmt.Errorf("%s: unable to release name %s: %w", b.busType.String(), name, err)
+	}
+
+	return nil
+}
+
+// Export exports the given object on the bus at the given path, with the given
+// interface. All exported methods of the object that return a *dbus.Error as
+// their last value are callable over D-Bus. The given properties are exported
+// with their initial values. They are read-only for other applications and can
+// be changed with SetProperty. The object is also introspectable.
+func (b *Bus) Export(object any, path, intr string, props map[string]any) (*ExportedObject, error) {
+	if !dbus.ObjectPath(path).IsValid() {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPath, path)
+	}
+
+	b.traceLog("Exporting object.", slog.String("path", path), slog.String("interface", intr))
+
+	exported := &ExportedObject{bus: b, path: dbus.ObjectPath(path), intr: intr}
+
+	if err := b.conn.Export(object, exported.path, intr); err != nil {
+		return nil, fmt.Errorf("%s: unable to export %s: %w", b.busType.String(), path, err)
+	}
+
+	propMap := make(map[string]*prop.Prop, len(props))
+	for name, value := range props {
+		propMap[name] = &prop.Prop{Value: value, Emit: prop.EmitTrue}
+	}
+
+	var err error
+
+	exported.props, err = prop.Export(b.conn, exported.path, prop.Map{intr: propMap})
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("%s: unable to export properties of %s: %w", b.busType.String(), path, err),
+			exported.Unexport())
+	}
+
+	node := &introspect.Node{
+		Name: path,
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+			{
+				Name:       intr,
+				Methods:    introspect.Methods(object),
+				Properties: exported.props.Introspection(intr),
+			},
+		},
+	}
+
+	if err := b.conn.Export(introspect.NewIntrospectable(node), exported.path, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return nil, errors.Join(fmt.Errorf("%s: unable to export introspection of %s: %w", b.busType.String(), path, err),
+			exported.Unexport())
+	}
+
+	return exported, nil
+}
+
+// SetProperty sets the value of the named property. If the value has changed,
+// a PropertiesChanged signal is emitted.
+func (o *ExportedObject) SetProperty(name string, value any) error {
+	current, dbusErr := o.props.Get(o.intr, name)
+	if dbusErr != nil {
+		return fmt.Errorf("%w: %s", ErrUnknownProperty, name)
+	}
+
+	if reflect.TypeOf(current.Value()) != reflect.TypeOf(value) {
+		return fmt.Errorf("%w: %s is %T, not %T", ErrPropertyType, name, current.Value(), value)
+	}
+
+	if reflect.DeepEqual(current.Value(), value) {
+		return nil
+	}
+
+	o.props.SetMust(o.intr, name, value)
+
+	return nil
+}
+
+// Unexport removes the object from the bus.
+func (o *ExportedObject) Unexport() error {
+	o.bus.traceLog("Unexporting object.", slog.String("path", string(o.path)), slog.String("interface", o.intr))
+
+	var err error
+
+	for _, intr := range []string{o.intr, "org.freedesktop.DBus.Properties", "org.freedesktop.DBus.Introspectable"} {
+		// Exporting nil removes any object exported with the interface.
+		if exportErr := o.bus.conn.Export(nil, o.path, intr); exportErr != nil {
+			err = errors.Join(err, exportErr)
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: unable to unexport %s: %w", o.bus.busType.String(), o.path, err)
+	}
+
+	return nil
+}
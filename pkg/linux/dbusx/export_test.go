@@ -0,0 +1,127 @@
+// Copyright (c) 2024 Joshua Rich <joshua.rich@gmail.com>
+//
+// This software is released under the MIT License.
+// https://opensource.org/licenses/MIT
+
+//nolint:paralleltest
+package dbusx
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startSessionBus starts a private session bus for the test. The test is
+// skipped if dbus-daemon is not installed.
+func startSessionBus(t *testing.T) {
+	t.Helper()
+
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("dbus-daemon not available")
+	}
+
+	cmd := exec.Command("dbus-daemon", "--session", "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill() //nolint:errcheck
+		_ = cmd.Wait()         //nolint:errcheck
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	require.NoError(t, err)
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", strings.TrimSpace(address))
+}
+
+type testObject struct {
+	greeting string
+}
+
+func (o *testObject) Greet(name string) (string, *dbus.Error) {
+	return o.greeting + " " + name, nil
+}
+
+func TestBus_Export(t *testing.T) {
+	startSessionBus(t)
+
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+
+	const (
+		name = "org.joshuar.GoHassAgent.Test"
+		path = "/org/joshuar/GoHassAgent/Test"
+	)
+
+	bus, err := NewBus(ctx, SessionBus)
+	require.NoError(t, err)
+	require.NoError(t, bus.RequestName(name))
+
+	exported, err := bus.Export(&testObject{greeting: "Hello"}, path, name, map[string]any{"Connected": false})
+	require.NoError(t, err)
+
+	client, err := NewBus(ctx, SessionBus)
+	require.NoError(t, err)
+
+	// Another connection cannot take the name.
+	require.ErrorIs(t, client.RequestName(name), ErrNameTaken)
+
+	// Methods can be called.
+	greeting, err := GetData[string](client, path, name, name+".Greet", "World")
+	require.NoError(t, err)
+	assert.Equal(t, "Hello World", greeting)
+
+	// Properties can be read, but not written, by other applications.
+	connected := NewProperty[bool](client, path, name, name+".Connected")
+	value, err := connected.Get()
+	require.NoError(t, err)
+	assert.False(t, value)
+	require.Error(t, connected.Set(true))
+
+	require.NoError(t, exported.SetProperty("Connected", true))
+	value, err = connected.Get()
+	require.NoError(t, err)
+	assert.True(t, value)
+
+	require.ErrorIs(t, exported.SetProperty("Connected", "yes"), ErrPropertyType)
+	require.ErrorIs(t, exported.SetProperty("Missing", true), ErrUnknownProperty)
+
+	// The object is introspectable.
+	node, err := introspect.Call(client.getObject(name, path))
+	require.NoError(t, err)
+
+	var intr *introspect.Interface
+
+	for i := range node.Interfaces {
+		if node.Interfaces[i].Name == name {
+			intr = &node.Interfaces[i]
+		}
+	}
+
+	require.NotNil(t, intr)
+	assert.Equal(t, "Greet", intr.Methods[0].Name)
+	assert.Equal(t, "Connected", intr.Properties[0].Name)
+
+	// Once unexported, methods can no longer be called.
+	require.NoError(t, exported.Unexport())
+
+	_, err = GetData[string](client, path, name, name+".Greet", "World")
+	require.Error(t, err)
+	require.NoError(t, bus.ReleaseName(name))
+}
+
+func TestBus_Export_invalidPath(t *testing.T) {
+	bus := &Bus{traceLog: func(_ string, _ ...any) {}}
+
+	_, err := bus.Export(&testObject{}, "not/a/path", "org.joshuar.GoHassAgent.Test", nil)
+	require.ErrorIs(t, err, ErrInvalidPath)
+}
@@ -196,10 +196,16 @@ func newChip(path string) (*Chip, error) {
 // are any errors in parsing chip or sensor values, it will return a non-nill
 // composite error as well.
 func GetAllChips() ([]*Chip, error) {
+	return GetAllChipsAt(HWMonPath)
+}
+
+// GetAllChipsAt is like GetAllChips, but reads the hwmon userspace API at the
+// given path instead of HWMonPath.
+func GetAllChipsAt(path string) ([]*Chip, error) {
 	// Get all the hwmon chips.
-	files, err := os.ReadDir(HWMonPath)
+	files, err := os.ReadDir(path)
 	if err != nil {
-		return nil, fmt.Errorf("could not read hwmon data at path %s: %w", HWMonPath, err)
+		return nil, fmt.Errorf("could not read hwmon data at path %s: %w", path, err)
 	}
 
 	chips := make([]*Chip, 0, len(files))
@@ -217,7 +223,7 @@ func GetAllChips() ([]*Chip, error) {
 			go func() {
 				defer wg.Done()
 
-				if chip, err := newChip(filepath.Join(HWMonPath, file.Name())); err != nil {
+				if chip, err := newChip(filepath.Join(path, file.Name())); err != nil {
 					slog.Debug("Could not process hwmon path.",
 						slog.String("path", file.Name()),
 						slog.Any("error", err))
@@ -411,9 +417,15 @@ func newSensor(file *sensorFile, chip *Chip) *Sensor {
 // chip sensors found on the host. If there were any errors in fetching chips or
 // chip sensors, it will also return a non-nill composite error.
 func GetAllSensors() ([]*Sensor, error) {
+	return GetAllSensorsAt(HWMonPath)
+}
+
+// GetAllSensorsAt is like GetAllSensors, but reads the hwmon userspace API at
+// the given path instead of HWMonPath.
+func GetAllSensorsAt(path string) ([]*Sensor, error) {
 	var sensors []*Sensor
 
-	chips, err := GetAllChips()
+	chips, err := GetAllChipsAt(path)
 	for _, chip := range chips {
 		sensors = append(sensors, chip.Sensors...)
 	}